@@ -4,9 +4,11 @@ import (
 	"CampusMonitorAPI/internal/models"
 	"context"
 	"encoding/json"
+	"flag"
 	"fmt"
 	"os"
 	"os/signal"
+	"runtime"
 	"strings"
 	"syscall"
 	"time"
@@ -15,15 +17,28 @@ import (
 	"CampusMonitorAPI/internal/database"
 	"CampusMonitorAPI/internal/handler"
 	"CampusMonitorAPI/internal/logger"
+	"CampusMonitorAPI/internal/metrics"
 	"CampusMonitorAPI/internal/mqtt"
 	"CampusMonitorAPI/internal/repository"
 	"CampusMonitorAPI/internal/server"
 	"CampusMonitorAPI/internal/service"
+	"CampusMonitorAPI/internal/websocket"
 
 	"golang.org/x/oauth2"
 )
 
+// Set by -ldflags "-X main.version=... -X main.commit=... -X main.date=...",
+// see .goreleaser.yml.
+var (
+	version = "dev"
+	commit  = "none"
+	date    = "unknown"
+)
+
 func main() {
+	migrateOnly := flag.Bool("migrate-only", false, "apply pending database migrations and exit")
+	flag.Parse()
+
 	// 1. Load Config
 	cfg, err := config.Load()
 	if err != nil {
@@ -35,8 +50,14 @@ func main() {
 	log, err := logger.New(logger.Config{
 		Level:       cfg.Logging.Level,
 		Mode:        cfg.Logging.Mode,
+		Format:      cfg.Logging.Format,
 		LogFilePath: cfg.Logging.FilePath,
 		UseColors:   cfg.Logging.UseColors,
+		MaxSizeMB:   cfg.Logging.MaxSizeMB,
+		MaxBackups:  cfg.Logging.MaxBackups,
+		Async:       cfg.Logging.Async,
+		BufferSize:  cfg.Logging.BufferSize,
+		RedactKeys:  cfg.Logging.RedactKeys,
 	})
 	if err != nil {
 		panic("Failed to initialize logger: " + err.Error())
@@ -46,38 +67,67 @@ func main() {
 	if err := cfg.Validate(); err != nil {
 		log.Fatal("Configuration validation failed: %v", err)
 	}
+	// cfg is not modified after this point; settings changed at runtime are
+	// published as new snapshots through liveCfg.
+	liveCfg := config.NewLive(cfg)
+
+	buildInfo := models.VersionInfo{
+		Version:   version,
+		Commit:    commit,
+		BuildTime: date,
+		GoVersion: runtime.Version(),
+	}
 
 	cfg.Print()
-	log.Info("Starting Campus Monitor API Server")
+	log.Info("Starting Campus Monitor API Server %s (commit %s, built %s, %s)",
+		buildInfo.Version, buildInfo.Commit, buildInfo.BuildTime, buildInfo.GoVersion)
 
 	// 3. Database Connection
-	db, err := database.New(&cfg.Database)
+	db, err := database.New(&cfg.Database, log)
 	if err != nil {
 		log.Fatal("Failed to connect to database: %v", err)
 	}
 	defer db.Close()
 
 	log.Info("Database connected successfully")
+	if db.Replica != nil {
+		log.Info("Using read replica at %s:%d for analytics", cfg.Database.ReplicaHost, cfg.Database.ReplicaPort)
+	}
 
 	ctx := context.Background()
 	if err := db.Health(ctx); err != nil {
 		log.Fatal("Database health check failed: %v", err)
 	}
 
+	applied, err := db.Migrate(ctx)
+	for _, name := range applied {
+		log.Info("Applied migration %s", name)
+	}
+	if err != nil {
+		log.Fatal("Database migration failed: %v", err)
+	}
+	if *migrateOnly {
+		log.Info("Migrations complete (%d applied), exiting", len(applied))
+		return
+	}
+
 	// 4. Initialize Repositories
 	probeRepo := repository.NewProbeRepository(db.DB)
 	telemetryRepo := repository.NewTelemetryRepository(db.DB)
 	commandRepo := repository.NewCommandRepository(db.DB)
 	alertRepo := repository.NewAlertRepository(db.DB)
-	analyticsRepo := repository.NewAnalyticsRepository(db.DB)
+	// Read-only aggregations go to the replica when one is configured.
+	analyticsRepo := repository.NewAnalyticsRepository(db.Reader(), cfg.Thresholds.ProbeActive, cfg.Analytics.DefaultReportInterval)
 	fleetRepo := repository.NewFleetRepository(db.DB)
 	scheduleRepo := repository.NewScheduleRepository(db.DB)
+	reportScheduleRepo := repository.NewReportScheduleRepository(db.DB)
 	userRepo := repository.NewUserRepository(db.DB)
 	oauthAccountRepo := repository.NewOAuthAccountRepository(db.DB)
 	totpRepo := repository.NewTOTPRepository(db.DB)
 	refreshTokenRepo := repository.NewRefreshTokenRepository(db.DB)
 	oauthStateRepo := repository.NewOAuthStateRepository(db.DB)
-	reportRepo := repository.NewReportRepository(alertRepo, telemetryRepo, probeRepo, commandRepo, fleetRepo, analyticsRepo, db.DB)
+	auditRepo := repository.NewAuditRepository(db.DB)
+	reportRepo := repository.NewReportRepository(alertRepo, telemetryRepo, probeRepo, commandRepo, fleetRepo, analyticsRepo, db.Reader())
 
 	oauthConfigs := make(map[string]*oauth2.Config)
 	for provider, pcfg := range cfg.Auth.OAuthProviders {
@@ -111,12 +161,15 @@ func main() {
 	if err := mqttClient.Connect(); err != nil {
 		log.Fatal("Failed to connect to MQTT broker: %v", err)
 	}
-	alertService := service.NewAlertService(alertRepo, srv.GetHub())
-	alertEvaluator := service.NewAlertEvaluator(models.DEFAULT_ALERT_CONFIG, alertService)
+	alertService := service.NewAlertService(alertRepo, probeRepo, srv.GetHub())
+	alertService.EnableAlertCoalescing(cfg.WebSocket.AlertCoalesceWindow)
+	alertEvaluator := service.NewAlertEvaluator(cfg.Alerts.Model(), alertService)
 	scheduleService := service.NewScheduleService(scheduleRepo, probeRepo, mqttClient, log)
-	telemetryService := service.NewTelemetryService(telemetryRepo, probeRepo, alertEvaluator, log)
-	probeService := service.NewProbeService(probeRepo, log)
-	analyticsService := service.NewAnalyticsService(analyticsRepo, log)
+	telemetryService := service.NewTelemetryService(telemetryRepo, probeRepo, alertEvaluator, srv.GetHub(), log)
+	telemetryService.EnableWriteBuffer(cfg.Telemetry.WriteBufferInterval, cfg.Telemetry.WriteBufferSize)
+	telemetryService.EnableAnomalyFlagging(cfg.Telemetry.AnomalyThreshold, cfg.Telemetry.AnomalyBroadcast)
+	probeService := service.NewProbeService(probeRepo, telemetryRepo, log)
+	analyticsService := service.NewAnalyticsService(analyticsRepo, alertRepo, probeRepo, log)
 	ldapService := service.NewLDAPService(&cfg.Auth.LdapConfig, log)
 	authService := service.NewAuthService(
 		userRepo, oauthAccountRepo, totpRepo, refreshTokenRepo, oauthStateRepo,
@@ -131,9 +184,11 @@ func main() {
 		mqttClient,
 		log,
 	)
-	commandService := service.NewCommandService(commandRepo, mqttClient, probeRepo, telemetryService, fleetService, scheduleService, log)
-	topologyService := service.NewTopologyService(probeRepo, telemetryRepo, alertRepo)
+	commandService := service.NewCommandService(commandRepo, mqttClient, probeRepo, alertRepo, telemetryService, fleetService, scheduleService, cfg.Thresholds.Model(), log)
+	commandService.SetRateLimit(cfg.Commands.RateLimitPerMinute, cfg.Commands.RateLimitBurst, cfg.Commands.TypeRateLimits)
+	topologyService := service.NewTopologyService(probeRepo, telemetryRepo, alertRepo, cfg.Thresholds.Model())
 	reportService := service.NewReportService(reportRepo)
+	auditService := service.NewAuditService(auditRepo)
 
 	// MQTT Subscriptions
 	// Telemetry
@@ -160,16 +215,27 @@ func main() {
 	log.Info("MQTT subscriptions active")
 
 	log.Info("Started background monitors")
-	probeMonitor := service.NewProbeMonitor(mqttClient, probeRepo, log)
+	probeMonitor := service.NewProbeMonitor(mqttClient, probeRepo, srv.GetHub(), cfg.Thresholds.Model(), log)
 	probeMonitor.Start()
 
+	healthBroadcaster := service.NewHealthBroadcaster(analyticsService, srv.GetHub(), cfg.WebSocket.HealthBroadcastInterval, log)
+	healthBroadcaster.Start()
+
+	cleanupWorker := service.NewCleanupWorker(commandService, alertService, cfg.Commands.CleanupInterval, cfg.Commands.RetentionDays, log)
+	cleanupWorker.Start()
+
+	reportScheduler := service.NewReportScheduler(reportScheduleRepo, analyticsService, cfg.Analytics.ReportScheduleInterval, cfg.Analytics.ReportWebhookAllowedNetworks, log)
+	reportScheduler.Start()
+
+	registerMetrics(srv.Metrics(), db, mqttClient, srv.GetHub(), alertService, log)
+
 	// 8. Initialize Handlers
 	probeHandler := handler.NewProbeHandler(probeService, commandService, probeMonitor, log)
 	telemetryHandler := handler.NewTelemetryHandler(telemetryService, log)
 	commandHandler := handler.NewCommandHandler(commandService, log)
 	analyticsHandler := handler.NewAnalyticsHandler(analyticsService, log)
-	healthHandler := handler.NewHealthHandler(db, mqttClient, log)
-	alertHandler := handler.NewAlertHandler(alertService, log)
+	healthHandler := handler.NewHealthHandler(db, mqttClient, srv.GetHub(), probeService, log)
+	alertHandler := handler.NewAlertHandler(alertService, alertEvaluator, log)
 	topologyHandler := handler.NewTopologyHandler(topologyService, log)
 	authHandler := handler.NewAuthHandler(authService, log)
 	fleetHandler := handler.NewFleetHandler(
@@ -179,7 +245,11 @@ func main() {
 		log,
 	)
 	reportHandler := handler.NewReportHandler(reportService, log)
+	reportScheduleHandler := handler.NewReportScheduleHandler(reportScheduler, log)
 	scheduleHandler := handler.NewScheduleHandler(scheduleService, log)
+	configHandler := handler.NewConfigHandler(liveCfg, log)
+	versionHandler := handler.NewVersionHandler(buildInfo)
+	auditHandler := handler.NewAuditHandler(auditService, log)
 
 	srv.RegisterHandlers(
 		probeHandler,
@@ -193,32 +263,127 @@ func main() {
 		scheduleHandler,
 		authHandler,
 		reportHandler,
+		reportScheduleHandler,
+		configHandler,
+		versionHandler,
+		auditHandler,
+		auditService,
 	)
 	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
 	defer stop()
+
+	go watchReload(ctx, liveCfg, log, srv, alertEvaluator, commandService)
+
+	// Start blocks until SIGINT/SIGTERM, then stops the HTTP server.
 	if err := srv.Start(ctx); err != nil {
 		log.Fatal("Server failed: %v", err)
 	}
 
-	log.Info("API server ready")
-
 	// 10. Graceful Shutdown
-	quit := make(chan os.Signal, 1)
-	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
-	<-quit
-
-	log.Warn("Shutdown signal received")
-	probeMonitor.Shutdown()
-	shutdownCtx, cancel := context.WithTimeout(context.Background(), cfg.Server.ShutdownTimeout)
-	defer cancel()
-
-	if err := srv.Shutdown(shutdownCtx); err != nil {
-		log.Error("Server shutdown error: %v", err)
+	log.Warn("Shutdown signal received, draining in-flight work")
+	if err := mqttClient.Drain(cfg.Server.ShutdownTimeout); err != nil {
+		log.Warn("MQTT drain incomplete: %v", err)
 	}
+	telemetryService.Shutdown()
+	probeMonitor.Shutdown()
+	healthBroadcaster.Shutdown()
+	cleanupWorker.Shutdown()
+	reportScheduler.Shutdown()
+	log.Flush()
 
 	log.Info("Shutdown complete")
 }
 
+// registerMetrics exposes gauges for the shared resources that the HTTP
+// middleware can't see. They are computed on each scrape.
+func registerMetrics(reg *metrics.Registry, db *database.Database, mqttClient *mqtt.Client, hub *websocket.Hub, alertService *service.AlertService, log *logger.Logger) {
+	reg.NewGaugeFunc("db_open_connections", "Established database connections, in use and idle.", func() float64 {
+		return float64(db.Stats().OpenConnections)
+	})
+	reg.NewGaugeFunc("db_in_use_connections", "Database connections currently in use.", func() float64 {
+		return float64(db.Stats().InUse)
+	})
+	reg.NewGaugeFunc("db_idle_connections", "Idle database connections.", func() float64 {
+		return float64(db.Stats().Idle)
+	})
+	reg.NewGaugeFunc("db_wait_count", "Total number of waits for a database connection.", func() float64 {
+		return float64(db.Stats().WaitCount)
+	})
+	reg.NewGaugeFunc("mqtt_connected", "1 if the MQTT client is connected to the broker.", func() float64 {
+		if mqttClient.IsConnected() {
+			return 1
+		}
+		return 0
+	})
+	reg.NewGaugeFunc("websocket_clients", "Connected WebSocket clients.", func() float64 {
+		return float64(hub.ClientCount())
+	})
+	reg.NewLabeledGaugeFunc("alerts_active", "Unresolved alerts by severity.", "severity", func() map[string]float64 {
+		ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+		defer cancel()
+
+		stats, err := alertService.GetStatistics(ctx)
+		if err != nil {
+			log.Error("Failed to count active alerts for metrics: %v", err)
+			return nil
+		}
+
+		out := make(map[string]float64, len(stats))
+		for severity, count := range stats {
+			out[severity] = float64(count)
+		}
+		return out
+	})
+}
+
+// watchReload re-applies the runtime-safe subset of the configuration each
+// time the process receives SIGHUP and publishes it through live.
+func watchReload(ctx context.Context, live *config.Live, log *logger.Logger, srv *server.Server, alertEvaluator *service.AlertEvaluator, commandService *service.CommandService) {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	defer signal.Stop(hup)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-hup:
+			log.Info("SIGHUP received, reloading configuration")
+
+			next, err := config.Reload()
+			if err != nil {
+				log.Error("Config reload failed: %v", err)
+				continue
+			}
+			if err := next.Validate(); err != nil {
+				log.Error("Reloaded configuration is invalid, keeping current settings: %v", err)
+				continue
+			}
+
+			current := live.Get()
+			for _, field := range current.RestartRequired(next) {
+				log.Warn("Config change to %s requires restart, ignoring", field)
+			}
+
+			log.SetLevel(next.Logging.Level)
+			log.SetMode(next.Logging.Mode)
+			log.SetFormat(next.Logging.Format)
+			srv.UpdateRateLimit(next.Security.RateLimitPerMinute, next.Security.RateLimitBurst, next.Security.APIKeyRateLimits)
+			srv.UpdateAPIKeys(next.Security.APIKeys)
+			commandService.SetRateLimit(next.Commands.RateLimitPerMinute, next.Commands.RateLimitBurst, next.Commands.TypeRateLimits)
+			if next.Alerts != current.Alerts {
+				alertEvaluator.UpdateConfig(next.Alerts.Model())
+			}
+
+			live.Update(func(cfg *config.Config) {
+				cfg.ApplyReloadable(next)
+			})
+
+			log.Info("Configuration reloaded")
+		}
+	}
+}
+
 func handleTelemetry(service *service.TelemetryService, log *logger.Logger) mqtt.MessageHandler {
 	return func(topic string, payload []byte) error {
 		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
@@ -339,8 +504,7 @@ func handleFleetStatus(probeService *service.ProbeService, fleetService *service
 			}
 		}
 
-		log.Debug("Fleet status processed for %s: fw=%s",
-			probeID, status)
+		log.Debug("Fleet status processed for %s: %s", probeID, log.Redact(payload))
 
 		return nil
 	}
@@ -15,6 +15,7 @@ require (
 	github.com/pquerna/otp v1.5.0
 	golang.org/x/crypto v0.49.0
 	golang.org/x/oauth2 v0.36.0
+	gopkg.in/yaml.v3 v3.0.1
 )
 
 require (
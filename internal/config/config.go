@@ -1,116 +1,264 @@
 package config
 
 import (
+	"errors"
 	"fmt"
+	"io/fs"
 	"log"
+	"net"
 	"os"
+	"reflect"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"CampusMonitorAPI/internal/logger"
+	"CampusMonitorAPI/internal/models"
 
 	"github.com/joho/godotenv"
 )
 
 type Config struct {
-	Server   ServerConfig
-	Database DatabaseConfig
-	MQTT     MQTTConfig
-	Security SecurityConfig
-	Logging  LoggingConfig
-	Auth     AuthConfig
+	Server     ServerConfig    `yaml:"server"`
+	Database   DatabaseConfig  `yaml:"database"`
+	MQTT       MQTTConfig      `yaml:"mqtt"`
+	Security   SecurityConfig  `yaml:"security"`
+	Logging    LoggingConfig   `yaml:"logging"`
+	Auth       AuthConfig      `yaml:"auth"`
+	WebSocket  WebSocketConfig `yaml:"websocket"`
+	Alerts     AlertConfig     `yaml:"alerts"`
+	Commands   CommandConfig   `yaml:"commands"`
+	Analytics  AnalyticsConfig `yaml:"analytics"`
+	Telemetry  TelemetryConfig `yaml:"telemetry"`
+	Thresholds ThresholdConfig `yaml:"thresholds"`
 }
 type AuthConfig struct {
-	LdapConfig              LDAPConfig
-	OAuthProviders          map[string]OAuthProviderConfig
-	JWTSecret               string
-	FrontendURL             string
-	JWTExpiry               time.Duration
-	RefreshTokenExpiry      time.Duration
-	EnableLocalLogin        bool
-	EnableRegistration      bool
-	Require2FA              bool
-	EnableAdminRegistration bool
+	LdapConfig              LDAPConfig                     `yaml:"ldap"`
+	OAuthProviders          map[string]OAuthProviderConfig `yaml:"oauth_providers"`
+	JWTSecret               string                         `yaml:"jwt_secret" env:"JWT_SECRET"`
+	FrontendURL             string                         `yaml:"frontend_url" env:"FRONTEND_URL"`
+	JWTExpiry               time.Duration                  `yaml:"jwt_expiry" env:"JWT_EXPIRY"`
+	RefreshTokenExpiry      time.Duration                  `yaml:"refresh_token_expiry" env:"REFRESH_TOKEN_EXPIRY"`
+	EnableLocalLogin        bool                           `yaml:"enable_local_login" env:"ENABLE_LOCAL_LOGIN"`
+	EnableRegistration      bool                           `yaml:"enable_registration" env:"ENABLE_REGISTRATION"`
+	Require2FA              bool                           `yaml:"require_2fa" env:"REQUIRE_2FA"`
+	EnableAdminRegistration bool                           `yaml:"enable_admin_registration" env:"ENABLE_ADMIN_REGISTRATION"`
 }
 
 type OAuthProviderConfig struct {
-	Scopes       []string
-	ClientID     string
-	ClientSecret string
-	AuthURL      string
-	TokenURL     string
-	UserInfoURL  string
+	Scopes       []string `yaml:"scopes"`
+	ClientID     string   `yaml:"client_id"`
+	ClientSecret string   `yaml:"client_secret"`
+	AuthURL      string   `yaml:"auth_url"`
+	TokenURL     string   `yaml:"token_url"`
+	UserInfoURL  string   `yaml:"userinfo_url"`
 }
 
 type ServerConfig struct {
-	ShutdownTimeout time.Duration
-	ReadTimeout     time.Duration
-	WriteTimeout    time.Duration
-	Host            string
-	PublicURL       string
-	CertDir         string
-	Environment     string
-	Port            int
-	MaxHeaderBytes  int
+	ShutdownTimeout time.Duration `yaml:"shutdown_timeout" env:"SHUTDOWN_TIMEOUT"`
+	ReadTimeout     time.Duration `yaml:"read_timeout" env:"READ_TIMEOUT"`
+	WriteTimeout    time.Duration `yaml:"write_timeout" env:"WRITE_TIMEOUT"`
+	RequestTimeout  time.Duration `yaml:"request_timeout" env:"REQUEST_TIMEOUT"`
+	Host            string        `yaml:"host" env:"SERVER_HOST"`
+	PublicURL       string        `yaml:"public_url" env:"PUBLIC_URL"`
+	CertDir         string        `yaml:"cert_dir" env:"CERT_DIR"`
+	Environment     string        `yaml:"environment" env:"ENVIRONMENT"`
+	Port            int           `yaml:"port" env:"SERVER_PORT"`
+	MaxHeaderBytes  int           `yaml:"max_header_bytes" env:"MAX_HEADER_BYTES"`
+	// MaxBodyBytes caps API request bodies; MaxBulkBodyBytes applies instead
+	// to bulk endpoints that take many items at once. 0 disables either.
+	MaxBodyBytes     int64 `yaml:"max_body_bytes" env:"MAX_BODY_BYTES"`
+	MaxBulkBodyBytes int64 `yaml:"max_bulk_body_bytes" env:"MAX_BULK_BODY_BYTES"`
+	EnableGzip       bool  `yaml:"enable_gzip" env:"ENABLE_GZIP"`
+	GzipMinSize      int   `yaml:"gzip_min_size" env:"GZIP_MIN_SIZE"`
+	EnableMetrics    bool  `yaml:"enable_metrics" env:"ENABLE_METRICS"`
+	// AnalyticsCacheTTL is how long analytics GET responses are reused; 0 disables.
+	AnalyticsCacheTTL  time.Duration `yaml:"analytics_cache_ttl" env:"ANALYTICS_CACHE_TTL"`
+	AnalyticsCacheSize int           `yaml:"analytics_cache_size" env:"ANALYTICS_CACHE_SIZE"`
 }
 
 type DatabaseConfig struct {
-	Host            string
-	User            string
-	Password        string
-	Database        string
-	SSLMode         string
-	ConnMaxLifetime time.Duration
-	ConnMaxIdleTime time.Duration
-	Port            int
-	MaxOpenConns    int
-	MaxIdleConns    int
+	Host            string        `yaml:"host" env:"DB_HOST"`
+	User            string        `yaml:"user" env:"DB_USER"`
+	Password        string        `yaml:"password" env:"DB_PASSWORD"`
+	Database        string        `yaml:"name" env:"DB_NAME"`
+	SSLMode         string        `yaml:"ssl_mode" env:"DB_SSL_MODE"`
+	ConnMaxLifetime time.Duration `yaml:"conn_max_lifetime" env:"DB_CONN_MAX_LIFETIME"`
+	ConnMaxIdleTime time.Duration `yaml:"conn_max_idle_time" env:"DB_CONN_MAX_IDLE_TIME"`
+	Port            int           `yaml:"port" env:"DB_PORT"`
+	MaxOpenConns    int           `yaml:"max_open_conns" env:"DB_MAX_OPEN_CONNS"`
+	MaxIdleConns    int           `yaml:"max_idle_conns" env:"DB_MAX_IDLE_CONNS"`
+	// ReplicaHost points analytics reads at a streaming replica that shares
+	// the primary's credentials. Empty means read from the primary.
+	ReplicaHost string `yaml:"replica_host" env:"DB_REPLICA_HOST"`
+	ReplicaPort int    `yaml:"replica_port" env:"DB_REPLICA_PORT"`
+	// SlowQueryThreshold logs statements that take at least this long; 0 disables.
+	SlowQueryThreshold time.Duration `yaml:"slow_query_threshold" env:"DB_SLOW_QUERY_THRESHOLD"`
 }
 
 type MQTTConfig struct {
-	Broker         string
-	ClientID       string
-	Username       string
-	Password       string
-	TelemetryTopic string
-	CommandTopic   string
-	KeepAlive      time.Duration
-	ConnectTimeout time.Duration
-	Port           int
-	QoS            byte
-	RetainMessages bool
-	AutoReconnect  bool
+	Broker         string        `yaml:"broker" env:"MQTT_BROKER"`
+	ClientID       string        `yaml:"client_id" env:"MQTT_CLIENT_ID"`
+	Username       string        `yaml:"username" env:"MQTT_USERNAME"`
+	Password       string        `yaml:"password" env:"MQTT_PASSWORD"`
+	TelemetryTopic string        `yaml:"telemetry_topic" env:"MQTT_TELEMETRY_TOPIC"`
+	CommandTopic   string        `yaml:"command_topic" env:"MQTT_COMMAND_TOPIC"`
+	KeepAlive      time.Duration `yaml:"keep_alive" env:"MQTT_KEEP_ALIVE"`
+	ConnectTimeout time.Duration `yaml:"connect_timeout" env:"MQTT_CONNECT_TIMEOUT"`
+	Port           int           `yaml:"port" env:"MQTT_PORT"`
+	QoS            byte          `yaml:"qos" env:"MQTT_QOS"`
+	RetainMessages bool          `yaml:"retain" env:"MQTT_RETAIN"`
+	AutoReconnect  bool          `yaml:"auto_reconnect" env:"MQTT_AUTO_RECONNECT"`
+	// ReadinessMaxIdle fails readiness, and marks /health degraded, when no
+	// message has arrived for this long; 0 disables the check.
+	ReadinessMaxIdle time.Duration `yaml:"readiness_max_idle" env:"MQTT_READINESS_MAX_IDLE"`
+	// StatusTopic, when set, receives a retained online/degraded message
+	// after every (re)connect saying whether all subscriptions came back.
+	StatusTopic string `yaml:"status_topic" env:"MQTT_STATUS_TOPIC"`
 }
 type LDAPConfig struct {
-	Enabled            bool
-	Host               string
-	Port               int
-	BaseDN             string
-	BindDN             string
-	BindPassword       string
-	UserSearchBase     string
-	UserSearchFilter   string
-	TLSCertFile        string
-	InsecureSkipVerify bool
-	FallbackToLocal    bool
+	Enabled            bool   `yaml:"enabled" env:"LDAP_ENABLED"`
+	Host               string `yaml:"host" env:"LDAP_HOST"`
+	Port               int    `yaml:"port" env:"LDAP_PORT"`
+	BaseDN             string `yaml:"base_dn" env:"LDAP_BASE_DN"`
+	BindDN             string `yaml:"bind_dn" env:"LDAP_BIND_DN"`
+	BindPassword       string `yaml:"bind_password" env:"LDAP_BIND_PASSWORD"`
+	UserSearchBase     string `yaml:"user_search_base" env:"LDAP_USER_SEARCH_BASE"`
+	UserSearchFilter   string `yaml:"user_search_filter" env:"LDAP_USER_SEARCH_FILTER"`
+	TLSCertFile        string `yaml:"tls_cert_file" env:"LDAP_TLS_CERT_FILE"`
+	InsecureSkipVerify bool   `yaml:"insecure_skip_verify" env:"LDAP_INSECURE_SKIP_VERIFY"`
+	FallbackToLocal    bool   `yaml:"fallback_to_local" env:"LDAP_FALLBACK_TO_LOCAL"`
 }
 
 type SecurityConfig struct {
-	CORSAllowedOrigins []string
-	CORSAllowedMethods []string
-	JWTSecret          string
-	APIKeyHeader       string
-	JWTExpirationHours int
-	RateLimitPerMinute int
-	EnableRateLimit    bool
+	CORSAllowedOrigins []string `yaml:"cors_allowed_origins" env:"CORS_ALLOWED_ORIGINS"`
+	CORSAllowedMethods []string `yaml:"cors_allowed_methods" env:"CORS_ALLOWED_METHODS"`
+	// CORSAllowCredentials sends Access-Control-Allow-Credentials, which
+	// requires explicit origins rather than "*".
+	CORSAllowCredentials bool `yaml:"cors_allow_credentials" env:"CORS_ALLOW_CREDENTIALS"`
+	// CORSSensitiveOrigins narrows the origins allowed on command and config
+	// routes. Empty means the same as CORSAllowedOrigins.
+	CORSSensitiveOrigins []string `yaml:"cors_sensitive_origins" env:"CORS_SENSITIVE_ORIGINS"`
+	APIKeyHeader         string   `yaml:"api_key_header" env:"API_KEY_HEADER"`
+	// APIKeys maps a label, used in audit logs, to a static API key.
+	APIKeys            map[string]string `yaml:"api_keys" env:"API_KEYS"`
+	JWTExpirationHours int               `yaml:"jwt_expiration_hours" env:"JWT_EXPIRATION_HOURS"`
+	RateLimitPerMinute int               `yaml:"rate_limit_per_minute" env:"RATE_LIMIT_PER_MINUTE"`
+	RateLimitBurst     int               `yaml:"rate_limit_burst" env:"RATE_LIMIT_BURST"`
+	// APIKeyRateLimits overrides RateLimitPerMinute for individual API key labels.
+	APIKeyRateLimits map[string]int `yaml:"api_key_rate_limits" env:"API_KEY_RATE_LIMITS"`
+	EnableRateLimit  bool           `yaml:"enable_rate_limit" env:"ENABLE_RATE_LIMIT"`
+	// TrustedProxies lists the CIDRs or IPs allowed to set X-Forwarded-For.
+	TrustedProxies []string `yaml:"trusted_proxies" env:"TRUSTED_PROXIES"`
+	// EnableSecurityHeaders can be turned off in development, e.g. to embed
+	// responses in a local tool.
+	EnableSecurityHeaders bool   `yaml:"enable_security_headers" env:"ENABLE_SECURITY_HEADERS"`
+	ContentSecurityPolicy string `yaml:"content_security_policy" env:"CONTENT_SECURITY_POLICY"`
+	// EnableAuditLog records every mutating API request in the audit_log table.
+	EnableAuditLog bool `yaml:"enable_audit_log" env:"ENABLE_AUDIT_LOG"`
+}
+
+type WebSocketConfig struct {
+	HealthBroadcastInterval time.Duration `yaml:"health_broadcast_interval" env:"WS_HEALTH_BROADCAST_INTERVAL"`
+	// BatchWindow coalesces broadcasts sent within the window into one
+	// write per client (0 sends every message on its own).
+	BatchWindow time.Duration `yaml:"batch_window" env:"WS_BATCH_WINDOW"`
+	// MaxMessageSize caps client messages in bytes; SendBuffer is how many
+	// outgoing messages queue before a slow client is dropped; ReadBuffer and
+	// WriteBuffer size the connection buffers in bytes.
+	MaxMessageSize int64 `yaml:"max_message_size" env:"WS_MAX_MESSAGE_SIZE"`
+	SendBuffer     int   `yaml:"send_buffer" env:"WS_SEND_BUFFER"`
+	ReadBuffer     int   `yaml:"read_buffer" env:"WS_READ_BUFFER"`
+	WriteBuffer    int   `yaml:"write_buffer" env:"WS_WRITE_BUFFER"`
+	// BroadcastBuffer is how many broadcasts queue for the hub before
+	// publishers such as alert dispatch block.
+	BroadcastBuffer int `yaml:"broadcast_buffer" env:"WS_BROADCAST_BUFFER"`
+	// AlertCoalesceWindow gathers non-critical alerts for a probe that
+	// follow its first one within the window into one ALERT_SUMMARY message
+	// (0 sends every alert on its own).
+	AlertCoalesceWindow time.Duration `yaml:"alert_coalesce_window" env:"WS_ALERT_COALESCE_WINDOW"`
+}
+
+// CommandConfig limits how fast commands can be sent to a single probe.
+type CommandConfig struct {
+	RateLimitPerMinute int `yaml:"rate_limit_per_minute" env:"COMMAND_RATE_LIMIT_PER_MINUTE"`
+	RateLimitBurst     int `yaml:"rate_limit_burst" env:"COMMAND_RATE_LIMIT_BURST"`
+	// TypeRateLimits overrides RateLimitPerMinute for individual command types.
+	TypeRateLimits map[string]int `yaml:"type_rate_limits" env:"COMMAND_TYPE_RATE_LIMITS"`
+	// Completed and failed commands older than RetentionDays are deleted
+	// every CleanupInterval (0 disables the cleanup).
+	RetentionDays   int           `yaml:"retention_days" env:"COMMAND_RETENTION_DAYS"`
+	CleanupInterval time.Duration `yaml:"cleanup_interval" env:"COMMAND_CLEANUP_INTERVAL"`
+}
+
+// AnalyticsConfig tunes the analytics queries.
+type AnalyticsConfig struct {
+	// ReportScheduleInterval is how often due report schedules are run
+	// (0 disables scheduled reports).
+	ReportScheduleInterval time.Duration `yaml:"report_schedule_interval" env:"REPORT_SCHEDULE_INTERVAL"`
+	// ReportWebhookAllowedNetworks lists IPs or CIDRs that report webhooks
+	// may target even though they are private, loopback or link-local.
+	ReportWebhookAllowedNetworks []string `yaml:"report_webhook_allowed_networks" env:"REPORT_WEBHOOK_ALLOWED_NETWORKS"`
+	// DefaultReportInterval is the report interval assumed for probes that
+	// have not confirmed their own, when computing uptime.
+	DefaultReportInterval time.Duration `yaml:"default_report_interval" env:"ANALYTICS_DEFAULT_REPORT_INTERVAL"`
+}
+
+// TelemetryConfig controls how incoming telemetry is written.
+type TelemetryConfig struct {
+	// WriteBufferInterval batches inserts, flushing every interval or once
+	// WriteBufferSize readings are waiting (0 inserts each message at once).
+	WriteBufferInterval time.Duration `yaml:"write_buffer_interval" env:"TELEMETRY_WRITE_BUFFER_INTERVAL"`
+	WriteBufferSize     int           `yaml:"write_buffer_size" env:"TELEMETRY_WRITE_BUFFER_SIZE"`
+	// AnomalyThreshold flags readings this many standard deviations from
+	// the probe's running baseline (0 disables).
+	AnomalyThreshold float64 `yaml:"anomaly_threshold" env:"TELEMETRY_ANOMALY_THRESHOLD"`
+	// AnomalyBroadcast sends a TELEMETRY_ANOMALY WebSocket message for
+	// flagged readings.
+	AnomalyBroadcast bool `yaml:"anomaly_broadcast" env:"TELEMETRY_ANOMALY_BROADCAST"`
+}
+
+// ThresholdConfig gathers the staleness windows that decide when a probe or
+// its data counts as current, so the map, health and command views agree.
+type ThresholdConfig struct {
+	// ProbeActive is how recently a probe must have reported to count as
+	// active in the network health summary. Keep it comfortably above the
+	// probes' report interval.
+	ProbeActive time.Duration `yaml:"probe_active" env:"PROBE_ACTIVE_WINDOW"`
+	// ProbeOffline marks a probe offline in the live ping status once it has
+	// been silent this long.
+	ProbeOffline time.Duration `yaml:"probe_offline" env:"PROBE_OFFLINE_THRESHOLD"`
+	// StatusRetention drops a probe's cached status and config broadcasts
+	// once they are this old.
+	StatusRetention time.Duration `yaml:"status_retention" env:"PROBE_STATUS_RETENTION"`
+	// TelemetryStale leaves readings older than this out of floor health on
+	// the map.
+	TelemetryStale time.Duration `yaml:"telemetry_stale" env:"TELEMETRY_STALE_THRESHOLD"`
+	// CommandPing is how recently a probe must have been seen for a command
+	// to be sent without pinging it first.
+	CommandPing time.Duration `yaml:"command_ping" env:"COMMAND_PING_THRESHOLD"`
+}
+
+type AlertConfig struct {
+	RSSIThreshold    float64 `yaml:"rssi_threshold" env:"ALERT_RSSI_THRESHOLD"`
+	RSSIOccurrences  int     `yaml:"rssi_occurrences" env:"ALERT_RSSI_OCCURRENCES"`
+	LatencyThreshold float64 `yaml:"latency_threshold" env:"ALERT_LATENCY_THRESHOLD"`
+	LatencyWindow    int     `yaml:"latency_window" env:"ALERT_LATENCY_WINDOW"`
+	HeartbeatTimeout int     `yaml:"heartbeat_timeout" env:"ALERT_HEARTBEAT_TIMEOUT"`
 }
 
 type LoggingConfig struct {
-	FilePath  string
-	Level     logger.Level
-	Mode      logger.Mode
-	UseColors bool
+	FilePath   string        `yaml:"file_path" env:"LOG_FILE_PATH"`
+	Level      logger.Level  `yaml:"level" env:"LOG_LEVEL"`
+	Mode       logger.Mode   `yaml:"mode" env:"LOG_MODE"`
+	Format     logger.Format `yaml:"format" env:"LOG_FORMAT"`
+	UseColors  bool          `yaml:"use_colors" env:"LOG_USE_COLORS"`
+	MaxSizeMB  int           `yaml:"max_size_mb" env:"LOG_MAX_SIZE_MB"`
+	MaxBackups int           `yaml:"max_backups" env:"LOG_MAX_BACKUPS"`
+	Async      bool          `yaml:"async" env:"LOG_ASYNC"`
+	BufferSize int           `yaml:"buffer_size" env:"LOG_BUFFER_SIZE"`
+	RedactKeys []string      `yaml:"redact_keys" env:"LOG_REDACT_KEYS"`
 }
 
 var requiredEnvVars = []string{
@@ -124,20 +272,102 @@ var requiredEnvVars = []string{
 }
 
 func Load() (*Config, error) {
+	return load(applyEnvFile)
+}
+
+// Reload re-reads the env file so edits take effect on SIGHUP. Precedence
+// is the same as for Load: variables set in the process environment before
+// the first load still win over the file.
+func Reload() (*Config, error) {
+	return load(applyEnvFile)
+}
+
+var (
+	envMu sync.Mutex
+	// externalEnv names the variables set before the env file was first
+	// read; fileEnv those the file set on the previous read.
+	externalEnv map[string]bool
+	fileEnv     = map[string]bool{}
+)
+
+// applyEnvFile sets the variables from the env files that were not already
+// set externally. Variables that an earlier read set but the files no longer
+// contain are unset, so a removed line reverts to its default on reload.
+func applyEnvFile(filenames ...string) error {
+	envMu.Lock()
+	defer envMu.Unlock()
+
+	if externalEnv == nil {
+		externalEnv = map[string]bool{}
+		for _, kv := range os.Environ() {
+			if name, _, ok := strings.Cut(kv, "="); ok {
+				externalEnv[name] = true
+			}
+		}
+	}
+
+	values, err := godotenv.Read(filenames...)
+	if err != nil {
+		return err
+	}
+
+	for name := range fileEnv {
+		if _, ok := values[name]; !ok {
+			os.Unsetenv(name)
+			delete(fileEnv, name)
+		}
+	}
+	for name, value := range values {
+		if externalEnv[name] {
+			continue
+		}
+		if err := os.Setenv(name, value); err != nil {
+			return err
+		}
+		fileEnv[name] = true
+	}
+	return nil
+}
+
+func load(loadEnv func(filenames ...string) error) (*Config, error) {
+	configFile := os.Getenv("CONFIG_FILE")
+
 	envFile := getEnv("CONFIG_PATH", ".env")
-	if err := godotenv.Load(envFile); err != nil {
-		return nil, fmt.Errorf("loading .env from %s: %w", envFile, err)
+	if err := loadEnv(envFile); err != nil {
+		// The env file is optional once a config file supplies the settings.
+		if configFile == "" || !errors.Is(err, fs.ErrNotExist) {
+			return nil, fmt.Errorf("loading .env from %s: %w", envFile, err)
+		}
+	}
+
+	cfg := &Config{
+		Server:     loadServerConfig(),
+		Database:   loadDatabaseConfig(),
+		MQTT:       loadMQTTConfig(),
+		Security:   loadSecurityConfig(),
+		Logging:    loadLoggingConfig(),
+		Auth:       loadAuthConfig(),
+		WebSocket:  loadWebSocketConfig(),
+		Alerts:     loadAlertConfig(),
+		Commands:   loadCommandConfig(),
+		Analytics:  loadAnalyticsConfig(),
+		Telemetry:  loadTelemetryConfig(),
+		Thresholds: loadThresholdConfig(),
+	}
+
+	if configFile == "" {
+		if err := validateRequired(nil); err != nil {
+			return nil, err
+		}
+		return cfg, nil
 	}
-	if err := validateRequired(); err != nil {
+
+	fileCfg, err := applyConfigFile(cfg, configFile)
+	if err != nil {
 		return nil, err
 	}
-	cfg := &Config{
-		Server:   loadServerConfig(),
-		Database: loadDatabaseConfig(),
-		MQTT:     loadMQTTConfig(),
-		Security: loadSecurityConfig(),
-		Logging:  loadLoggingConfig(),
-		Auth:     loadAuthConfig(),
+	if err := validateRequired(fileCfg); err != nil {
+		return nil, err
 	}
 
 	return cfg, nil
@@ -196,17 +426,26 @@ func loadAuthConfig() AuthConfig {
 		LdapConfig:              ldapCfg,
 	}
 }
-func validateRequired() error {
+
+// validateRequired checks that every required setting is present in the
+// environment or, when one is in use, set in the config file.
+func validateRequired(fileCfg *Config) error {
 	var missing []string
 
 	for _, key := range requiredEnvVars {
-		if os.Getenv(key) == "" {
-			missing = append(missing, key)
+		if os.Getenv(key) != "" {
+			continue
+		}
+		if fileCfg != nil {
+			if v, ok := fieldByEnv(reflect.ValueOf(fileCfg).Elem(), key); ok && !v.IsZero() {
+				continue
+			}
 		}
+		missing = append(missing, key)
 	}
 
 	if len(missing) > 0 {
-		return fmt.Errorf("missing required environment variables: %s", strings.Join(missing, ", "))
+		return fmt.Errorf("missing required configuration: %s", strings.Join(missing, ", "))
 	}
 
 	return nil
@@ -220,9 +459,20 @@ func loadServerConfig() ServerConfig {
 		ShutdownTimeout: getEnvAsDuration("SHUTDOWN_TIMEOUT", "15s"),
 		ReadTimeout:     getEnvAsDuration("READ_TIMEOUT", "10s"),
 		WriteTimeout:    getEnvAsDuration("WRITE_TIMEOUT", "10s"),
+		RequestTimeout:  getEnvAsDuration("REQUEST_TIMEOUT", "8s"),
 		MaxHeaderBytes:  getEnvAsInt("MAX_HEADER_BYTES", 1048576),
-		PublicURL:       getEnv("PUBLIC_URL", "http://localhost:9080"),
-		CertDir:         getEnv("CERT_DIR", "certs"),
+
+		MaxBodyBytes:     int64(getEnvAsInt("MAX_BODY_BYTES", 1<<20)),
+		MaxBulkBodyBytes: int64(getEnvAsInt("MAX_BULK_BODY_BYTES", 10<<20)),
+
+		PublicURL:     getEnv("PUBLIC_URL", "http://localhost:9080"),
+		CertDir:       getEnv("CERT_DIR", "certs"),
+		EnableGzip:    getEnvAsBool("ENABLE_GZIP", true),
+		GzipMinSize:   getEnvAsInt("GZIP_MIN_SIZE", 1024),
+		EnableMetrics: getEnvAsBool("ENABLE_METRICS", true),
+
+		AnalyticsCacheTTL:  getEnvAsDuration("ANALYTICS_CACHE_TTL", "10s"),
+		AnalyticsCacheSize: getEnvAsInt("ANALYTICS_CACHE_SIZE", 256),
 	}
 }
 func loadDatabaseConfig() DatabaseConfig {
@@ -237,6 +487,10 @@ func loadDatabaseConfig() DatabaseConfig {
 		MaxIdleConns:    getEnvAsInt("DB_MAX_IDLE_CONNS", 5),
 		ConnMaxLifetime: getEnvAsDuration("DB_CONN_MAX_LIFETIME", "5m"),
 		ConnMaxIdleTime: getEnvAsDuration("DB_CONN_MAX_IDLE_TIME", "5m"),
+		ReplicaHost:     getEnv("DB_REPLICA_HOST", ""),
+		ReplicaPort:     getEnvAsInt("DB_REPLICA_PORT", 5432),
+
+		SlowQueryThreshold: getEnvAsDuration("DB_SLOW_QUERY_THRESHOLD", "500ms"),
 	}
 }
 
@@ -254,6 +508,9 @@ func loadMQTTConfig() MQTTConfig {
 		KeepAlive:      getEnvAsDuration("MQTT_KEEP_ALIVE", "60s"),
 		ConnectTimeout: getEnvAsDuration("MQTT_CONNECT_TIMEOUT", "10s"),
 		AutoReconnect:  getEnvAsBool("MQTT_AUTO_RECONNECT", true),
+
+		ReadinessMaxIdle: getEnvAsDuration("MQTT_READINESS_MAX_IDLE", "0"),
+		StatusTopic:      getEnv("MQTT_STATUS_TOPIC", ""),
 	}
 }
 
@@ -262,22 +519,154 @@ func loadSecurityConfig() SecurityConfig {
 	methods := getEnv("CORS_ALLOWED_METHODS", "GET,POST,PUT,DELETE,OPTIONS")
 
 	return SecurityConfig{
-		JWTSecret:          getEnv("JWT_SECRET", "campus_monitor_secret_change_in_production"),
 		JWTExpirationHours: getEnvAsInt("JWT_EXPIRATION_HOURS", 24),
 		APIKeyHeader:       getEnv("API_KEY_HEADER", "X-API-Key"),
+		APIKeys:            parseAPIKeys(getEnv("API_KEYS", "")),
 		CORSAllowedOrigins: strings.Split(origins, ","),
 		CORSAllowedMethods: strings.Split(methods, ","),
+
+		CORSAllowCredentials: getEnvAsBool("CORS_ALLOW_CREDENTIALS", false),
+		CORSSensitiveOrigins: getEnvAsList("CORS_SENSITIVE_ORIGINS", ""),
+
 		RateLimitPerMinute: getEnvAsInt("RATE_LIMIT_PER_MINUTE", 100),
+		RateLimitBurst:     getEnvAsInt("RATE_LIMIT_BURST", 20),
+		APIKeyRateLimits:   parseRateLimits(getEnv("API_KEY_RATE_LIMITS", "")),
 		EnableRateLimit:    getEnvAsBool("ENABLE_RATE_LIMIT", true),
+		TrustedProxies:     getEnvAsList("TRUSTED_PROXIES", ""),
+
+		EnableSecurityHeaders: getEnvAsBool("ENABLE_SECURITY_HEADERS", true),
+		ContentSecurityPolicy: getEnv("CONTENT_SECURITY_POLICY", "default-src 'none'; frame-ancestors 'none'"),
+		EnableAuditLog:        getEnvAsBool("ENABLE_AUDIT_LOG", true),
+	}
+}
+
+// parseAPIKeys reads "label:key" pairs separated by commas.
+func parseAPIKeys(value string) map[string]string {
+	keys := make(map[string]string)
+	for _, pair := range strings.Split(value, ",") {
+		label, key, ok := strings.Cut(strings.TrimSpace(pair), ":")
+		if !ok || label == "" || key == "" {
+			continue
+		}
+		keys[label] = key
+	}
+	return keys
+}
+
+// parseRateLimits reads "name:requests_per_minute" pairs separated by
+// commas. Entries with a non-positive or unparsable limit are skipped.
+func parseRateLimits(value string) map[string]int {
+	limits := make(map[string]int)
+	for _, pair := range strings.Split(value, ",") {
+		label, limit, ok := strings.Cut(strings.TrimSpace(pair), ":")
+		if !ok || label == "" {
+			continue
+		}
+		n, err := strconv.Atoi(limit)
+		if err != nil || n < 1 {
+			continue
+		}
+		limits[label] = n
 	}
+	return limits
 }
 
 func loadLoggingConfig() LoggingConfig {
 	return LoggingConfig{
-		Level:     logger.ParseLevel(getEnv("LOG_LEVEL", "info")),
-		Mode:      logger.ParseMode(getEnv("LOG_MODE", "normal")),
-		FilePath:  getEnv("LOG_FILE_PATH", ""),
-		UseColors: getEnvAsBool("LOG_USE_COLORS", true),
+		Level:      logger.ParseLevel(getEnv("LOG_LEVEL", "info")),
+		Mode:       logger.ParseMode(getEnv("LOG_MODE", "normal")),
+		Format:     logger.ParseFormat(getEnv("LOG_FORMAT", "text")),
+		FilePath:   getEnv("LOG_FILE_PATH", ""),
+		UseColors:  getEnvAsBool("LOG_USE_COLORS", true),
+		MaxSizeMB:  getEnvAsInt("LOG_MAX_SIZE_MB", 100),
+		MaxBackups: getEnvAsInt("LOG_MAX_BACKUPS", 5),
+		Async:      getEnvAsBool("LOG_ASYNC", false),
+		BufferSize: getEnvAsInt("LOG_BUFFER_SIZE", 1024),
+		RedactKeys: getEnvAsList("LOG_REDACT_KEYS", ""),
+	}
+}
+
+func loadWebSocketConfig() WebSocketConfig {
+	return WebSocketConfig{
+		HealthBroadcastInterval: getEnvAsDuration("WS_HEALTH_BROADCAST_INTERVAL", "30s"),
+		BatchWindow:             getEnvAsDuration("WS_BATCH_WINDOW", "0s"),
+		MaxMessageSize:          int64(getEnvAsInt("WS_MAX_MESSAGE_SIZE", 512)),
+		SendBuffer:              getEnvAsInt("WS_SEND_BUFFER", 256),
+		ReadBuffer:              getEnvAsInt("WS_READ_BUFFER", 1024),
+		WriteBuffer:             getEnvAsInt("WS_WRITE_BUFFER", 1024),
+		BroadcastBuffer:         getEnvAsInt("WS_BROADCAST_BUFFER", 1024),
+		AlertCoalesceWindow:     getEnvAsDuration("WS_ALERT_COALESCE_WINDOW", "1s"),
+	}
+}
+
+func loadCommandConfig() CommandConfig {
+	return CommandConfig{
+		RateLimitPerMinute: getEnvAsInt("COMMAND_RATE_LIMIT_PER_MINUTE", 30),
+		RateLimitBurst:     getEnvAsInt("COMMAND_RATE_LIMIT_BURST", 5),
+		TypeRateLimits:     parseRateLimits(getEnv("COMMAND_TYPE_RATE_LIMITS", "ping:120,deep_scan:4,ota_update:1")),
+		RetentionDays:      getEnvAsInt("COMMAND_RETENTION_DAYS", 30),
+		CleanupInterval:    getEnvAsDuration("COMMAND_CLEANUP_INTERVAL", "1h"),
+	}
+}
+
+func loadAnalyticsConfig() AnalyticsConfig {
+	return AnalyticsConfig{
+		ReportScheduleInterval:       getEnvAsDuration("REPORT_SCHEDULE_INTERVAL", "1m"),
+		ReportWebhookAllowedNetworks: getEnvAsList("REPORT_WEBHOOK_ALLOWED_NETWORKS", ""),
+		DefaultReportInterval:        getEnvAsDuration("ANALYTICS_DEFAULT_REPORT_INTERVAL", "60s"),
+	}
+}
+
+func loadThresholdConfig() ThresholdConfig {
+	return ThresholdConfig{
+		// ANALYTICS_ACTIVE_WINDOW is the setting's former name.
+		ProbeActive:     getEnvAsDuration("PROBE_ACTIVE_WINDOW", getEnv("ANALYTICS_ACTIVE_WINDOW", "5m")),
+		ProbeOffline:    getEnvAsDuration("PROBE_OFFLINE_THRESHOLD", "3m"),
+		StatusRetention: getEnvAsDuration("PROBE_STATUS_RETENTION", "15m"),
+		TelemetryStale:  getEnvAsDuration("TELEMETRY_STALE_THRESHOLD", "15m"),
+		CommandPing:     getEnvAsDuration("COMMAND_PING_THRESHOLD", "60s"),
+	}
+}
+
+// Model converts the loaded settings into the thresholds handed to services.
+func (t ThresholdConfig) Model() models.Thresholds {
+	return models.Thresholds{
+		ProbeActive:     t.ProbeActive,
+		ProbeOffline:    t.ProbeOffline,
+		StatusRetention: t.StatusRetention,
+		TelemetryStale:  t.TelemetryStale,
+		CommandPing:     t.CommandPing,
+	}
+}
+
+func loadTelemetryConfig() TelemetryConfig {
+	return TelemetryConfig{
+		WriteBufferInterval: getEnvAsDuration("TELEMETRY_WRITE_BUFFER_INTERVAL", "0s"),
+		WriteBufferSize:     getEnvAsInt("TELEMETRY_WRITE_BUFFER_SIZE", 500),
+		AnomalyThreshold:    getEnvAsFloat("TELEMETRY_ANOMALY_THRESHOLD", 3),
+		AnomalyBroadcast:    getEnvAsBool("TELEMETRY_ANOMALY_BROADCAST", false),
+	}
+}
+
+func loadAlertConfig() AlertConfig {
+	defaults := models.DEFAULT_ALERT_CONFIG
+	return AlertConfig{
+		RSSIThreshold:    getEnvAsFloat("ALERT_RSSI_THRESHOLD", defaults.RSSIThreshold),
+		RSSIOccurrences:  getEnvAsInt("ALERT_RSSI_OCCURRENCES", defaults.RSSIOccurrences),
+		LatencyThreshold: getEnvAsFloat("ALERT_LATENCY_THRESHOLD", defaults.LatencyThreshold),
+		LatencyWindow:    getEnvAsInt("ALERT_LATENCY_WINDOW", defaults.LatencyWindow),
+		HeartbeatTimeout: getEnvAsInt("ALERT_HEARTBEAT_TIMEOUT", defaults.HeartbeatTimeout),
+	}
+}
+
+// Model converts the loaded settings into the evaluator's alert config.
+func (a AlertConfig) Model() models.AlertConfig {
+	return models.AlertConfig{
+		RSSIThreshold:    a.RSSIThreshold,
+		RSSIOccurrences:  a.RSSIOccurrences,
+		LatencyThreshold: a.LatencyThreshold,
+		LatencyWindow:    a.LatencyWindow,
+		HeartbeatTimeout: a.HeartbeatTimeout,
 	}
 }
 
@@ -297,6 +686,23 @@ func getEnvAsInt(key string, defaultValue int) int {
 	return defaultValue
 }
 
+func getEnvAsFloat(key string, defaultValue float64) float64 {
+	if value := os.Getenv(key); value != "" {
+		if floatVal, err := strconv.ParseFloat(value, 64); err == nil {
+			return floatVal
+		}
+	}
+	return defaultValue
+}
+
+func getEnvAsList(key, defaultValue string) []string {
+	value := getEnv(key, defaultValue)
+	if value == "" {
+		return nil
+	}
+	return strings.Split(value, ",")
+}
+
 func getEnvAsBool(key string, defaultValue bool) bool {
 	if value := os.Getenv(key); value != "" {
 		if boolVal, err := strconv.ParseBool(value); err == nil {
@@ -350,6 +756,84 @@ func (c *Config) Validate() error {
 	if c.MQTT.Port < 1 || c.MQTT.Port > 65535 {
 		errors = append(errors, "MQTT_PORT must be between 1 and 65535")
 	}
+	if c.Server.MaxBodyBytes < 0 || c.Server.MaxBulkBodyBytes < 0 {
+		errors = append(errors, "MAX_BODY_BYTES and MAX_BULK_BODY_BYTES cannot be negative")
+	}
+	if c.Server.RequestTimeout > 0 && c.Server.WriteTimeout > 0 && c.Server.RequestTimeout >= c.Server.WriteTimeout {
+		logger.Warn("REQUEST_TIMEOUT (%v) is not below WRITE_TIMEOUT (%v); slow requests will be cut off without a 503", c.Server.RequestTimeout, c.Server.WriteTimeout)
+	}
+	if err := validateTopicFilter(c.MQTT.TelemetryTopic); err != nil {
+		errors = append(errors, fmt.Sprintf("MQTT_TELEMETRY_TOPIC %q is invalid: %v", c.MQTT.TelemetryTopic, err))
+	}
+	if err := validateTopicFilter(c.MQTT.CommandTopic); err != nil {
+		errors = append(errors, fmt.Sprintf("MQTT_COMMAND_TOPIC %q is invalid: %v", c.MQTT.CommandTopic, err))
+	} else if !strings.Contains(c.MQTT.CommandTopic, "+") {
+		logger.Warn("MQTT_COMMAND_TOPIC %q has no '+' probe placeholder; commands will not be addressed per probe", c.MQTT.CommandTopic)
+	}
+	if c.Security.EnableRateLimit && c.Security.RateLimitPerMinute < 1 {
+		errors = append(errors, "RATE_LIMIT_PER_MINUTE must be at least 1 when ENABLE_RATE_LIMIT=true")
+	}
+	if c.Security.CORSAllowCredentials {
+		for _, origin := range append(c.Security.CORSAllowedOrigins, c.Security.CORSSensitiveOrigins...) {
+			if strings.TrimSpace(origin) == "*" {
+				errors = append(errors, "CORS_ALLOW_CREDENTIALS=true requires explicit origins, not \"*\"")
+				break
+			}
+		}
+	}
+	for _, proxy := range c.Security.TrustedProxies {
+		proxy = strings.TrimSpace(proxy)
+		if _, _, err := net.ParseCIDR(proxy); err != nil && net.ParseIP(proxy) == nil {
+			errors = append(errors, fmt.Sprintf("TRUSTED_PROXIES entry %q is not an IP or CIDR", proxy))
+		}
+	}
+	for _, network := range c.Analytics.ReportWebhookAllowedNetworks {
+		network = strings.TrimSpace(network)
+		if _, _, err := net.ParseCIDR(network); err != nil && net.ParseIP(network) == nil {
+			errors = append(errors, fmt.Sprintf("REPORT_WEBHOOK_ALLOWED_NETWORKS entry %q is not an IP or CIDR", network))
+		}
+	}
+	if c.Commands.RateLimitPerMinute < 0 {
+		errors = append(errors, "COMMAND_RATE_LIMIT_PER_MINUTE cannot be negative")
+	}
+	if c.Commands.CleanupInterval > 0 && c.Commands.RetentionDays < 1 {
+		errors = append(errors, "COMMAND_RETENTION_DAYS must be at least 1 when COMMAND_CLEANUP_INTERVAL is set")
+	}
+	if c.Thresholds.ProbeActive <= 0 || c.Thresholds.ProbeOffline <= 0 || c.Thresholds.StatusRetention <= 0 ||
+		c.Thresholds.TelemetryStale <= 0 || c.Thresholds.CommandPing <= 0 {
+		errors = append(errors, "PROBE_ACTIVE_WINDOW, PROBE_OFFLINE_THRESHOLD, PROBE_STATUS_RETENTION, TELEMETRY_STALE_THRESHOLD and COMMAND_PING_THRESHOLD must be positive")
+	}
+	if c.WebSocket.BatchWindow < 0 || c.WebSocket.BatchWindow > time.Second {
+		errors = append(errors, "WS_BATCH_WINDOW must be between 0 and 1s")
+	}
+	if c.WebSocket.MaxMessageSize < 1 || c.WebSocket.SendBuffer < 1 || c.WebSocket.ReadBuffer < 1 || c.WebSocket.WriteBuffer < 1 ||
+		c.WebSocket.BroadcastBuffer < 1 {
+		errors = append(errors, "WS_MAX_MESSAGE_SIZE, WS_SEND_BUFFER, WS_READ_BUFFER, WS_WRITE_BUFFER and WS_BROADCAST_BUFFER must be at least 1")
+	}
+	if c.WebSocket.AlertCoalesceWindow < 0 || c.WebSocket.AlertCoalesceWindow > time.Minute {
+		errors = append(errors, "WS_ALERT_COALESCE_WINDOW must be between 0 and 1m")
+	}
+	if c.Analytics.DefaultReportInterval < time.Second {
+		errors = append(errors, "ANALYTICS_DEFAULT_REPORT_INTERVAL must be at least 1s")
+	}
+	if c.Telemetry.AnomalyThreshold < 0 {
+		errors = append(errors, "TELEMETRY_ANOMALY_THRESHOLD cannot be negative")
+	}
+	if c.Telemetry.WriteBufferInterval < 0 {
+		errors = append(errors, "TELEMETRY_WRITE_BUFFER_INTERVAL cannot be negative")
+	}
+	if c.Telemetry.WriteBufferInterval > 0 && c.Telemetry.WriteBufferSize < 1 {
+		errors = append(errors, "TELEMETRY_WRITE_BUFFER_SIZE must be at least 1 when TELEMETRY_WRITE_BUFFER_INTERVAL is set")
+	}
+	if c.Alerts.RSSIOccurrences < 1 {
+		errors = append(errors, "ALERT_RSSI_OCCURRENCES must be at least 1")
+	}
+	if c.Alerts.LatencyWindow < 1 {
+		errors = append(errors, "ALERT_LATENCY_WINDOW must be at least 1")
+	}
+	if c.Alerts.HeartbeatTimeout < 1 {
+		errors = append(errors, "ALERT_HEARTBEAT_TIMEOUT must be at least 1 second")
+	}
 	if c.Auth.LdapConfig.Enabled {
 		if c.Auth.LdapConfig.Host == "" {
 			errors = append(errors, "LDAP_HOST is required when LDAP_ENABLED=true")
@@ -368,6 +852,129 @@ func (c *Config) Validate() error {
 	return nil
 }
 
+// reloadable lists the settings watchReload applies to a running server.
+// Every other setting needs a restart.
+var reloadable = map[string]bool{
+	"LOG_LEVEL":                     true,
+	"LOG_MODE":                      true,
+	"LOG_FORMAT":                    true,
+	"RATE_LIMIT_PER_MINUTE":         true,
+	"RATE_LIMIT_BURST":              true,
+	"API_KEY_RATE_LIMITS":           true,
+	"API_KEYS":                      true,
+	"COMMAND_RATE_LIMIT_PER_MINUTE": true,
+	"COMMAND_RATE_LIMIT_BURST":      true,
+	"COMMAND_TYPE_RATE_LIMITS":      true,
+	"ALERT_RSSI_THRESHOLD":          true,
+	"ALERT_RSSI_OCCURRENCES":        true,
+	"ALERT_LATENCY_THRESHOLD":       true,
+	"ALERT_LATENCY_WINDOW":          true,
+	"ALERT_HEARTBEAT_TIMEOUT":       true,
+}
+
+// RestartRequired lists the settings that differ between c and next but
+// cannot be applied to a running server, by env var name (or YAML key for
+// file-only settings).
+func (c *Config) RestartRequired(next *Config) []string {
+	var changed []string
+	walkSettings(reflect.ValueOf(c).Elem(), reflect.ValueOf(next).Elem(), func(key string, cur, nxt reflect.Value) {
+		if !reloadable[key] && !reflect.DeepEqual(cur.Interface(), nxt.Interface()) {
+			changed = append(changed, key)
+		}
+	})
+	return changed
+}
+
+// ApplyReloadable copies the reloadable settings from next into c.
+func (c *Config) ApplyReloadable(next *Config) {
+	walkSettings(reflect.ValueOf(c).Elem(), reflect.ValueOf(next).Elem(), func(key string, cur, nxt reflect.Value) {
+		if reloadable[key] {
+			cur.Set(nxt)
+		}
+	})
+}
+
+// walkSettings calls fn for each setting in a and the matching field in b,
+// keyed by its env var, or its YAML key when it has none.
+func walkSettings(a, b reflect.Value, fn func(key string, a, b reflect.Value)) {
+	for i := 0; i < a.NumField(); i++ {
+		field := a.Type().Field(i)
+		key := field.Tag.Get("env")
+		if key == "" && field.Type.Kind() == reflect.Struct {
+			walkSettings(a.Field(i), b.Field(i), fn)
+			continue
+		}
+		if key == "" {
+			key, _, _ = strings.Cut(field.Tag.Get("yaml"), ",")
+		}
+		fn(key, a.Field(i), b.Field(i))
+	}
+}
+
+// validateTopicFilter checks an MQTT topic filter against the spec rules:
+// no empty or oversized topics, no NUL characters, '+' must fill a whole
+// level, and '#' must fill the last level.
+func validateTopicFilter(topic string) error {
+	if topic == "" {
+		return fmt.Errorf("topic is empty")
+	}
+	if len(topic) > 65535 {
+		return fmt.Errorf("topic exceeds 65535 bytes")
+	}
+	if strings.ContainsRune(topic, 0) {
+		return fmt.Errorf("topic contains a NUL character")
+	}
+
+	levels := strings.Split(topic, "/")
+	for i, level := range levels {
+		if strings.Contains(level, "+") && level != "+" {
+			return fmt.Errorf("'+' must occupy an entire topic level (level %d is %q)", i+1, level)
+		}
+		if strings.Contains(level, "#") {
+			if level != "#" {
+				return fmt.Errorf("'#' must occupy an entire topic level (level %d is %q)", i+1, level)
+			}
+			if i != len(levels)-1 {
+				return fmt.Errorf("'#' is only allowed as the last topic level")
+			}
+		}
+	}
+	return nil
+}
+
+const redactedValue = "****"
+
+// Redacted returns a copy of the config with passwords and secrets masked,
+// suitable for exposing over the API or in logs.
+func (c *Config) Redacted() *Config {
+	out := *c
+
+	out.Database.Password = redact(out.Database.Password)
+	out.MQTT.Password = redact(out.MQTT.Password)
+	out.Auth.JWTSecret = redact(out.Auth.JWTSecret)
+	out.Auth.LdapConfig.BindPassword = redact(out.Auth.LdapConfig.BindPassword)
+
+	out.Security.APIKeys = make(map[string]string, len(c.Security.APIKeys))
+	for label, key := range c.Security.APIKeys {
+		out.Security.APIKeys[label] = redact(key)
+	}
+
+	out.Auth.OAuthProviders = make(map[string]OAuthProviderConfig, len(c.Auth.OAuthProviders))
+	for name, provider := range c.Auth.OAuthProviders {
+		provider.ClientSecret = redact(provider.ClientSecret)
+		out.Auth.OAuthProviders[name] = provider
+	}
+
+	return &out
+}
+
+func redact(value string) string {
+	if value == "" {
+		return ""
+	}
+	return redactedValue
+}
+
 func (c *Config) Print() {
 	fmt.Println("Campus Monitor config summary \n {")
 	fmt.Printf("\n Environment:     %s\n", c.Server.Environment)
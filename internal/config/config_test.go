@@ -0,0 +1,106 @@
+package config
+
+import (
+	"reflect"
+	"testing"
+)
+
+// changeValue sets v to a value different from its current one.
+func changeValue(t *testing.T, key string, v reflect.Value) {
+	t.Helper()
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(v.String() + "-changed")
+	case reflect.Bool:
+		v.SetBool(!v.Bool())
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		v.SetInt(v.Int() + 1)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		v.SetUint(v.Uint() + 1)
+	case reflect.Float32, reflect.Float64:
+		v.SetFloat(v.Float() + 1)
+	case reflect.Slice:
+		v.Set(reflect.Append(reflect.MakeSlice(v.Type(), 0, v.Len()+1), reflect.Zero(v.Type().Elem())))
+		if v.Len() == 1 && v.Index(0).IsZero() {
+			changeValue(t, key, v.Index(0))
+		}
+	case reflect.Map:
+		m := reflect.MakeMap(v.Type())
+		m.SetMapIndex(reflect.ValueOf("changed").Convert(v.Type().Key()), reflect.Zero(v.Type().Elem()))
+		v.Set(m)
+	default:
+		t.Fatalf("%s: no way to change a %s setting", key, v.Type())
+	}
+}
+
+// TestEverySettingIsReloadableOrReported makes sure a SIGHUP change to any
+// setting is either applied or logged as needing a restart.
+func TestEverySettingIsReloadableOrReported(t *testing.T) {
+	base := &Config{}
+
+	var keys []string
+	walkSettings(reflect.ValueOf(base).Elem(), reflect.ValueOf(base).Elem(), func(key string, _, _ reflect.Value) {
+		keys = append(keys, key)
+	})
+
+	for i, key := range keys {
+		next := &Config{}
+		n := 0
+		walkSettings(reflect.ValueOf(next).Elem(), reflect.ValueOf(next).Elem(), func(_ string, v, _ reflect.Value) {
+			if n == i {
+				changeValue(t, key, v)
+			}
+			n++
+		})
+
+		reported := base.RestartRequired(next)
+		if reloadable[key] {
+			if len(reported) != 0 {
+				t.Errorf("%s is reloadable but RestartRequired reported %v", key, reported)
+			}
+			applied := &Config{}
+			applied.ApplyReloadable(next)
+			if !reflect.DeepEqual(applied, next) {
+				t.Errorf("%s is reloadable but ApplyReloadable did not copy it", key)
+			}
+			continue
+		}
+		if len(reported) != 1 || reported[0] != key {
+			t.Errorf("changing %s: RestartRequired = %v, want [%s]", key, reported, key)
+		}
+	}
+
+	known := make(map[string]bool, len(keys))
+	for _, key := range keys {
+		known[key] = true
+	}
+	for key := range reloadable {
+		if !known[key] {
+			t.Errorf("reloadable lists %s, which is not a setting", key)
+		}
+	}
+}
+
+func TestEnvTagsAreUnique(t *testing.T) {
+	seen := make(map[string]bool)
+	cfg := &Config{}
+	walkSettings(reflect.ValueOf(cfg).Elem(), reflect.ValueOf(cfg).Elem(), func(key string, _, _ reflect.Value) {
+		if seen[key] {
+			t.Errorf("%s tags more than one field", key)
+		}
+		seen[key] = true
+	})
+}
+
+func TestFieldByEnvFindsJWTSecretFromFile(t *testing.T) {
+	fileCfg := &Config{}
+	fileCfg.Auth.JWTSecret = "from-file"
+
+	v, ok := fieldByEnv(reflect.ValueOf(fileCfg).Elem(), "JWT_SECRET")
+	if !ok {
+		t.Fatal("JWT_SECRET not found")
+	}
+	if v.String() != "from-file" {
+		t.Errorf("JWT_SECRET = %q, want the auth.jwt_secret value", v.String())
+	}
+}
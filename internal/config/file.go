@@ -0,0 +1,83 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"reflect"
+
+	"gopkg.in/yaml.v3"
+)
+
+// applyConfigFile layers the YAML file at path onto cfg, which has already been
+// built from env vars and defaults. Precedence is env > file > default: values
+// from the file replace defaults, then any field whose env var is set is put
+// back. It returns the file's contents on their own so callers can tell which
+// settings the file actually provided.
+func applyConfigFile(cfg *Config, path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading config file %s: %w", path, err)
+	}
+
+	fileCfg := &Config{}
+	if err := yaml.Unmarshal(data, fileCfg); err != nil {
+		return nil, fmt.Errorf("parsing config file %s: %w", path, err)
+	}
+
+	envCfg := *cfg
+	if err := yaml.Unmarshal(data, cfg); err != nil {
+		return nil, fmt.Errorf("parsing config file %s: %w", path, err)
+	}
+	restoreEnvOverrides(reflect.ValueOf(cfg).Elem(), reflect.ValueOf(&envCfg).Elem())
+
+	return fileCfg, nil
+}
+
+// restoreEnvOverrides copies every field tagged with a set env var from src to dst.
+func restoreEnvOverrides(dst, src reflect.Value) {
+	for i := 0; i < dst.NumField(); i++ {
+		field := dst.Type().Field(i)
+		key := field.Tag.Get("env")
+
+		if key == "" {
+			if field.Type.Kind() == reflect.Struct {
+				restoreEnvOverrides(dst.Field(i), src.Field(i))
+			}
+			continue
+		}
+		if os.Getenv(key) != "" {
+			dst.Field(i).Set(src.Field(i))
+		}
+	}
+}
+
+// fieldByEnv finds the field in v tagged with the given env var name. Each
+// env var tags a single field, so a file key never has two targets.
+func fieldByEnv(v reflect.Value, key string) (reflect.Value, bool) {
+	for i := 0; i < v.NumField(); i++ {
+		field := v.Type().Field(i)
+		if field.Tag.Get("env") == key {
+			return v.Field(i), true
+		}
+		if field.Type.Kind() == reflect.Struct {
+			if found, ok := fieldByEnv(v.Field(i), key); ok {
+				return found, true
+			}
+		}
+	}
+	return reflect.Value{}, false
+}
+
+// ToMap renders the config using the same keys as the YAML config file.
+func (c *Config) ToMap() (map[string]interface{}, error) {
+	data, err := yaml.Marshal(c)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal config: %w", err)
+	}
+
+	out := make(map[string]interface{})
+	if err := yaml.Unmarshal(data, &out); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal config: %w", err)
+	}
+	return out, nil
+}
@@ -0,0 +1,38 @@
+package config
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+// Live holds the configuration of a running server. Readers take an
+// immutable snapshot with Get; changes are made with Update, which swaps in
+// a modified copy, so a snapshot never changes underneath its reader.
+type Live struct {
+	current atomic.Pointer[Config]
+	mu      sync.Mutex
+}
+
+// NewLive wraps cfg, which must not be modified afterwards.
+func NewLive(cfg *Config) *Live {
+	l := &Live{}
+	l.current.Store(cfg)
+	return l
+}
+
+// Get returns the current snapshot. It must be treated as read-only.
+func (l *Live) Get() *Config {
+	return l.current.Load()
+}
+
+// Update applies fn to a copy of the current config and publishes the copy.
+// The copy is shallow: fn must replace maps and slices rather than modify
+// them in place.
+func (l *Live) Update(fn func(cfg *Config)) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	next := *l.current.Load()
+	fn(&next)
+	l.current.Store(&next)
+}
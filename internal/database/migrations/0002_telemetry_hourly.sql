@@ -0,0 +1,26 @@
+-- Hourly rollup read by TelemetryRepository.GetHourlyStats. Created WITH NO
+-- DATA so it can run inside the migration transaction; the refresh policy
+-- backfills it.
+
+CREATE MATERIALIZED VIEW IF NOT EXISTS telemetry_hourly
+WITH (timescaledb.continuous) AS
+SELECT
+    time_bucket('1 hour', timestamp) AS hour,
+    probe_id,
+    COUNT(*) AS sample_count,
+    AVG(rssi) AS avg_rssi,
+    MIN(rssi) AS min_rssi,
+    MAX(rssi) AS max_rssi,
+    AVG(latency) AS avg_latency,
+    AVG(packet_loss) AS avg_packet_loss,
+    mode() WITHIN GROUP (ORDER BY bssid) AS most_common_bssid,
+    mode() WITHIN GROUP (ORDER BY channel) AS most_common_channel
+FROM telemetry
+GROUP BY hour, probe_id
+WITH NO DATA;
+
+SELECT add_continuous_aggregate_policy('telemetry_hourly',
+    start_offset => INTERVAL '3 days',
+    end_offset => INTERVAL '1 hour',
+    schedule_interval => INTERVAL '1 hour',
+    if_not_exists => TRUE);
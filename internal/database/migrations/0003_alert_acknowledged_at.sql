@@ -0,0 +1,4 @@
+-- Records when an alert was first acknowledged so AlertRepository.GetSummary
+-- can count acknowledgements over a recent window.
+
+ALTER TABLE alerts ADD COLUMN IF NOT EXISTS acknowledged_at TIMESTAMPTZ;
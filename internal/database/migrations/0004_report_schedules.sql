@@ -0,0 +1,32 @@
+-- Recurring analytics reports (ReportScheduler) and the snapshots they
+-- produce, kept so they can be downloaded again later.
+
+CREATE TABLE IF NOT EXISTS report_schedules (
+    id VARCHAR(50) PRIMARY KEY,
+    name VARCHAR(100) NOT NULL,
+    cron VARCHAR(20) NOT NULL,
+    start_at TIMESTAMPTZ,
+    range_spec VARCHAR(20) NOT NULL,
+    top_n INTEGER NOT NULL DEFAULT 10,
+    target_type VARCHAR(20) NOT NULL DEFAULT 'none',
+    target_url TEXT,
+    enabled BOOLEAN DEFAULT true,
+    last_run TIMESTAMPTZ,
+    next_run TIMESTAMPTZ,
+    created_at TIMESTAMPTZ DEFAULT NOW(),
+    updated_at TIMESTAMPTZ DEFAULT NOW()
+);
+
+CREATE TABLE IF NOT EXISTS generated_reports (
+    id SERIAL PRIMARY KEY,
+    schedule_id VARCHAR(50) REFERENCES report_schedules(id) ON DELETE CASCADE,
+    generated_at TIMESTAMPTZ DEFAULT NOW(),
+    range_start TIMESTAMPTZ NOT NULL,
+    range_end TIMESTAMPTZ NOT NULL,
+    delivery_status VARCHAR(20) NOT NULL,
+    delivery_error TEXT,
+    payload JSONB NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_report_schedules_next_run ON report_schedules (next_run) WHERE enabled;
+CREATE INDEX IF NOT EXISTS idx_generated_reports_schedule ON generated_reports (schedule_id, generated_at DESC);
@@ -0,0 +1,17 @@
+-- Every access point a probe saw during a deep scan, not just the one it is
+-- associated with. Rows cascade with the probe since they are derived from
+-- its scans.
+
+CREATE TABLE IF NOT EXISTS neighbor_aps (
+    timestamp TIMESTAMPTZ NOT NULL,
+    seen_by_probe VARCHAR(50) NOT NULL REFERENCES probes(probe_id) ON DELETE CASCADE,
+    bssid VARCHAR(17) NOT NULL,
+    ssid VARCHAR(64),
+    channel INTEGER,
+    rssi INTEGER
+);
+
+SELECT create_hypertable('neighbor_aps', 'timestamp', if_not_exists => TRUE);
+
+CREATE INDEX IF NOT EXISTS idx_neighbor_aps_bssid_time ON neighbor_aps (bssid, timestamp DESC);
+CREATE INDEX IF NOT EXISTS idx_neighbor_aps_probe_time ON neighbor_aps (seen_by_probe, timestamp DESC);
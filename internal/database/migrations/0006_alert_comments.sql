@@ -0,0 +1,12 @@
+-- Discussion trail on alerts. Comments outlive resolution and are only
+-- removed along with the alert itself.
+
+CREATE TABLE IF NOT EXISTS alert_comments (
+    id SERIAL PRIMARY KEY,
+    alert_id INTEGER NOT NULL REFERENCES alerts(id) ON DELETE CASCADE,
+    author VARCHAR(255) NOT NULL,
+    body TEXT NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_alert_comments_alert ON alert_comments (alert_id, created_at);
@@ -0,0 +1,5 @@
+-- Each probe's telemetry report interval in seconds, as last confirmed by a
+-- config_update or get_config result. NULL until the probe reports one, in
+-- which case analytics fall back to ANALYTICS_DEFAULT_REPORT_INTERVAL.
+
+ALTER TABLE probes ADD COLUMN IF NOT EXISTS report_interval INTEGER;
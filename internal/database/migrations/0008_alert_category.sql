@@ -0,0 +1,9 @@
+-- The evaluator's alert category (SIGNAL, NETWORK, SYSTEM) used to ride in
+-- the metadata JSON. Give it a column so it can be filtered and grouped on,
+-- and carry over what older rows recorded.
+
+ALTER TABLE alerts ADD COLUMN IF NOT EXISTS category VARCHAR(20);
+
+UPDATE alerts
+SET category = metadata->>'category'
+WHERE category IS NULL AND metadata ? 'category';
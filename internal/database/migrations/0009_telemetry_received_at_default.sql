@@ -0,0 +1,5 @@
+-- received_at is NOT NULL but had no default, so any insert that left it out
+-- failed. The repository now always writes it; the default covers other
+-- writers, and ingest latency (received_at - timestamp) relies on it.
+
+ALTER TABLE telemetry ALTER COLUMN received_at SET DEFAULT NOW();
@@ -0,0 +1,17 @@
+-- Mutating API requests, for security review of who issued commands and
+-- changed configuration.
+
+CREATE TABLE IF NOT EXISTS audit_log (
+    id BIGSERIAL PRIMARY KEY,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    method TEXT NOT NULL,
+    path TEXT NOT NULL,
+    principal TEXT NOT NULL,
+    client_ip TEXT,
+    status INTEGER NOT NULL,
+    request_id TEXT,
+    body TEXT
+);
+
+CREATE INDEX IF NOT EXISTS idx_audit_log_created_at ON audit_log (created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_audit_log_principal ON audit_log (principal, created_at DESC);
@@ -0,0 +1,19 @@
+-- Where each probe was installed over time, so telemetry can be attributed
+-- to the location in effect when it was recorded rather than the current
+-- one. A row holds from effective_from until the probe's next row.
+
+CREATE TABLE IF NOT EXISTS probe_location_history (
+    probe_id VARCHAR(50) NOT NULL REFERENCES probes(probe_id) ON DELETE CASCADE,
+    building TEXT,
+    floor TEXT,
+    location TEXT,
+    effective_from TIMESTAMPTZ NOT NULL,
+    PRIMARY KEY (probe_id, effective_from)
+);
+
+-- Nothing is known about earlier moves, so each probe's current location
+-- is taken to have always applied.
+INSERT INTO probe_location_history (probe_id, building, floor, location, effective_from)
+SELECT probe_id, building, floor, location, '-infinity'
+FROM probes
+ON CONFLICT DO NOTHING;
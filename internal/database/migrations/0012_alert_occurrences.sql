@@ -0,0 +1,12 @@
+-- The evaluator recorded how many consecutive samples breached a threshold
+-- in the metadata JSON. Give it a column alongside category, and move what
+-- older rows recorded out of the metadata.
+
+ALTER TABLE alerts ADD COLUMN IF NOT EXISTS occurrences INTEGER;
+
+UPDATE alerts
+SET occurrences = (metadata->>'occurrences')::INTEGER,
+    metadata = metadata - 'occurrences'
+WHERE occurrences IS NULL
+  AND metadata ? 'occurrences'
+  AND metadata->>'occurrences' ~ '^[0-9]+$';
@@ -0,0 +1,120 @@
+package database
+
+import (
+	"context"
+	"database/sql/driver"
+	"strings"
+	"time"
+
+	"CampusMonitorAPI/internal/logger"
+)
+
+// maxLoggedQueryLen caps how much of a slow statement ends up in the log.
+const maxLoggedQueryLen = 300
+
+// slowQueryConnector wraps the driver so every Query/Exec issued through the
+// pool, including those inside transactions, is timed without repositories
+// having to change how they call database/sql.
+type slowQueryConnector struct {
+	driver.Connector
+	threshold time.Duration
+	log       *logger.Logger
+}
+
+func (c *slowQueryConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	conn, err := c.Connector.Connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &slowQueryConn{Conn: conn, threshold: c.threshold, log: c.log}, nil
+}
+
+// slowQueryConn forwards the optional driver interfaces lib/pq implements so
+// wrapping doesn't change how database/sql drives the connection.
+type slowQueryConn struct {
+	driver.Conn
+	threshold time.Duration
+	log       *logger.Logger
+}
+
+func (c *slowQueryConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	queryer, ok := c.Conn.(driver.QueryerContext)
+	if !ok {
+		return nil, driver.ErrSkip
+	}
+
+	start := time.Now()
+	rows, err := queryer.QueryContext(ctx, query, args)
+	c.observe(query, time.Since(start))
+	return rows, err
+}
+
+func (c *slowQueryConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	execer, ok := c.Conn.(driver.ExecerContext)
+	if !ok {
+		return nil, driver.ErrSkip
+	}
+
+	start := time.Now()
+	result, err := execer.ExecContext(ctx, query, args)
+	c.observe(query, time.Since(start))
+	return result, err
+}
+
+func (c *slowQueryConn) PrepareContext(ctx context.Context, query string) (driver.Stmt, error) {
+	if preparer, ok := c.Conn.(driver.ConnPrepareContext); ok {
+		return preparer.PrepareContext(ctx, query)
+	}
+	return c.Conn.Prepare(query)
+}
+
+func (c *slowQueryConn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	if beginner, ok := c.Conn.(driver.ConnBeginTx); ok {
+		return beginner.BeginTx(ctx, opts)
+	}
+	return c.Conn.Begin()
+}
+
+func (c *slowQueryConn) Ping(ctx context.Context) error {
+	if pinger, ok := c.Conn.(driver.Pinger); ok {
+		return pinger.Ping(ctx)
+	}
+	return nil
+}
+
+func (c *slowQueryConn) ResetSession(ctx context.Context) error {
+	if resetter, ok := c.Conn.(driver.SessionResetter); ok {
+		return resetter.ResetSession(ctx)
+	}
+	return nil
+}
+
+func (c *slowQueryConn) IsValid() bool {
+	if validator, ok := c.Conn.(driver.Validator); ok {
+		return validator.IsValid()
+	}
+	return true
+}
+
+func (c *slowQueryConn) CheckNamedValue(nv *driver.NamedValue) error {
+	if checker, ok := c.Conn.(driver.NamedValueChecker); ok {
+		return checker.CheckNamedValue(nv)
+	}
+	return driver.ErrSkip
+}
+
+func (c *slowQueryConn) observe(query string, elapsed time.Duration) {
+	if elapsed < c.threshold {
+		return
+	}
+	c.log.Warn("Slow query (%dms): %s", elapsed.Milliseconds(), truncateQuery(query))
+}
+
+// truncateQuery collapses whitespace so multi-line SQL fits on one log line.
+func truncateQuery(query string) string {
+	query = strings.Join(strings.Fields(query), " ")
+	if len(query) > maxLoggedQueryLen {
+		return query[:maxLoggedQueryLen] + "..."
+	}
+	return query
+}
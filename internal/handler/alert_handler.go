@@ -1,33 +1,47 @@
 package handler
 
 import (
+	"encoding/json"
+	"errors"
 	"net/http"
 	"strconv"
 
 	"CampusMonitorAPI/internal/logger"
+	"CampusMonitorAPI/internal/middleware"
+	"CampusMonitorAPI/internal/models"
+	"CampusMonitorAPI/internal/repository"
 	"CampusMonitorAPI/internal/service"
 
 	"github.com/gorilla/mux"
 )
 
 type AlertHandler struct {
-	alertService service.IAlertService
-	log          *logger.Logger
+	alertService   service.IAlertService
+	alertEvaluator service.IAlertEvaluator
+	log            *logger.Logger
 }
 
-func NewAlertHandler(alertService service.IAlertService, log *logger.Logger) *AlertHandler {
+func NewAlertHandler(alertService service.IAlertService, alertEvaluator service.IAlertEvaluator, log *logger.Logger) *AlertHandler {
 	return &AlertHandler{
-		alertService: alertService,
-		log:          log,
+		alertService:   alertService,
+		alertEvaluator: alertEvaluator,
+		log:            log,
 	}
 }
 
 func (h *AlertHandler) RegisterRoutes(r *mux.Router) {
 	r.HandleFunc("/alerts/active", h.GetActiveAlerts).Methods("GET")
 	r.HandleFunc("/alerts/history", h.GetAlertHistory).Methods("GET")
+	r.HandleFunc("/alerts/statistics", h.GetStatistics).Methods("GET")
+	r.Handle("/alerts/evaluator/{probe_id}", middleware.RequireAdmin(http.HandlerFunc(h.GetEvaluatorState))).Methods("GET")
 	r.HandleFunc("/alerts/probe/{probe_id}", h.GetProbeAlerts).Methods("GET")
+	r.HandleFunc("/alerts/probe/{probe_id}/patterns", h.GetRecurringPatterns).Methods("GET")
+	r.HandleFunc("/alerts/acknowledge", h.AcknowledgeBulk).Methods("PUT")
 	r.HandleFunc("/alerts/acknowledge/{id}", h.Acknowledge).Methods("PUT")
 	r.HandleFunc("/alerts/resolve/{id}", h.Resolve).Methods("PUT")
+	r.HandleFunc("/alerts/reopen/{id}", h.Reopen).Methods("PUT")
+	r.HandleFunc("/alerts/{id}/comments", h.GetComments).Methods("GET")
+	r.HandleFunc("/alerts/{id}/comments", h.AddComment).Methods("POST")
 	r.HandleFunc("/alerts/{id}", h.Delete).Methods("DELETE")
 	r.HandleFunc("/alerts/test", h.SendTest).Methods("POST")
 
@@ -36,21 +50,36 @@ func (h *AlertHandler) RegisterRoutes(r *mux.Router) {
 func (h *AlertHandler) GetActiveAlerts(w http.ResponseWriter, r *http.Request) {
 	alerts, err := h.alertService.GetActiveAlerts(r.Context())
 	if err != nil {
-		h.log.Error("Failed to get active alerts: %v", err)
+		h.log.ErrorCtx(r.Context(), "Failed to get active alerts: %v", err)
 		respondError(w, http.StatusInternalServerError, err.Error())
 		return
 	}
 
 	respondJSON(w, http.StatusOK, alerts)
 }
+
+// GetEvaluatorState exposes the evaluator's sliding windows for a probe so
+// thresholds can be tuned against what it actually sees.
+func (h *AlertHandler) GetEvaluatorState(w http.ResponseWriter, r *http.Request) {
+	probeID := mux.Vars(r)["probe_id"]
+
+	state, ok := h.alertEvaluator.GetProbeState(probeID)
+	if !ok {
+		respondError(w, http.StatusNotFound, "No evaluator state for probe")
+		return
+	}
+
+	respondJSON(w, http.StatusOK, state)
+}
+
 func (h *AlertHandler) SendTest(w http.ResponseWriter, r *http.Request) {
 	if err := h.alertService.SendTestAlert(r.Context()); err != nil {
-		h.log.Error("Failed to send test alert: %v", err)
+		h.log.ErrorCtx(r.Context(), "Failed to send test alert: %v", err)
 		respondError(w, http.StatusInternalServerError, err.Error())
 		return
 	}
 
-	h.log.Info("Simulation: Test alert triggered successfully")
+	h.log.InfoCtx(r.Context(), "Simulation: Test alert triggered successfully")
 	respondJSON(w, http.StatusOK, map[string]string{
 		"message": "Test alert dispatched to all connected clients",
 		"type":    "SIMULATION",
@@ -74,21 +103,36 @@ func (h *AlertHandler) GetAlertHistory(w http.ResponseWriter, r *http.Request) {
 
 	alerts, err := h.alertService.GetAlertHistory(r.Context(), limit, offset)
 	if err != nil {
-		h.log.Error("Failed to get alert history: %v", err)
+		h.log.ErrorCtx(r.Context(), "Failed to get alert history: %v", err)
 		respondError(w, http.StatusInternalServerError, err.Error())
 		return
 	}
 
+	if wantsCSV(r) {
+		respondCSV(w, "alerts", alerts)
+		return
+	}
 	respondJSON(w, http.StatusOK, alerts)
 }
 
+func (h *AlertHandler) GetStatistics(w http.ResponseWriter, r *http.Request) {
+	stats, err := h.alertService.GetSummary(r.Context())
+	if err != nil {
+		h.log.ErrorCtx(r.Context(), "Failed to get alert statistics: %v", err)
+		respondError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	respondJSON(w, http.StatusOK, stats)
+}
+
 func (h *AlertHandler) GetProbeAlerts(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	probeID := vars["probe_id"]
 
 	alerts, err := h.alertService.GetProbeAlerts(r.Context(), probeID)
 	if err != nil {
-		h.log.Error("Failed to get alerts for probe %s: %v", probeID, err)
+		h.log.ErrorCtx(r.Context(), "Failed to get alerts for probe %s: %v", probeID, err)
 		respondError(w, http.StatusInternalServerError, err.Error())
 		return
 	}
@@ -96,6 +140,29 @@ func (h *AlertHandler) GetProbeAlerts(w http.ResponseWriter, r *http.Request) {
 	respondJSON(w, http.StatusOK, alerts)
 }
 
+func (h *AlertHandler) GetRecurringPatterns(w http.ResponseWriter, r *http.Request) {
+	probeID := mux.Vars(r)["probe_id"]
+
+	days := 30
+	if v := r.URL.Query().Get("days"); v != "" {
+		parsed, err := strconv.Atoi(v)
+		if err != nil || parsed < 1 || parsed > 365 {
+			respondError(w, http.StatusBadRequest, "days must be between 1 and 365")
+			return
+		}
+		days = parsed
+	}
+
+	report, err := h.alertService.DetectRecurringPatterns(r.Context(), probeID, days)
+	if err != nil {
+		h.log.ErrorCtx(r.Context(), "Failed to detect alert patterns for probe %s: %v", probeID, err)
+		respondError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	respondJSON(w, http.StatusOK, report)
+}
+
 func (h *AlertHandler) Acknowledge(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	idStr := vars["id"]
@@ -107,7 +174,7 @@ func (h *AlertHandler) Acknowledge(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if err := h.alertService.Acknowledge(r.Context(), uint(id)); err != nil {
-		h.log.Error("Failed to acknowledge alert %d: %v", id, err)
+		h.log.ErrorCtx(r.Context(), "Failed to acknowledge alert %d: %v", id, err)
 		respondError(w, http.StatusInternalServerError, err.Error())
 		return
 	}
@@ -115,6 +182,30 @@ func (h *AlertHandler) Acknowledge(w http.ResponseWriter, r *http.Request) {
 	respondJSON(w, http.StatusOK, map[string]string{"status": "alert acknowledged"})
 }
 
+func (h *AlertHandler) AcknowledgeBulk(w http.ResponseWriter, r *http.Request) {
+	var req models.BulkAcknowledgeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.log.WarnCtx(r.Context(), "Invalid request body: %v", err)
+		respondError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	user := getUserFromContext(r)
+	count, err := h.alertService.AcknowledgeBulk(r.Context(), req, user)
+	if errors.Is(err, service.ErrEmptyAlertFilter) {
+		respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err != nil {
+		h.log.ErrorCtx(r.Context(), "Failed to bulk acknowledge alerts: %v", err)
+		respondError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	h.log.InfoCtx(r.Context(), "%s acknowledged %d alerts", user, count)
+	respondJSON(w, http.StatusOK, map[string]int64{"acknowledged": count})
+}
+
 func (h *AlertHandler) Resolve(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	idStr := vars["id"]
@@ -125,8 +216,24 @@ func (h *AlertHandler) Resolve(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if err := h.alertService.Resolve(r.Context(), uint(id)); err != nil {
-		h.log.Error("Failed to resolve alert %d: %v", id, err)
+	// Resolving acknowledges too unless the caller opts out, so resolved
+	// alerts don't linger as unacknowledged incidents.
+	acknowledgedBy := getUserFromContext(r)
+	if ack := r.URL.Query().Get("acknowledge"); ack != "" {
+		if v, err := strconv.ParseBool(ack); err != nil {
+			respondError(w, http.StatusBadRequest, "acknowledge must be true or false")
+			return
+		} else if !v {
+			acknowledgedBy = ""
+		}
+	}
+
+	if err := h.alertService.Resolve(r.Context(), uint(id), acknowledgedBy); err != nil {
+		if status := alertTransitionStatus(err); status != 0 {
+			respondError(w, status, err.Error())
+			return
+		}
+		h.log.ErrorCtx(r.Context(), "Failed to resolve alert %d: %v", id, err)
 		respondError(w, http.StatusInternalServerError, err.Error())
 		return
 	}
@@ -134,6 +241,41 @@ func (h *AlertHandler) Resolve(w http.ResponseWriter, r *http.Request) {
 	respondJSON(w, http.StatusOK, map[string]string{"status": "alert resolved"})
 }
 
+func (h *AlertHandler) Reopen(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	idStr := vars["id"]
+
+	id, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid alert ID")
+		return
+	}
+
+	if err := h.alertService.Reopen(r.Context(), uint(id)); err != nil {
+		if status := alertTransitionStatus(err); status != 0 {
+			respondError(w, status, err.Error())
+			return
+		}
+		h.log.ErrorCtx(r.Context(), "Failed to reopen alert %d: %v", id, err)
+		respondError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	respondJSON(w, http.StatusOK, map[string]string{"status": "alert reopened"})
+}
+
+// alertTransitionStatus maps resolve/reopen state errors to an HTTP status,
+// or 0 if err is not one of them.
+func alertTransitionStatus(err error) int {
+	switch {
+	case errors.Is(err, repository.ErrAlertNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, repository.ErrAlertAlreadyResolved), errors.Is(err, repository.ErrAlertNotResolved):
+		return http.StatusConflict
+	}
+	return 0
+}
+
 func (h *AlertHandler) Delete(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	idStr := vars["id"]
@@ -145,10 +287,63 @@ func (h *AlertHandler) Delete(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if err := h.alertService.DeleteAlert(r.Context(), uint(id)); err != nil {
-		h.log.Error("Failed to delete alert %d: %v", id, err)
+		h.log.ErrorCtx(r.Context(), "Failed to delete alert %d: %v", id, err)
 		respondError(w, http.StatusInternalServerError, err.Error())
 		return
 	}
 
 	w.WriteHeader(http.StatusNoContent)
 }
+
+func (h *AlertHandler) AddComment(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseUint(mux.Vars(r)["id"], 10, 32)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid alert ID")
+		return
+	}
+
+	var req models.CreateAlertCommentRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.log.WarnCtx(r.Context(), "Invalid request body: %v", err)
+		respondError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	comment, err := h.alertService.AddComment(r.Context(), uint(id), getUserFromContext(r), req.Body)
+	if errors.Is(err, service.ErrInvalidComment) {
+		respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if errors.Is(err, repository.ErrAlertNotFound) {
+		respondError(w, http.StatusNotFound, err.Error())
+		return
+	}
+	if err != nil {
+		h.log.ErrorCtx(r.Context(), "Failed to comment on alert %d: %v", id, err)
+		respondError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	respondJSON(w, http.StatusCreated, comment)
+}
+
+func (h *AlertHandler) GetComments(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseUint(mux.Vars(r)["id"], 10, 32)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid alert ID")
+		return
+	}
+
+	comments, err := h.alertService.GetComments(r.Context(), uint(id))
+	if errors.Is(err, repository.ErrAlertNotFound) {
+		respondError(w, http.StatusNotFound, err.Error())
+		return
+	}
+	if err != nil {
+		h.log.ErrorCtx(r.Context(), "Failed to get comments for alert %d: %v", id, err)
+		respondError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	respondJSON(w, http.StatusOK, comments)
+}
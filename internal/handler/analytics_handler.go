@@ -1,11 +1,15 @@
 package handler
 
 import (
+	"errors"
+	"fmt"
 	"net/http"
 	"strconv"
+	"strings"
 	"time"
 
 	"CampusMonitorAPI/internal/logger"
+	"CampusMonitorAPI/internal/repository"
 	"CampusMonitorAPI/internal/service"
 
 	"github.com/gorilla/mux"
@@ -29,27 +33,35 @@ func (h *AnalyticsHandler) RegisterRoutes(r *mux.Router) {
 	r.HandleFunc("/analytics/heatmap", h.GetHeatmap).Methods("GET")
 	r.HandleFunc("/analytics/channels", h.GetChannelDistribution).Methods("GET")
 	r.HandleFunc("/analytics/aps", h.GetAPAnalysis).Methods("GET")
+	r.HandleFunc("/analytics/neighbors", h.GetNeighborAPs).Methods("GET")
+	r.HandleFunc("/analytics/correlation", h.GetMetricCorrelation).Methods("GET")
 	r.HandleFunc("/analytics/congestion", h.GetCongestionAnalysis).Methods("GET")
 	r.HandleFunc("/analytics/performance/{probe_id}", h.GetPerformanceMetrics).Methods("GET")
+	r.HandleFunc("/analytics/performance/floor/{building}/{floor}", h.GetFloorPerformance).Methods("GET")
 	r.HandleFunc("/analytics/comparison", h.GetProbeComparison).Methods("GET")
 	r.HandleFunc("/analytics/health", h.GetNetworkHealth).Methods("GET")
 	r.HandleFunc("/analytics/anomalies/{probe_id}", h.DetectAnomalies).Methods("GET")
 	r.HandleFunc("/analytics/roaming/{probe_id}", h.GetRoamingAnalysis).Methods("GET")
 	r.HandleFunc("/analytics/coverage", h.GetDailyCoverage).Methods("GET")
+	r.HandleFunc("/analytics/coverage/gaps", h.GetCoverageGaps).Methods("GET")
+	r.HandleFunc("/analytics/stability", h.GetStabilityRanking).Methods("GET")
+	r.HandleFunc("/analytics/ingest-latency", h.GetIngestLatency).Methods("GET")
+	r.HandleFunc("/analytics/usage-profile/{probe_id}", h.GetUsageProfile).Methods("GET")
+	r.HandleFunc("/analytics/report", h.GetReport).Methods("GET")
 }
 
 func (h *AnalyticsHandler) GetRSSITimeSeries(w http.ResponseWriter, r *http.Request) {
 	probeID := r.URL.Query().Get("probe_id")
-	interval := r.URL.Query().Get("interval")
-	if interval == "" {
-		interval = "5 minutes"
-	}
-
 	start, end := parseTimeRange(r)
 
+	interval, ok := parseSeriesInterval(w, r, start, end)
+	if !ok {
+		return
+	}
+
 	data, err := h.analyticsService.GetRSSITimeSeries(r.Context(), probeID, start, end, interval)
 	if err != nil {
-		h.log.Error("Failed to get RSSI time series: %v", err)
+		h.log.ErrorCtx(r.Context(), "Failed to get RSSI time series: %v", err)
 		respondError(w, http.StatusInternalServerError, err.Error())
 		return
 	}
@@ -59,16 +71,16 @@ func (h *AnalyticsHandler) GetRSSITimeSeries(w http.ResponseWriter, r *http.Requ
 
 func (h *AnalyticsHandler) GetLatencyTimeSeries(w http.ResponseWriter, r *http.Request) {
 	probeID := r.URL.Query().Get("probe_id")
-	interval := r.URL.Query().Get("interval")
-	if interval == "" {
-		interval = "5 minutes"
-	}
-
 	start, end := parseTimeRange(r)
 
+	interval, ok := parseSeriesInterval(w, r, start, end)
+	if !ok {
+		return
+	}
+
 	data, err := h.analyticsService.GetLatencyTimeSeries(r.Context(), probeID, start, end, interval)
 	if err != nil {
-		h.log.Error("Failed to get latency time series: %v", err)
+		h.log.ErrorCtx(r.Context(), "Failed to get latency time series: %v", err)
 		respondError(w, http.StatusInternalServerError, err.Error())
 		return
 	}
@@ -78,10 +90,14 @@ func (h *AnalyticsHandler) GetLatencyTimeSeries(w http.ResponseWriter, r *http.R
 
 func (h *AnalyticsHandler) GetHeatmap(w http.ResponseWriter, r *http.Request) {
 	start, end := parseTimeRange(r)
+	historical, ok := parseHistorical(w, r)
+	if !ok {
+		return
+	}
 
-	data, err := h.analyticsService.GetHeatmapData(r.Context(), start, end)
+	data, err := h.analyticsService.GetHeatmapData(r.Context(), start, end, historical)
 	if err != nil {
-		h.log.Error("Failed to get heatmap data: %v", err)
+		h.log.ErrorCtx(r.Context(), "Failed to get heatmap data: %v", err)
 		respondError(w, http.StatusInternalServerError, err.Error())
 		return
 	}
@@ -93,7 +109,7 @@ func (h *AnalyticsHandler) GetChannelDistribution(w http.ResponseWriter, r *http
 
 	data, err := h.analyticsService.GetChannelDistribution(r.Context(), start, end)
 	if err != nil {
-		h.log.Error("Failed to get channel distribution: %v", err)
+		h.log.ErrorCtx(r.Context(), "Failed to get channel distribution: %v", err)
 		respondError(w, http.StatusInternalServerError, err.Error())
 		return
 	}
@@ -106,7 +122,48 @@ func (h *AnalyticsHandler) GetAPAnalysis(w http.ResponseWriter, r *http.Request)
 
 	data, err := h.analyticsService.GetAPAnalysis(r.Context(), start, end)
 	if err != nil {
-		h.log.Error("Failed to get AP analysis: %v", err)
+		h.log.ErrorCtx(r.Context(), "Failed to get AP analysis: %v", err)
+		respondError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	respondJSON(w, http.StatusOK, data)
+}
+
+func (h *AnalyticsHandler) GetMetricCorrelation(w http.ResponseWriter, r *http.Request) {
+	probeID := r.URL.Query().Get("probe_id")
+	metricA := r.URL.Query().Get("metric_a")
+	if metricA == "" {
+		metricA = "latency"
+	}
+	metricB := r.URL.Query().Get("metric_b")
+	if metricB == "" {
+		metricB = "rssi"
+	}
+
+	start, end := parseTimeRange(r)
+
+	data, err := h.analyticsService.GetMetricCorrelation(r.Context(), probeID, metricA, metricB, start, end)
+	if errors.Is(err, repository.ErrUnknownMetric) {
+		respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err != nil {
+		h.log.ErrorCtx(r.Context(), "Failed to get metric correlation: %v", err)
+		respondError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	respondJSON(w, http.StatusOK, data)
+}
+
+func (h *AnalyticsHandler) GetNeighborAPs(w http.ResponseWriter, r *http.Request) {
+	start, end := parseTimeRange(r)
+	probeID := r.URL.Query().Get("probe_id")
+
+	data, err := h.analyticsService.GetNeighborAPs(r.Context(), start, end, probeID)
+	if err != nil {
+		h.log.ErrorCtx(r.Context(), "Failed to get neighbor APs: %v", err)
 		respondError(w, http.StatusInternalServerError, err.Error())
 		return
 	}
@@ -119,7 +176,7 @@ func (h *AnalyticsHandler) GetCongestionAnalysis(w http.ResponseWriter, r *http.
 
 	data, err := h.analyticsService.GetCongestionAnalysis(r.Context(), start, end)
 	if err != nil {
-		h.log.Error("Failed to get congestion analysis: %v", err)
+		h.log.ErrorCtx(r.Context(), "Failed to get congestion analysis: %v", err)
 		respondError(w, http.StatusInternalServerError, err.Error())
 		return
 	}
@@ -132,10 +189,32 @@ func (h *AnalyticsHandler) GetPerformanceMetrics(w http.ResponseWriter, r *http.
 	probeID := vars["probe_id"]
 
 	start, end := parseTimeRange(r)
+	percentiles, ok := parsePercentiles(w, r)
+	if !ok {
+		return
+	}
 
-	data, err := h.analyticsService.GetPerformanceMetrics(r.Context(), probeID, start, end)
+	data, err := h.analyticsService.GetPerformanceMetrics(r.Context(), probeID, start, end, percentiles)
 	if err != nil {
-		h.log.Error("Failed to get performance metrics: %v", err)
+		h.log.ErrorCtx(r.Context(), "Failed to get performance metrics: %v", err)
+		respondError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	respondJSON(w, http.StatusOK, data)
+}
+
+func (h *AnalyticsHandler) GetFloorPerformance(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	start, end := parseTimeRange(r)
+
+	data, err := h.analyticsService.GetFloorPerformance(r.Context(), vars["building"], vars["floor"], start, end)
+	if errors.Is(err, service.ErrNoFloorProbes) {
+		respondError(w, http.StatusNotFound, err.Error())
+		return
+	}
+	if err != nil {
+		h.log.ErrorCtx(r.Context(), "Failed to get floor performance metrics: %v", err)
 		respondError(w, http.StatusInternalServerError, err.Error())
 		return
 	}
@@ -161,7 +240,7 @@ func (h *AnalyticsHandler) GetProbeComparison(w http.ResponseWriter, r *http.Req
 
 	data, err := h.analyticsService.GetProbeComparison(r.Context(), probeIDs, start, end)
 	if err != nil {
-		h.log.Error("Failed to compare probes: %v", err)
+		h.log.ErrorCtx(r.Context(), "Failed to compare probes: %v", err)
 		respondError(w, http.StatusInternalServerError, err.Error())
 		return
 	}
@@ -170,9 +249,19 @@ func (h *AnalyticsHandler) GetProbeComparison(w http.ResponseWriter, r *http.Req
 }
 
 func (h *AnalyticsHandler) GetNetworkHealth(w http.ResponseWriter, r *http.Request) {
-	data, err := h.analyticsService.GetNetworkHealth(r.Context())
+	var window time.Duration
+	if v := r.URL.Query().Get("window"); v != "" {
+		parsed, err := time.ParseDuration(v)
+		if err != nil || parsed <= 0 {
+			respondError(w, http.StatusBadRequest, "window must be a positive duration such as 10m")
+			return
+		}
+		window = parsed
+	}
+
+	data, err := h.analyticsService.GetNetworkHealth(r.Context(), window)
 	if err != nil {
-		h.log.Error("Failed to get network health: %v", err)
+		h.log.ErrorCtx(r.Context(), "Failed to get network health: %v", err)
 		respondError(w, http.StatusInternalServerError, err.Error())
 		return
 	}
@@ -184,16 +273,15 @@ func (h *AnalyticsHandler) DetectAnomalies(w http.ResponseWriter, r *http.Reques
 	vars := mux.Vars(r)
 	probeID := vars["probe_id"]
 
-	hours := 24
-	if h := r.URL.Query().Get("hours"); h != "" {
-		if parsed, err := strconv.Atoi(h); err == nil {
-			hours = parsed
-		}
+	windows, err := parseAnomalyWindows(r)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, err.Error())
+		return
 	}
 
-	data, err := h.analyticsService.DetectAnomalies(r.Context(), probeID, hours)
+	data, err := h.analyticsService.DetectAnomalies(r.Context(), probeID, windows)
 	if err != nil {
-		h.log.Error("Failed to detect anomalies: %v", err)
+		h.log.ErrorCtx(r.Context(), "Failed to detect anomalies: %v", err)
 		respondError(w, http.StatusInternalServerError, err.Error())
 		return
 	}
@@ -201,6 +289,48 @@ func (h *AnalyticsHandler) DetectAnomalies(w http.ResponseWriter, r *http.Reques
 	respondJSON(w, http.StatusOK, data)
 }
 
+// parseAnomalyWindows starts from the default per-metric windows, applies
+// the legacy hours parameter as the baseline for every metric, then any
+// <metric>_baseline and <metric>_recent durations (e.g. rssi_baseline=168h,
+// packet_loss_recent=2m).
+func parseAnomalyWindows(r *http.Request) (map[string]repository.AnomalyWindow, error) {
+	q := r.URL.Query()
+	var baseline time.Duration
+	if v := q.Get("hours"); v != "" {
+		hours, err := strconv.Atoi(v)
+		if err != nil || hours <= 0 {
+			return nil, errors.New("hours must be a positive integer")
+		}
+		baseline = time.Duration(hours) * time.Hour
+	}
+
+	windows := make(map[string]repository.AnomalyWindow, len(repository.DefaultAnomalyWindows))
+	for metric, window := range repository.DefaultAnomalyWindows {
+		if baseline > 0 {
+			window.Baseline = baseline
+		}
+		for param, field := range map[string]*time.Duration{
+			metric + "_baseline": &window.Baseline,
+			metric + "_recent":   &window.Recent,
+		} {
+			v := q.Get(param)
+			if v == "" {
+				continue
+			}
+			d, err := time.ParseDuration(v)
+			if err != nil || d <= 0 {
+				return nil, fmt.Errorf("%s must be a positive duration such as 30m", param)
+			}
+			*field = d
+		}
+		if window.Recent > window.Baseline {
+			return nil, fmt.Errorf("%s recent window must not exceed its baseline", metric)
+		}
+		windows[metric] = window
+	}
+	return windows, nil
+}
+
 func (h *AnalyticsHandler) GetRoamingAnalysis(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	probeID := vars["probe_id"]
@@ -209,7 +339,7 @@ func (h *AnalyticsHandler) GetRoamingAnalysis(w http.ResponseWriter, r *http.Req
 
 	data, err := h.analyticsService.GetRoamingAnalysis(r.Context(), probeID, start, end)
 	if err != nil {
-		h.log.Error("Failed to get roaming analysis: %v", err)
+		h.log.ErrorCtx(r.Context(), "Failed to get roaming analysis: %v", err)
 		respondError(w, http.StatusInternalServerError, err.Error())
 		return
 	}
@@ -227,13 +357,194 @@ func (h *AnalyticsHandler) GetDailyCoverage(w http.ResponseWriter, r *http.Reque
 	start, end := parseTimeRange(r)
 	coverage, err := h.analyticsService.GetDailyCoverage(r.Context(), probeID, start, end)
 	if err != nil {
-		h.log.Error("Failed to get daily coverage: %v", err)
+		h.log.ErrorCtx(r.Context(), "Failed to get daily coverage: %v", err)
 		respondError(w, http.StatusInternalServerError, err.Error())
 		return
 	}
 	respondJSON(w, http.StatusOK, coverage)
 }
 
+// GetUsageProfile returns load by hour of day over the last `days` days
+// (default 7); probe_id "all" covers the whole fleet.
+func (h *AnalyticsHandler) GetUsageProfile(w http.ResponseWriter, r *http.Request) {
+	probeID := mux.Vars(r)["probe_id"]
+
+	days := 7
+	if d := r.URL.Query().Get("days"); d != "" {
+		parsed, err := strconv.Atoi(d)
+		if err != nil || parsed < 1 || parsed > 90 {
+			respondError(w, http.StatusBadRequest, "days must be between 1 and 90")
+			return
+		}
+		days = parsed
+	}
+
+	data, err := h.analyticsService.GetHourlyUsageProfile(r.Context(), probeID, days)
+	if err != nil {
+		h.log.ErrorCtx(r.Context(), "Failed to get usage profile: %v", err)
+		respondError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	respondJSON(w, http.StatusOK, data)
+}
+
+// GetStabilityRanking lists probes by stability score, worst first.
+func (h *AnalyticsHandler) GetStabilityRanking(w http.ResponseWriter, r *http.Request) {
+	start, end := parseTimeRange(r)
+
+	data, err := h.analyticsService.GetProbeStabilityRanking(r.Context(), start, end)
+	if err != nil {
+		h.log.ErrorCtx(r.Context(), "Failed to get stability ranking: %v", err)
+		respondError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	respondJSON(w, http.StatusOK, data)
+}
+
+func (h *AnalyticsHandler) GetIngestLatency(w http.ResponseWriter, r *http.Request) {
+	probeID := r.URL.Query().Get("probe_id")
+	start, end := parseTimeRange(r)
+
+	data, err := h.analyticsService.GetIngestLatency(r.Context(), probeID, start, end)
+	if err != nil {
+		h.log.ErrorCtx(r.Context(), "Failed to get ingest latency: %v", err)
+		respondError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	respondJSON(w, http.StatusOK, data)
+}
+
+// GetCoverageGaps ranks floors by how much of the period they spent below an
+// acceptable signal level.
+func (h *AnalyticsHandler) GetCoverageGaps(w http.ResponseWriter, r *http.Request) {
+	threshold := -75.0
+	if v := r.URL.Query().Get("rssi_threshold"); v != "" {
+		parsed, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			respondError(w, http.StatusBadRequest, "rssi_threshold must be a number")
+			return
+		}
+		threshold = parsed
+	}
+	interval := r.URL.Query().Get("interval")
+	if interval == "" {
+		interval = "1 hour"
+	}
+
+	start, end := parseTimeRange(r)
+	historical, ok := parseHistorical(w, r)
+	if !ok {
+		return
+	}
+
+	data, err := h.analyticsService.GetCoverageGaps(r.Context(), start, end, threshold, interval, historical)
+	if err != nil {
+		h.log.ErrorCtx(r.Context(), "Failed to get coverage gaps: %v", err)
+		respondError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	respondJSON(w, http.StatusOK, data)
+}
+
+func (h *AnalyticsHandler) GetReport(w http.ResponseWriter, r *http.Request) {
+	start, end := parseTimeRange(r)
+	if !start.Before(end) {
+		respondError(w, http.StatusBadRequest, "start_time must be before end_time")
+		return
+	}
+
+	top := 10
+	if v := r.URL.Query().Get("top"); v != "" {
+		parsed, err := strconv.Atoi(v)
+		if err != nil || parsed < 1 || parsed > 100 {
+			respondError(w, http.StatusBadRequest, "top must be between 1 and 100")
+			return
+		}
+		top = parsed
+	}
+
+	report, err := h.analyticsService.BuildReport(r.Context(), start, end, top)
+	if err != nil {
+		h.log.ErrorCtx(r.Context(), "Failed to build analytics report: %v", err)
+		respondError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	respondJSON(w, http.StatusOK, report)
+}
+
+// maxPercentiles caps how many percentiles one request can ask for.
+const maxPercentiles = 10
+
+// parsePercentiles reads a comma-separated percentiles list such as
+// "50,90,99.9". It returns nil when the parameter is absent.
+func parsePercentiles(w http.ResponseWriter, r *http.Request) ([]float64, bool) {
+	v := r.URL.Query().Get("percentiles")
+	if v == "" {
+		return nil, true
+	}
+
+	parts := strings.Split(v, ",")
+	if len(parts) > maxPercentiles {
+		respondError(w, http.StatusBadRequest, fmt.Sprintf("at most %d percentiles may be requested", maxPercentiles))
+		return nil, false
+	}
+	percentiles := make([]float64, 0, len(parts))
+	for _, part := range parts {
+		p, err := strconv.ParseFloat(strings.TrimSpace(part), 64)
+		if err != nil || !(p >= 0 && p <= 100) {
+			respondError(w, http.StatusBadRequest, "percentiles must be numbers between 0 and 100")
+			return nil, false
+		}
+		percentiles = append(percentiles, p)
+	}
+	return percentiles, true
+}
+
+// maxSeriesPoints caps max_points so a request can't ask for a bucket per second.
+const maxSeriesPoints = 10000
+
+// parseSeriesInterval picks the time_bucket interval for a time series. With
+// max_points it is derived from the range so the series stays under that many
+// points and any interval parameter is ignored; otherwise interval is used
+// as given (default 5 minutes). It writes a 400 and returns false on a bad
+// max_points.
+func parseSeriesInterval(w http.ResponseWriter, r *http.Request, start, end time.Time) (string, bool) {
+	if v := r.URL.Query().Get("max_points"); v != "" {
+		maxPoints, err := strconv.Atoi(v)
+		if err != nil || maxPoints < 1 || maxPoints > maxSeriesPoints {
+			respondError(w, http.StatusBadRequest, fmt.Sprintf("max_points must be between 1 and %d", maxSeriesPoints))
+			return "", false
+		}
+		return service.DownsampleInterval(start, end, maxPoints), true
+	}
+
+	interval := r.URL.Query().Get("interval")
+	if interval == "" {
+		interval = "5 minutes"
+	}
+	return interval, true
+}
+
+// parseHistorical reads the historical flag that makes location-aware
+// analytics attribute each reading to where its probe was at the time.
+func parseHistorical(w http.ResponseWriter, r *http.Request) (bool, bool) {
+	v := r.URL.Query().Get("historical")
+	if v == "" {
+		return false, true
+	}
+	historical, err := strconv.ParseBool(v)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "historical must be true or false")
+		return false, false
+	}
+	return historical, true
+}
+
 func parseTimeRange(r *http.Request) (time.Time, time.Time) {
 	end := time.Now()
 	start := end.Add(-24 * time.Hour)
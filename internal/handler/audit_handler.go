@@ -0,0 +1,76 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"CampusMonitorAPI/internal/logger"
+	"CampusMonitorAPI/internal/middleware"
+	"CampusMonitorAPI/internal/models"
+	"CampusMonitorAPI/internal/service"
+
+	"github.com/gorilla/mux"
+)
+
+type AuditHandler struct {
+	auditService *service.AuditService
+	log          *logger.Logger
+}
+
+func NewAuditHandler(auditService *service.AuditService, log *logger.Logger) *AuditHandler {
+	return &AuditHandler{
+		auditService: auditService,
+		log:          log,
+	}
+}
+
+func (h *AuditHandler) RegisterRoutes(r *mux.Router) {
+	r.Handle("/audit", middleware.RequireAdmin(http.HandlerFunc(h.ListAudit))).Methods("GET")
+}
+
+// ListAudit returns the audit trail, newest first, filtered by user and
+// time range.
+func (h *AuditHandler) ListAudit(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	q := models.AuditQuery{
+		Principal: query.Get("user"),
+		Limit:     100,
+	}
+
+	if s := query.Get("start_time"); s != "" {
+		t, err := time.Parse(time.RFC3339, s)
+		if err != nil {
+			respondError(w, http.StatusBadRequest, "start_time must be RFC3339")
+			return
+		}
+		q.Start = t
+	}
+	if e := query.Get("end_time"); e != "" {
+		t, err := time.Parse(time.RFC3339, e)
+		if err != nil {
+			respondError(w, http.StatusBadRequest, "end_time must be RFC3339")
+			return
+		}
+		q.End = t
+	}
+	if l := query.Get("limit"); l != "" {
+		if parsed, err := strconv.Atoi(l); err == nil {
+			q.Limit = parsed
+		}
+	}
+	if o := query.Get("offset"); o != "" {
+		if parsed, err := strconv.Atoi(o); err == nil {
+			q.Offset = parsed
+		}
+	}
+
+	entries, err := h.auditService.List(r.Context(), q)
+	if err != nil {
+		h.log.ErrorCtx(r.Context(), "Failed to list audit entries: %v", err)
+		respondError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	respondJSON(w, http.StatusOK, entries)
+}
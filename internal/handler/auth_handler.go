@@ -69,7 +69,7 @@ func (h *AuthHandler) Register(w http.ResponseWriter, r *http.Request) {
 			respondError(w, http.StatusForbidden, "Admin registration is disabled")
 			return
 		}
-		h.log.Warn("Registration failed: %v", err)
+		h.log.WarnCtx(r.Context(), "Registration failed: %v", err)
 		respondError(w, http.StatusConflict, err.Error())
 		return
 	}
@@ -96,7 +96,7 @@ func (h *AuthHandler) Login(w http.ResponseWriter, r *http.Request) {
 	if twoFARequired {
 		tempToken, err := h.authService.CreateTemp2FAToken(user.ID)
 		if err != nil {
-			h.log.Error("Failed to create temp token: %v", err)
+			h.log.ErrorCtx(r.Context(), "Failed to create temp token: %v", err)
 			respondError(w, http.StatusInternalServerError, "Internal error")
 			return
 		}
@@ -108,7 +108,7 @@ func (h *AuthHandler) Login(w http.ResponseWriter, r *http.Request) {
 	}
 	accessToken, refreshToken, err := h.authService.IssueTokens(r.Context(), user, false)
 	if err != nil {
-		h.log.Error("Failed to issue tokens: %v", err)
+		h.log.ErrorCtx(r.Context(), "Failed to issue tokens: %v", err)
 		respondError(w, http.StatusInternalServerError, "Internal error")
 		return
 	}
@@ -134,12 +134,12 @@ func (h *AuthHandler) OAuthInit(w http.ResponseWriter, r *http.Request) {
 
 	state, err := h.authService.GenerateOAuthState(r.Context(), redirectURI)
 	if err != nil {
-		h.log.Error("Failed to generate OAuth state: %v", err)
+		h.log.ErrorCtx(r.Context(), "Failed to generate OAuth state: %v", err)
 		respondError(w, http.StatusInternalServerError, "Internal error")
 		return
 	}
 	authURL := cfg.AuthCodeURL(state)
-	h.log.Info("OAuth redirect URL: %s", authURL)
+	h.log.InfoCtx(r.Context(), "OAuth redirect URL: %s", authURL)
 	http.Redirect(w, r, authURL, http.StatusTemporaryRedirect)
 }
 
@@ -154,7 +154,7 @@ func (h *AuthHandler) OAuthCallback(w http.ResponseWriter, r *http.Request) {
 	}
 	redirectURI, err := h.authService.VerifyOAuthState(r.Context(), state)
 	if err != nil {
-		h.log.Warn("Invalid OAuth state: %v", err)
+		h.log.WarnCtx(r.Context(), "Invalid OAuth state: %v", err)
 		respondError(w, http.StatusBadRequest, "Invalid state")
 		return
 	}
@@ -165,26 +165,26 @@ func (h *AuthHandler) OAuthCallback(w http.ResponseWriter, r *http.Request) {
 	}
 	token, err := cfg.Exchange(r.Context(), code)
 	if err != nil {
-		h.log.Error("OAuth token exchange failed: %v", err)
+		h.log.ErrorCtx(r.Context(), "OAuth token exchange failed: %v", err)
 		respondError(w, http.StatusInternalServerError, "OAuth exchange failed")
 		return
 	}
 	userInfo, err := h.getUserInfo(r.Context(), provider, token)
 	if err != nil {
-		h.log.Error("Failed to get user info: %v", err)
+		h.log.ErrorCtx(r.Context(), "Failed to get user info: %v", err)
 		respondError(w, http.StatusInternalServerError, "Failed to get user info")
 		return
 	}
 	user, twoFARequired, err := h.authService.HandleOAuthCallback(r.Context(), provider, userInfo, token)
 	if err != nil {
-		h.log.Error("OAuth callback handling failed: %v", err)
+		h.log.ErrorCtx(r.Context(), "OAuth callback handling failed: %v", err)
 		respondError(w, http.StatusInternalServerError, "OAuth processing failed")
 		return
 	}
 	if twoFARequired {
 		tempToken, err := h.authService.CreateTemp2FAToken(user.ID)
 		if err != nil {
-			h.log.Error("Failed to create temp token: %v", err)
+			h.log.ErrorCtx(r.Context(), "Failed to create temp token: %v", err)
 			respondError(w, http.StatusInternalServerError, "Internal error")
 			return
 		}
@@ -194,7 +194,7 @@ func (h *AuthHandler) OAuthCallback(w http.ResponseWriter, r *http.Request) {
 	}
 	accessToken, refreshToken, err := h.authService.IssueTokens(r.Context(), user, false)
 	if err != nil {
-		h.log.Error("Failed to issue tokens: %v", err)
+		h.log.ErrorCtx(r.Context(), "Failed to issue tokens: %v", err)
 		respondError(w, http.StatusInternalServerError, "Internal error")
 		return
 	}
@@ -233,36 +233,36 @@ func (h *AuthHandler) Verify2FA(w http.ResponseWriter, r *http.Request) {
 		respondError(w, http.StatusBadRequest, "Invalid request")
 		return
 	}
-	h.log.Info("Verify2FA: tempToken received, length=%d", len(req.TempToken))
+	h.log.InfoCtx(r.Context(), "Verify2FA: tempToken received, length=%d", len(req.TempToken))
 
 	claims, err := auth.ValidateToken(req.TempToken, h.authService.Cfg.JWTSecret)
 	if err != nil || !claims.Temp {
-		h.log.Warn("Verify2FA: invalid temp token: %v", err)
+		h.log.WarnCtx(r.Context(), "Verify2FA: invalid temp token: %v", err)
 		respondError(w, http.StatusUnauthorized, "Invalid or expired temp token")
 		return
 	}
-	h.log.Info("Verify2FA: userID from token = %d", claims.UserID)
+	h.log.InfoCtx(r.Context(), "Verify2FA: userID from token = %d", claims.UserID)
 
 	valid, err := h.authService.ValidateTOTP(r.Context(), claims.UserID, req.Code)
 	if err != nil {
-		h.log.Error("ValidateTOTP error: %v", err)
+		h.log.ErrorCtx(r.Context(), "ValidateTOTP error: %v", err)
 		respondError(w, http.StatusInternalServerError, "Internal error")
 		return
 	}
 	if !valid {
-		h.log.Warn("Verify2FA: invalid code for user %d", claims.UserID)
+		h.log.WarnCtx(r.Context(), "Verify2FA: invalid code for user %d", claims.UserID)
 		respondError(w, http.StatusUnauthorized, "Invalid 2FA code")
 		return
 	}
 	user, err := h.authService.UserRepo.GetUserByID(r.Context(), claims.UserID)
 	if err != nil {
-		h.log.Error("Failed to get user: %v", err)
+		h.log.ErrorCtx(r.Context(), "Failed to get user: %v", err)
 		respondError(w, http.StatusInternalServerError, "Internal error")
 		return
 	}
 	accessToken, refreshToken, err := h.authService.IssueTokens(r.Context(), user, true)
 	if err != nil {
-		h.log.Error("Failed to issue tokens: %v", err)
+		h.log.ErrorCtx(r.Context(), "Failed to issue tokens: %v", err)
 		respondError(w, http.StatusInternalServerError, "Internal error")
 		return
 	}
@@ -337,7 +337,7 @@ func (h *AuthHandler) GetMe(w http.ResponseWriter, r *http.Request) {
 	}
 	user, err := h.authService.UserRepo.GetUserByID(r.Context(), claims.UserID)
 	if err != nil {
-		h.log.Error("Failed to get user: %v", err)
+		h.log.ErrorCtx(r.Context(), "Failed to get user: %v", err)
 		respondError(w, http.StatusInternalServerError, "Internal error")
 		return
 	}
@@ -360,13 +360,13 @@ func (h *AuthHandler) Enable2FA(w http.ResponseWriter, r *http.Request) {
 	}
 	user, err := h.authService.UserRepo.GetUserByID(r.Context(), claims.UserID)
 	if err != nil {
-		h.log.Error("Failed to get user: %v", err)
+		h.log.ErrorCtx(r.Context(), "Failed to get user: %v", err)
 		respondError(w, http.StatusInternalServerError, "Internal error")
 		return
 	}
 	secret, uri, err := h.authService.GenerateTOTPSecret(r.Context(), user.ID, user.Email)
 	if err != nil {
-		h.log.Error("Failed to generate TOTP secret: %v", err)
+		h.log.ErrorCtx(r.Context(), "Failed to generate TOTP secret: %v", err)
 		respondError(w, http.StatusInternalServerError, "Internal error")
 		return
 	}
@@ -403,7 +403,7 @@ func (h *AuthHandler) Disable2FA(w http.ResponseWriter, r *http.Request) {
 	}
 	err := h.authService.DisableTOTP(r.Context(), claims.UserID)
 	if err != nil {
-		h.log.Error("Failed to disable 2FA: %v", err)
+		h.log.ErrorCtx(r.Context(), "Failed to disable 2FA: %v", err)
 		respondError(w, http.StatusInternalServerError, "Internal error")
 		return
 	}
@@ -4,10 +4,12 @@ package handler
 
 import (
 	"encoding/json"
+	"errors"
 	"net/http"
 	"strconv"
 
 	"CampusMonitorAPI/internal/logger"
+	"CampusMonitorAPI/internal/middleware"
 	"CampusMonitorAPI/internal/models"
 	"CampusMonitorAPI/internal/service"
 
@@ -32,7 +34,9 @@ func (h *CommandHandler) RegisterRoutes(r *mux.Router) {
 	r.HandleFunc("/commands/probe/{probe_id}", h.GetCommandHistory).Methods("GET")
 	r.HandleFunc("/commands/pending", h.GetPendingCommands).Methods("GET")
 	r.HandleFunc("/commands/broadcast", h.BroadcastCommand).Methods("POST")
+	r.Handle("/commands/broadcast/tag/{tag}", middleware.RequireAdmin(http.HandlerFunc(h.BroadcastToTag))).Methods("POST")
 	r.HandleFunc("/commands/statistics", h.GetStatistics).Methods("GET")
+	r.HandleFunc("/commands/ota/status", h.GetOTAStatus).Methods("GET")
 	r.HandleFunc("/commands/{id}/result", h.UpdateCommandResult).Methods("PUT")
 	r.HandleFunc("/commands/{id}", h.DeleteCommand).Methods("DELETE")
 	r.HandleFunc("/probes/{probe_id}/ping-status", h.GetPingStatus).Methods("GET")
@@ -41,19 +45,21 @@ func (h *CommandHandler) RegisterRoutes(r *mux.Router) {
 func (h *CommandHandler) IssueCommand(w http.ResponseWriter, r *http.Request) {
 	var req models.CommandRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		h.log.Warn("Invalid request body: %v", err)
+		h.log.WarnCtx(r.Context(), "Invalid request body: %v", err)
 		respondError(w, http.StatusBadRequest, "Invalid request body")
 		return
 	}
 
-	if req.ProbeID == "" || req.CommandType == "" {
-		respondError(w, http.StatusBadRequest, "probe_id and command_type are required")
+	command, err := h.commandService.IssueCommand(r.Context(), &req)
+	if respondIfValidation(w, err) {
+		return
+	}
+	if errors.Is(err, service.ErrCommandRateLimited) {
+		respondError(w, http.StatusTooManyRequests, err.Error())
 		return
 	}
-
-	command, err := h.commandService.IssueCommand(r.Context(), &req)
 	if err != nil {
-		h.log.Error("Failed to issue command: %v", err)
+		h.log.ErrorCtx(r.Context(), "Failed to issue command: %v", err)
 		respondError(w, http.StatusInternalServerError, err.Error())
 		return
 	}
@@ -69,9 +75,9 @@ func (h *CommandHandler) GetCommand(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	command, err := h.commandService.GetCommandHistory(r.Context(), strconv.Itoa(id))
+	command, err := h.commandService.GetCommandByID(r.Context(), id)
 	if err != nil {
-		h.log.Error("Failed to get command: %v", err)
+		h.log.ErrorCtx(r.Context(), "Failed to get command: %v", err)
 		respondError(w, http.StatusNotFound, "Command not found")
 		return
 	}
@@ -81,11 +87,33 @@ func (h *CommandHandler) GetCommand(w http.ResponseWriter, r *http.Request) {
 
 func (h *CommandHandler) GetCommandHistory(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
-	probeID := vars["probe_id"]
+	query := r.URL.Query()
 
-	commands, err := h.commandService.GetCommandHistory(r.Context(), probeID)
+	req := &models.CommandHistoryRequest{
+		ProbeID:     vars["probe_id"],
+		Status:      query.Get("status"),
+		CommandType: query.Get("command_type"),
+	}
+	if v := query.Get("limit"); v != "" {
+		limit, err := strconv.Atoi(v)
+		if err != nil {
+			respondError(w, http.StatusBadRequest, "Invalid limit")
+			return
+		}
+		req.Limit = limit
+	}
+	if v := query.Get("offset"); v != "" {
+		offset, err := strconv.Atoi(v)
+		if err != nil {
+			respondError(w, http.StatusBadRequest, "Invalid offset")
+			return
+		}
+		req.Offset = offset
+	}
+
+	commands, err := h.commandService.GetCommandHistory(r.Context(), req)
 	if err != nil {
-		h.log.Error("Failed to get command history: %v", err)
+		h.log.ErrorCtx(r.Context(), "Failed to get command history: %v", err)
 		respondError(w, http.StatusInternalServerError, err.Error())
 		return
 	}
@@ -96,7 +124,7 @@ func (h *CommandHandler) GetCommandHistory(w http.ResponseWriter, r *http.Reques
 func (h *CommandHandler) GetPendingCommands(w http.ResponseWriter, r *http.Request) {
 	commands, err := h.commandService.GetPendingCommands(r.Context())
 	if err != nil {
-		h.log.Error("Failed to get pending commands: %v", err)
+		h.log.ErrorCtx(r.Context(), "Failed to get pending commands: %v", err)
 		respondError(w, http.StatusInternalServerError, err.Error())
 		return
 	}
@@ -111,7 +139,7 @@ func (h *CommandHandler) BroadcastCommand(w http.ResponseWriter, r *http.Request
 	}
 
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		h.log.Warn("Invalid request body: %v", err)
+		h.log.WarnCtx(r.Context(), "Invalid request body: %v", err)
 		respondError(w, http.StatusBadRequest, "Invalid request body")
 		return
 	}
@@ -123,7 +151,7 @@ func (h *CommandHandler) BroadcastCommand(w http.ResponseWriter, r *http.Request
 
 	err := h.commandService.BroadcastCommand(r.Context(), req.CommandType, req.Params)
 	if err != nil {
-		h.log.Error("Failed to broadcast command: %v", err)
+		h.log.ErrorCtx(r.Context(), "Failed to broadcast command: %v", err)
 		respondError(w, http.StatusInternalServerError, err.Error())
 		return
 	}
@@ -134,10 +162,44 @@ func (h *CommandHandler) BroadcastCommand(w http.ResponseWriter, r *http.Request
 	})
 }
 
+func (h *CommandHandler) BroadcastToTag(w http.ResponseWriter, r *http.Request) {
+	tag := mux.Vars(r)["tag"]
+
+	var req struct {
+		CommandType string                 `json:"command_type"`
+		Params      map[string]interface{} `json:"params,omitempty"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.log.WarnCtx(r.Context(), "Invalid request body: %v", err)
+		respondError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	result, err := h.commandService.BroadcastToTag(r.Context(), tag, req.CommandType, req.Params, getUserFromContext(r))
+	if respondIfValidation(w, err) {
+		return
+	}
+	if errors.Is(err, service.ErrNoTaggedProbes) {
+		respondError(w, http.StatusNotFound, err.Error())
+		return
+	}
+	if err != nil {
+		h.log.ErrorCtx(r.Context(), "Failed to broadcast command to tag %s: %v", tag, err)
+		respondError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	respondJSON(w, http.StatusOK, result)
+}
+
+func (h *CommandHandler) GetOTAStatus(w http.ResponseWriter, r *http.Request) {
+	respondJSON(w, http.StatusOK, h.commandService.GetOTAStatus())
+}
+
 func (h *CommandHandler) GetStatistics(w http.ResponseWriter, r *http.Request) {
 	stats, err := h.commandService.GetCommandStatistics(r.Context())
 	if err != nil {
-		h.log.Error("Failed to get command statistics: %v", err)
+		h.log.ErrorCtx(r.Context(), "Failed to get command statistics: %v", err)
 		respondError(w, http.StatusInternalServerError, err.Error())
 		return
 	}
@@ -155,13 +217,13 @@ func (h *CommandHandler) UpdateCommandResult(w http.ResponseWriter, r *http.Requ
 
 	var result map[string]interface{}
 	if err := json.NewDecoder(r.Body).Decode(&result); err != nil {
-		h.log.Warn("Invalid request body: %v", err)
+		h.log.WarnCtx(r.Context(), "Invalid request body: %v", err)
 		respondError(w, http.StatusBadRequest, "Invalid request body")
 		return
 	}
 
 	if err := h.commandService.UpdateResultByID(r.Context(), id, result); err != nil {
-		h.log.Error("Failed to update command result: %v", err)
+		h.log.ErrorCtx(r.Context(), "Failed to update command result: %v", err)
 		respondError(w, http.StatusInternalServerError, err.Error())
 		return
 	}
@@ -180,7 +242,7 @@ func (h *CommandHandler) DeleteCommand(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if err := h.commandService.DeleteCommand(r.Context(), id); err != nil {
-		h.log.Error("Failed to delete command: %v", err)
+		h.log.ErrorCtx(r.Context(), "Failed to delete command: %v", err)
 		respondError(w, http.StatusInternalServerError, err.Error())
 		return
 	}
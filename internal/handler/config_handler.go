@@ -0,0 +1,65 @@
+package handler
+
+import (
+	"net/http"
+
+	"CampusMonitorAPI/internal/config"
+	"CampusMonitorAPI/internal/logger"
+	"CampusMonitorAPI/internal/middleware"
+
+	"github.com/gorilla/mux"
+)
+
+type ConfigHandler struct {
+	cfg *config.Live
+	log *logger.Logger
+}
+
+func NewConfigHandler(cfg *config.Live, log *logger.Logger) *ConfigHandler {
+	return &ConfigHandler{
+		cfg: cfg,
+		log: log,
+	}
+}
+
+func (h *ConfigHandler) RegisterRoutes(r *mux.Router) {
+	r.Handle("/config", middleware.RequireAdmin(http.HandlerFunc(h.GetConfig))).Methods("GET")
+	r.Handle("/log/level", middleware.RequireAdmin(http.HandlerFunc(h.SetLogLevel))).Methods("PUT")
+}
+
+// GetConfig returns the effective configuration with secrets redacted.
+func (h *ConfigHandler) GetConfig(w http.ResponseWriter, r *http.Request) {
+	data, err := h.cfg.Get().Redacted().ToMap()
+	if err != nil {
+		h.log.ErrorCtx(r.Context(), "Failed to render config: %v", err)
+		respondError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	respondJSON(w, http.StatusOK, data)
+}
+
+// SetLogLevel changes the logger's level at runtime without a restart.
+func (h *ConfigHandler) SetLogLevel(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Level string `json:"level"`
+	}
+	if err := decodeJSON(r, &req); err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	level, ok := logger.LookupLevel(req.Level)
+	if !ok {
+		respondError(w, http.StatusBadRequest, "level must be one of debug, info, warn, error, fatal")
+		return
+	}
+
+	h.log.SetLevel(level)
+	h.cfg.Update(func(cfg *config.Config) {
+		cfg.Logging.Level = level
+	})
+	h.log.InfoCtx(r.Context(), "Log level changed to %s", level)
+
+	respondJSON(w, http.StatusOK, map[string]string{"level": level.String()})
+}
@@ -0,0 +1,147 @@
+package handler
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// wantsCSV reports whether the client asked for CSV, either with
+// ?format=csv or an Accept header listing text/csv.
+func wantsCSV(r *http.Request) bool {
+	if format := r.URL.Query().Get("format"); format != "" {
+		return strings.EqualFold(format, "csv")
+	}
+	return strings.Contains(r.Header.Get("Accept"), "text/csv")
+}
+
+// respondCSV writes rows, a slice of structs, as a CSV attachment named
+// name.csv. Columns follow the struct's JSON field names in declaration order.
+func respondCSV(w http.ResponseWriter, name string, rows interface{}) {
+	records, err := csvRecords(rows)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%s.csv", name))
+	w.WriteHeader(http.StatusOK)
+
+	cw := csv.NewWriter(w)
+	_ = cw.WriteAll(records)
+}
+
+// csvRecords flattens a slice of structs into a header row followed by one
+// row per element. Embedded structs contribute their fields inline; nested
+// maps, slices and structs are written as JSON; nil pointers are empty.
+func csvRecords(rows interface{}) ([][]string, error) {
+	v := reflect.ValueOf(rows)
+	if v.Kind() != reflect.Slice {
+		return nil, fmt.Errorf("csv: expected a slice, got %s", v.Kind())
+	}
+
+	elem := v.Type().Elem()
+	for elem.Kind() == reflect.Ptr {
+		elem = elem.Elem()
+	}
+	if elem.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("csv: expected a slice of structs, got %s", elem.Kind())
+	}
+
+	columns := csvColumns(elem, nil)
+	header := make([]string, len(columns))
+	for i, c := range columns {
+		header[i] = c.name
+	}
+
+	records := [][]string{header}
+	for i := 0; i < v.Len(); i++ {
+		item := reflect.Indirect(v.Index(i))
+		record := make([]string, len(columns))
+		if item.IsValid() {
+			for j, c := range columns {
+				record[j] = csvValue(item.FieldByIndex(c.index))
+			}
+		}
+		records = append(records, record)
+	}
+	return records, nil
+}
+
+type csvColumn struct {
+	name  string
+	index []int
+}
+
+func csvColumns(t reflect.Type, prefix []int) []csvColumn {
+	var columns []csvColumn
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		index := append(append([]int(nil), prefix...), i)
+
+		if field.Anonymous && field.Type.Kind() == reflect.Struct {
+			columns = append(columns, csvColumns(field.Type, index)...)
+			continue
+		}
+		if !field.IsExported() {
+			continue
+		}
+
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		columns = append(columns, csvColumn{name: name, index: index})
+	}
+	return columns
+}
+
+// csvValue renders one cell. Strings that a spreadsheet would run as a
+// formula are prefixed with a quote; numbers are left alone.
+func csvValue(v reflect.Value) string {
+	for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return ""
+		}
+		v = v.Elem()
+	}
+
+	if t, ok := v.Interface().(time.Time); ok {
+		if t.IsZero() {
+			return ""
+		}
+		return t.Format(time.RFC3339)
+	}
+
+	switch v.Kind() {
+	case reflect.Map, reflect.Slice, reflect.Struct:
+		if (v.Kind() == reflect.Map || v.Kind() == reflect.Slice) && v.Len() == 0 {
+			return ""
+		}
+		b, err := json.Marshal(v.Interface())
+		if err != nil {
+			return ""
+		}
+		return string(b)
+	case reflect.String:
+		return escapeCSVFormula(v.String())
+	}
+	return fmt.Sprint(v.Interface())
+}
+
+// escapeCSVFormula neutralises values such as probe names or alert messages
+// that start with a formula trigger.
+func escapeCSVFormula(s string) string {
+	if s != "" && strings.ContainsRune("=+-@\t\r", rune(s[0])) {
+		return "'" + s
+	}
+	return s
+}
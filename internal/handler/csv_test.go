@@ -0,0 +1,33 @@
+package handler
+
+import "testing"
+
+func TestCSVRecordsEscapeFormulas(t *testing.T) {
+	type row struct {
+		Name    string `json:"name"`
+		Message string `json:"message"`
+		RSSI    int    `json:"rssi"`
+	}
+	records, err := csvRecords([]row{
+		{Name: "=HYPERLINK(\"http://evil\")", Message: "@SUM(A1)", RSSI: -60},
+		{Name: "+1", Message: "\tcmd", RSSI: -70},
+		{Name: "Lab-2", Message: "-3 dBm drop", RSSI: 0},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := [][]string{
+		{"name", "message", "rssi"},
+		{"'=HYPERLINK(\"http://evil\")", "'@SUM(A1)", "-60"},
+		{"'+1", "'\tcmd", "-70"},
+		{"Lab-2", "'-3 dBm drop", "0"},
+	}
+	for i := range want {
+		for j := range want[i] {
+			if records[i][j] != want[i][j] {
+				t.Errorf("record[%d][%d] = %q, want %q", i, j, records[i][j], want[i][j])
+			}
+		}
+	}
+}
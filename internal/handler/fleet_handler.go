@@ -6,6 +6,7 @@ import (
 	"strconv"
 	"time"
 
+	"CampusMonitorAPI/internal/auth"
 	"CampusMonitorAPI/internal/logger"
 	"CampusMonitorAPI/internal/models"
 	"CampusMonitorAPI/internal/service"
@@ -78,14 +79,14 @@ func (h *FleetHandler) EnrollProbe(w http.ResponseWriter, r *http.Request) {
 	// Verify probe exists
 	_, err := h.probeService.GetProbe(r.Context(), probeID)
 	if err != nil {
-		h.log.Warn("Attempted to enroll non-existent probe: %s", probeID)
+		h.log.WarnCtx(r.Context(), "Attempted to enroll non-existent probe: %s", probeID)
 		respondError(w, http.StatusNotFound, "Probe not found")
 		return
 	}
 
 	var req models.FleetEnrollRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		h.log.Warn("Invalid enroll request: %v", err)
+		h.log.WarnCtx(r.Context(), "Invalid enroll request: %v", err)
 		respondError(w, http.StatusBadRequest, "Invalid request body")
 		return
 	}
@@ -93,7 +94,7 @@ func (h *FleetHandler) EnrollProbe(w http.ResponseWriter, r *http.Request) {
 	user := getUserFromContext(r)
 
 	if err := h.fleetService.EnrollProbe(r.Context(), probeID, &req, user); err != nil {
-		h.log.Error("Failed to enroll probe %s: %v", probeID, err)
+		h.log.ErrorCtx(r.Context(), "Failed to enroll probe %s: %v", probeID, err)
 		respondError(w, http.StatusInternalServerError, err.Error())
 		return
 	}
@@ -106,7 +107,7 @@ func (h *FleetHandler) EnrollProbe(w http.ResponseWriter, r *http.Request) {
 func (h *FleetHandler) ListUnenrolledProbes(w http.ResponseWriter, r *http.Request) {
 	probes, err := h.fleetService.GetUnenrolledProbes(r.Context())
 	if err != nil {
-		h.log.Error("Failed to list unenrolled probes: %v", err)
+		h.log.ErrorCtx(r.Context(), "Failed to list unenrolled probes: %v", err)
 		respondError(w, http.StatusInternalServerError, "Failed to fetch unenrolled probes")
 		return
 	}
@@ -117,7 +118,7 @@ func (h *FleetHandler) UnenrollProbe(w http.ResponseWriter, r *http.Request) {
 	probeID := vars["id"]
 
 	if err := h.fleetService.UnenrollProbe(r.Context(), probeID); err != nil {
-		h.log.Error("Failed to unenroll probe %s: %v", probeID, err)
+		h.log.ErrorCtx(r.Context(), "Failed to unenroll probe %s: %v", probeID, err)
 		respondError(w, http.StatusInternalServerError, err.Error())
 		return
 	}
@@ -134,7 +135,7 @@ func (h *FleetHandler) GetFleetProbe(w http.ResponseWriter, r *http.Request) {
 
 	probe, err := h.fleetService.GetFleetProbe(r.Context(), probeID)
 	if err != nil {
-		h.log.Error("Failed to get fleet probe %s: %v", probeID, err)
+		h.log.ErrorCtx(r.Context(), "Failed to get fleet probe %s: %v", probeID, err)
 		respondError(w, http.StatusNotFound, "Fleet probe not found")
 		return
 	}
@@ -147,7 +148,7 @@ func (h *FleetHandler) ListFleetProbes(w http.ResponseWriter, r *http.Request) {
 
 	probes, err := h.fleetService.ListFleetProbes(r.Context(), group)
 	if err != nil {
-		h.log.Error("Failed to list fleet probes: %v", err)
+		h.log.ErrorCtx(r.Context(), "Failed to list fleet probes: %v", err)
 		respondError(w, http.StatusInternalServerError, err.Error())
 		return
 	}
@@ -161,13 +162,13 @@ func (h *FleetHandler) UpdateFleetProbe(w http.ResponseWriter, r *http.Request)
 
 	var req models.FleetUpdateRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		h.log.Warn("Invalid update request: %v", err)
+		h.log.WarnCtx(r.Context(), "Invalid update request: %v", err)
 		respondError(w, http.StatusBadRequest, "Invalid request body")
 		return
 	}
 
 	if err := h.fleetService.UpdateFleetProbe(r.Context(), probeID, &req); err != nil {
-		h.log.Error("Failed to update fleet probe %s: %v", probeID, err)
+		h.log.ErrorCtx(r.Context(), "Failed to update fleet probe %s: %v", probeID, err)
 		respondError(w, http.StatusInternalServerError, err.Error())
 		return
 	}
@@ -180,7 +181,7 @@ func (h *FleetHandler) UpdateFleetProbe(w http.ResponseWriter, r *http.Request)
 func (h *FleetHandler) SendFleetCommand(w http.ResponseWriter, r *http.Request) {
 	var req models.FleetCommandRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		h.log.Warn("Invalid fleet command request: %v", err)
+		h.log.WarnCtx(r.Context(), "Invalid fleet command request: %v", err)
 		respondError(w, http.StatusBadRequest, "Invalid request body")
 		return
 	}
@@ -199,7 +200,7 @@ func (h *FleetHandler) SendFleetCommand(w http.ResponseWriter, r *http.Request)
 
 	cmd, err := h.fleetService.SendFleetCommand(r.Context(), &req, user)
 	if err != nil {
-		h.log.Error("Failed to send fleet command: %v", err)
+		h.log.ErrorCtx(r.Context(), "Failed to send fleet command: %v", err)
 		respondError(w, http.StatusInternalServerError, err.Error())
 		return
 	}
@@ -213,7 +214,7 @@ func (h *FleetHandler) GetFleetCommandStatus(w http.ResponseWriter, r *http.Requ
 
 	status, err := h.fleetService.GetFleetCommandStatus(r.Context(), commandID)
 	if err != nil {
-		h.log.Error("Failed to get command status: %v", err)
+		h.log.ErrorCtx(r.Context(), "Failed to get command status: %v", err)
 		respondError(w, http.StatusNotFound, "Command not found")
 		return
 	}
@@ -230,7 +231,7 @@ func (h *FleetHandler) ListFleetCommands(w http.ResponseWriter, r *http.Request)
 
 	commands, err := h.fleetService.ListFleetCommands(r.Context(), status, limit)
 	if err != nil {
-		h.log.Error("Failed to list fleet commands: %v", err)
+		h.log.ErrorCtx(r.Context(), "Failed to list fleet commands: %v", err)
 		respondError(w, http.StatusInternalServerError, err.Error())
 		return
 	}
@@ -243,7 +244,7 @@ func (h *FleetHandler) CancelFleetCommand(w http.ResponseWriter, r *http.Request
 	commandID := vars["id"]
 
 	if err := h.fleetService.CancelFleetCommand(r.Context(), commandID); err != nil {
-		h.log.Error("Failed to cancel command %s: %v", commandID, err)
+		h.log.ErrorCtx(r.Context(), "Failed to cancel command %s: %v", commandID, err)
 		respondError(w, http.StatusInternalServerError, err.Error())
 		return
 	}
@@ -256,20 +257,19 @@ func (h *FleetHandler) CancelFleetCommand(w http.ResponseWriter, r *http.Request
 func (h *FleetHandler) CreateTemplate(w http.ResponseWriter, r *http.Request) {
 	var template models.FleetConfigTemplate
 	if err := json.NewDecoder(r.Body).Decode(&template); err != nil {
-		h.log.Warn("Invalid template request: %v", err)
+		h.log.WarnCtx(r.Context(), "Invalid template request: %v", err)
 		respondError(w, http.StatusBadRequest, "Invalid request body")
 		return
 	}
 
-	if template.Name == "" {
-		respondError(w, http.StatusBadRequest, "template name is required")
-		return
-	}
-
 	user := getUserFromContext(r)
 
-	if err := h.fleetService.CreateTemplate(r.Context(), &template, user); err != nil {
-		h.log.Error("Failed to create template: %v", err)
+	err := h.fleetService.CreateTemplate(r.Context(), &template, user)
+	if respondIfValidation(w, err) {
+		return
+	}
+	if err != nil {
+		h.log.ErrorCtx(r.Context(), "Failed to create template: %v", err)
 		respondError(w, http.StatusInternalServerError, err.Error())
 		return
 	}
@@ -287,7 +287,7 @@ func (h *FleetHandler) GetTemplate(w http.ResponseWriter, r *http.Request) {
 
 	template, err := h.fleetService.GetTemplate(r.Context(), id)
 	if err != nil {
-		h.log.Error("Failed to get template %d: %v", id, err)
+		h.log.ErrorCtx(r.Context(), "Failed to get template %d: %v", id, err)
 		respondError(w, http.StatusNotFound, "Template not found")
 		return
 	}
@@ -298,7 +298,7 @@ func (h *FleetHandler) GetTemplate(w http.ResponseWriter, r *http.Request) {
 func (h *FleetHandler) ListTemplates(w http.ResponseWriter, r *http.Request) {
 	templates, err := h.fleetService.ListTemplates(r.Context())
 	if err != nil {
-		h.log.Error("Failed to list templates: %v", err)
+		h.log.ErrorCtx(r.Context(), "Failed to list templates: %v", err)
 		respondError(w, http.StatusInternalServerError, err.Error())
 		return
 	}
@@ -318,7 +318,7 @@ func (h *FleetHandler) ApplyTemplate(w http.ResponseWriter, r *http.Request) {
 		ProbeIDs []string `json:"probe_ids"`
 	}
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		h.log.Warn("Invalid apply template request: %v", err)
+		h.log.WarnCtx(r.Context(), "Invalid apply template request: %v", err)
 		respondError(w, http.StatusBadRequest, "Invalid request body")
 		return
 	}
@@ -331,7 +331,7 @@ func (h *FleetHandler) ApplyTemplate(w http.ResponseWriter, r *http.Request) {
 	user := getUserFromContext(r)
 
 	if err := h.fleetService.ApplyTemplate(r.Context(), id, req.ProbeIDs, user); err != nil {
-		h.log.Error("Failed to apply template: %v", err)
+		h.log.ErrorCtx(r.Context(), "Failed to apply template: %v", err)
 		respondError(w, http.StatusInternalServerError, err.Error())
 		return
 	}
@@ -350,7 +350,7 @@ func (h *FleetHandler) DeleteTemplate(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if err := h.fleetService.DeleteTemplate(r.Context(), id); err != nil {
-		h.log.Error("Failed to delete template %d: %v", id, err)
+		h.log.ErrorCtx(r.Context(), "Failed to delete template %d: %v", id, err)
 		respondError(w, http.StatusInternalServerError, err.Error())
 		return
 	}
@@ -367,7 +367,7 @@ func (h *FleetHandler) CreateGroup(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		h.log.Warn("Invalid create group request: %v", err)
+		h.log.WarnCtx(r.Context(), "Invalid create group request: %v", err)
 		respondError(w, http.StatusBadRequest, "Invalid request body")
 		return
 	}
@@ -379,7 +379,7 @@ func (h *FleetHandler) CreateGroup(w http.ResponseWriter, r *http.Request) {
 
 	group, err := h.fleetService.CreateGroup(r.Context(), req.Name, req.Description)
 	if err != nil {
-		h.log.Error("Failed to create group: %v", err)
+		h.log.ErrorCtx(r.Context(), "Failed to create group: %v", err)
 		respondError(w, http.StatusInternalServerError, err.Error())
 		return
 	}
@@ -390,7 +390,7 @@ func (h *FleetHandler) CreateGroup(w http.ResponseWriter, r *http.Request) {
 func (h *FleetHandler) ListGroups(w http.ResponseWriter, r *http.Request) {
 	groups, err := h.fleetService.ListGroups(r.Context())
 	if err != nil {
-		h.log.Error("Failed to list groups: %v", err)
+		h.log.ErrorCtx(r.Context(), "Failed to list groups: %v", err)
 		respondError(w, http.StatusInternalServerError, err.Error())
 		return
 	}
@@ -403,7 +403,7 @@ func (h *FleetHandler) DeleteGroup(w http.ResponseWriter, r *http.Request) {
 	groupID := vars["id"]
 
 	if err := h.fleetService.DeleteGroup(r.Context(), groupID); err != nil {
-		h.log.Error("Failed to delete group %s: %v", groupID, err)
+		h.log.ErrorCtx(r.Context(), "Failed to delete group %s: %v", groupID, err)
 		respondError(w, http.StatusInternalServerError, err.Error())
 		return
 	}
@@ -416,7 +416,7 @@ func (h *FleetHandler) DeleteGroup(w http.ResponseWriter, r *http.Request) {
 func (h *FleetHandler) GetFleetStatus(w http.ResponseWriter, r *http.Request) {
 	status, err := h.fleetService.GetFleetStatus(r.Context())
 	if err != nil {
-		h.log.Error("Failed to get fleet status: %v", err)
+		h.log.ErrorCtx(r.Context(), "Failed to get fleet status: %v", err)
 		respondError(w, http.StatusInternalServerError, err.Error())
 		return
 	}
@@ -446,7 +446,7 @@ func (h *FleetHandler) GetProbeSchedules(w http.ResponseWriter, r *http.Request)
 	var schedulesJSON []byte
 	err := h.fleetService.GetProbeSchedules(r.Context(), probeID, &schedulesJSON)
 	if err != nil {
-		h.log.Error("Failed to get probe schedules: %v", err)
+		h.log.ErrorCtx(r.Context(), "Failed to get probe schedules: %v", err)
 		respondError(w, http.StatusNotFound, "No schedules found")
 		return
 	}
@@ -473,7 +473,7 @@ func (h *FleetHandler) DeleteProbeSchedule(w http.ResponseWriter, r *http.Reques
 	user := getUserFromContext(r)
 	_, err := h.fleetService.SendFleetCommand(r.Context(), cmdReq, user)
 	if err != nil {
-		h.log.Error("Failed to send delete schedule command: %v", err)
+		h.log.ErrorCtx(r.Context(), "Failed to send delete schedule command: %v", err)
 		respondError(w, http.StatusInternalServerError, err.Error())
 		return
 	}
@@ -489,7 +489,7 @@ func (h *FleetHandler) GetGroupSchedules(w http.ResponseWriter, r *http.Request)
 	// Get all probes in this group from fleet_probes
 	probes, err := h.fleetService.ListFleetProbes(r.Context(), groupID)
 	if err != nil {
-		h.log.Error("Failed to list probes in group: %v", err)
+		h.log.ErrorCtx(r.Context(), "Failed to list probes in group: %v", err)
 		respondError(w, http.StatusInternalServerError, err.Error())
 		return
 	}
@@ -506,9 +506,11 @@ func (h *FleetHandler) GetGroupSchedules(w http.ResponseWriter, r *http.Request)
 	respondJSON(w, http.StatusOK, result)
 }
 
-// Helper function
+// getUserFromContext returns the authenticated username, or "system" for
+// requests without JWT claims (e.g. API key callers).
 func getUserFromContext(r *http.Request) string {
-	// This would come from your auth middleware
-	// For now, return a default
+	if claims, ok := r.Context().Value("user").(*auth.Claims); ok && claims.Username != "" {
+		return claims.Username
+	}
 	return "system"
 }
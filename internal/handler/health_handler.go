@@ -2,6 +2,7 @@ package handler
 
 import (
 	"context"
+	"database/sql"
 	"net/http"
 	"time"
 
@@ -9,21 +10,27 @@ import (
 	"CampusMonitorAPI/internal/logger"
 	"CampusMonitorAPI/internal/models"
 	"CampusMonitorAPI/internal/mqtt"
+	"CampusMonitorAPI/internal/service"
+	"CampusMonitorAPI/internal/websocket"
 
 	"github.com/gorilla/mux"
 )
 
 type HealthHandler struct {
-	db         *database.Database
-	mqttClient *mqtt.Client
-	log        *logger.Logger
+	db           *database.Database
+	mqttClient   *mqtt.Client
+	hub          *websocket.Hub
+	probeService *service.ProbeService
+	log          *logger.Logger
 }
 
-func NewHealthHandler(db *database.Database, mqttClient *mqtt.Client, log *logger.Logger) *HealthHandler {
+func NewHealthHandler(db *database.Database, mqttClient *mqtt.Client, hub *websocket.Hub, probeService *service.ProbeService, log *logger.Logger) *HealthHandler {
 	return &HealthHandler{
-		db:         db,
-		mqttClient: mqttClient,
-		log:        log,
+		db:           db,
+		mqttClient:   mqttClient,
+		hub:          hub,
+		probeService: probeService,
+		log:          log,
 	}
 }
 
@@ -31,6 +38,68 @@ func (h *HealthHandler) RegisterRoutes(r *mux.Router) {
 	r.HandleFunc("/health", h.Health).Methods("GET")
 	r.HandleFunc("/health/live", h.Liveness).Methods("GET")
 	r.HandleFunc("/health/ready", h.Readiness).Methods("GET")
+	r.HandleFunc("/health/detail", h.Detail).Methods("GET")
+	r.HandleFunc("/health/db", h.Database).Methods("GET")
+}
+
+// poolSaturationThreshold is the share of MaxOpenConns in use at which a
+// pool is reported as saturated.
+const poolSaturationThreshold = 0.9
+
+// Database reports connection pool usage so an incident can tell a slow
+// database apart from an exhausted pool. It returns 503 only when the
+// database is unreachable; a saturated pool is reported with status
+// "saturated" and logged.
+func (h *HealthHandler) Database(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	response := models.DatabaseHealthResponse{
+		Status:    "healthy",
+		Timestamp: time.Now(),
+		Healthy:   h.db.Health(ctx) == nil,
+		Primary:   poolStats(h.db.Stats()),
+	}
+	if replica := h.db.ReplicaStats(); replica != nil {
+		stats := poolStats(*replica)
+		response.Replica = &stats
+	}
+
+	saturated := response.Primary.Saturated || (response.Replica != nil && response.Replica.Saturated)
+	if saturated {
+		response.Status = "saturated"
+		h.log.WarnCtx(r.Context(), "Database pool saturated - in use: %d/%d, waits: %d (%s)",
+			response.Primary.InUse, response.Primary.MaxOpenConnections,
+			response.Primary.WaitCount, response.Primary.WaitDuration)
+	}
+
+	statusCode := http.StatusOK
+	if !response.Healthy {
+		response.Status = "unhealthy"
+		statusCode = http.StatusServiceUnavailable
+	}
+
+	respondJSON(w, statusCode, response)
+}
+
+func poolStats(s sql.DBStats) models.DatabasePoolStats {
+	stats := models.DatabasePoolStats{
+		MaxOpenConnections: s.MaxOpenConnections,
+		OpenConnections:    s.OpenConnections,
+		InUse:              s.InUse,
+		Idle:               s.Idle,
+		WaitCount:          s.WaitCount,
+		WaitDuration:       s.WaitDuration.String(),
+		WaitDurationMs:     s.WaitDuration.Milliseconds(),
+		MaxIdleClosed:      s.MaxIdleClosed,
+		MaxIdleTimeClosed:  s.MaxIdleTimeClosed,
+		MaxLifetimeClosed:  s.MaxLifetimeClosed,
+	}
+	if s.MaxOpenConnections > 0 {
+		stats.Utilization = float64(s.InUse) / float64(s.MaxOpenConnections)
+		stats.Saturated = stats.Utilization >= poolSaturationThreshold
+	}
+	return stats
 }
 
 func (h *HealthHandler) Health(w http.ResponseWriter, r *http.Request) {
@@ -47,10 +116,16 @@ func (h *HealthHandler) Health(w http.ResponseWriter, r *http.Request) {
 
 	mqttHealth, mqttErr := h.mqttClient.Health(ctx)
 	response.Services.MQTT = (mqttErr == nil && mqttHealth.Connected)
+	if mqttErr == nil {
+		response.MQTT.MessagesLastMinute = mqttHealth.MessagesLastMinute
+		response.MQTT.SecondsSinceMessage = mqttHealth.SecondsSinceMessage
+		response.MQTT.Silent = mqttHealth.Silent
+	}
 
-	if !response.Services.Database || !response.Services.MQTT {
+	if !response.Services.Database || !response.Services.MQTT || response.MQTT.Silent {
 		response.Status = "degraded"
-		h.log.Warn("Health check degraded - DB: %v, MQTT: %v", response.Services.Database, response.Services.MQTT)
+		h.log.WarnCtx(r.Context(), "Health check degraded - DB: %v, MQTT: %v, MQTT silent: %v",
+			response.Services.Database, response.Services.MQTT, response.MQTT.Silent)
 	}
 
 	statusCode := http.StatusOK
@@ -61,6 +136,56 @@ func (h *HealthHandler) Health(w http.ResponseWriter, r *http.Request) {
 	respondJSON(w, statusCode, response)
 }
 
+// Detail reports connection pool, MQTT and WebSocket state plus probe counts
+// for on-call diagnostics. Like Health it returns 503 when degraded.
+func (h *HealthHandler) Detail(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	response := models.DetailedHealthResponse{
+		Status:    "healthy",
+		Timestamp: time.Now(),
+	}
+
+	stats := h.db.Stats()
+	response.Database.Healthy = h.db.Health(ctx) == nil
+	response.Database.OpenConnections = stats.OpenConnections
+	response.Database.InUse = stats.InUse
+	response.Database.Idle = stats.Idle
+	response.Database.WaitCount = stats.WaitCount
+
+	if mqttHealth, err := h.mqttClient.Health(ctx); err == nil {
+		response.MQTT.Connected = mqttHealth.Connected
+		response.MQTT.LastConnected = mqttHealth.LastConnected
+		response.MQTT.LastDisconnect = mqttHealth.LastDisconnect
+		response.MQTT.Subscriptions = mqttHealth.Subscriptions
+		response.MQTT.ExpectedSubscriptions = mqttHealth.ExpectedSubscriptions
+		response.MQTT.LastMessage = mqttHealth.LastMessage
+		response.MQTT.MessagesReceived = mqttHealth.MessagesReceived
+		response.MQTT.MessagesLastMinute = mqttHealth.MessagesLastMinute
+		response.MQTT.SecondsSinceMessage = mqttHealth.SecondsSinceMessage
+		response.MQTT.Silent = mqttHealth.Silent
+	}
+
+	response.WebSocketClients = h.hub.ClientCount()
+
+	if response.Database.Healthy {
+		counts, err := h.probeService.CountByStatus(ctx)
+		if err != nil {
+			h.log.ErrorCtx(r.Context(), "Failed to count probes for health detail: %v", err)
+		}
+		response.Probes = counts
+	}
+
+	statusCode := http.StatusOK
+	if !response.Database.Healthy || !response.MQTT.Connected || response.MQTT.Silent {
+		response.Status = "degraded"
+		statusCode = http.StatusServiceUnavailable
+	}
+
+	respondJSON(w, statusCode, response)
+}
+
 func (h *HealthHandler) Liveness(w http.ResponseWriter, r *http.Request) {
 	respondJSON(w, http.StatusOK, map[string]string{
 		"status": "alive",
@@ -72,10 +197,18 @@ func (h *HealthHandler) Readiness(w http.ResponseWriter, r *http.Request) {
 	defer cancel()
 
 	dbErr := h.db.Health(ctx)
-	mqttConnected := h.mqttClient.IsConnected()
+	mqttHealth, mqttErr := h.mqttClient.Health(ctx)
+	if mqttErr != nil {
+		h.log.WarnCtx(r.Context(), "Readiness check failed - DB error: %v, MQTT error: %v", dbErr, mqttErr)
+		respondJSON(w, http.StatusServiceUnavailable, map[string]string{
+			"status": "not ready",
+		})
+		return
+	}
 
-	if dbErr != nil || !mqttConnected {
-		h.log.Warn("Readiness check failed - DB error: %v, MQTT connected: %v", dbErr, mqttConnected)
+	if dbErr != nil || !mqttHealth.Ready() {
+		h.log.WarnCtx(r.Context(), "Readiness check failed - DB error: %v, MQTT connected: %v, subscriptions: %d/%d, stale: %v",
+			dbErr, mqttHealth.Connected, mqttHealth.Subscriptions, mqttHealth.ExpectedSubscriptions, mqttHealth.Stale)
 		respondJSON(w, http.StatusServiceUnavailable, map[string]string{
 			"status": "not ready",
 		})
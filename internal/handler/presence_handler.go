@@ -0,0 +1,32 @@
+package handler
+
+import (
+	"net/http"
+
+	"CampusMonitorAPI/internal/middleware"
+	"CampusMonitorAPI/internal/websocket"
+
+	"github.com/gorilla/mux"
+)
+
+type PresenceHandler struct {
+	hub *websocket.Hub
+}
+
+func NewPresenceHandler(hub *websocket.Hub) *PresenceHandler {
+	return &PresenceHandler{hub: hub}
+}
+
+func (h *PresenceHandler) RegisterRoutes(r *mux.Router) {
+	r.HandleFunc("/api/v1/ws/presence", h.GetPresence).Methods("GET")
+}
+
+// GetPresence reports how many WebSocket clients are connected. Only
+// authenticated callers are told who they are.
+func (h *PresenceHandler) GetPresence(w http.ResponseWriter, r *http.Request) {
+	presence := h.hub.Presence()
+	if middleware.Principal(r.Context()) == "" {
+		presence.Principals = nil
+	}
+	respondJSON(w, http.StatusOK, presence)
+}
@@ -2,10 +2,14 @@ package handler
 
 import (
 	"encoding/json"
+	"errors"
 	"net/http"
+	"strconv"
+	"time"
 
 	"CampusMonitorAPI/internal/logger"
 	"CampusMonitorAPI/internal/models"
+	"CampusMonitorAPI/internal/repository"
 	"CampusMonitorAPI/internal/service"
 
 	"github.com/gorilla/mux"
@@ -35,31 +39,41 @@ func NewProbeHandler(
 func (h *ProbeHandler) RegisterRoutes(r *mux.Router) {
 	r.HandleFunc("/probes", h.CreateProbe).Methods("POST")
 	r.HandleFunc("/probes", h.ListProbes).Methods("GET")
+	// Registered ahead of /probes/{id} so the literal path wins.
+	r.HandleFunc("/probes/firmware-compliance", h.GetFirmwareCompliance).Methods("GET")
+	r.HandleFunc("/probes/by-department", h.GetProbesByDepartment).Methods("GET")
 	r.HandleFunc("/probes/{id}", h.GetProbe).Methods("GET")
 	r.HandleFunc("/probes/{id}", h.UpdateProbe).Methods("PUT", "PATCH")
 	r.HandleFunc("/probes/{id}", h.DeleteProbe).Methods("DELETE")
 	r.HandleFunc("/probes/{id}/command", h.SendCommand).Methods("POST")
 	r.HandleFunc("/probes/{id}/adopt", h.AdoptProbe).Methods("POST")
+	r.HandleFunc("/probes/{id}/decommission", h.DecommissionProbe).Methods("POST")
 	r.HandleFunc("/probes/active", h.GetActiveProbes).Methods("GET")
 	r.HandleFunc("/probes/building/{building}", h.GetProbesByBuilding).Methods("GET")
 	r.HandleFunc("/probes/{probe_id}/ping", h.CheckConnectivity).Methods("POST")
 	r.HandleFunc("/probes/{probe_id}/status", h.GetProbeStatus).Methods("GET")
 	r.HandleFunc("/probes/{probe_id}/config", h.GetProbeConfig).Methods("GET")
+	r.HandleFunc("/probes/{probe_id}/live-config", h.GetProbeConfig).Methods("GET")
 	r.HandleFunc("/probes/{probe_id}/ping-status", h.GetPingStatus).Methods("GET")
+	r.HandleFunc("/probes/{id}/scans", h.GetDeepScans).Methods("GET")
+	r.HandleFunc("/probes/{id}/diagnostics", h.GetDiagnostics).Methods("GET")
 	r.HandleFunc("/probes/locations", h.GetLocationOptions).Methods("GET")
 }
 
 func (h *ProbeHandler) CreateProbe(w http.ResponseWriter, r *http.Request) {
 	var req models.CreateProbeRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		h.log.Warn("Invalid request body: %v", err)
+		h.log.WarnCtx(r.Context(), "Invalid request body: %v", err)
 		respondError(w, http.StatusBadRequest, "Invalid request body")
 		return
 	}
 
 	probe, err := h.probeService.RegisterProbe(r.Context(), &req)
+	if respondIfValidation(w, err) {
+		return
+	}
 	if err != nil {
-		h.log.Error("Failed to create probe: %v", err)
+		h.log.ErrorCtx(r.Context(), "Failed to create probe: %v", err)
 		respondError(w, http.StatusInternalServerError, err.Error())
 		return
 	}
@@ -68,13 +82,41 @@ func (h *ProbeHandler) CreateProbe(w http.ResponseWriter, r *http.Request) {
 }
 
 func (h *ProbeHandler) ListProbes(w http.ResponseWriter, r *http.Request) {
-	probes, err := h.probeService.ListProbes(r.Context())
+	query := r.URL.Query()
+	q := models.ProbeQuery{Status: query.Get("status")}
+
+	times := []struct {
+		param string
+		dest  *time.Time
+	}{
+		{"created_after", &q.CreatedAfter},
+		{"created_before", &q.CreatedBefore},
+		{"last_seen_before", &q.LastSeenBefore},
+	}
+	for _, t := range times {
+		v := query.Get(t.param)
+		if v == "" {
+			continue
+		}
+		parsed, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			respondError(w, http.StatusBadRequest, t.param+" must be RFC3339")
+			return
+		}
+		*t.dest = parsed
+	}
+
+	probes, err := h.probeService.ListProbes(r.Context(), q)
 	if err != nil {
-		h.log.Error("Failed to list probes: %v", err)
+		h.log.ErrorCtx(r.Context(), "Failed to list probes: %v", err)
 		respondError(w, http.StatusInternalServerError, err.Error())
 		return
 	}
 
+	if wantsCSV(r) {
+		respondCSV(w, "probes", probes)
+		return
+	}
 	respondJSON(w, http.StatusOK, probes)
 }
 
@@ -84,7 +126,7 @@ func (h *ProbeHandler) GetProbe(w http.ResponseWriter, r *http.Request) {
 
 	probe, err := h.probeService.GetProbe(r.Context(), probeID)
 	if err != nil {
-		h.log.Error("Failed to get probe: %v", err)
+		h.log.ErrorCtx(r.Context(), "Failed to get probe: %v", err)
 		respondError(w, http.StatusNotFound, "Probe not found")
 		return
 	}
@@ -98,14 +140,14 @@ func (h *ProbeHandler) UpdateProbe(w http.ResponseWriter, r *http.Request) {
 
 	var req models.UpdateProbeRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		h.log.Warn("Invalid request body: %v", err)
+		h.log.WarnCtx(r.Context(), "Invalid request body: %v", err)
 		respondError(w, http.StatusBadRequest, "Invalid request body")
 		return
 	}
 
 	probe, err := h.probeService.UpdateProbe(r.Context(), probeID, &req)
 	if err != nil {
-		h.log.Error("Failed to update probe: %v", err)
+		h.log.ErrorCtx(r.Context(), "Failed to update probe: %v", err)
 		respondError(w, http.StatusInternalServerError, err.Error())
 		return
 	}
@@ -117,20 +159,52 @@ func (h *ProbeHandler) DeleteProbe(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	probeID := vars["id"]
 
-	if err := h.probeService.DeleteProbe(r.Context(), probeID); err != nil {
-		h.log.Error("Failed to delete probe: %v", err)
+	purge := false
+	if v := r.URL.Query().Get("purge"); v != "" {
+		parsed, err := strconv.ParseBool(v)
+		if err != nil {
+			respondError(w, http.StatusBadRequest, "purge must be true or false")
+			return
+		}
+		purge = parsed
+	}
+
+	result, err := h.probeService.DeleteProbe(r.Context(), probeID, purge)
+	if err != nil {
+		if errors.Is(err, repository.ErrProbeHasDependents) {
+			respondError(w, http.StatusConflict, err.Error())
+			return
+		}
+		h.log.ErrorCtx(r.Context(), "Failed to delete probe: %v", err)
 		respondError(w, http.StatusInternalServerError, err.Error())
 		return
 	}
 
-	respondJSON(w, http.StatusOK, map[string]string{"message": "Probe deleted successfully"})
+	respondJSON(w, http.StatusOK, result)
+}
+
+func (h *ProbeHandler) DecommissionProbe(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	probeID := vars["id"]
 
+	result, err := h.probeService.DecommissionProbe(r.Context(), probeID)
+	if err != nil {
+		if errors.Is(err, repository.ErrProbeNotFound) {
+			respondError(w, http.StatusNotFound, "Probe not found")
+			return
+		}
+		h.log.ErrorCtx(r.Context(), "Failed to decommission probe: %v", err)
+		respondError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	respondJSON(w, http.StatusOK, result)
 }
 
 func (h *ProbeHandler) GetActiveProbes(w http.ResponseWriter, r *http.Request) {
 	probes, err := h.probeService.GetActiveProbes(r.Context())
 	if err != nil {
-		h.log.Error("Failed to get active probes: %v", err)
+		h.log.ErrorCtx(r.Context(), "Failed to get active probes: %v", err)
 		respondError(w, http.StatusInternalServerError, err.Error())
 		return
 	}
@@ -144,7 +218,7 @@ func (h *ProbeHandler) GetProbesByBuilding(w http.ResponseWriter, r *http.Reques
 
 	probes, err := h.probeService.GetProbesByBuilding(r.Context(), building)
 	if err != nil {
-		h.log.Error("Failed to get probes by building: %v", err)
+		h.log.ErrorCtx(r.Context(), "Failed to get probes by building: %v", err)
 		respondError(w, http.StatusInternalServerError, err.Error())
 		return
 	}
@@ -152,12 +226,52 @@ func (h *ProbeHandler) GetProbesByBuilding(w http.ResponseWriter, r *http.Reques
 	respondJSON(w, http.StatusOK, probes)
 }
 
+func (h *ProbeHandler) GetFirmwareCompliance(w http.ResponseWriter, r *http.Request) {
+	target := r.URL.Query().Get("target")
+	if target == "" {
+		respondError(w, http.StatusBadRequest, "target firmware version is required")
+		return
+	}
+
+	compliance, err := h.probeService.GetFirmwareCompliance(r.Context(), target)
+	if errors.Is(err, service.ErrInvalidFirmwareVersion) {
+		respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err != nil {
+		h.log.ErrorCtx(r.Context(), "Failed to check firmware compliance: %v", err)
+		respondError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	respondJSON(w, http.StatusOK, compliance)
+}
+
+func (h *ProbeHandler) GetProbesByDepartment(w http.ResponseWriter, r *http.Request) {
+	groups, err := h.probeService.GetProbesByDepartment(r.Context())
+	if err != nil {
+		h.log.ErrorCtx(r.Context(), "Failed to group probes by department: %v", err)
+		respondError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	if counts, _ := strconv.ParseBool(r.URL.Query().Get("counts")); counts {
+		totals := make(map[string]int, len(groups))
+		for department, probes := range groups {
+			totals[department] = len(probes)
+		}
+		respondJSON(w, http.StatusOK, totals)
+		return
+	}
+	respondJSON(w, http.StatusOK, groups)
+}
+
 // GetLocationOptions handles GET /api/v1/probes/locations
 func (h *ProbeHandler) GetLocationOptions(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 	opts, err := h.probeService.GetDistinctLocations(ctx)
 	if err != nil {
-		h.log.Error("Failed to get location options: %v", err)
+		h.log.ErrorCtx(r.Context(), "Failed to get location options: %v", err)
 		respondError(w, http.StatusInternalServerError, "Failed to fetch location options")
 		return
 	}
@@ -173,12 +287,12 @@ func (h *ProbeHandler) SendCommand(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		h.log.Warn("Invalid request body: %v", err)
+		h.log.WarnCtx(r.Context(), "Invalid request body: %v", err)
 		respondError(w, http.StatusBadRequest, "Invalid request body")
 		return
 	}
 
-	h.log.Info("Sending command %s to probe %s", req.CommandType, probeID)
+	h.log.InfoCtx(r.Context(), "Sending command %s to probe %s", req.CommandType, probeID)
 
 	commandReq := &models.CommandRequest{
 		ProbeID:     probeID,
@@ -187,8 +301,15 @@ func (h *ProbeHandler) SendCommand(w http.ResponseWriter, r *http.Request) {
 	}
 
 	command, err := h.commandService.IssueCommand(r.Context(), commandReq)
+	if respondIfValidation(w, err) {
+		return
+	}
+	if errors.Is(err, service.ErrCommandRateLimited) {
+		respondError(w, http.StatusTooManyRequests, err.Error())
+		return
+	}
 	if err != nil {
-		h.log.Error("Failed to issue command: %v", err)
+		h.log.ErrorCtx(r.Context(), "Failed to issue command: %v", err)
 		respondError(w, http.StatusInternalServerError, err.Error())
 		return
 	}
@@ -220,21 +341,21 @@ func (h *ProbeHandler) AdoptProbe(w http.ResponseWriter, r *http.Request) {
 
 	var req models.UpdateProbeRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		h.log.Warn("Invalid request body: %v", err)
+		h.log.WarnCtx(r.Context(), "Invalid request body: %v", err)
 		respondError(w, http.StatusBadRequest, "Invalid request body")
 		return
 	}
-	status := "active"
-	req.Status = &status
-
-	probe, err := h.probeService.UpdateProbe(r.Context(), probeID, &req)
+	probe, err := h.probeService.AdoptProbe(r.Context(), probeID, &req)
+	if respondIfValidation(w, err) {
+		return
+	}
 	if err != nil {
-		h.log.Error("Failed to adopt probe: %v", err)
+		h.log.ErrorCtx(r.Context(), "Failed to adopt probe: %v", err)
 		respondError(w, http.StatusInternalServerError, err.Error())
 		return
 	}
 
-	h.log.Info("Probe %s adopted successfully", probeID)
+	h.log.InfoCtx(r.Context(), "Probe %s adopted successfully", probeID)
 	respondJSON(w, http.StatusOK, probe)
 }
 func (h *ProbeHandler) GetProbeStatus(w http.ResponseWriter, r *http.Request) {
@@ -250,6 +371,48 @@ func (h *ProbeHandler) GetProbeStatus(w http.ResponseWriter, r *http.Request) {
 	respondJSON(w, http.StatusOK, status)
 }
 
+func (h *ProbeHandler) GetDeepScans(w http.ResponseWriter, r *http.Request) {
+	probeID := mux.Vars(r)["id"]
+
+	limit := 0
+	if v := r.URL.Query().Get("limit"); v != "" {
+		parsed, err := strconv.Atoi(v)
+		if err != nil {
+			respondError(w, http.StatusBadRequest, "Invalid limit")
+			return
+		}
+		limit = parsed
+	}
+
+	scans, err := h.commandService.GetDeepScanResults(r.Context(), probeID, limit)
+	if err != nil {
+		h.log.ErrorCtx(r.Context(), "Failed to get deep scans for %s: %v", probeID, err)
+		respondError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	respondJSON(w, http.StatusOK, scans)
+}
+
+// GetDiagnostics returns the probe's latest diagnostics bundle, collected
+// by a "diagnostics" command.
+func (h *ProbeHandler) GetDiagnostics(w http.ResponseWriter, r *http.Request) {
+	probeID := mux.Vars(r)["id"]
+
+	bundle, err := h.commandService.GetLatestDiagnostics(r.Context(), probeID)
+	if err != nil {
+		if errors.Is(err, service.ErrNoDiagnostics) {
+			respondError(w, http.StatusNotFound, err.Error())
+			return
+		}
+		h.log.ErrorCtx(r.Context(), "Failed to get diagnostics for %s: %v", probeID, err)
+		respondError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	respondJSON(w, http.StatusOK, bundle)
+}
+
 func (h *ProbeHandler) GetProbeConfig(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	probeID := vars["probe_id"]
@@ -78,11 +78,11 @@ func (h *ReportHandler) GenerateReport(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
-	h.log.Info("Generating report: type=%s, format=%s, from=%v, to=%v", req.Type, req.Format, req.From, req.To)
+	h.log.InfoCtx(r.Context(), "Generating report: type=%s, format=%s, from=%v, to=%v", req.Type, req.Format, req.From, req.To)
 
 	data, contentType, err := h.reportService.GenerateReport(r.Context(), &req)
 	if err != nil {
-		h.log.Error("Failed to generate report: %v", err)
+		h.log.ErrorCtx(r.Context(), "Failed to generate report: %v", err)
 		respondError(w, http.StatusInternalServerError, "Failed to generate report")
 		return
 	}
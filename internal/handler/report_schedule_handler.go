@@ -0,0 +1,154 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"CampusMonitorAPI/internal/logger"
+	"CampusMonitorAPI/internal/middleware"
+	"CampusMonitorAPI/internal/models"
+	"CampusMonitorAPI/internal/repository"
+	"CampusMonitorAPI/internal/service"
+
+	"github.com/gorilla/mux"
+)
+
+type ReportScheduleHandler struct {
+	scheduler *service.ReportScheduler
+	log       *logger.Logger
+}
+
+func NewReportScheduleHandler(scheduler *service.ReportScheduler, log *logger.Logger) *ReportScheduleHandler {
+	return &ReportScheduleHandler{
+		scheduler: scheduler,
+		log:       log,
+	}
+}
+
+func (h *ReportScheduleHandler) RegisterRoutes(r *mux.Router) {
+	// Schedules post reports to arbitrary URLs, so only admins manage them.
+	r.Handle("/reports/schedules", middleware.RequireAdmin(http.HandlerFunc(h.ListSchedules))).Methods("GET")
+	r.Handle("/reports/schedules", middleware.RequireAdmin(http.HandlerFunc(h.CreateSchedule))).Methods("POST")
+	r.Handle("/reports/schedules/{id}", middleware.RequireAdmin(http.HandlerFunc(h.GetSchedule))).Methods("GET")
+	r.Handle("/reports/schedules/{id}", middleware.RequireAdmin(http.HandlerFunc(h.DeleteSchedule))).Methods("DELETE")
+	r.Handle("/reports/schedules/{id}/reports", middleware.RequireAdmin(http.HandlerFunc(h.ListReports))).Methods("GET")
+	r.HandleFunc("/reports/generated/{id}", h.DownloadReport).Methods("GET")
+}
+
+func (h *ReportScheduleHandler) CreateSchedule(w http.ResponseWriter, r *http.Request) {
+	var req models.CreateReportScheduleRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.log.WarnCtx(r.Context(), "Invalid request body: %v", err)
+		respondError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	schedule, err := h.scheduler.Create(r.Context(), &req)
+	if errors.Is(err, service.ErrInvalidReportSchedule) {
+		respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err != nil {
+		h.log.ErrorCtx(r.Context(), "Failed to create report schedule: %v", err)
+		respondError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	respondJSON(w, http.StatusCreated, schedule)
+}
+
+func (h *ReportScheduleHandler) ListSchedules(w http.ResponseWriter, r *http.Request) {
+	schedules, err := h.scheduler.List(r.Context())
+	if err != nil {
+		h.log.ErrorCtx(r.Context(), "Failed to list report schedules: %v", err)
+		respondError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	respondJSON(w, http.StatusOK, schedules)
+}
+
+func (h *ReportScheduleHandler) GetSchedule(w http.ResponseWriter, r *http.Request) {
+	schedule, err := h.scheduler.Get(r.Context(), mux.Vars(r)["id"])
+	if errors.Is(err, repository.ErrReportScheduleNotFound) {
+		respondError(w, http.StatusNotFound, err.Error())
+		return
+	}
+	if err != nil {
+		h.log.ErrorCtx(r.Context(), "Failed to get report schedule: %v", err)
+		respondError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	respondJSON(w, http.StatusOK, schedule)
+}
+
+func (h *ReportScheduleHandler) DeleteSchedule(w http.ResponseWriter, r *http.Request) {
+	err := h.scheduler.Delete(r.Context(), mux.Vars(r)["id"])
+	if errors.Is(err, repository.ErrReportScheduleNotFound) {
+		respondError(w, http.StatusNotFound, err.Error())
+		return
+	}
+	if err != nil {
+		h.log.ErrorCtx(r.Context(), "Failed to delete report schedule: %v", err)
+		respondError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	respondJSON(w, http.StatusOK, map[string]string{"message": "Report schedule deleted"})
+}
+
+func (h *ReportScheduleHandler) ListReports(w http.ResponseWriter, r *http.Request) {
+	limit := 0
+	if v := r.URL.Query().Get("limit"); v != "" {
+		parsed, err := strconv.Atoi(v)
+		if err != nil {
+			respondError(w, http.StatusBadRequest, "Invalid limit")
+			return
+		}
+		limit = parsed
+	}
+
+	reports, err := h.scheduler.ListReports(r.Context(), mux.Vars(r)["id"], limit)
+	if errors.Is(err, repository.ErrReportScheduleNotFound) {
+		respondError(w, http.StatusNotFound, err.Error())
+		return
+	}
+	if err != nil {
+		h.log.ErrorCtx(r.Context(), "Failed to list generated reports: %v", err)
+		respondError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	respondJSON(w, http.StatusOK, reports)
+}
+
+// DownloadReport returns the stored report document itself, as an
+// attachment so browsers save it.
+func (h *ReportScheduleHandler) DownloadReport(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid report ID")
+		return
+	}
+
+	report, err := h.scheduler.GetReport(r.Context(), id)
+	if errors.Is(err, repository.ErrReportNotFound) {
+		respondError(w, http.StatusNotFound, err.Error())
+		return
+	}
+	if err != nil {
+		h.log.ErrorCtx(r.Context(), "Failed to get generated report: %v", err)
+		respondError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	filename := fmt.Sprintf("report-%d-%s.json", report.ID, report.GeneratedAt.UTC().Format("20060102-1504"))
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%s", filename))
+	w.WriteHeader(http.StatusOK)
+	w.Write(report.Payload)
+}
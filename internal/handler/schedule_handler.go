@@ -38,7 +38,7 @@ func (h *ScheduleHandler) ListTasks(w http.ResponseWriter, r *http.Request) {
 
 	tasks, err := h.scheduleService.List(r.Context(), probeID)
 	if err != nil {
-		h.log.Error("Failed to list tasks: %v", err)
+		h.log.ErrorCtx(r.Context(), "Failed to list tasks: %v", err)
 		respondError(w, http.StatusInternalServerError, err.Error())
 		return
 	}
@@ -51,14 +51,14 @@ func (h *ScheduleHandler) CreateTask(w http.ResponseWriter, r *http.Request) {
 
 	var task models.ScheduledTask
 	if err := json.NewDecoder(r.Body).Decode(&task); err != nil {
-		h.log.Warn("Invalid request body: %v", err)
+		h.log.WarnCtx(r.Context(), "Invalid request body: %v", err)
 		respondError(w, http.StatusBadRequest, "Invalid request body")
 		return
 	}
 	task.ProbeID = probeID
 
 	if err := h.scheduleService.Create(r.Context(), &task); err != nil {
-		h.log.Error("Failed to create task: %v", err)
+		h.log.ErrorCtx(r.Context(), "Failed to create task: %v", err)
 		respondError(w, http.StatusInternalServerError, err.Error())
 		return
 	}
@@ -71,7 +71,7 @@ func (h *ScheduleHandler) GetTask(w http.ResponseWriter, r *http.Request) {
 
 	task, err := h.scheduleService.Get(r.Context(), taskID)
 	if err != nil {
-		h.log.Error("Failed to get task: %v", err)
+		h.log.ErrorCtx(r.Context(), "Failed to get task: %v", err)
 		respondError(w, http.StatusNotFound, "Task not found")
 		return
 	}
@@ -84,13 +84,13 @@ func (h *ScheduleHandler) UpdateTask(w http.ResponseWriter, r *http.Request) {
 
 	var task models.ScheduledTask
 	if err := json.NewDecoder(r.Body).Decode(&task); err != nil {
-		h.log.Warn("Invalid request body: %v", err)
+		h.log.WarnCtx(r.Context(), "Invalid request body: %v", err)
 		respondError(w, http.StatusBadRequest, "Invalid request body")
 		return
 	}
 
 	if err := h.scheduleService.Update(r.Context(), taskID, &task); err != nil {
-		h.log.Error("Failed to update task: %v", err)
+		h.log.ErrorCtx(r.Context(), "Failed to update task: %v", err)
 		respondError(w, http.StatusInternalServerError, err.Error())
 		return
 	}
@@ -102,7 +102,7 @@ func (h *ScheduleHandler) DeleteTask(w http.ResponseWriter, r *http.Request) {
 	taskID := vars["task_id"]
 
 	if err := h.scheduleService.Delete(r.Context(), taskID); err != nil {
-		h.log.Error("Failed to delete task: %v", err)
+		h.log.ErrorCtx(r.Context(), "Failed to delete task: %v", err)
 		respondError(w, http.StatusInternalServerError, err.Error())
 		return
 	}
@@ -2,12 +2,15 @@ package handler
 
 import (
 	_ "encoding/json"
+	"errors"
 	"net/http"
 	"strconv"
+	"strings"
 	"time"
 
 	"CampusMonitorAPI/internal/logger"
 	"CampusMonitorAPI/internal/models"
+	"CampusMonitorAPI/internal/repository"
 	"CampusMonitorAPI/internal/service"
 
 	"github.com/gorilla/mux"
@@ -27,18 +30,25 @@ func NewTelemetryHandler(telemetryService *service.TelemetryService, log *logger
 
 func (h *TelemetryHandler) RegisterRoutes(r *mux.Router) {
 	r.HandleFunc("/telemetry", h.QueryTelemetry).Methods("GET")
+	r.HandleFunc("/telemetry/latest", h.GetLatestForProbes).Methods("GET")
 	r.HandleFunc("/telemetry/{probe_id}/latest", h.GetLatestTelemetry).Methods("GET")
 	r.HandleFunc("/telemetry/{probe_id}/stats", h.GetProbeStats).Methods("GET")
+	r.HandleFunc("/telemetry/{probe_id}/stats/{metric}", h.GetMetricStats).Methods("GET")
+	r.HandleFunc("/telemetry/{probe_id}/count", h.CountTelemetry).Methods("GET")
 }
 
 func (h *TelemetryHandler) QueryTelemetry(w http.ResponseWriter, r *http.Request) {
 	query := r.URL.Query()
 
 	req := &models.TelemetryQueryRequest{
-		ProbeIDs: query["probe_id"],
-		Type:     query.Get("type"),
-		Limit:    100,
-		Offset:   0,
+		ProbeIDs:   query["probe_id"],
+		Building:   query.Get("building"),
+		Floor:      query.Get("floor"),
+		Department: query.Get("department"),
+		Tag:        query.Get("tag"),
+		Type:       query.Get("type"),
+		Limit:      100,
+		Offset:     0,
 	}
 
 	if limit := query.Get("limit"); limit != "" {
@@ -67,11 +77,17 @@ func (h *TelemetryHandler) QueryTelemetry(w http.ResponseWriter, r *http.Request
 
 	response, err := h.telemetryService.GetTelemetry(r.Context(), req)
 	if err != nil {
-		h.log.Error("Failed to query telemetry: %v", err)
+		h.log.ErrorCtx(r.Context(), "Failed to query telemetry: %v", err)
 		respondError(w, http.StatusInternalServerError, err.Error())
 		return
 	}
 
+	if wantsCSV(r) {
+		// Pagination metadata has no place in the CSV body.
+		w.Header().Set("X-Total-Count", strconv.Itoa(response.TotalCount))
+		respondCSV(w, "telemetry", response.Data)
+		return
+	}
 	respondJSON(w, http.StatusOK, response)
 }
 
@@ -88,7 +104,7 @@ func (h *TelemetryHandler) GetLatestTelemetry(w http.ResponseWriter, r *http.Req
 
 	telemetry, err := h.telemetryService.GetLatestTelemetry(r.Context(), probeID, limit)
 	if err != nil {
-		h.log.Error("Failed to get latest telemetry: %v", err)
+		h.log.ErrorCtx(r.Context(), "Failed to get latest telemetry: %v", err)
 		respondError(w, http.StatusInternalServerError, err.Error())
 		return
 	}
@@ -96,6 +112,38 @@ func (h *TelemetryHandler) GetLatestTelemetry(w http.ResponseWriter, r *http.Req
 	respondJSON(w, http.StatusOK, telemetry)
 }
 
+// GetLatestForProbes returns the latest reading of each probe in the
+// comma-separated probe_ids, or of every probe for probe_ids=all.
+func (h *TelemetryHandler) GetLatestForProbes(w http.ResponseWriter, r *http.Request) {
+	param := r.URL.Query().Get("probe_ids")
+	if param == "" {
+		respondError(w, http.StatusBadRequest, "probe_ids is required")
+		return
+	}
+
+	var probeIDs []string
+	if param != "all" {
+		for _, id := range strings.Split(param, ",") {
+			if id = strings.TrimSpace(id); id != "" {
+				probeIDs = append(probeIDs, id)
+			}
+		}
+		if len(probeIDs) == 0 {
+			respondError(w, http.StatusBadRequest, "probe_ids is required")
+			return
+		}
+	}
+
+	latest, err := h.telemetryService.GetLatestForProbes(r.Context(), probeIDs)
+	if err != nil {
+		h.log.ErrorCtx(r.Context(), "Failed to get latest telemetry: %v", err)
+		respondError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	respondJSON(w, http.StatusOK, latest)
+}
+
 func (h *TelemetryHandler) GetProbeStats(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	probeID := vars["probe_id"]
@@ -109,10 +157,44 @@ func (h *TelemetryHandler) GetProbeStats(w http.ResponseWriter, r *http.Request)
 
 	stats, err := h.telemetryService.GetProbeStats(r.Context(), probeID, hours)
 	if err != nil {
-		h.log.Error("Failed to get probe stats: %v", err)
+		h.log.ErrorCtx(r.Context(), "Failed to get probe stats: %v", err)
+		respondError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	respondJSON(w, http.StatusOK, stats)
+}
+
+func (h *TelemetryHandler) GetMetricStats(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	start, end := parseTimeRange(r)
+
+	stats, err := h.telemetryService.GetMetricStats(r.Context(), vars["probe_id"], vars["metric"], start, end)
+	if errors.Is(err, repository.ErrUnknownMetric) {
+		respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err != nil {
+		h.log.ErrorCtx(r.Context(), "Failed to get %s stats: %v", vars["metric"], err)
 		respondError(w, http.StatusInternalServerError, err.Error())
 		return
 	}
 
 	respondJSON(w, http.StatusOK, stats)
 }
+
+// CountTelemetry reports how many rows a probe has in the range without
+// returning them, for quick coverage checks.
+func (h *TelemetryHandler) CountTelemetry(w http.ResponseWriter, r *http.Request) {
+	probeID := mux.Vars(r)["probe_id"]
+	start, end := parseTimeRange(r)
+
+	count, err := h.telemetryService.CountTelemetry(r.Context(), probeID, start, end)
+	if err != nil {
+		h.log.ErrorCtx(r.Context(), "Failed to count telemetry: %v", err)
+		respondError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	respondJSON(w, http.StatusOK, count)
+}
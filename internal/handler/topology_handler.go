@@ -30,12 +30,15 @@ func (h *TopologyHandler) RegisterRoutes(r *mux.Router) {
 
 	// e.g. GET /api/v1/topology/building/LIB-01/floor/2
 	r.HandleFunc("/topology/building/{building}/floor/{floor}", h.GetFloorDetails).Methods("GET")
+
+	// e.g. GET /api/v1/topology/building/LIB-01/compare?metric=rssi
+	r.HandleFunc("/topology/building/{building}/compare", h.CompareFloors).Methods("GET")
 }
 
 func (h *TopologyHandler) GetLayout(w http.ResponseWriter, r *http.Request) {
 	layout, err := h.topologyService.GetLayout(r.Context())
 	if err != nil {
-		h.log.Error("Failed to get topology layout: %v", err)
+		h.log.ErrorCtx(r.Context(), "Failed to get topology layout: %v", err)
 		respondError(w, http.StatusInternalServerError, "Failed to calculate topology layout")
 		return
 	}
@@ -50,9 +53,14 @@ func (h *TopologyHandler) GetHeatmap(w http.ResponseWriter, r *http.Request) {
 		metric = "rssi"
 	}
 
-	heatmap, err := h.topologyService.GetHeatmap(r.Context(), metric)
+	aggregate, ok := parseAggregate(w, r)
+	if !ok {
+		return
+	}
+
+	heatmap, err := h.topologyService.GetHeatmap(r.Context(), metric, aggregate)
 	if err != nil {
-		h.log.Error("Failed to get topology heatmap: %v", err)
+		h.log.ErrorCtx(r.Context(), "Failed to get topology heatmap: %v", err)
 		respondError(w, http.StatusInternalServerError, "Failed to calculate heatmap")
 		return
 	}
@@ -67,10 +75,46 @@ func (h *TopologyHandler) GetFloorDetails(w http.ResponseWriter, r *http.Request
 
 	details, err := h.topologyService.GetFloorDetails(r.Context(), building, floor)
 	if err != nil {
-		h.log.Error("Failed to get floor details for building %s, floor %s: %v", building, floor, err)
+		h.log.ErrorCtx(r.Context(), "Failed to get floor details for building %s, floor %s: %v", building, floor, err)
 		respondError(w, http.StatusInternalServerError, "Failed to fetch floor details")
 		return
 	}
 
 	respondJSON(w, http.StatusOK, details)
 }
+
+func (h *TopologyHandler) CompareFloors(w http.ResponseWriter, r *http.Request) {
+	building := mux.Vars(r)["building"]
+
+	metric := r.URL.Query().Get("metric")
+	if metric == "" {
+		metric = "rssi"
+	}
+
+	aggregate, ok := parseAggregate(w, r)
+	if !ok {
+		return
+	}
+
+	comparison, err := h.topologyService.CompareFloors(r.Context(), building, metric, aggregate)
+	if err != nil {
+		h.log.ErrorCtx(r.Context(), "Failed to compare floors for building %s: %v", building, err)
+		respondError(w, http.StatusInternalServerError, "Failed to compare floors")
+		return
+	}
+
+	respondJSON(w, http.StatusOK, comparison)
+}
+
+// parseAggregate reads the aggregate query parameter, defaulting to avg. It
+// writes a 400 and returns false for anything else.
+func parseAggregate(w http.ResponseWriter, r *http.Request) (string, bool) {
+	switch aggregate := r.URL.Query().Get("aggregate"); aggregate {
+	case "":
+		return service.AggregateAvg, true
+	case service.AggregateAvg, service.AggregateWorst:
+		return aggregate, true
+	}
+	respondError(w, http.StatusBadRequest, "aggregate must be avg or worst")
+	return "", false
+}
@@ -2,7 +2,10 @@ package handler
 
 import (
 	"encoding/json"
+	"errors"
 	"net/http"
+
+	"CampusMonitorAPI/internal/models"
 )
 
 type ErrorResponse struct {
@@ -21,3 +24,20 @@ func respondJSON(w http.ResponseWriter, statusCode int, data interface{}) {
 func respondError(w http.ResponseWriter, statusCode int, message string) {
 	respondJSON(w, statusCode, ErrorResponse{Error: message})
 }
+
+// respondValidationError writes a 422 with per-field messages, e.g.
+// {"errors": [{"field": "ssid", "message": "required"}]}.
+func respondValidationError(w http.ResponseWriter, ve *models.ValidationError) {
+	respondJSON(w, http.StatusUnprocessableEntity, ve)
+}
+
+// respondIfValidation writes err as a 422 and returns true if it is a
+// *models.ValidationError.
+func respondIfValidation(w http.ResponseWriter, err error) bool {
+	var ve *models.ValidationError
+	if !errors.As(err, &ve) {
+		return false
+	}
+	respondValidationError(w, ve)
+	return true
+}
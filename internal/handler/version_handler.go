@@ -0,0 +1,25 @@
+package handler
+
+import (
+	"net/http"
+
+	"CampusMonitorAPI/internal/models"
+
+	"github.com/gorilla/mux"
+)
+
+type VersionHandler struct {
+	info models.VersionInfo
+}
+
+func NewVersionHandler(info models.VersionInfo) *VersionHandler {
+	return &VersionHandler{info: info}
+}
+
+func (h *VersionHandler) RegisterRoutes(r *mux.Router) {
+	r.HandleFunc("/version", h.GetVersion).Methods("GET")
+}
+
+func (h *VersionHandler) GetVersion(w http.ResponseWriter, r *http.Request) {
+	respondJSON(w, http.StatusOK, h.info)
+}
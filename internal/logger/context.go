@@ -0,0 +1,41 @@
+package logger
+
+import "context"
+
+type contextKey string
+
+const requestIDKey contextKey = "request_id"
+
+// WithRequestID returns a copy of ctx carrying the given request id.
+func WithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDKey, id)
+}
+
+// RequestIDFromContext returns the request id stored in ctx, if any.
+func RequestIDFromContext(ctx context.Context) string {
+	if ctx == nil {
+		return ""
+	}
+	id, _ := ctx.Value(requestIDKey).(string)
+	return id
+}
+
+// DebugCtx logs like Debug, tagging the entry with the request id in ctx.
+func (l *Logger) DebugCtx(ctx context.Context, format string, args ...interface{}) {
+	l.log(DEBUG, RequestIDFromContext(ctx), format, args...)
+}
+
+// InfoCtx logs like Info, tagging the entry with the request id in ctx.
+func (l *Logger) InfoCtx(ctx context.Context, format string, args ...interface{}) {
+	l.log(INFO, RequestIDFromContext(ctx), format, args...)
+}
+
+// WarnCtx logs like Warn, tagging the entry with the request id in ctx.
+func (l *Logger) WarnCtx(ctx context.Context, format string, args ...interface{}) {
+	l.log(WARN, RequestIDFromContext(ctx), format, args...)
+}
+
+// ErrorCtx logs like Error, tagging the entry with the request id in ctx.
+func (l *Logger) ErrorCtx(ctx context.Context, format string, args ...interface{}) {
+	l.log(ERROR, RequestIDFromContext(ctx), format, args...)
+}
@@ -1,17 +1,21 @@
 package logger
 
 import (
+	"encoding/json"
 	"fmt"
 	"io"
 	"os"
 	"path/filepath"
 	"runtime"
+	"sort"
+	"strings"
 	"sync"
 	"time"
 )
 
 type Level uint8
 type Mode uint8
+type Format uint8
 
 const (
 	DEBUG Level = iota
@@ -27,6 +31,11 @@ const (
 	FULL
 )
 
+const (
+	TEXT Format = iota
+	JSON
+)
+
 var (
 	levelNames = map[Level]string{
 		DEBUG: "DEBUG",
@@ -55,13 +64,57 @@ type Logger struct {
 	fileOut    io.Writer
 	logFile    *os.File
 	useColors  bool
+	format     Format
+
+	filePath   string
+	fileSize   int64
+	maxSize    int64
+	maxBackups int
+
+	// writeMu serialises writes to the outputs; in async mode the writer
+	// goroutine holds it instead of the caller.
+	writeMu    sync.Mutex
+	entries    chan logEntry
+	writerDone chan struct{}
+
+	redactKeys []string
+}
+
+// logEntry is a fully formatted line waiting to be written.
+type logEntry struct {
+	console string
+	file    string
 }
 
 type Config struct {
 	Level       Level
 	Mode        Mode
+	Format      Format
 	LogFilePath string
 	UseColors   bool
+	// MaxSizeMB rotates the log file once it grows past this size; 0 disables rotation.
+	MaxSizeMB int
+	// MaxBackups is the number of rotated files to keep; 0 keeps them all.
+	MaxBackups int
+	// Async hands entries to a background writer instead of writing on the
+	// caller's goroutine. BufferSize bounds how many entries may be queued;
+	// when the queue is full an entry is written on the caller's goroutine.
+	// Formatting always happens on the caller's goroutine, outside any lock.
+	Async      bool
+	BufferSize int
+	// RedactKeys lists extra payload keys (e.g. "ssid") masked by Redact on
+	// top of the built-in password/secret/token keys.
+	RedactKeys []string
+}
+
+// jsonEntry is the shape of a single line written in JSON format.
+type jsonEntry struct {
+	Level     string `json:"level"`
+	Timestamp string `json:"timestamp"`
+	Message   string `json:"message"`
+	File      string `json:"file"`
+	Line      int    `json:"line"`
+	RequestID string `json:"request_id,omitempty"`
 }
 
 func New(cfg Config) (*Logger, error) {
@@ -70,6 +123,15 @@ func New(cfg Config) (*Logger, error) {
 		mode:       cfg.Mode,
 		consoleOut: os.Stdout,
 		useColors:  cfg.UseColors,
+		format:     cfg.Format,
+		maxSize:    int64(cfg.MaxSizeMB) * 1024 * 1024,
+		maxBackups: cfg.MaxBackups,
+	}
+
+	for _, key := range cfg.RedactKeys {
+		if key = strings.ToLower(strings.TrimSpace(key)); key != "" {
+			logger.redactKeys = append(logger.redactKeys, key)
+		}
 	}
 
 	if cfg.LogFilePath != "" {
@@ -78,9 +140,39 @@ func New(cfg Config) (*Logger, error) {
 		}
 	}
 
+	if cfg.Async {
+		bufferSize := cfg.BufferSize
+		if bufferSize <= 0 {
+			bufferSize = 1024
+		}
+		logger.entries = make(chan logEntry, bufferSize)
+		logger.writerDone = make(chan struct{})
+		go logger.runWriter()
+	}
+
 	return logger, nil
 }
 
+func (l *Logger) runWriter() {
+	defer close(l.writerDone)
+	for entry := range l.entries {
+		l.writeMu.Lock()
+		l.write(entry)
+		l.writeMu.Unlock()
+	}
+}
+
+// stopWriter drains the async queue and waits for the writer to exit.
+// Callers must hold l.mu so no new entries are queued concurrently.
+func (l *Logger) stopWriter() {
+	if l.entries == nil {
+		return
+	}
+	close(l.entries)
+	<-l.writerDone
+	l.entries = nil
+}
+
 func (l *Logger) setupLogFile(path string) error {
 	dir := filepath.Dir(path)
 	if err := os.MkdirAll(dir, 0755); err != nil {
@@ -92,63 +184,176 @@ func (l *Logger) setupLogFile(path string) error {
 		return err
 	}
 
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return err
+	}
+
 	l.logFile = file
 	l.fileOut = file
+	l.filePath = path
+	l.fileSize = info.Size()
 	return nil
 }
 
+// rotate renames the current log file with a timestamp suffix, opens a fresh
+// one in its place and prunes old backups. Callers must hold l.writeMu. The
+// old file stays open until the new one is, so if any step fails logging
+// carries on into the original file.
+func (l *Logger) rotate() error {
+	old := l.logFile
+
+	backup := fmt.Sprintf("%s.%s", l.filePath, time.Now().Format("20060102-150405.000"))
+	if err := os.Rename(l.filePath, backup); err != nil {
+		return err
+	}
+
+	if err := l.setupLogFile(l.filePath); err != nil {
+		_ = os.Rename(backup, l.filePath)
+		return err
+	}
+	old.Close()
+
+	return l.pruneBackups()
+}
+
+func (l *Logger) pruneBackups() error {
+	if l.maxBackups <= 0 {
+		return nil
+	}
+
+	backups, err := filepath.Glob(l.filePath + ".*")
+	if err != nil {
+		return err
+	}
+	if len(backups) <= l.maxBackups {
+		return nil
+	}
+
+	// The timestamp suffix sorts lexically, oldest first.
+	sort.Strings(backups)
+	for _, old := range backups[:len(backups)-l.maxBackups] {
+		if err := os.Remove(old); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Flush writes out everything queued by the async writer. Entries logged
+// afterwards are written synchronously, so it is meant for shutdown.
+func (l *Logger) Flush() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.stopWriter()
+}
+
+// Close flushes any queued entries and closes the log file.
 func (l *Logger) Close() error {
+	l.mu.Lock()
+	l.stopWriter()
+	l.mu.Unlock()
+
+	l.writeMu.Lock()
+	defer l.writeMu.Unlock()
 	if l.logFile != nil {
 		return l.logFile.Close()
 	}
 	return nil
 }
 
-func (l *Logger) log(level Level, format string, args ...interface{}) {
-	if level < l.level {
+func (l *Logger) log(level Level, requestID string, format string, args ...interface{}) {
+	l.mu.Lock()
+	minLevel, mode, logFormat := l.level, l.mode, l.format
+	l.mu.Unlock()
+
+	if level < minLevel {
 		return
 	}
 
-	l.mu.Lock()
-	defer l.mu.Unlock()
-
 	timestamp := time.Now().Format("2006-01-02 15:04:05")
 	message := fmt.Sprintf(format, args...)
 
 	var consoleMsg, fileMsg string
 
-	switch l.mode {
-	case MINIMAL:
+	if requestID != "" && logFormat != JSON {
+		message = fmt.Sprintf("[req=%s] %s", requestID, message)
+	}
+
+	switch {
+	case logFormat == JSON:
+		file, line := l.getCaller()
+		consoleMsg = l.formatJSON(level, file, line, requestID, message)
+		fileMsg = consoleMsg
+
+	case mode == MINIMAL:
 		consoleMsg = l.formatMinimal(level, message)
 		fileMsg = l.formatMinimalFile(level, timestamp, message)
 
-	case NORMAL:
+	case mode == NORMAL:
 		consoleMsg = l.formatNormal(level, timestamp, message)
 		fileMsg = l.formatNormalFile(level, timestamp, message)
 
-	case FULL:
+	case mode == FULL:
 		file, line := l.getCaller()
 		consoleMsg = l.formatFull(level, timestamp, file, line, message)
 		fileMsg = l.formatFullFile(level, timestamp, file, line, message)
 	}
 
+	entry := logEntry{console: consoleMsg, file: fileMsg}
+
+	if level == FATAL {
+		// Flush what's queued and write synchronously so the exit reason
+		// is never lost.
+		l.mu.Lock()
+		l.stopWriter()
+		l.writeMu.Lock()
+		l.write(entry)
+		os.Exit(1)
+	}
+
+	// l.mu only guards the queue against stopWriter closing it; the send
+	// never blocks. A full queue means the writer is behind, so the entry
+	// is written here instead, which stalls this caller alone.
+	l.mu.Lock()
+	if l.entries != nil {
+		select {
+		case l.entries <- entry:
+			l.mu.Unlock()
+			return
+		default:
+		}
+	}
+	l.mu.Unlock()
+
+	l.writeMu.Lock()
+	l.write(entry)
+	l.writeMu.Unlock()
+}
+
+// write emits an entry to the console and file. Callers must hold l.writeMu.
+func (l *Logger) write(entry logEntry) {
 	if l.consoleOut != nil {
-		_, err := fmt.Fprintln(l.consoleOut, consoleMsg)
+		_, err := fmt.Fprintln(l.consoleOut, entry.console)
 		if err != nil {
 			return
 		}
 	}
 
 	if l.fileOut != nil {
-		_, err := fmt.Fprintln(l.fileOut, fileMsg)
+		if l.maxSize > 0 && l.fileSize+int64(len(entry.file)+1) > l.maxSize {
+			if err := l.rotate(); err != nil {
+				fmt.Fprintf(os.Stderr, "log rotation failed: %v\n", err)
+			}
+		}
+
+		n, err := fmt.Fprintln(l.fileOut, entry.file)
+		l.fileSize += int64(n)
 		if err != nil {
 			return
 		}
 	}
-
-	if level == FATAL {
-		os.Exit(1)
-	}
 }
 
 func (l *Logger) formatMinimal(level Level, msg string) string {
@@ -194,6 +399,21 @@ func (l *Logger) formatFullFile(level Level, timestamp, file string, line int, m
 	return fmt.Sprintf("%s [%s] %s | %s", timestamp, levelNames[level], location, msg)
 }
 
+func (l *Logger) formatJSON(level Level, file string, line int, requestID, msg string) string {
+	data, err := json.Marshal(jsonEntry{
+		Level:     levelNames[level],
+		Timestamp: time.Now().UTC().Format(time.RFC3339Nano),
+		Message:   msg,
+		File:      file,
+		Line:      line,
+		RequestID: requestID,
+	})
+	if err != nil {
+		return fmt.Sprintf(`{"level":%q,"message":%q}`, levelNames[level], msg)
+	}
+	return string(data)
+}
+
 func (l *Logger) getCaller() (string, int) {
 	_, file, line, ok := runtime.Caller(3)
 	if !ok {
@@ -203,23 +423,23 @@ func (l *Logger) getCaller() (string, int) {
 }
 
 func (l *Logger) Debug(format string, args ...interface{}) {
-	l.log(DEBUG, format, args...)
+	l.log(DEBUG, "", format, args...)
 }
 
 func (l *Logger) Info(format string, args ...interface{}) {
-	l.log(INFO, format, args...)
+	l.log(INFO, "", format, args...)
 }
 
 func (l *Logger) Warn(format string, args ...interface{}) {
-	l.log(WARN, format, args...)
+	l.log(WARN, "", format, args...)
 }
 
 func (l *Logger) Error(format string, args ...interface{}) {
-	l.log(ERROR, format, args...)
+	l.log(ERROR, "", format, args...)
 }
 
 func (l *Logger) Fatal(format string, args ...interface{}) {
-	l.log(FATAL, format, args...)
+	l.log(FATAL, "", format, args...)
 }
 
 func (l *Logger) SetLevel(level Level) {
@@ -234,20 +454,35 @@ func (l *Logger) SetMode(mode Mode) {
 	l.mode = mode
 }
 
+func (l *Logger) SetFormat(format Format) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.format = format
+}
+
 func ParseLevel(s string) Level {
+	if level, ok := LookupLevel(s); ok {
+		return level
+	}
+	return INFO
+}
+
+// LookupLevel is like ParseLevel but reports whether s named a known level
+// instead of falling back to INFO.
+func LookupLevel(s string) (Level, bool) {
 	switch s {
 	case "debug", "DEBUG":
-		return DEBUG
+		return DEBUG, true
 	case "info", "INFO":
-		return INFO
+		return INFO, true
 	case "warn", "WARN", "warning", "WARNING":
-		return WARN
+		return WARN, true
 	case "error", "ERROR":
-		return ERROR
+		return ERROR, true
 	case "fatal", "FATAL":
-		return FATAL
+		return FATAL, true
 	default:
-		return INFO
+		return INFO, false
 	}
 }
 
@@ -264,6 +499,70 @@ func ParseMode(s string) Mode {
 	}
 }
 
+func (l Level) String() string {
+	return levelNames[l]
+}
+
+// MarshalText writes a level by name, mirroring UnmarshalText.
+func (l Level) MarshalText() ([]byte, error) {
+	return []byte(l.String()), nil
+}
+
+func (m Mode) String() string {
+	switch m {
+	case MINIMAL:
+		return "MINIMAL"
+	case FULL:
+		return "FULL"
+	default:
+		return "NORMAL"
+	}
+}
+
+// MarshalText writes a mode by name, mirroring UnmarshalText.
+func (m Mode) MarshalText() ([]byte, error) {
+	return []byte(m.String()), nil
+}
+
+func ParseFormat(s string) Format {
+	switch s {
+	case "json", "JSON":
+		return JSON
+	default:
+		return TEXT
+	}
+}
+
+func (f Format) String() string {
+	if f == JSON {
+		return "JSON"
+	}
+	return "TEXT"
+}
+
+// MarshalText writes a format by name, mirroring UnmarshalText.
+func (f Format) MarshalText() ([]byte, error) {
+	return []byte(f.String()), nil
+}
+
+// UnmarshalText lets a format be written by name in config files.
+func (f *Format) UnmarshalText(text []byte) error {
+	*f = ParseFormat(string(text))
+	return nil
+}
+
+// UnmarshalText lets a level be written by name in config files.
+func (l *Level) UnmarshalText(text []byte) error {
+	*l = ParseLevel(string(text))
+	return nil
+}
+
+// UnmarshalText lets a mode be written by name in config files.
+func (m *Mode) UnmarshalText(text []byte) error {
+	*m = ParseMode(string(text))
+	return nil
+}
+
 var defaultLogger *Logger
 
 func init() {
@@ -0,0 +1,67 @@
+package logger
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+const redactedValue = "****"
+
+// sensitiveKeyParts marks any JSON key containing one of these substrings as
+// secret, so "password", "wifi_password" and "mqtt_pass" are all caught.
+var sensitiveKeyParts = []string{"pass", "secret", "token", "psk", "api_key"}
+
+// Redact renders a JSON payload for logging with the values of sensitive
+// keys masked. Payloads that aren't JSON are summarised rather than logged
+// verbatim, since they can't be inspected for secrets.
+func (l *Logger) Redact(payload []byte) string {
+	var v interface{}
+	if err := json.Unmarshal(payload, &v); err != nil {
+		return fmt.Sprintf("<%d bytes, not JSON>", len(payload))
+	}
+
+	data, err := json.Marshal(l.redactValue(v))
+	if err != nil {
+		return fmt.Sprintf("<%d bytes>", len(payload))
+	}
+	return string(data)
+}
+
+func (l *Logger) redactValue(v interface{}) interface{} {
+	switch val := v.(type) {
+	case map[string]interface{}:
+		out := make(map[string]interface{}, len(val))
+		for k, inner := range val {
+			if l.isSensitiveKey(k) {
+				out[k] = redactedValue
+				continue
+			}
+			out[k] = l.redactValue(inner)
+		}
+		return out
+	case []interface{}:
+		out := make([]interface{}, len(val))
+		for i, inner := range val {
+			out[i] = l.redactValue(inner)
+		}
+		return out
+	default:
+		return v
+	}
+}
+
+func (l *Logger) isSensitiveKey(key string) bool {
+	key = strings.ToLower(key)
+	for _, part := range sensitiveKeyParts {
+		if strings.Contains(key, part) {
+			return true
+		}
+	}
+	for _, extra := range l.redactKeys {
+		if key == extra {
+			return true
+		}
+	}
+	return false
+}
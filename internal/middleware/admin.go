@@ -0,0 +1,29 @@
+package middleware
+
+import (
+	"net/http"
+
+	"CampusMonitorAPI/internal/auth"
+	"CampusMonitorAPI/internal/models"
+)
+
+// RequireAdmin rejects requests whose authenticated user is not an admin.
+// It must run after Auth, which places the claims in the request context.
+func RequireAdmin(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		claims, ok := r.Context().Value("user").(*auth.Claims)
+		if !ok {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusUnauthorized)
+			w.Write([]byte(`{"error": "Unauthorized"}`))
+			return
+		}
+		if claims.Role != string(models.RoleAdmin) {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusForbidden)
+			w.Write([]byte(`{"error": "Admin access required"}`))
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,88 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"CampusMonitorAPI/internal/logger"
+	"CampusMonitorAPI/internal/models"
+)
+
+// maxAuditBody is the largest request body copied into the audit trail;
+// bigger bodies are recorded by size only.
+const maxAuditBody = 8 << 10
+
+// auditWriteTimeout bounds the insert made after each audited request.
+const auditWriteTimeout = 5 * time.Second
+
+// AuditRecorder stores audit entries.
+type AuditRecorder interface {
+	Record(ctx context.Context, entry *models.AuditEntry) error
+}
+
+// AuditLog records every request other than GET, HEAD and OPTIONS with its
+// principal, client address and response status. The body is kept with
+// secret values masked by log.Redact, so LOG_REDACT_KEYS applies here too.
+// It must run after Auth so the principal is known; requests the auth
+// middleware rejects never reach it.
+func AuditLog(recorder AuditRecorder, log *logger.Logger, proxies TrustedProxies) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			switch r.Method {
+			case http.MethodGet, http.MethodHead, http.MethodOptions:
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			body := auditBody(r, log)
+			rw := &responseWriter{ResponseWriter: w, statusCode: http.StatusOK}
+			next.ServeHTTP(rw, r)
+
+			entry := &models.AuditEntry{
+				Method:    r.Method,
+				Path:      r.URL.Path,
+				Principal: auditPrincipal(r.Context()),
+				ClientIP:  proxies.ClientIP(r),
+				Status:    rw.statusCode,
+				RequestID: logger.RequestIDFromContext(r.Context()),
+				Body:      body,
+			}
+
+			ctx, cancel := context.WithTimeout(context.WithoutCancel(r.Context()), auditWriteTimeout)
+			defer cancel()
+			if err := recorder.Record(ctx, entry); err != nil {
+				log.ErrorCtx(r.Context(), "Failed to record audit entry for %s %s: %v", r.Method, r.URL.Path, err)
+			}
+		})
+	}
+}
+
+// auditBody reads up to maxAuditBody bytes of the request body for the
+// audit entry and puts them back for the handler.
+func auditBody(r *http.Request, log *logger.Logger) string {
+	if r.Body == nil || r.Body == http.NoBody {
+		return ""
+	}
+
+	buf, err := io.ReadAll(io.LimitReader(r.Body, maxAuditBody+1))
+	// The server closes the original body once the handler returns.
+	r.Body = io.NopCloser(io.MultiReader(bytes.NewReader(buf), r.Body))
+	if err != nil || len(buf) == 0 {
+		return ""
+	}
+	if len(buf) > maxAuditBody {
+		return fmt.Sprintf("<more than %d bytes>", maxAuditBody)
+	}
+	return log.Redact(buf)
+}
+
+func auditPrincipal(ctx context.Context) string {
+	if principal := Principal(ctx); principal != "" {
+		return principal
+	}
+	return "anonymous"
+}
@@ -27,6 +27,39 @@ func Auth(jwtSecret string) func(http.Handler) http.Handler {
 	}
 }
 
+// OptionalAuth attaches the claims of a valid token like Auth but lets
+// requests without one through anonymously. Besides the Authorization header
+// it reads a token query parameter, since browsers cannot set headers on a
+// WebSocket handshake.
+func OptionalAuth(jwtSecret string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			tokenStr := extractToken(r)
+			if tokenStr == "" {
+				tokenStr = r.URL.Query().Get("token")
+			}
+			if tokenStr != "" {
+				if claims, err := auth.ValidateToken(tokenStr, jwtSecret); err == nil {
+					r = r.WithContext(context.WithValue(r.Context(), "user", claims))
+				}
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// Principal names who authenticated the request: the username, or
+// "api_key:<label>" for API keys. It is empty for anonymous requests.
+func Principal(ctx context.Context) string {
+	if label := APIKeyLabel(ctx); label != "" {
+		return "api_key:" + label
+	}
+	if claims, ok := ctx.Value("user").(*auth.Claims); ok && claims.Username != "" {
+		return claims.Username
+	}
+	return ""
+}
+
 func extractToken(r *http.Request) string {
 	authHeader := r.Header.Get("Authorization")
 	if len(authHeader) > 7 && strings.ToLower(authHeader[:7]) == "bearer " {
@@ -0,0 +1,80 @@
+package middleware
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// MaxBodyBytes caps request bodies at limit bytes; 0 leaves them unbounded.
+// A declared Content-Length over the limit is rejected with 413 before the
+// handler runs. A body that only turns out too long while being read fails
+// the handler's decode, and the handler's error reply is replaced by the
+// same 413.
+func MaxBodyBytes(limit int64) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		if limit <= 0 {
+			return next
+		}
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.ContentLength > limit {
+				writeTooLarge(w, limit)
+				return
+			}
+			if r.Body == nil || r.Body == http.NoBody {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			body := &limitedBody{ReadCloser: http.MaxBytesReader(w, r.Body, limit)}
+			r.Body = body
+			next.ServeHTTP(&bodyLimitWriter{ResponseWriter: w, body: body, limit: limit}, r)
+		})
+	}
+}
+
+func writeTooLarge(w http.ResponseWriter, limit int64) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusRequestEntityTooLarge)
+	fmt.Fprintf(w, `{"error": "request body exceeds %d bytes"}`, limit)
+}
+
+// limitedBody notes when the MaxBytesReader cut the body off.
+type limitedBody struct {
+	io.ReadCloser
+	exceeded bool
+}
+
+func (b *limitedBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		b.exceeded = true
+	}
+	return n, err
+}
+
+// bodyLimitWriter swaps an error reply caused by an oversized body for 413.
+type bodyLimitWriter struct {
+	http.ResponseWriter
+	body     *limitedBody
+	limit    int64
+	replaced bool
+}
+
+func (w *bodyLimitWriter) WriteHeader(code int) {
+	if code >= http.StatusBadRequest && w.body.exceeded {
+		w.replaced = true
+		writeTooLarge(w.ResponseWriter, w.limit)
+		return
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *bodyLimitWriter) Write(b []byte) (int, error) {
+	if w.replaced {
+		return len(b), nil
+	}
+	return w.ResponseWriter.Write(b)
+}
@@ -0,0 +1,141 @@
+package middleware
+
+import (
+	"bytes"
+	"container/list"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Cache serves repeated GET requests from an in-memory LRU of up to size
+// responses for ttl. Only 200 responses are stored. A request sent with
+// Cache-Control: no-cache skips the lookup and refreshes the entry. Responses
+// carry X-Cache: HIT or MISS.
+//
+// The key is method, path and query only, so apply it to routes whose output
+// doesn't depend on who is asking.
+func Cache(ttl time.Duration, size int) func(http.Handler) http.Handler {
+	cache := newResponseCache(size)
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Method != http.MethodGet {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			key := r.Method + " " + r.URL.Path + "?" + r.URL.RawQuery
+
+			if !strings.Contains(r.Header.Get("Cache-Control"), "no-cache") {
+				if entry, ok := cache.get(key); ok {
+					for k, v := range entry.header {
+						w.Header()[k] = v
+					}
+					w.Header().Set("X-Cache", "HIT")
+					w.WriteHeader(entry.statusCode)
+					w.Write(entry.body)
+					return
+				}
+			}
+
+			cw := &cacheWriter{ResponseWriter: w, statusCode: http.StatusOK}
+			w.Header().Set("X-Cache", "MISS")
+			next.ServeHTTP(cw, r)
+
+			if cw.statusCode == http.StatusOK {
+				header := w.Header().Clone()
+				header.Del("X-Cache")
+				cache.set(key, &cachedResponse{
+					statusCode: cw.statusCode,
+					header:     header,
+					body:       cw.body.Bytes(),
+					expires:    time.Now().Add(ttl),
+				})
+			}
+		})
+	}
+}
+
+// cacheWriter passes the response through while keeping a copy of the body.
+type cacheWriter struct {
+	http.ResponseWriter
+	body       bytes.Buffer
+	statusCode int
+}
+
+func (cw *cacheWriter) WriteHeader(code int) {
+	cw.statusCode = code
+	cw.ResponseWriter.WriteHeader(code)
+}
+
+func (cw *cacheWriter) Write(b []byte) (int, error) {
+	cw.body.Write(b)
+	return cw.ResponseWriter.Write(b)
+}
+
+type cachedResponse struct {
+	key        string
+	statusCode int
+	header     http.Header
+	body       []byte
+	expires    time.Time
+}
+
+type responseCache struct {
+	mu      sync.Mutex
+	size    int
+	order   *list.List // front is most recently used
+	entries map[string]*list.Element
+}
+
+func newResponseCache(size int) *responseCache {
+	if size < 1 {
+		size = 1
+	}
+	return &responseCache{
+		size:    size,
+		order:   list.New(),
+		entries: make(map[string]*list.Element),
+	}
+}
+
+func (c *responseCache) get(key string) (*cachedResponse, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	elem, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+
+	entry := elem.Value.(*cachedResponse)
+	if time.Now().After(entry.expires) {
+		c.order.Remove(elem)
+		delete(c.entries, key)
+		return nil, false
+	}
+
+	c.order.MoveToFront(elem)
+	return entry, true
+}
+
+func (c *responseCache) set(key string, entry *cachedResponse) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry.key = key
+	if elem, ok := c.entries[key]; ok {
+		elem.Value = entry
+		c.order.MoveToFront(elem)
+		return
+	}
+
+	c.entries[key] = c.order.PushFront(entry)
+	for c.order.Len() > c.size {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*cachedResponse).key)
+	}
+}
@@ -2,25 +2,62 @@ package middleware
 
 import (
 	"net/http"
+	"strings"
 )
 
-func CORS(allowedOrigins, allowedMethods []string) func(http.Handler) http.Handler {
+// CORSPolicy is the cross-origin policy for a set of routes.
+type CORSPolicy struct {
+	AllowedOrigins []string
+	AllowedMethods []string
+	// AllowCredentials lets browsers send cookies and read the response.
+	// It only applies to origins listed explicitly: an origin matched by "*"
+	// gets "*" back without credentials, never its own origin echoed.
+	AllowCredentials bool
+}
+
+// CORSRule applies Policy to the requests Match accepts, e.g. a stricter
+// origin list for command routes than for read-only analytics.
+type CORSRule struct {
+	Match  func(path string) bool
+	Policy CORSPolicy
+}
+
+// CORS answers preflights and sets the CORS headers for the first rule that
+// matches the request path, falling back to def. It wraps the whole router
+// rather than running as router middleware, because the router rejects an
+// OPTIONS request to a POST-only route before any middleware runs.
+func CORS(def CORSPolicy, rules ...CORSRule) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			policy := def
+			for _, rule := range rules {
+				if rule.Match(r.URL.Path) {
+					policy = rule.Policy
+					break
+				}
+			}
+
 			origin := r.Header.Get("Origin")
+			h := w.Header()
+			h.Add("Vary", "Origin")
 
-			if len(allowedOrigins) == 1 && allowedOrigins[0] == "*" {
-				w.Header().Set("Access-Control-Allow-Origin", "*")
-			} else if contains(allowedOrigins, origin) {
-				w.Header().Set("Access-Control-Allow-Origin", origin)
+			switch {
+			case origin == "":
+			case contains(policy.AllowedOrigins, origin):
+				h.Set("Access-Control-Allow-Origin", origin)
+				if policy.AllowCredentials {
+					h.Set("Access-Control-Allow-Credentials", "true")
+				}
+			case contains(policy.AllowedOrigins, "*"):
+				h.Set("Access-Control-Allow-Origin", "*")
 			}
 
-			w.Header().Set("Access-Control-Allow-Origin", "*")
-			w.Header().Set("Access-Control-Allow-Methods", "GET,POST,PUT,DELETE,OPTIONS,PATCH")
-			w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization, X-API-Key")
-			w.Header().Set("Access-Control-Max-Age", "86400")
+			h.Set("Access-Control-Allow-Methods", strings.Join(policy.AllowedMethods, ","))
+			h.Set("Access-Control-Allow-Headers", "Content-Type, Authorization, X-API-Key, X-Request-ID")
+			h.Set("Access-Control-Expose-Headers", "X-Request-ID")
+			h.Set("Access-Control-Max-Age", "86400")
 
-			if r.Method == "OPTIONS" {
+			if r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != "" {
 				w.WriteHeader(http.StatusNoContent)
 				return
 			}
@@ -30,6 +67,18 @@ func CORS(allowedOrigins, allowedMethods []string) func(http.Handler) http.Handl
 	}
 }
 
+// PathPrefixes matches paths starting with any of prefixes.
+func PathPrefixes(prefixes ...string) func(path string) bool {
+	return func(path string) bool {
+		for _, p := range prefixes {
+			if strings.HasPrefix(path, p) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
 func contains(slice []string, item string) bool {
 	for _, s := range slice {
 		if s == item {
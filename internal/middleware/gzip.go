@@ -0,0 +1,138 @@
+package middleware
+
+import (
+	"compress/gzip"
+	"net/http"
+	"strings"
+)
+
+// Gzip compresses responses of at least minSize bytes for clients that
+// accept gzip. Smaller responses, content that is already compressed and
+// WebSocket upgrades are passed through untouched.
+func Gzip(minSize int) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if !strings.Contains(r.Header.Get("Accept-Encoding"), "gzip") ||
+				strings.EqualFold(r.Header.Get("Upgrade"), "websocket") {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			gw := &gzipResponseWriter{
+				ResponseWriter: w,
+				minSize:        minSize,
+				statusCode:     http.StatusOK,
+			}
+			defer gw.Close()
+
+			next.ServeHTTP(gw, r)
+		})
+	}
+}
+
+// gzipResponseWriter buffers the start of a response until it knows whether
+// the body is large enough to be worth compressing, then either switches to
+// gzip or writes the buffer through as-is.
+type gzipResponseWriter struct {
+	http.ResponseWriter
+	gz          *gzip.Writer
+	buf         []byte
+	minSize     int
+	statusCode  int
+	decided     bool
+	wroteHeader bool
+}
+
+func (gw *gzipResponseWriter) WriteHeader(code int) {
+	if gw.wroteHeader {
+		return
+	}
+	gw.wroteHeader = true
+	gw.statusCode = code
+}
+
+func (gw *gzipResponseWriter) Write(b []byte) (int, error) {
+	gw.wroteHeader = true
+
+	if gw.decided {
+		if gw.gz != nil {
+			return gw.gz.Write(b)
+		}
+		return gw.ResponseWriter.Write(b)
+	}
+
+	gw.buf = append(gw.buf, b...)
+	if len(gw.buf) < gw.minSize {
+		return len(b), nil
+	}
+
+	if err := gw.decide(); err != nil {
+		return 0, err
+	}
+	return len(b), nil
+}
+
+// decide commits to compressing or passing through and flushes the buffer.
+func (gw *gzipResponseWriter) decide() error {
+	gw.decided = true
+	header := gw.ResponseWriter.Header()
+
+	if header.Get("Content-Type") == "" && len(gw.buf) > 0 {
+		header.Set("Content-Type", http.DetectContentType(gw.buf))
+	}
+
+	if len(gw.buf) >= gw.minSize && header.Get("Content-Encoding") == "" && compressible(header.Get("Content-Type")) {
+		header.Set("Content-Encoding", "gzip")
+		header.Add("Vary", "Accept-Encoding")
+		header.Del("Content-Length")
+		gw.gz = gzip.NewWriter(gw.ResponseWriter)
+		gw.ResponseWriter.WriteHeader(gw.statusCode)
+		_, err := gw.gz.Write(gw.buf)
+		gw.buf = nil
+		return err
+	}
+
+	gw.ResponseWriter.WriteHeader(gw.statusCode)
+	if len(gw.buf) == 0 {
+		return nil
+	}
+	_, err := gw.ResponseWriter.Write(gw.buf)
+	gw.buf = nil
+	return err
+}
+
+// Close flushes a response that never reached minSize and finishes the gzip stream.
+func (gw *gzipResponseWriter) Close() error {
+	if !gw.decided {
+		if err := gw.decide(); err != nil {
+			return err
+		}
+	}
+	if gw.gz != nil {
+		return gw.gz.Close()
+	}
+	return nil
+}
+
+func (gw *gzipResponseWriter) Flush() {
+	if !gw.decided {
+		gw.decide()
+	}
+	if gw.gz != nil {
+		gw.gz.Flush()
+	}
+	if f, ok := gw.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// compressible skips media that is already compressed.
+func compressible(contentType string) bool {
+	contentType = strings.ToLower(contentType)
+	for _, prefix := range []string{"image/", "video/", "audio/", "application/zip", "application/gzip", "application/x-gzip", "application/pdf"} {
+		if strings.HasPrefix(contentType, prefix) {
+			return false
+		}
+	}
+	return true
+}
@@ -24,7 +24,9 @@ func (rw *responseWriter) Write(b []byte) (int, error) {
 	return n, err
 }
 
-func RequestLogger(log *logger.Logger) func(http.Handler) http.Handler {
+// RequestLogger logs one line per request. The client address is resolved
+// through proxies so a spoofed X-Forwarded-For can't forge audit entries.
+func RequestLogger(log *logger.Logger, proxies TrustedProxies) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			start := time.Now()
@@ -37,13 +39,28 @@ func RequestLogger(log *logger.Logger) func(http.Handler) http.Handler {
 			next.ServeHTTP(rw, r)
 
 			duration := time.Since(start)
+			client := proxies.ClientIP(r)
 
-			log.Info("%s %s %d %dms %d bytes",
+			if label := APIKeyLabel(r.Context()); label != "" {
+				log.InfoCtx(r.Context(), "%s %s %d %dms %d bytes client=%s api_key=%s",
+					r.Method,
+					r.URL.Path,
+					rw.statusCode,
+					duration.Milliseconds(),
+					rw.bytesWritten,
+					client,
+					label,
+				)
+				return
+			}
+
+			log.InfoCtx(r.Context(), "%s %s %d %dms %d bytes client=%s",
 				r.Method,
 				r.URL.Path,
 				rw.statusCode,
 				duration.Milliseconds(),
 				rw.bytesWritten,
+				client,
 			)
 		})
 	}
@@ -0,0 +1,52 @@
+package middleware
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"CampusMonitorAPI/internal/metrics"
+
+	"github.com/gorilla/mux"
+)
+
+// Metrics records request count, latency and in-flight requests labelled by
+// route template (not raw path, to keep cardinality bounded) and status.
+// WebSocket upgrades are skipped since they need the underlying Hijacker.
+func Metrics(reg *metrics.Registry) func(http.Handler) http.Handler {
+	requests := reg.NewCounterVec("http_requests_total", "Total HTTP requests.", "method", "route", "status")
+	duration := reg.NewHistogramVec("http_request_duration_seconds", "HTTP request latency in seconds.", metrics.DefaultBuckets, "method", "route", "status")
+	inFlight := reg.NewGaugeVec("http_requests_in_flight", "HTTP requests currently being served.", "route")
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if strings.EqualFold(r.Header.Get("Upgrade"), "websocket") {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			route := "unmatched"
+			if current := mux.CurrentRoute(r); current != nil {
+				if tpl, err := current.GetPathTemplate(); err == nil {
+					route = tpl
+				}
+			}
+
+			inFlight.Add(1, route)
+			defer inFlight.Add(-1, route)
+
+			start := time.Now()
+			rw := &responseWriter{
+				ResponseWriter: w,
+				statusCode:     http.StatusOK,
+			}
+
+			next.ServeHTTP(rw, r)
+
+			status := strconv.Itoa(rw.statusCode)
+			requests.Inc(r.Method, route, status)
+			duration.Observe(time.Since(start).Seconds(), r.Method, route, status)
+		})
+	}
+}
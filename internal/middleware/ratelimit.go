@@ -6,38 +6,66 @@ import (
 	"time"
 )
 
+// visitor is a token bucket: it holds up to burst tokens, refills at the
+// configured rate and each request spends one token.
 type visitor struct {
+	tokens   float64
 	lastSeen time.Time
-	count    int
 }
 
-type rateLimiter struct {
+// RateLimiter is a per-client request limiter whose limit can be changed at
+// runtime (e.g. on config reload) without rebuilding the router. Requests
+// authenticated with an API key get a bucket per key, so clients sharing an
+// egress IP don't throttle each other; everyone else is limited per IP.
+type RateLimiter struct {
 	visitors map[string]*visitor
 	mu       sync.RWMutex
-	limit    int
-	window   time.Duration
+	rate     float64 // tokens per second
+	burst    float64
+	keyRates map[string]float64 // per API key label overrides of rate
+	proxies  TrustedProxies
+	now      func() time.Time
 }
 
-func newRateLimiter(requestsPerMinute int) *rateLimiter {
-	rl := &rateLimiter{
+// NewRateLimiter allows requestsPerMinute on average with bursts of up to
+// burst requests. A non-positive burst defaults to requestsPerMinute.
+// Clients are identified by IP as resolved through proxies.
+func NewRateLimiter(requestsPerMinute, burst int, proxies TrustedProxies) *RateLimiter {
+	rl := &RateLimiter{
 		visitors: make(map[string]*visitor),
-		limit:    requestsPerMinute,
-		window:   time.Minute,
+		keyRates: make(map[string]float64),
+		proxies:  proxies,
+		now:      time.Now,
 	}
+	rl.SetLimit(requestsPerMinute, burst)
 
 	go rl.cleanup()
 
 	return rl
 }
 
-func (rl *rateLimiter) cleanup() {
+func (rl *RateLimiter) cleanup() {
 	ticker := time.NewTicker(time.Minute)
 	defer ticker.Stop()
 
 	for range ticker.C {
 		rl.mu.Lock()
+		// A visitor idle long enough to refill completely is indistinguishable
+		// from a new one, so it can be dropped.
+		idle := time.Minute
+		slowest := rl.rate
+		for _, rate := range rl.keyRates {
+			if rate < slowest {
+				slowest = rate
+			}
+		}
+		if slowest > 0 {
+			if refill := time.Duration(rl.burst / slowest * float64(time.Second)); refill > idle {
+				idle = refill
+			}
+		}
 		for ip, v := range rl.visitors {
-			if time.Since(v.lastSeen) > rl.window {
+			if rl.now().Sub(v.lastSeen) > idle {
 				delete(rl.visitors, ip)
 			}
 		}
@@ -45,46 +73,79 @@ func (rl *rateLimiter) cleanup() {
 	}
 }
 
-func (rl *rateLimiter) allow(ip string) bool {
+// allow spends a token from the bucket for client. keyLabel is the API key
+// label the request authenticated with, if any, and selects its rate.
+func (rl *RateLimiter) allow(client, keyLabel string) bool {
 	rl.mu.Lock()
 	defer rl.mu.Unlock()
 
-	v, exists := rl.visitors[ip]
+	rate := rl.rate
+	if keyRate, ok := rl.keyRates[keyLabel]; ok {
+		rate = keyRate
+	}
+
+	now := rl.now()
+	v, exists := rl.visitors[client]
 	if !exists {
-		rl.visitors[ip] = &visitor{
-			lastSeen: time.Now(),
-			count:    1,
-		}
-		return true
+		v = &visitor{tokens: rl.burst, lastSeen: now}
+		rl.visitors[client] = v
 	}
 
-	if time.Since(v.lastSeen) > rl.window {
-		v.count = 1
-		v.lastSeen = time.Now()
-		return true
+	v.tokens += now.Sub(v.lastSeen).Seconds() * rate
+	if v.tokens > rl.burst {
+		v.tokens = rl.burst
 	}
+	v.lastSeen = now
 
-	if v.count >= rl.limit {
+	if v.tokens < 1 {
 		return false
 	}
 
-	v.count++
-	v.lastSeen = time.Now()
+	v.tokens--
 	return true
 }
 
-func RateLimit(requestsPerMinute int) func(http.Handler) http.Handler {
-	rl := newRateLimiter(requestsPerMinute)
+// SetLimit changes the refill rate and burst size. Existing buckets keep
+// their tokens, capped to the new burst on their next request.
+func (rl *RateLimiter) SetLimit(requestsPerMinute, burst int) {
+	if burst <= 0 {
+		burst = requestsPerMinute
+	}
+
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+	rl.rate = float64(requestsPerMinute) / 60
+	rl.burst = float64(burst)
+}
+
+// SetKeyLimits replaces the per-minute rates for individual API keys, keyed
+// by label. Keys without an entry use the default rate.
+func (rl *RateLimiter) SetKeyLimits(limits map[string]int) {
+	rates := make(map[string]float64, len(limits))
+	for label, requestsPerMinute := range limits {
+		rates[label] = float64(requestsPerMinute) / 60
+	}
+
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+	rl.keyRates = rates
+}
+
+func RateLimit(requestsPerMinute, burst int, proxies TrustedProxies) func(http.Handler) http.Handler {
+	return NewRateLimiter(requestsPerMinute, burst, proxies).Middleware()
+}
 
+func (rl *RateLimiter) Middleware() func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			ip := r.RemoteAddr
+			client := rl.proxies.ClientIP(r)
 
-			if forwarded := r.Header.Get("X-Forwarded-For"); forwarded != "" {
-				ip = forwarded
+			label := APIKeyLabel(r.Context())
+			if label != "" {
+				client = "key:" + label
 			}
 
-			if !rl.allow(ip) {
+			if !rl.allow(client, label) {
 				w.Header().Set("Content-Type", "application/json")
 				w.WriteHeader(http.StatusTooManyRequests)
 				w.Write([]byte(`{"error": "Rate limit exceeded"}`))
@@ -0,0 +1,81 @@
+package middleware
+
+import (
+	"testing"
+	"time"
+)
+
+// fakeClock is a manually advanced time source for the rate limiter.
+type fakeClock struct {
+	t time.Time
+}
+
+func (c *fakeClock) Now() time.Time          { return c.t }
+func (c *fakeClock) Advance(d time.Duration) { c.t = c.t.Add(d) }
+
+func newTestLimiter(requestsPerMinute, burst int) (*RateLimiter, *fakeClock) {
+	clock := &fakeClock{t: time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)}
+	rl := NewRateLimiter(requestsPerMinute, burst, nil)
+	rl.now = clock.Now
+	return rl, clock
+}
+
+// spend makes requests until one is refused and returns how many passed.
+func spend(rl *RateLimiter, client string) int {
+	allowed := 0
+	for rl.allow(client, "") {
+		allowed++
+		if allowed > 10000 {
+			break
+		}
+	}
+	return allowed
+}
+
+func TestRateLimiterBurst(t *testing.T) {
+	rl, _ := newTestLimiter(60, 10)
+
+	if got := spend(rl, "10.0.0.1"); got != 10 {
+		t.Fatalf("fresh client got %d requests, want burst of 10", got)
+	}
+	if got := spend(rl, "10.0.0.2"); got != 10 {
+		t.Fatalf("second client got %d requests, want its own burst of 10", got)
+	}
+}
+
+func TestRateLimiterNoDoubleAtWindowBoundary(t *testing.T) {
+	// With a fixed one-minute window, a client could spend the full limit
+	// just before the boundary and again just after it.
+	rl, clock := newTestLimiter(60, 60)
+
+	clock.Advance(59*time.Second + 900*time.Millisecond)
+	if got := spend(rl, "10.0.0.1"); got != 60 {
+		t.Fatalf("got %d requests before the boundary, want 60", got)
+	}
+
+	clock.Advance(200 * time.Millisecond)
+	if got := spend(rl, "10.0.0.1"); got != 0 {
+		t.Fatalf("got %d requests just after the boundary, want 0", got)
+	}
+}
+
+func TestRateLimiterRefill(t *testing.T) {
+	rl, clock := newTestLimiter(60, 5)
+	spend(rl, "10.0.0.1")
+
+	clock.Advance(time.Second)
+	if got := spend(rl, "10.0.0.1"); got != 1 {
+		t.Fatalf("got %d requests after 1s at 1/s, want 1", got)
+	}
+
+	clock.Advance(3 * time.Second)
+	if got := spend(rl, "10.0.0.1"); got != 3 {
+		t.Fatalf("got %d requests after 3s at 1/s, want 3", got)
+	}
+
+	// Idle time never refills past the burst size.
+	clock.Advance(10 * time.Minute)
+	if got := spend(rl, "10.0.0.1"); got != 5 {
+		t.Fatalf("got %d requests after a long idle, want burst of 5", got)
+	}
+}
@@ -1,27 +1,35 @@
 package middleware
 
 import (
-	"fmt"
+	"encoding/json"
 	"net/http"
 	"runtime/debug"
 
 	"CampusMonitorAPI/internal/logger"
 )
 
+// recoveryResponse is what a client sees after a panic. The panic value is
+// only logged; it may hold internals or characters that break the JSON.
+type recoveryResponse struct {
+	Error     string `json:"error"`
+	RequestID string `json:"request_id,omitempty"`
+}
+
 func Recovery(log *logger.Logger) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			defer func() {
 				if err := recover(); err != nil {
-					log.Error("PANIC: %v", err)
-					log.Error("Stack trace:\n%s", debug.Stack())
+					log.ErrorCtx(r.Context(), "PANIC: %v", err)
+					log.ErrorCtx(r.Context(), "Stack trace:\n%s", debug.Stack())
 
+					body, _ := json.Marshal(recoveryResponse{
+						Error:     "Internal server error",
+						RequestID: logger.RequestIDFromContext(r.Context()),
+					})
 					w.Header().Set("Content-Type", "application/json")
 					w.WriteHeader(http.StatusInternalServerError)
-					_, err2 := w.Write([]byte(fmt.Sprintf(`{"error": "Internal server error: %v"}`, err)))
-					if err2 != nil {
-						return
-					}
+					w.Write(body)
 				}
 			}()
 
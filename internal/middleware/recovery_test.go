@@ -0,0 +1,51 @@
+package middleware
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"CampusMonitorAPI/internal/logger"
+)
+
+func TestRecoveryReturnsValidJSONWithoutPanicDetails(t *testing.T) {
+	log, err := logger.New(logger.Config{Level: logger.FATAL})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	const secret = "db password \"hunter2\"\nat /srv/app/internal/repo.go:42\t{\"x\":1}"
+	handler := Recovery(log)(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {
+		panic(secret)
+	}))
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/probes", nil)
+	req = req.WithContext(logger.WithRequestID(req.Context(), "req-123"))
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusInternalServerError {
+		t.Fatalf("status = %d, want 500", rec.Code)
+	}
+	if ct := rec.Header().Get("Content-Type"); ct != "application/json" {
+		t.Fatalf("Content-Type = %q, want application/json", ct)
+	}
+
+	var body map[string]interface{}
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		t.Fatalf("body is not valid JSON: %v\n%s", err, rec.Body.String())
+	}
+	if body["error"] != "Internal server error" {
+		t.Errorf("error = %v, want generic message", body["error"])
+	}
+	if body["request_id"] != "req-123" {
+		t.Errorf("request_id = %v, want req-123", body["request_id"])
+	}
+	for _, leak := range []string{"hunter2", "repo.go", "password"} {
+		if strings.Contains(rec.Body.String(), leak) {
+			t.Errorf("body leaks %q: %s", leak, rec.Body.String())
+		}
+	}
+}
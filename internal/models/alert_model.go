@@ -17,11 +17,42 @@ const (
 	CategorySystem  = "SYSTEM"
 )
 
-// Alert represents the persistent history of a network event
+// ValidSeverity reports whether s is one of the Severity constants.
+func ValidSeverity(s string) bool {
+	switch s {
+	case SeverityInfo, SeverityWarning, SeverityCritical:
+		return true
+	}
+	return false
+}
+
+// ValidStatus reports whether s is one of the Status constants.
+func ValidStatus(s string) bool {
+	switch s {
+	case StatusActive, StatusAcknowledged, StatusResolved:
+		return true
+	}
+	return false
+}
+
+// ValidCategory reports whether c is one of the Category constants.
+func ValidCategory(c string) bool {
+	switch c {
+	case CategorySignal, CategoryNetwork, CategorySystem:
+		return true
+	}
+	return false
+}
+
+// Alert represents the persistent history of a network event. For alerts
+// raised by the evaluator, AlertType is the metric key (rssi, latency) and
+// Occurrences the number of consecutive samples that breached it. Status is
+// not stored: it is derived from ResolvedAt and Acknowledged.
 type Alert struct {
 	ID             int                    `json:"id" db:"id"`
 	ProbeID        string                 `json:"probe_id" db:"probe_id"`
 	AlertType      string                 `json:"alert_type" db:"alert_type"`
+	Category       string                 `json:"category,omitempty" db:"category"`
 	Severity       string                 `json:"severity" db:"severity"`
 	Message        string                 `json:"message" db:"message"`
 	ThresholdValue *float64               `json:"threshold_value" db:"threshold_value"`
@@ -29,9 +60,89 @@ type Alert struct {
 	TriggeredAt    time.Time              `json:"triggered_at" db:"triggered_at"`
 	ResolvedAt     *time.Time             `json:"resolved_at" db:"resolved_at"`
 	Acknowledged   bool                   `json:"acknowledged" db:"acknowledged"`
+	Occurrences    int                    `json:"occurrences,omitempty" db:"occurrences"`
+	Status         string                 `json:"status" db:"-"`
 	Metadata       map[string]interface{} `json:"metadata" db:"metadata"`
 }
 
+// AlertStatusOf derives an alert's Status from its resolved_at and
+// acknowledged columns.
+func AlertStatusOf(resolvedAt *time.Time, acknowledged bool) string {
+	switch {
+	case resolvedAt != nil:
+		return StatusResolved
+	case acknowledged:
+		return StatusAcknowledged
+	}
+	return StatusActive
+}
+
+// AlertComment is one entry in an alert's discussion trail.
+type AlertComment struct {
+	ID        int       `json:"id"`
+	AlertID   int       `json:"alert_id"`
+	Author    string    `json:"author"`
+	Body      string    `json:"body"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// CreateAlertCommentRequest is the body of POST /alerts/{id}/comments.
+type CreateAlertCommentRequest struct {
+	Body string `json:"body"`
+}
+
+// BulkAcknowledgeRequest selects unresolved, unacknowledged alerts to
+// acknowledge together. IDs, ProbeID and Severity are ANDed; at least one
+// must be set.
+type BulkAcknowledgeRequest struct {
+	IDs      []uint `json:"ids,omitempty"`
+	ProbeID  string `json:"probe_id,omitempty"`
+	Severity string `json:"severity,omitempty"`
+	Note     string `json:"note,omitempty"`
+}
+
+// AlertStatistics summarises the alert table for dashboard widgets. The
+// severity and category breakdowns only count unresolved alerts.
+type AlertStatistics struct {
+	BySeverity          map[string]int `json:"by_severity"`
+	ByCategory          map[string]int `json:"by_category"`
+	TotalActive         int            `json:"total_active"`
+	TotalUnacknowledged int            `json:"total_unacknowledged"`
+	Total               int            `json:"total"`
+	ResolvedLast24h     int            `json:"resolved_last_24h"`
+	AcknowledgedLast24h int            `json:"acknowledged_last_24h"`
+}
+
+// Recurring pattern kinds.
+const (
+	PatternHourOfDay     = "hour_of_day"
+	PatternDayOfWeekHour = "day_of_week_hour"
+)
+
+// AlertPattern is an alert type that keeps firing in the same time slot.
+// Frequency is the share of slots in the window (days for hour_of_day,
+// weeks for day_of_week_hour) that saw at least one such alert.
+type AlertPattern struct {
+	AlertType   string  `json:"alert_type"`
+	Kind        string  `json:"kind"`
+	DayOfWeek   string  `json:"day_of_week,omitempty"`
+	Hour        int     `json:"hour"`
+	Occurrences int     `json:"occurrences"`
+	ActiveSlots int     `json:"active_slots"`
+	TotalSlots  int     `json:"total_slots"`
+	Frequency   float64 `json:"frequency"`
+}
+
+// RecurringPatternReport lists the recurring patterns found in a probe's
+// alert history, most frequent first.
+type RecurringPatternReport struct {
+	ProbeID     string         `json:"probe_id"`
+	Days        int            `json:"days"`
+	Threshold   float64        `json:"threshold"`
+	TotalAlerts int            `json:"total_alerts"`
+	Patterns    []AlertPattern `json:"patterns"`
+}
+
 // AlertConfig defines the program-defined defaults
 type AlertConfig struct {
 	RSSIThreshold    float64 `json:"rssi_threshold"`
@@ -41,7 +152,7 @@ type AlertConfig struct {
 	HeartbeatTimeout int     `json:"heartbeat_timeout"`
 }
 
-// TODO: Make this part of a config or something
+// DEFAULT_ALERT_CONFIG holds the fallbacks used when the ALERT_* settings are unset.
 var DEFAULT_ALERT_CONFIG = AlertConfig{
 	RSSIThreshold:    -85.0,
 	RSSIOccurrences:  3,
@@ -7,6 +7,16 @@ type DailyCoverage struct {
 	HasData bool      `json:"has_data"`
 }
 
+// NeighborAP is one access point seen in a probe's deep scan.
+type NeighborAP struct {
+	Timestamp   time.Time `json:"timestamp" db:"timestamp"`
+	SeenByProbe string    `json:"seen_by_probe" db:"seen_by_probe"`
+	BSSID       string    `json:"bssid" db:"bssid"`
+	SSID        *string   `json:"ssid,omitempty" db:"ssid"`
+	Channel     *int      `json:"channel,omitempty" db:"channel"`
+	RSSI        *int      `json:"rssi,omitempty" db:"rssi"`
+}
+
 type AnomalyDetection struct {
 	ProbeID       string    `json:"probe_id"`
 	Timestamp     time.Time `json:"timestamp"`
@@ -0,0 +1,30 @@
+package models
+
+import "time"
+
+// AuditEntry records one mutating API request: who made it, from where,
+// and how it ended.
+type AuditEntry struct {
+	ID        int64     `json:"id" db:"id"`
+	Timestamp time.Time `json:"timestamp" db:"created_at"`
+	Method    string    `json:"method" db:"method"`
+	Path      string    `json:"path" db:"path"`
+	// Principal is the username, "api_key:<label>" for API key clients, or
+	// "anonymous".
+	Principal string `json:"principal" db:"principal"`
+	ClientIP  string `json:"client_ip" db:"client_ip"`
+	Status    int    `json:"status" db:"status"`
+	RequestID string `json:"request_id,omitempty" db:"request_id"`
+	// Body is the request body with secret values masked, or a size summary
+	// when it is too large or not JSON.
+	Body string `json:"body,omitempty" db:"body"`
+}
+
+// AuditQuery filters the audit trail. Zero values don't filter.
+type AuditQuery struct {
+	Principal string
+	Start     time.Time
+	End       time.Time
+	Limit     int
+	Offset    int
+}
@@ -199,6 +199,43 @@ type RolloutProgress struct {
 	Percentage   float64 `json:"percentage"`
 }
 
+// TagBroadcastResult reports a command fanned out to every probe with a
+// tag. FleetCommandID points at the fleet command recording the tag and the
+// probes it resolved to at the time.
+type TagBroadcastResult struct {
+	Tag            string            `json:"tag"`
+	FleetCommandID string            `json:"fleet_command_id,omitempty"`
+	CommandType    string            `json:"command_type"`
+	TotalTargets   int               `json:"total_targets"`
+	Commands       map[string]int    `json:"commands"`
+	Failed         map[string]string `json:"failed,omitempty"`
+}
+
+// OTA phases as seen from command results.
+const (
+	OTAPhaseSent        = "sent"
+	OTAPhaseDownloading = "downloading"
+	OTAPhaseCompleted   = "completed"
+	OTAPhaseFailed      = "failed"
+)
+
+// OTAProbeProgress is the latest OTA state reported by one probe.
+type OTAProbeProgress struct {
+	ProbeID   string    `json:"probe_id"`
+	CommandID string    `json:"command_id,omitempty"`
+	Phase     string    `json:"phase"`
+	Progress  float64   `json:"progress"`
+	Error     string    `json:"error,omitempty"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// OTARollup summarises OTA progress across the fleet.
+type OTARollup struct {
+	Total   int                `json:"total"`
+	ByPhase map[string]int     `json:"by_phase"`
+	Probes  []OTAProbeProgress `json:"probes"`
+}
+
 type GroupRolloutStatus struct {
 	GroupID   string          `json:"group_id"`
 	GroupName string          `json:"group_name"`
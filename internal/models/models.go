@@ -12,12 +12,21 @@ type Probe struct {
 	Department      string                 `json:"department" db:"department"`
 	Status          string                 `json:"status" db:"status"`
 	FirmwareVersion string                 `json:"firmware_version" db:"firmware_version"`
+	ReportInterval  *int                   `json:"report_interval" db:"report_interval"` // seconds, nil until the probe confirms one
 	LastSeen        time.Time              `json:"last_seen" db:"last_seen"`
 	CreatedAt       time.Time              `json:"created_at" db:"created_at"`
 	UpdatedAt       time.Time              `json:"updated_at" db:"updated_at"`
 	Metadata        map[string]interface{} `json:"metadata" db:"metadata"`
 }
 
+// ProbeQuery filters the probe list. Zero values don't filter.
+type ProbeQuery struct {
+	Status         string
+	CreatedAfter   time.Time
+	CreatedBefore  time.Time
+	LastSeenBefore time.Time
+}
+
 type Telemetry struct {
 	Timestamp time.Time `json:"timestamp" db:"timestamp"`
 	ProbeID   string    `json:"probe_id" db:"probe_id"`
@@ -83,6 +92,47 @@ type CreateProbeRequest struct {
 	Metadata        map[string]interface{} `json:"metadata"`
 }
 
+// ProbeDeleteResult reports what a probe deletion removed. The dependent row
+// counts are only non-zero when the delete was made with purge=true.
+type ProbeDeleteResult struct {
+	ProbeID        string `json:"probe_id"`
+	Purged         bool   `json:"purged"`
+	Telemetry      int64  `json:"telemetry"`
+	Commands       int64  `json:"commands"`
+	Alerts         int64  `json:"alerts"`
+	ScheduledTasks int64  `json:"scheduled_tasks"`
+}
+
+// ProbeDecommissionResult reports the cleanup done when a probe was
+// decommissioned.
+type ProbeDecommissionResult struct {
+	ProbeID           string `json:"probe_id"`
+	AlertsResolved    int64  `json:"alerts_resolved"`
+	CommandsCancelled int64  `json:"commands_cancelled"`
+}
+
+// Thresholds are the staleness windows shared by the services that judge
+// whether a probe or its data is current. See config.ThresholdConfig.
+type Thresholds struct {
+	ProbeActive     time.Duration `json:"probe_active"`
+	ProbeOffline    time.Duration `json:"probe_offline"`
+	StatusRetention time.Duration `json:"status_retention"`
+	TelemetryStale  time.Duration `json:"telemetry_stale"`
+	CommandPing     time.Duration `json:"command_ping"`
+}
+
+// FirmwareCompliance buckets probes against a target firmware version.
+// Probes on a newer version count as up to date; probes with no version or
+// one that can't be parsed are unknown.
+type FirmwareCompliance struct {
+	TargetVersion  string   `json:"target_version"`
+	Total          int      `json:"total"`
+	UpToDate       int      `json:"up_to_date"`
+	Outdated       int      `json:"outdated"`
+	Unknown        int      `json:"unknown"`
+	OutdatedProbes []string `json:"outdated_probes"`
+}
+
 type UpdateProbeRequest struct {
 	Location   *string                `json:"location"`
 	Building   *string                `json:"building"`
@@ -93,19 +143,27 @@ type UpdateProbeRequest struct {
 }
 
 type TelemetryQueryRequest struct {
-	ProbeIDs  []string   `form:"probe_ids"`
-	Type      string     `form:"type"`
-	StartTime *time.Time `form:"start_time" time_format:"2006-01-02T15:04:05Z"`
-	EndTime   *time.Time `form:"end_time" time_format:"2006-01-02T15:04:05Z"`
-	Limit     int        `form:"limit"`
-	Offset    int        `form:"offset"`
+	ProbeIDs []string `form:"probe_ids"`
+	// Building, Floor, Department and Tag narrow the query to a group of
+	// probes; they combine with ProbeIDs and each other.
+	Building   string     `form:"building"`
+	Floor      string     `form:"floor"`
+	Department string     `form:"department"`
+	Tag        string     `form:"tag"`
+	Type       string     `form:"type"`
+	StartTime  *time.Time `form:"start_time" time_format:"2006-01-02T15:04:05Z"`
+	EndTime    *time.Time `form:"end_time" time_format:"2006-01-02T15:04:05Z"`
+	Limit      int        `form:"limit"`
+	Offset     int        `form:"offset"`
 }
 
 type TelemetryQueryResponse struct {
 	Data       []Telemetry `json:"data"`
 	TotalCount int         `json:"total_count"`
-	Limit      int         `json:"limit"`
-	Offset     int         `json:"offset"`
+	// TypeCounts breaks TotalCount down by telemetry type, e.g. light and enhanced.
+	TypeCounts map[string]int `json:"type_counts,omitempty"`
+	Limit      int            `json:"limit"`
+	Offset     int            `json:"offset"`
 }
 
 type StatsResponse struct {
@@ -121,6 +179,31 @@ type StatsResponse struct {
 	MostCommonChan int     `json:"most_common_channel"`
 }
 
+// MetricStats summarises one numeric telemetry metric for a probe over a
+// time range. The aggregates are nil when Count is zero; StdDev also needs
+// at least two samples.
+type MetricStats struct {
+	ProbeID   string    `json:"probe_id"`
+	Metric    string    `json:"metric"`
+	StartTime time.Time `json:"start_time"`
+	EndTime   time.Time `json:"end_time"`
+	Count     int64     `json:"count"`
+	Avg       *float64  `json:"avg"`
+	Min       *float64  `json:"min"`
+	Max       *float64  `json:"max"`
+	StdDev    *float64  `json:"stddev"`
+}
+
+// TelemetryCount summarises how much telemetry a probe has in a time range.
+type TelemetryCount struct {
+	ProbeID   string     `json:"probe_id"`
+	StartTime time.Time  `json:"start_time"`
+	EndTime   time.Time  `json:"end_time"`
+	Count     int64      `json:"count"`
+	First     *time.Time `json:"first,omitempty"`
+	Last      *time.Time `json:"last,omitempty"`
+}
+
 type HealthResponse struct {
 	Status    string    `json:"status"`
 	Timestamp time.Time `json:"timestamp"`
@@ -128,6 +211,74 @@ type HealthResponse struct {
 		Database bool `json:"database"`
 		MQTT     bool `json:"mqtt"`
 	} `json:"services"`
+	MQTT struct {
+		MessagesLastMinute  int      `json:"messages_last_minute"`
+		SecondsSinceMessage *float64 `json:"seconds_since_message,omitempty"`
+		Silent              bool     `json:"silent"`
+	} `json:"mqtt"`
+}
+
+// VersionInfo identifies the running build. Version, Commit and BuildTime are
+// injected with -ldflags at build time.
+type VersionInfo struct {
+	Version   string `json:"version"`
+	Commit    string `json:"commit"`
+	BuildTime string `json:"build_time"`
+	GoVersion string `json:"go_version"`
+}
+
+// DatabasePoolStats describes one connection pool. Utilization is InUse
+// over MaxOpenConnections and is 0 when the pool is unlimited.
+type DatabasePoolStats struct {
+	MaxOpenConnections int     `json:"max_open_connections"`
+	OpenConnections    int     `json:"open_connections"`
+	InUse              int     `json:"in_use"`
+	Idle               int     `json:"idle"`
+	WaitCount          int64   `json:"wait_count"`
+	WaitDuration       string  `json:"wait_duration"`
+	WaitDurationMs     int64   `json:"wait_duration_ms"`
+	MaxIdleClosed      int64   `json:"max_idle_closed"`
+	MaxIdleTimeClosed  int64   `json:"max_idle_time_closed"`
+	MaxLifetimeClosed  int64   `json:"max_lifetime_closed"`
+	Utilization        float64 `json:"utilization"`
+	Saturated          bool    `json:"saturated"`
+}
+
+// DatabaseHealthResponse is served on /health/db.
+type DatabaseHealthResponse struct {
+	Status    string             `json:"status"`
+	Timestamp time.Time          `json:"timestamp"`
+	Healthy   bool               `json:"healthy"`
+	Primary   DatabasePoolStats  `json:"primary"`
+	Replica   *DatabasePoolStats `json:"replica,omitempty"`
+}
+
+// DetailedHealthResponse is the diagnostics view served on /health/detail.
+type DetailedHealthResponse struct {
+	Status    string    `json:"status"`
+	Timestamp time.Time `json:"timestamp"`
+	Database  struct {
+		Healthy         bool  `json:"healthy"`
+		OpenConnections int   `json:"open_connections"`
+		InUse           int   `json:"in_use"`
+		Idle            int   `json:"idle"`
+		WaitCount       int64 `json:"wait_count"`
+	} `json:"database"`
+	MQTT struct {
+		Connected      bool      `json:"connected"`
+		LastConnected  time.Time `json:"last_connected"`
+		LastDisconnect time.Time `json:"last_disconnect"`
+		Subscriptions  int       `json:"subscriptions"`
+		// ExpectedSubscriptions is how many topics have handlers registered.
+		ExpectedSubscriptions int       `json:"expected_subscriptions"`
+		LastMessage           time.Time `json:"last_message"`
+		MessagesReceived      uint64    `json:"messages_received"`
+		MessagesLastMinute    int       `json:"messages_last_minute"`
+		SecondsSinceMessage   *float64  `json:"seconds_since_message,omitempty"`
+		Silent                bool      `json:"silent"`
+	} `json:"mqtt"`
+	WebSocketClients int            `json:"websocket_clients"`
+	Probes           map[string]int `json:"probes"`
 }
 
 type ProbeRepository interface {
@@ -185,19 +336,6 @@ type MQTTService interface {
 	IsConnected() bool
 }
 
-type WSMessage struct {
-	Type      string      `json:"type"`
-	ProbeID   string      `json:"probe_id,omitempty"`
-	Timestamp time.Time   `json:"timestamp"`
-	Data      interface{} `json:"data"`
-}
-
-type WSClient struct {
-	ID           string
-	ProbeID      string
-	SendChannel  chan WSMessage
-	CloseChannel chan bool
-}
 type Command struct {
 	ID          int                    `json:"id"`
 	ProbeID     string                 `json:"probe_id"`
@@ -209,6 +347,21 @@ type Command struct {
 	ExecutedAt  *time.Time             `json:"executed_at,omitempty"`
 }
 
+type CommandHistoryRequest struct {
+	ProbeID     string
+	Status      string
+	CommandType string
+	Limit       int
+	Offset      int
+}
+
+type CommandHistoryResponse struct {
+	Data       []Command `json:"data"`
+	TotalCount int       `json:"total_count"`
+	Limit      int       `json:"limit"`
+	Offset     int       `json:"offset"`
+}
+
 type CommandRequest struct {
 	ProbeID     string                 `json:"probe_id"`
 	CommandType string                 `json:"command_type"`
@@ -1,5 +1,12 @@
 package models
 
+// UnassignedDepartment groups probes whose department is blank.
+const UnassignedDepartment = "Unassigned"
+
+// PlaceholderUnknown fills the location fields of probes registered by
+// AutoDiscover until an operator adopts them.
+const PlaceholderUnknown = "unknown"
+
 type LocationOptions struct {
 	Buildings   []string `json:"buildings"`
 	Floors      []string `json:"floors"`
@@ -79,6 +79,7 @@ type AlertHistoryEntry struct {
 	ID           int        `json:"id"`
 	ProbeID      string     `json:"probe_id"`
 	AlertType    string     `json:"alert_type"`
+	Category     string     `json:"category,omitempty"`
 	Severity     string     `json:"severity"`
 	Message      string     `json:"message"`
 	TriggeredAt  time.Time  `json:"triggered_at"`
@@ -0,0 +1,61 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Report delivery targets and outcomes.
+const (
+	ReportTargetNone    = "none"
+	ReportTargetWebhook = "webhook"
+
+	ReportDeliveryStored    = "stored"
+	ReportDeliveryDelivered = "delivered"
+	ReportDeliveryFailed    = "failed"
+)
+
+// ReportSchedule generates an analytics report every Cron period covering
+// the Range leading up to the run, and optionally posts it to a webhook.
+type ReportSchedule struct {
+	ID   string `json:"id" db:"id"`
+	Name string `json:"name" db:"name"`
+	// Cron is "@hourly", "@daily" or "@weekly"; StartAt anchors the time of
+	// day and weekday, like ScheduleSpec.ExecuteAt does for probe tasks.
+	Cron    string     `json:"cron" db:"cron"`
+	StartAt *time.Time `json:"start_at,omitempty" db:"start_at"`
+	// Range is a Go duration such as "24h" or "168h".
+	Range      string     `json:"range" db:"range_spec"`
+	TopN       int        `json:"top_n" db:"top_n"`
+	TargetType string     `json:"target_type" db:"target_type"`
+	TargetURL  string     `json:"target_url,omitempty" db:"target_url"`
+	Enabled    bool       `json:"enabled" db:"enabled"`
+	LastRun    *time.Time `json:"last_run,omitempty" db:"last_run"`
+	NextRun    *time.Time `json:"next_run,omitempty" db:"next_run"`
+	CreatedAt  time.Time  `json:"created_at" db:"created_at"`
+	UpdatedAt  time.Time  `json:"updated_at" db:"updated_at"`
+}
+
+type CreateReportScheduleRequest struct {
+	Name       string     `json:"name"`
+	Cron       string     `json:"cron"`
+	StartAt    *time.Time `json:"start_at,omitempty"`
+	Range      string     `json:"range"`
+	TopN       int        `json:"top_n"`
+	TargetType string     `json:"target_type"`
+	TargetURL  string     `json:"target_url,omitempty"`
+	Enabled    *bool      `json:"enabled,omitempty"`
+}
+
+// GeneratedReport is one stored run of a ReportSchedule. Payload is only
+// loaded when a single report is fetched.
+type GeneratedReport struct {
+	ID             int             `json:"id" db:"id"`
+	ScheduleID     string          `json:"schedule_id" db:"schedule_id"`
+	GeneratedAt    time.Time       `json:"generated_at" db:"generated_at"`
+	RangeStart     time.Time       `json:"range_start" db:"range_start"`
+	RangeEnd       time.Time       `json:"range_end" db:"range_end"`
+	DeliveryStatus string          `json:"delivery_status" db:"delivery_status"`
+	DeliveryError  string          `json:"delivery_error,omitempty" db:"delivery_error"`
+	Payload        json.RawMessage `json:"payload,omitempty" db:"payload"`
+}
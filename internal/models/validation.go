@@ -0,0 +1,40 @@
+package models
+
+import (
+	"fmt"
+	"strings"
+)
+
+// FieldError describes why one request field was rejected. Field uses the
+// JSON name, with dots for nested values (e.g. "payload.ssid").
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// ValidationError collects every field problem in a request so a form can
+// highlight all of them at once. Handlers render it as 422.
+type ValidationError struct {
+	Errors []FieldError `json:"errors"`
+}
+
+func (e *ValidationError) Add(field, message string) {
+	e.Errors = append(e.Errors, FieldError{Field: field, Message: message})
+}
+
+// Err returns e if any field failed and nil otherwise, so callers can
+// build one up unconditionally and return ve.Err().
+func (e *ValidationError) Err() error {
+	if len(e.Errors) == 0 {
+		return nil
+	}
+	return e
+}
+
+func (e *ValidationError) Error() string {
+	parts := make([]string, len(e.Errors))
+	for i, fe := range e.Errors {
+		parts[i] = fmt.Sprintf("%s: %s", fe.Field, fe.Message)
+	}
+	return "validation failed: " + strings.Join(parts, "; ")
+}
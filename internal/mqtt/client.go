@@ -4,6 +4,7 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"sort"
 	"sync"
 	"time"
 
@@ -22,6 +23,20 @@ type Client struct {
 	connected bool
 	ctx       context.Context
 	cancel    context.CancelFunc
+
+	lastConnected  time.Time
+	lastDisconnect time.Time
+	lastMessage    time.Time
+	received       uint64
+	window         messageWindow
+	// subscribed holds the topics the broker has acknowledged since the last
+	// connect, as opposed to handlers, which is what we want subscribed.
+	subscribed map[string]bool
+
+	// inflight tracks running message handlers so Drain can wait for them;
+	// once draining is set no new ones are started.
+	inflight sync.WaitGroup
+	draining bool
 }
 type Message struct {
 	Topic   string
@@ -43,11 +58,12 @@ func NewClient(cfg ClientConfig) (*Client, error) {
 	ctx, cancel := context.WithCancel(context.Background())
 
 	c := &Client{
-		cfg:      cfg.MQTT,
-		log:      cfg.Logger,
-		handlers: make(map[string]MessageHandler),
-		ctx:      ctx,
-		cancel:   cancel,
+		cfg:        cfg.MQTT,
+		log:        cfg.Logger,
+		handlers:   make(map[string]MessageHandler),
+		subscribed: make(map[string]bool),
+		ctx:        ctx,
+		cancel:     cancel,
 	}
 
 	opts := mqtt.NewClientOptions()
@@ -87,6 +103,7 @@ func (c *Client) Connect() error {
 
 	c.mu.Lock()
 	c.connected = true
+	c.lastConnected = time.Now()
 	c.mu.Unlock()
 
 	c.log.Info("Successfully connected to MQTT broker")
@@ -100,6 +117,7 @@ func (c *Client) Disconnect() error {
 
 	c.mu.Lock()
 	c.connected = false
+	c.lastDisconnect = time.Now()
 	c.mu.Unlock()
 
 	c.client.Disconnect(250)
@@ -108,6 +126,45 @@ func (c *Client) Disconnect() error {
 	return nil
 }
 
+// Drain stops delivery of new messages and waits up to timeout for handlers
+// that are already running, so shutdown doesn't cut off a write halfway.
+func (c *Client) Drain(timeout time.Duration) error {
+	c.mu.Lock()
+	topics := make([]string, 0, len(c.subscribed))
+	for topic := range c.subscribed {
+		topics = append(topics, topic)
+	}
+	c.mu.Unlock()
+
+	if len(topics) > 0 && c.client.IsConnected() {
+		token := c.client.Unsubscribe(topics...)
+		if !token.WaitTimeout(5 * time.Second) {
+			c.log.Warn("Timed out unsubscribing before drain")
+		} else if err := token.Error(); err != nil {
+			c.log.Warn("Failed to unsubscribe before drain: %v", err)
+		}
+	}
+
+	c.mu.Lock()
+	c.draining = true
+	c.subscribed = make(map[string]bool)
+	c.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		c.inflight.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		c.log.Info("MQTT message handlers drained")
+		return nil
+	case <-time.After(timeout):
+		return fmt.Errorf("timed out after %v waiting for in-flight message handlers", timeout)
+	}
+}
+
 func (c *Client) IsConnected() bool {
 	c.mu.RLock()
 	defer c.mu.RUnlock()
@@ -137,6 +194,10 @@ func (c *Client) Subscribe(topic string, handler MessageHandler) error {
 		return fmt.Errorf("subscribe failed for topic %s: %w", topic, err)
 	}
 
+	c.mu.Lock()
+	c.subscribed[topic] = true
+	c.mu.Unlock()
+
 	c.log.Info("Successfully subscribed to topic: %s", topic)
 	return nil
 }
@@ -193,6 +254,7 @@ func (c *Client) Unsubscribe(topic string) error {
 
 	c.mu.Lock()
 	delete(c.handlers, topic)
+	delete(c.subscribed, topic)
 	c.mu.Unlock()
 
 	c.log.Info("Successfully unsubscribed from topic: %s", topic)
@@ -234,6 +296,19 @@ func (c *Client) handleMessage(msg mqtt.Message) {
 
 	c.log.Debug("Received message on topic: %s (size: %d bytes)", topic, len(payload))
 
+	c.mu.Lock()
+	if c.draining {
+		c.mu.Unlock()
+		c.log.Debug("Dropping message on %s: shutting down", topic)
+		return
+	}
+	c.inflight.Add(1)
+	c.lastMessage = time.Now()
+	c.received++
+	c.window.record(c.lastMessage)
+	c.mu.Unlock()
+	defer c.inflight.Done()
+
 	c.mu.RLock()
 	handler, exists := c.handlers[topic]
 	c.mu.RUnlock()
@@ -261,6 +336,9 @@ func (c *Client) handleMessage(msg mqtt.Message) {
 func (c *Client) onConnect(client mqtt.Client) {
 	c.mu.Lock()
 	c.connected = true
+	c.lastConnected = time.Now()
+	// Clean sessions start with no subscriptions on the broker side.
+	c.subscribed = make(map[string]bool)
 	c.mu.Unlock()
 
 	c.log.Info("MQTT connection established")
@@ -277,15 +355,88 @@ func (c *Client) onConnect(client mqtt.Client) {
 		token := client.Subscribe(topic, c.cfg.QoS, func(client mqtt.Client, msg mqtt.Message) {
 			c.handleMessage(msg)
 		})
-		if token.Wait() && token.Error() != nil {
-			c.log.Error("Failed to re-subscribe to %s: %v", topic, token.Error())
+		if !token.WaitTimeout(5 * time.Second) {
+			c.log.Error("Timed out re-subscribing to %s", topic)
+			continue
+		}
+		if err := token.Error(); err != nil {
+			c.log.Error("Failed to re-subscribe to %s: %v", topic, err)
+			continue
 		}
+		c.mu.Lock()
+		c.subscribed[topic] = true
+		c.mu.Unlock()
+	}
+
+	c.mu.RLock()
+	missing := c.missingSubscriptions()
+	c.mu.RUnlock()
+	if len(missing) > 0 {
+		c.log.Error("Re-subscribed to %d of %d topics after reconnect; missing: %v",
+			len(topics)-len(missing), len(topics), missing)
+	}
+	c.publishStatus(client, len(topics), missing)
+}
+
+// missingSubscriptions returns the handler topics the broker hasn't
+// acknowledged, sorted. Callers must hold c.mu.
+func (c *Client) missingSubscriptions() []string {
+	var missing []string
+	for topic := range c.handlers {
+		if !c.subscribed[topic] {
+			missing = append(missing, topic)
+		}
+	}
+	sort.Strings(missing)
+	return missing
+}
+
+// backendStatus is published to MQTT_STATUS_TOPIC after each connect.
+type backendStatus struct {
+	Status     string    `json:"status"`
+	Expected   int       `json:"expected_subscriptions"`
+	Subscribed int       `json:"subscriptions"`
+	Missing    []string  `json:"missing_subscriptions,omitempty"`
+	Timestamp  time.Time `json:"timestamp"`
+}
+
+// publishStatus tells anything watching MQTT_STATUS_TOPIC whether the
+// backend came back with all its subscriptions. It is retained so a
+// dashboard that connects later still sees a degraded backend.
+func (c *Client) publishStatus(client mqtt.Client, expected int, missing []string) {
+	if c.cfg.StatusTopic == "" {
+		return
+	}
+
+	status := backendStatus{
+		Status:     "online",
+		Expected:   expected,
+		Subscribed: expected - len(missing),
+		Missing:    missing,
+		Timestamp:  time.Now(),
+	}
+	if len(missing) > 0 {
+		status.Status = "degraded"
+	}
+
+	payload, err := json.Marshal(status)
+	if err != nil {
+		c.log.Error("Failed to marshal backend status: %v", err)
+		return
+	}
+	token := client.Publish(c.cfg.StatusTopic, c.cfg.QoS, true, payload)
+	if !token.WaitTimeout(5 * time.Second) {
+		c.log.Warn("Timed out publishing backend status to %s", c.cfg.StatusTopic)
+	} else if err := token.Error(); err != nil {
+		c.log.Warn("Failed to publish backend status to %s: %v", c.cfg.StatusTopic, err)
 	}
 }
 
 func (c *Client) onConnectionLost(client mqtt.Client, err error) {
 	c.mu.Lock()
 	c.connected = false
+	c.lastDisconnect = time.Now()
+	c.subscribed = make(map[string]bool)
 	c.mu.Unlock()
 
 	c.log.Error("MQTT connection lost: %v", err)
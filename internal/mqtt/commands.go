@@ -196,7 +196,7 @@ func (c *Client) publishCommand(probeID string, cmd Command) error {
 	topic := fmt.Sprintf("campus/probes/%s/command", probeID)
 
 	c.log.Info("Publishing to topic: %s", topic)
-	c.log.Info("Payload: %s", string(payload))
+	c.log.Info("Payload: %s", c.log.Redact(payload))
 
 	token := c.client.Publish(topic, 1, false, payload)
 	token.Wait()
@@ -10,7 +10,34 @@ type HealthStatus struct {
 	Connected      bool      `json:"connected"`
 	LastConnected  time.Time `json:"last_connected,omitempty"`
 	LastDisconnect time.Time `json:"last_disconnect,omitempty"`
-	Subscriptions  int       `json:"subscriptions"`
+	LastMessage    time.Time `json:"last_message,omitempty"`
+	// Subscriptions counts topics the broker has acknowledged;
+	// ExpectedSubscriptions counts topics we have handlers for.
+	Subscriptions         int `json:"subscriptions"`
+	ExpectedSubscriptions int `json:"expected_subscriptions"`
+	// MissingSubscriptions lists handler topics the broker has not
+	// acknowledged, e.g. after a resubscribe failed on reconnect.
+	MissingSubscriptions []string `json:"missing_subscriptions,omitempty"`
+	// Stale is set when nothing has arrived for longer than
+	// MQTT_READINESS_MAX_IDLE since the last message or reconnect.
+	Stale bool `json:"stale"`
+	// MessagesReceived counts every message since startup.
+	MessagesReceived   uint64 `json:"messages_received"`
+	MessagesLastMinute int    `json:"messages_last_minute"`
+	// SecondsSinceMessage is nil until the first message arrives.
+	SecondsSinceMessage *float64 `json:"seconds_since_message,omitempty"`
+	// Silent is Stale while connected: the broker link is up but nothing
+	// is arriving.
+	Silent bool `json:"silent"`
+}
+
+// Ready reports whether the client is connected, every handler's topic is
+// actually subscribed and messages are still flowing.
+func (s *HealthStatus) Ready() bool {
+	return s.Connected &&
+		s.ExpectedSubscriptions > 0 &&
+		s.Subscriptions >= s.ExpectedSubscriptions &&
+		!s.Stale
 }
 
 func (c *Client) Health(ctx context.Context) (*HealthStatus, error) {
@@ -18,13 +45,40 @@ func (c *Client) Health(ctx context.Context) (*HealthStatus, error) {
 	defer c.mu.RUnlock()
 
 	status := &HealthStatus{
-		Connected:     c.connected && c.client.IsConnected(),
-		Subscriptions: len(c.handlers),
+		Connected:             c.connected && c.client.IsConnected(),
+		LastConnected:         c.lastConnected,
+		LastDisconnect:        c.lastDisconnect,
+		LastMessage:           c.lastMessage,
+		Subscriptions:         len(c.subscribed),
+		ExpectedSubscriptions: len(c.handlers),
+		MessagesReceived:      c.received,
+		MessagesLastMinute:    c.window.lastMinute(time.Now()),
+		MissingSubscriptions:  c.missingSubscriptions(),
+	}
+
+	if !c.lastMessage.IsZero() {
+		since := time.Since(c.lastMessage).Seconds()
+		status.SecondsSinceMessage = &since
+	}
+
+	if c.cfg.ReadinessMaxIdle > 0 {
+		status.Stale = c.idleFor() > c.cfg.ReadinessMaxIdle
+		status.Silent = status.Stale && status.Connected
 	}
 
 	return status, nil
 }
 
+// idleFor is the time since the last message or, if more recent, the last
+// reconnect. Callers must hold c.mu.
+func (c *Client) idleFor() time.Duration {
+	since := c.lastMessage
+	if c.lastConnected.After(since) {
+		since = c.lastConnected
+	}
+	return time.Since(since)
+}
+
 func (c *Client) WaitForConnection(timeout time.Duration) error {
 	deadline := time.Now().Add(timeout)
 
@@ -0,0 +1,35 @@
+package mqtt
+
+import "time"
+
+// messageWindow counts messages per second over the last minute in a ring of
+// one-second buckets. Callers must hold the client's mutex.
+type messageWindow struct {
+	buckets [60]struct {
+		second int64
+		count  int
+	}
+}
+
+func (w *messageWindow) record(now time.Time) {
+	sec := now.Unix()
+	b := &w.buckets[sec%int64(len(w.buckets))]
+	if b.second != sec {
+		b.second = sec
+		b.count = 0
+	}
+	b.count++
+}
+
+// lastMinute returns how many messages were recorded in the 60 seconds
+// before now.
+func (w *messageWindow) lastMinute(now time.Time) int {
+	sec := now.Unix()
+	total := 0
+	for _, b := range w.buckets {
+		if sec-b.second < int64(len(w.buckets)) {
+			total += b.count
+		}
+	}
+	return total
+}
@@ -4,10 +4,13 @@ import (
 	"context"
 	"database/sql"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"time"
 
 	"CampusMonitorAPI/internal/models"
+
+	"github.com/lib/pq"
 )
 
 // IAlertRepository defines the operations for managing network alerts.
@@ -16,14 +19,26 @@ type IAlertRepository interface {
 	GetByID(ctx context.Context, id uint) (*models.Alert, error)
 	GetActive(ctx context.Context) ([]models.Alert, error) // Added this method!
 	GetActiveByProbe(ctx context.Context, probeID string) ([]models.Alert, error)
+	GetProbeHistory(ctx context.Context, probeID string, since time.Time) ([]models.Alert, error)
 	GetHistory(ctx context.Context, limit int, offset int) ([]models.Alert, error)
 	Acknowledge(ctx context.Context, id uint) error
-	Resolve(ctx context.Context, id uint) error
+	AcknowledgeBulk(ctx context.Context, filter models.BulkAcknowledgeRequest, user string) (int64, error)
+	Resolve(ctx context.Context, id uint, acknowledgedBy string) error
+	Reopen(ctx context.Context, id uint) error
 	Delete(ctx context.Context, id uint) error
 	DeleteOld(ctx context.Context, olderThan time.Duration) (int64, error)
 	GetStatistics(ctx context.Context) (map[string]int, error)
+	GetSummary(ctx context.Context, since time.Time) (*models.AlertStatistics, error)
+	AddComment(ctx context.Context, comment *models.AlertComment) error
+	GetComments(ctx context.Context, alertID uint) ([]models.AlertComment, error)
 }
 
+var (
+	ErrAlertNotFound        = errors.New("alert not found")
+	ErrAlertAlreadyResolved = errors.New("alert is already resolved")
+	ErrAlertNotResolved     = errors.New("alert is not resolved")
+)
+
 type AlertRepository struct {
 	db *sql.DB
 }
@@ -32,6 +47,43 @@ func NewAlertRepository(db *sql.DB) *AlertRepository {
 	return &AlertRepository{db: db}
 }
 
+// alertColumns is the column list every alert query selects, in the order
+// scanAlert reads it.
+const alertColumns = `id, probe_id, alert_type, category, severity, message,
+		       threshold_value, actual_value, triggered_at,
+		       resolved_at, acknowledged, occurrences, metadata`
+
+// rowScanner is satisfied by *sql.Row and *sql.Rows.
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// scanAlert reads one row selected with alertColumns. Status is derived
+// from resolved_at and acknowledged rather than stored.
+func scanAlert(row rowScanner) (models.Alert, error) {
+	var a models.Alert
+	var category sql.NullString
+	var occurrences sql.NullInt64
+	var metadataJSON []byte
+
+	err := row.Scan(
+		&a.ID, &a.ProbeID, &a.AlertType, &category, &a.Severity, &a.Message,
+		&a.ThresholdValue, &a.ActualValue, &a.TriggeredAt,
+		&a.ResolvedAt, &a.Acknowledged, &occurrences, &metadataJSON,
+	)
+	if err != nil {
+		return a, err
+	}
+
+	a.Category = category.String
+	a.Occurrences = int(occurrences.Int64)
+	if len(metadataJSON) > 0 {
+		_ = json.Unmarshal(metadataJSON, &a.Metadata)
+	}
+	a.Status = models.AlertStatusOf(a.ResolvedAt, a.Acknowledged)
+	return a, nil
+}
+
 // Create inserts a new alert record into the database.
 func (r *AlertRepository) Create(ctx context.Context, alert *models.Alert) error {
 	var metadataJSON []byte
@@ -45,10 +97,10 @@ func (r *AlertRepository) Create(ctx context.Context, alert *models.Alert) error
 
 	query := `
 		INSERT INTO alerts (
-			probe_id, alert_type, severity, message, 
+			probe_id, alert_type, category, severity, message, 
 			threshold_value, actual_value, triggered_at, 
-			resolved_at, acknowledged, metadata
-		) VALUES ($1, $2, $3, $4, $5, $6, COALESCE($7, now()), $8, $9, $10)
+			resolved_at, acknowledged, occurrences, metadata
+		) VALUES ($1, $2, NULLIF($3, ''), $4, $5, $6, $7, COALESCE($8, now()), $9, $10, NULLIF($11, 0), $12)
 		RETURNING id, triggered_at
 	`
 
@@ -62,6 +114,7 @@ func (r *AlertRepository) Create(ctx context.Context, alert *models.Alert) error
 	err = r.db.QueryRowContext(ctx, query,
 		alert.ProbeID,
 		alert.AlertType,
+		alert.Category,
 		alert.Severity,
 		alert.Message,
 		alert.ThresholdValue,
@@ -69,46 +122,35 @@ func (r *AlertRepository) Create(ctx context.Context, alert *models.Alert) error
 		triggeredAt,
 		alert.ResolvedAt,
 		alert.Acknowledged,
+		alert.Occurrences,
 		metadataJSON,
 	).Scan(&alert.ID, &alert.TriggeredAt)
+	if err != nil {
+		return err
+	}
 
-	return err
+	alert.Status = models.AlertStatusOf(alert.ResolvedAt, alert.Acknowledged)
+	return nil
 }
 
 func (r *AlertRepository) GetByID(ctx context.Context, id uint) (*models.Alert, error) {
 	query := `
-		SELECT id, probe_id, alert_type, severity, message, 
-		       threshold_value, actual_value, triggered_at, 
-		       resolved_at, acknowledged, metadata
+		SELECT ` + alertColumns + `
 		FROM alerts
 		WHERE id = $1
 	`
 
-	var a models.Alert
-	var metadataJSON []byte
-
-	err := r.db.QueryRowContext(ctx, query, id).Scan(
-		&a.ID, &a.ProbeID, &a.AlertType, &a.Severity, &a.Message,
-		&a.ThresholdValue, &a.ActualValue, &a.TriggeredAt,
-		&a.ResolvedAt, &a.Acknowledged, &metadataJSON,
-	)
+	a, err := scanAlert(r.db.QueryRowContext(ctx, query, id))
 	if err != nil {
 		return nil, err
 	}
-
-	if len(metadataJSON) > 0 {
-		_ = json.Unmarshal(metadataJSON, &a.Metadata)
-	}
-
 	return &a, nil
 }
 
 // GetActive fetches ALL unresolved alerts across the entire system
 func (r *AlertRepository) GetActive(ctx context.Context) ([]models.Alert, error) {
 	query := `
-		SELECT id, probe_id, alert_type, severity, message, 
-		       threshold_value, actual_value, triggered_at, 
-		       resolved_at, acknowledged, metadata
+		SELECT ` + alertColumns + `
 		FROM alerts
 		WHERE resolved_at IS NULL
 		ORDER BY triggered_at DESC
@@ -122,21 +164,10 @@ func (r *AlertRepository) GetActive(ctx context.Context) ([]models.Alert, error)
 
 	var alerts []models.Alert
 	for rows.Next() {
-		var a models.Alert
-		var metadataJSON []byte
-
-		err := rows.Scan(
-			&a.ID, &a.ProbeID, &a.AlertType, &a.Severity, &a.Message,
-			&a.ThresholdValue, &a.ActualValue, &a.TriggeredAt,
-			&a.ResolvedAt, &a.Acknowledged, &metadataJSON,
-		)
+		a, err := scanAlert(rows)
 		if err != nil {
 			return nil, err
 		}
-
-		if len(metadataJSON) > 0 {
-			_ = json.Unmarshal(metadataJSON, &a.Metadata)
-		}
 		alerts = append(alerts, a)
 	}
 	return alerts, rows.Err()
@@ -145,9 +176,7 @@ func (r *AlertRepository) GetActive(ctx context.Context) ([]models.Alert, error)
 // GetActiveByProbe fetches unresolved alerts for a SPECIFIC probe
 func (r *AlertRepository) GetActiveByProbe(ctx context.Context, probeID string) ([]models.Alert, error) {
 	query := `
-		SELECT id, probe_id, alert_type, severity, message, 
-		       threshold_value, actual_value, triggered_at, 
-		       resolved_at, acknowledged, metadata
+		SELECT ` + alertColumns + `
 		FROM alerts
 		WHERE probe_id = $1 AND resolved_at IS NULL
 		ORDER BY triggered_at DESC
@@ -161,20 +190,36 @@ func (r *AlertRepository) GetActiveByProbe(ctx context.Context, probeID string)
 
 	var alerts []models.Alert
 	for rows.Next() {
-		var a models.Alert
-		var metadataJSON []byte
-
-		err := rows.Scan(
-			&a.ID, &a.ProbeID, &a.AlertType, &a.Severity, &a.Message,
-			&a.ThresholdValue, &a.ActualValue, &a.TriggeredAt,
-			&a.ResolvedAt, &a.Acknowledged, &metadataJSON,
-		)
+		a, err := scanAlert(rows)
 		if err != nil {
 			return nil, err
 		}
+		alerts = append(alerts, a)
+	}
+	return alerts, rows.Err()
+}
+
+// GetProbeHistory fetches every alert (active and resolved) a probe raised
+// since the given time, oldest first.
+func (r *AlertRepository) GetProbeHistory(ctx context.Context, probeID string, since time.Time) ([]models.Alert, error) {
+	query := `
+		SELECT ` + alertColumns + `
+		FROM alerts
+		WHERE probe_id = $1 AND triggered_at >= $2
+		ORDER BY triggered_at
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, probeID, since)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query probe alert history: %w", err)
+	}
+	defer rows.Close()
 
-		if len(metadataJSON) > 0 {
-			_ = json.Unmarshal(metadataJSON, &a.Metadata)
+	alerts := []models.Alert{}
+	for rows.Next() {
+		a, err := scanAlert(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan alert: %w", err)
 		}
 		alerts = append(alerts, a)
 	}
@@ -184,9 +229,7 @@ func (r *AlertRepository) GetActiveByProbe(ctx context.Context, probeID string)
 // GetHistory fetches all alerts (both active and resolved)
 func (r *AlertRepository) GetHistory(ctx context.Context, limit int, offset int) ([]models.Alert, error) {
 	query := `
-		SELECT id, probe_id, alert_type, severity, message, 
-		       threshold_value, actual_value, triggered_at, 
-		       resolved_at, acknowledged, metadata
+		SELECT ` + alertColumns + `
 		FROM alerts
 		ORDER BY triggered_at DESC
 		LIMIT $1 OFFSET $2
@@ -200,37 +243,109 @@ func (r *AlertRepository) GetHistory(ctx context.Context, limit int, offset int)
 
 	var alerts []models.Alert
 	for rows.Next() {
-		var a models.Alert
-		var metadataJSON []byte
-
-		err := rows.Scan(
-			&a.ID, &a.ProbeID, &a.AlertType, &a.Severity, &a.Message,
-			&a.ThresholdValue, &a.ActualValue, &a.TriggeredAt,
-			&a.ResolvedAt, &a.Acknowledged, &metadataJSON,
-		)
+		a, err := scanAlert(rows)
 		if err != nil {
 			return nil, err
 		}
-
-		if len(metadataJSON) > 0 {
-			_ = json.Unmarshal(metadataJSON, &a.Metadata)
-		}
 		alerts = append(alerts, a)
 	}
 	return alerts, rows.Err()
 }
 
 func (r *AlertRepository) Acknowledge(ctx context.Context, id uint) error {
-	query := `UPDATE alerts SET acknowledged = true WHERE id = $1`
+	query := `UPDATE alerts SET acknowledged = true, acknowledged_at = COALESCE(acknowledged_at, NOW()) WHERE id = $1`
 	_, err := r.db.ExecContext(ctx, query, id)
 	return err
 }
 
-func (r *AlertRepository) Resolve(ctx context.Context, id uint) error {
+// AcknowledgeBulk acknowledges every active, unacknowledged alert matching
+// the filter in one UPDATE, recording the user and note in the metadata.
+// It returns how many alerts were acknowledged.
+func (r *AlertRepository) AcknowledgeBulk(ctx context.Context, filter models.BulkAcknowledgeRequest, user string) (int64, error) {
+	ids := make([]int64, len(filter.IDs))
+	for i, id := range filter.IDs {
+		ids[i] = int64(id)
+	}
+
+	query := `
+		UPDATE alerts
+		SET acknowledged = true,
+		    acknowledged_at = NOW(),
+		    metadata = COALESCE(metadata, '{}'::jsonb) || jsonb_strip_nulls(jsonb_build_object(
+		        'acknowledged_by', $4::text,
+		        'acknowledge_note', NULLIF($5::text, '')
+		    ))
+		WHERE resolved_at IS NULL
+		  AND NOT COALESCE(acknowledged, false)
+		  AND (cardinality($1::bigint[]) = 0 OR id = ANY($1::bigint[]))
+		  AND ($2::text = '' OR probe_id = $2)
+		  AND ($3::text = '' OR severity = $3)
+	`
+	result, err := r.db.ExecContext(ctx, query, pq.Array(ids), filter.ProbeID, filter.Severity, user, filter.Note)
+	if err != nil {
+		return 0, fmt.Errorf("failed to acknowledge alerts: %w", err)
+	}
+	return result.RowsAffected()
+}
+
+// Resolve sets resolved_at on an active alert. The update only applies while
+// resolved_at is still NULL, so a manual resolve racing the evaluator's
+// auto-resolve (or a reopen) settles on one outcome and the loser gets
+// ErrAlertAlreadyResolved. With a non-empty acknowledgedBy, an alert that was
+// never acknowledged is acknowledged by that user in the same update, so
+// history doesn't show resolved incidents as unacknowledged.
+func (r *AlertRepository) Resolve(ctx context.Context, id uint, acknowledgedBy string) error {
 	// Instead of 'status = RESOLVED', we set the 'resolved_at' timestamp
-	query := `UPDATE alerts SET resolved_at = $1 WHERE id = $2`
-	_, err := r.db.ExecContext(ctx, query, time.Now(), id)
-	return err
+	query := `
+		UPDATE alerts
+		SET resolved_at = $1,
+		    acknowledged = COALESCE(acknowledged, false) OR $3::text <> '',
+		    acknowledged_at = CASE
+		        WHEN $3::text <> '' AND NOT COALESCE(acknowledged, false) THEN $1
+		        ELSE acknowledged_at END,
+		    metadata = CASE
+		        WHEN $3::text <> '' AND NOT COALESCE(acknowledged, false)
+		        THEN COALESCE(metadata, '{}'::jsonb) || jsonb_build_object('acknowledged_by', $3::text)
+		        ELSE metadata END
+		WHERE id = $2 AND resolved_at IS NULL
+	`
+	result, err := r.db.ExecContext(ctx, query, time.Now(), id, acknowledgedBy)
+	if err != nil {
+		return fmt.Errorf("failed to resolve alert: %w", err)
+	}
+	return r.checkTransition(ctx, result, id, ErrAlertAlreadyResolved)
+}
+
+// Reopen clears resolved_at on a resolved alert, returning
+// ErrAlertNotResolved if it is already active.
+func (r *AlertRepository) Reopen(ctx context.Context, id uint) error {
+	query := `UPDATE alerts SET resolved_at = NULL WHERE id = $1 AND resolved_at IS NOT NULL`
+	result, err := r.db.ExecContext(ctx, query, id)
+	if err != nil {
+		return fmt.Errorf("failed to reopen alert: %w", err)
+	}
+	return r.checkTransition(ctx, result, id, ErrAlertNotResolved)
+}
+
+// checkTransition turns a conditional update that matched no rows into
+// ErrAlertNotFound or, if the alert exists, the state conflict error.
+func (r *AlertRepository) checkTransition(ctx context.Context, result sql.Result, id uint, conflict error) error {
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get affected rows: %w", err)
+	}
+	if rows > 0 {
+		return nil
+	}
+
+	var exists bool
+	if err := r.db.QueryRowContext(ctx, `SELECT EXISTS(SELECT 1 FROM alerts WHERE id = $1)`, id).Scan(&exists); err != nil {
+		return fmt.Errorf("failed to check alert: %w", err)
+	}
+	if !exists {
+		return ErrAlertNotFound
+	}
+	return conflict
 }
 
 func (r *AlertRepository) Delete(ctx context.Context, id uint) error {
@@ -273,3 +388,109 @@ func (r *AlertRepository) GetStatistics(ctx context.Context) (map[string]int, er
 	}
 	return stats, nil
 }
+
+// GetSummary breaks unresolved alerts down by severity and category (falling
+// back to alert_type for alerts raised without one) and counts the alerts
+// resolved or acknowledged since the given time.
+func (r *AlertRepository) GetSummary(ctx context.Context, since time.Time) (*models.AlertStatistics, error) {
+	stats := &models.AlertStatistics{
+		BySeverity: make(map[string]int),
+		ByCategory: make(map[string]int),
+	}
+
+	query := `
+		SELECT COALESCE(severity, ''), COALESCE(category, alert_type, ''), COUNT(*),
+		       COUNT(*) FILTER (WHERE NOT COALESCE(acknowledged, false))
+		FROM alerts
+		WHERE resolved_at IS NULL
+		GROUP BY 1, 2
+	`
+	rows, err := r.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get active alert breakdown: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var severity, category string
+		var count, unacknowledged int
+		if err := rows.Scan(&severity, &category, &count, &unacknowledged); err != nil {
+			return nil, fmt.Errorf("failed to scan alert breakdown: %w", err)
+		}
+		stats.BySeverity[severity] += count
+		stats.ByCategory[category] += count
+		stats.TotalActive += count
+		stats.TotalUnacknowledged += unacknowledged
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	query = `
+		SELECT COUNT(*),
+		       COUNT(*) FILTER (WHERE resolved_at >= $1),
+		       COUNT(*) FILTER (WHERE acknowledged_at >= $1)
+		FROM alerts
+	`
+	err = r.db.QueryRowContext(ctx, query, since).Scan(
+		&stats.Total, &stats.ResolvedLast24h, &stats.AcknowledgedLast24h,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get alert totals: %w", err)
+	}
+
+	return stats, nil
+}
+
+// AddComment appends a comment to an alert, filling in its ID and
+// CreatedAt. It returns ErrAlertNotFound if the alert doesn't exist.
+func (r *AlertRepository) AddComment(ctx context.Context, comment *models.AlertComment) error {
+	query := `
+		INSERT INTO alert_comments (alert_id, author, body)
+		SELECT id, $2, $3 FROM alerts WHERE id = $1
+		RETURNING id, created_at
+	`
+	err := r.db.QueryRowContext(ctx, query, comment.AlertID, comment.Author, comment.Body).
+		Scan(&comment.ID, &comment.CreatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrAlertNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("failed to add alert comment: %w", err)
+	}
+	return nil
+}
+
+// GetComments returns an alert's comments oldest first, or ErrAlertNotFound
+// if the alert doesn't exist.
+func (r *AlertRepository) GetComments(ctx context.Context, alertID uint) ([]models.AlertComment, error) {
+	var exists bool
+	if err := r.db.QueryRowContext(ctx, `SELECT EXISTS(SELECT 1 FROM alerts WHERE id = $1)`, alertID).Scan(&exists); err != nil {
+		return nil, fmt.Errorf("failed to check alert: %w", err)
+	}
+	if !exists {
+		return nil, ErrAlertNotFound
+	}
+
+	query := `
+		SELECT id, alert_id, author, body, created_at
+		FROM alert_comments
+		WHERE alert_id = $1
+		ORDER BY created_at, id
+	`
+	rows, err := r.db.QueryContext(ctx, query, alertID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query alert comments: %w", err)
+	}
+	defer rows.Close()
+
+	comments := []models.AlertComment{}
+	for rows.Next() {
+		var c models.AlertComment
+		if err := rows.Scan(&c.ID, &c.AlertID, &c.Author, &c.Body, &c.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan alert comment: %w", err)
+		}
+		comments = append(comments, c)
+	}
+	return comments, rows.Err()
+}
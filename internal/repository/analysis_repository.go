@@ -4,18 +4,44 @@ import (
 	"CampusMonitorAPI/internal/models"
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
+	"sort"
+	"strconv"
 	"time"
 
 	"github.com/lib/pq"
 )
 
+// ErrUnknownMetric is returned for a metric name outside metricColumns.
+var ErrUnknownMetric = errors.New("unknown metric")
+
+// metricColumns maps the metric names accepted by GetMetricCorrelation and
+// GetMetricStats to telemetry columns. Only these are ever interpolated into SQL.
+var metricColumns = map[string]string{
+	"rssi":         "rssi",
+	"latency":      "latency",
+	"packet_loss":  "packet_loss",
+	"dns_time":     "dns_time",
+	"snr":          "snr",
+	"link_quality": "link_quality",
+	"utilization":  "utilization",
+	"throughput":   "throughput",
+	"noise_floor":  "noise_floor",
+}
+
 type AnalyticsRepository struct {
 	db *sql.DB
+	// activeWindow is the default lookback GetNetworkHealth uses to decide
+	// which probes are active.
+	activeWindow time.Duration
+	// defaultReportInterval stands in for probes without a known
+	// report_interval when computing expected samples.
+	defaultReportInterval time.Duration
 }
 
-func NewAnalyticsRepository(db *sql.DB) *AnalyticsRepository {
-	return &AnalyticsRepository{db: db}
+func NewAnalyticsRepository(db *sql.DB, activeWindow, defaultReportInterval time.Duration) *AnalyticsRepository {
+	return &AnalyticsRepository{db: db, activeWindow: activeWindow, defaultReportInterval: defaultReportInterval}
 }
 
 type TimeSeriesPoint struct {
@@ -48,6 +74,34 @@ type APAnalysis struct {
 	TotalSamples    int       `json:"total_samples"`
 }
 
+// NeighborAPSummary aggregates deep scan sightings of one BSSID.
+// Associated reports whether any probe connected to it in the same window.
+type NeighborAPSummary struct {
+	BSSID        string    `json:"bssid"`
+	SSID         string    `json:"ssid"`
+	Channel      int       `json:"channel"`
+	AvgRSSI      float64   `json:"avg_rssi"`
+	MaxRSSI      int       `json:"max_rssi"`
+	SeenByProbes []string  `json:"seen_by_probes"`
+	Sightings    int       `json:"sightings"`
+	FirstSeen    time.Time `json:"first_seen"`
+	LastSeen     time.Time `json:"last_seen"`
+	Associated   bool      `json:"associated"`
+}
+
+// MetricCorrelation is the Pearson coefficient between two telemetry
+// metrics. Coefficient is nil when there are fewer than two paired samples
+// or one metric is constant.
+type MetricCorrelation struct {
+	ProbeID     string    `json:"probe_id"`
+	MetricA     string    `json:"metric_a"`
+	MetricB     string    `json:"metric_b"`
+	Coefficient *float64  `json:"coefficient"`
+	SampleCount int       `json:"sample_count"`
+	Start       time.Time `json:"start"`
+	End         time.Time `json:"end"`
+}
+
 type CongestionAnalysis struct {
 	Hour            time.Time `json:"hour"`
 	AvgNeighbors    float64   `json:"avg_neighbors"`
@@ -72,6 +126,18 @@ type PerformanceMetrics struct {
 	AvgDNSTime     float64 `json:"avg_dns_time"`
 	StabilityScore float64 `json:"stability_score"`
 	SampleCount    int     `json:"sample_count"`
+
+	// LatencyPercentiles holds the requested latency percentiles keyed by
+	// PercentileKey, e.g. "p90" or "p99.9".
+	LatencyPercentiles map[string]float64 `json:"latency_percentiles"`
+}
+
+// FloorPerformance is PerformanceMetrics aggregated over a floor's probes.
+type FloorPerformance struct {
+	Building string   `json:"building"`
+	Floor    string   `json:"floor"`
+	ProbeIDs []string `json:"probe_ids"`
+	*PerformanceMetrics
 }
 
 type ProbeComparison struct {
@@ -95,6 +161,7 @@ type NetworkHealth struct {
 	AvgLatency    float64   `json:"avg_latency"`
 	AvgPacketLoss float64   `json:"avg_packet_loss"`
 	HealthScore   float64   `json:"health_score"`
+	ActiveWindow  string    `json:"active_window"`
 }
 
 func (r *AnalyticsRepository) GetRSSITimeSeries(ctx context.Context, probeID string, start, end time.Time, interval string) ([]TimeSeriesPoint, error) {
@@ -170,7 +237,37 @@ func (r *AnalyticsRepository) GetLatencyTimeSeries(ctx context.Context, probeID
 	return points, nil
 }
 
-func (r *AnalyticsRepository) GetHeatmapData(ctx context.Context, start, end time.Time) ([]HeatmapData, error) {
+// probeLocationJoin joins telemetry t to p exposing p.building, p.floor and
+// p.location. With historical set these come from the probe_location_history
+// row in effect at t.timestamp, so readings stay with the place they were
+// taken after a probe moves; probes without history use their current
+// location either way.
+func probeLocationJoin(historical bool) string {
+	if !historical {
+		return `JOIN probes p ON t.probe_id = p.probe_id`
+	}
+	return `JOIN LATERAL (
+			SELECT
+				cur.probe_id,
+				CASE WHEN h.found THEN h.building ELSE cur.building END as building,
+				CASE WHEN h.found THEN h.floor ELSE cur.floor END as floor,
+				CASE WHEN h.found THEN h.location ELSE cur.location END as location
+			FROM probes cur
+			LEFT JOIN LATERAL (
+				SELECT true as found, building, floor, location
+				FROM probe_location_history
+				WHERE probe_id = t.probe_id
+				  AND effective_from <= t.timestamp
+				ORDER BY effective_from DESC
+				LIMIT 1
+			) h ON true
+			WHERE cur.probe_id = t.probe_id
+		) p ON true`
+}
+
+// GetHeatmapData averages RSSI per building, floor and location. See
+// probeLocationJoin for historical.
+func (r *AnalyticsRepository) GetHeatmapData(ctx context.Context, start, end time.Time, historical bool) ([]HeatmapData, error) {
 	query := `
 		SELECT 
 			p.building,
@@ -179,7 +276,7 @@ func (r *AnalyticsRepository) GetHeatmapData(ctx context.Context, start, end tim
 			AVG(t.rssi) as avg_rssi,
 			COUNT(*) as count
 		FROM telemetry t
-		JOIN probes p ON t.probe_id = p.probe_id
+		` + probeLocationJoin(historical) + `
 		WHERE t.timestamp >= $1
 		  AND t.timestamp <= $2
 		  AND t.rssi IS NOT NULL
@@ -203,6 +300,70 @@ func (r *AnalyticsRepository) GetHeatmapData(ctx context.Context, start, end tim
 	return heatmap, nil
 }
 
+// CoverageGap is the share of time buckets in which a floor's average RSSI
+// fell below the requested threshold.
+type CoverageGap struct {
+	Building      string  `json:"building"`
+	Floor         string  `json:"floor"`
+	TotalBuckets  int     `json:"total_buckets"`
+	WeakBuckets   int     `json:"weak_buckets"`
+	WeakFraction  float64 `json:"weak_fraction"`
+	AvgRSSI       float64 `json:"avg_rssi"`
+	WorstRSSI     float64 `json:"worst_rssi"`
+	RSSIThreshold float64 `json:"rssi_threshold"`
+}
+
+// GetCoverageGaps buckets telemetry per building/floor and ranks floors by
+// the fraction of buckets whose average RSSI was below rssiThreshold, worst
+// first. See probeLocationJoin for historical.
+func (r *AnalyticsRepository) GetCoverageGaps(ctx context.Context, start, end time.Time, rssiThreshold float64, interval string, historical bool) ([]CoverageGap, error) {
+	query := `
+		WITH buckets AS (
+			SELECT
+				p.building,
+				p.floor,
+				time_bucket($4::interval, t.timestamp) as bucket,
+				AVG(t.rssi) as avg_rssi
+			FROM telemetry t
+			` + probeLocationJoin(historical) + `
+			WHERE t.timestamp >= $1
+			  AND t.timestamp <= $2
+			  AND t.rssi IS NOT NULL
+			GROUP BY p.building, p.floor, bucket
+		)
+		SELECT
+			building,
+			floor,
+			COUNT(*) as total_buckets,
+			COUNT(*) FILTER (WHERE avg_rssi < $3) as weak_buckets,
+			AVG(avg_rssi) as avg_rssi,
+			MIN(avg_rssi) as worst_rssi
+		FROM buckets
+		GROUP BY building, floor
+		ORDER BY COUNT(*) FILTER (WHERE avg_rssi < $3)::float / COUNT(*) DESC, AVG(avg_rssi) ASC
+	`
+	rows, err := r.db.QueryContext(ctx, query, start, end, rssiThreshold, interval)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get coverage gaps: %w", err)
+	}
+	defer rows.Close()
+
+	gaps := []CoverageGap{}
+	for rows.Next() {
+		var g CoverageGap
+		if err := rows.Scan(&g.Building, &g.Floor, &g.TotalBuckets, &g.WeakBuckets, &g.AvgRSSI, &g.WorstRSSI); err != nil {
+			return nil, fmt.Errorf("failed to scan coverage gap: %w", err)
+		}
+		if g.TotalBuckets > 0 {
+			g.WeakFraction = float64(g.WeakBuckets) / float64(g.TotalBuckets)
+		}
+		g.RSSIThreshold = rssiThreshold
+		gaps = append(gaps, g)
+	}
+
+	return gaps, nil
+}
+
 type DailyCoverage struct {
 	Day     time.Time `json:"day"`
 	HasData bool      `json:"has_data"`
@@ -312,6 +473,102 @@ func (r *AnalyticsRepository) GetAPAnalysis(ctx context.Context, start, end time
 	return res, nil
 }
 
+// GetMetricCorrelation computes CORR() between metricA and metricB over the
+// samples where both are present. An empty or "all" probeID covers the fleet.
+func (r *AnalyticsRepository) GetMetricCorrelation(ctx context.Context, probeID, metricA, metricB string, start, end time.Time) (*MetricCorrelation, error) {
+	colA, ok := metricColumns[metricA]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownMetric, metricA)
+	}
+	colB, ok := metricColumns[metricB]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownMetric, metricB)
+	}
+
+	whereClause := "timestamp >= $1 AND timestamp <= $2"
+	args := []interface{}{start, end}
+	if probeID != "" && probeID != "all" {
+		whereClause += " AND probe_id = $3"
+		args = append(args, probeID)
+	}
+
+	query := fmt.Sprintf(`
+		SELECT CORR(%[1]s, %[2]s), REGR_COUNT(%[1]s, %[2]s)
+		FROM telemetry
+		WHERE %[3]s
+	`, colA, colB, whereClause)
+
+	result := &MetricCorrelation{
+		ProbeID: probeID,
+		MetricA: metricA,
+		MetricB: metricB,
+		Start:   start,
+		End:     end,
+	}
+	if result.ProbeID == "" {
+		result.ProbeID = "all"
+	}
+
+	var coefficient sql.NullFloat64
+	if err := r.db.QueryRowContext(ctx, query, args...).Scan(&coefficient, &result.SampleCount); err != nil {
+		return nil, fmt.Errorf("failed to get metric correlation: %w", err)
+	}
+	if coefficient.Valid {
+		result.Coefficient = &coefficient.Float64
+	}
+	return result, nil
+}
+
+// GetNeighborAPs summarises every BSSID seen in deep scans between start
+// and end, strongest first. A non-empty probeID limits it to that probe's scans.
+func (r *AnalyticsRepository) GetNeighborAPs(ctx context.Context, start, end time.Time, probeID string) ([]NeighborAPSummary, error) {
+	query := `
+		SELECT
+			n.bssid,
+			COALESCE(MODE() WITHIN GROUP (ORDER BY n.ssid), '') as ssid,
+			MODE() WITHIN GROUP (ORDER BY n.channel) as channel,
+			AVG(n.rssi) as avg_rssi,
+			MAX(n.rssi) as max_rssi,
+			ARRAY_AGG(DISTINCT n.seen_by_probe) as seen_by,
+			COUNT(*) as sightings,
+			MIN(n.timestamp) as first_seen,
+			MAX(n.timestamp) as last_seen,
+			EXISTS (
+				SELECT 1 FROM telemetry t
+				WHERE t.bssid = n.bssid AND t.timestamp >= $1 AND t.timestamp <= $2
+			) as associated
+		FROM neighbor_aps n
+		WHERE n.timestamp >= $1
+		  AND n.timestamp <= $2
+		  AND ($3::text = '' OR n.seen_by_probe = $3)
+		GROUP BY n.bssid
+		ORDER BY avg_rssi DESC NULLS LAST
+	`
+	rows, err := r.db.QueryContext(ctx, query, start, end, probeID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get neighbor APs: %w", err)
+	}
+	defer rows.Close()
+
+	res := []NeighborAPSummary{}
+	for rows.Next() {
+		var n NeighborAPSummary
+		var channel, maxRSSI sql.NullInt64
+		var avgRSSI sql.NullFloat64
+		if err := rows.Scan(
+			&n.BSSID, &n.SSID, &channel, &avgRSSI, &maxRSSI,
+			pq.Array(&n.SeenByProbes), &n.Sightings, &n.FirstSeen, &n.LastSeen, &n.Associated,
+		); err != nil {
+			return nil, fmt.Errorf("failed to scan neighbor AP: %w", err)
+		}
+		n.Channel = int(channel.Int64)
+		n.AvgRSSI = avgRSSI.Float64
+		n.MaxRSSI = int(maxRSSI.Int64)
+		res = append(res, n)
+	}
+	return res, rows.Err()
+}
+
 func (r *AnalyticsRepository) GetCongestionAnalysis(ctx context.Context, start, end time.Time) ([]CongestionAnalysis, error) {
 	query := `
 		SELECT 
@@ -352,12 +609,58 @@ func (r *AnalyticsRepository) GetCongestionAnalysis(ctx context.Context, start,
 	return res, nil
 }
 
-func (r *AnalyticsRepository) GetPerformanceMetrics(ctx context.Context, probeID string, start, end time.Time) (*PerformanceMetrics, error) {
+// DefaultPercentiles are the latency percentiles reported when none are
+// requested. They always fill the fixed P50/P95/P99 fields.
+var DefaultPercentiles = []float64{50, 95, 99}
+
+// PercentileKey names a percentile in LatencyPercentiles, e.g. 99.9 is "p99.9".
+func PercentileKey(p float64) string {
+	return "p" + strconv.FormatFloat(p, 'f', -1, 64)
+}
+
+// GetPerformanceMetrics aggregates performance metrics for one probe, or the
+// whole fleet for "" or "all". percentiles (0-100) selects the entries of
+// LatencyPercentiles and defaults to DefaultPercentiles.
+func (r *AnalyticsRepository) GetPerformanceMetrics(ctx context.Context, probeID string, start, end time.Time, percentiles []float64) (*PerformanceMetrics, error) {
+	if probeID == "" || probeID == "all" {
+		return r.performanceMetrics(ctx, nil, start, end, percentiles)
+	}
+	return r.performanceMetrics(ctx, []string{probeID}, start, end, percentiles)
+}
+
+// GetPerformanceMetricsForProbes aggregates performance metrics over the
+// given probes together, e.g. every probe on one floor.
+func (r *AnalyticsRepository) GetPerformanceMetricsForProbes(ctx context.Context, probeIDs []string, start, end time.Time) (*PerformanceMetrics, error) {
+	if len(probeIDs) == 0 {
+		return nil, fmt.Errorf("no probes to aggregate")
+	}
+	return r.performanceMetrics(ctx, probeIDs, start, end, nil)
+}
+
+// performanceMetrics aggregates over probeIDs, or over every probe when nil.
+func (r *AnalyticsRepository) performanceMetrics(ctx context.Context, probeIDs []string, start, end time.Time, percentiles []float64) (*PerformanceMetrics, error) {
+	if len(percentiles) == 0 {
+		percentiles = DefaultPercentiles
+	}
+
+	// The defaults are always computed for the fixed fields; everything is
+	// done by one array-form PERCENTILE_CONT.
+	wanted := append([]float64{}, DefaultPercentiles...)
+	for _, p := range percentiles {
+		if !containsFloat(wanted, p) {
+			wanted = append(wanted, p)
+		}
+	}
+	fractions := make([]float64, len(wanted))
+	for i, p := range wanted {
+		fractions[i] = p / 100
+	}
+
 	whereClause := "timestamp >= $1 AND timestamp <= $2 AND latency IS NOT NULL"
-	args := []interface{}{start, end}
-	if probeID != "" && probeID != "all" {
-		whereClause += " AND probe_id = $3"
-		args = append(args, probeID)
+	args := []interface{}{start, end, pq.Array(fractions)}
+	if probeIDs != nil {
+		whereClause += " AND probe_id = ANY($4)"
+		args = append(args, pq.Array(probeIDs))
 	}
 
 	query := fmt.Sprintf(`
@@ -368,9 +671,7 @@ func (r *AnalyticsRepository) GetPerformanceMetrics(ctx context.Context, probeID
 			AVG(latency) as avg_latency,
 			MIN(latency) as min_latency,
 			MAX(latency) as max_latency,
-			PERCENTILE_CONT(0.5) WITHIN GROUP (ORDER BY latency) as p50_latency,
-			PERCENTILE_CONT(0.95) WITHIN GROUP (ORDER BY latency) as p95_latency,
-			PERCENTILE_CONT(0.99) WITHIN GROUP (ORDER BY latency) as p99_latency,
+			PERCENTILE_CONT($3::float8[]) WITHIN GROUP (ORDER BY latency) as latency_percentiles,
 			AVG(packet_loss) as avg_packet_loss,
 			AVG(dns_time) as avg_dns_time,
 			COUNT(*) as sample_count
@@ -382,13 +683,14 @@ func (r *AnalyticsRepository) GetPerformanceMetrics(ctx context.Context, probeID
 		Period: fmt.Sprintf("%s to %s", start.Format("2006-01-02"), end.Format("2006-01-02")),
 	}
 
-	var avgRSSI, avgLat, minLat, maxLat, p50, p95, p99, avgLoss, avgDNS sql.NullFloat64
+	var avgRSSI, avgLat, minLat, maxLat, avgLoss, avgDNS sql.NullFloat64
 	var minRSSI, maxRSSI sql.NullInt64
+	var latencyPercentiles pq.Float64Array
 
 	err := r.db.QueryRowContext(ctx, query, args...).Scan(
 		&avgRSSI, &minRSSI, &maxRSSI,
 		&avgLat, &minLat, &maxLat,
-		&p50, &p95, &p99,
+		&latencyPercentiles,
 		&avgLoss, &avgDNS,
 		&metrics.SampleCount,
 	)
@@ -416,14 +718,19 @@ func (r *AnalyticsRepository) GetPerformanceMetrics(ctx context.Context, probeID
 		metrics.MaxLatency = maxLat.Float64
 	}
 
-	if p50.Valid {
-		metrics.P50Latency = p50.Float64
-	}
-	if p95.Valid {
-		metrics.P95Latency = p95.Float64
+	// The array is NULL when no samples matched; percentiles stay zero then.
+	byPercentile := make(map[float64]float64, len(wanted))
+	for i, p := range wanted {
+		if i < len(latencyPercentiles) {
+			byPercentile[p] = latencyPercentiles[i]
+		}
 	}
-	if p99.Valid {
-		metrics.P99Latency = p99.Float64
+	metrics.P50Latency = byPercentile[50]
+	metrics.P95Latency = byPercentile[95]
+	metrics.P99Latency = byPercentile[99]
+	metrics.LatencyPercentiles = make(map[string]float64, len(percentiles))
+	for _, p := range percentiles {
+		metrics.LatencyPercentiles[PercentileKey(p)] = byPercentile[p]
 	}
 
 	if avgLoss.Valid {
@@ -437,6 +744,18 @@ func (r *AnalyticsRepository) GetPerformanceMetrics(ctx context.Context, probeID
 	return metrics, nil
 }
 
+func containsFloat(values []float64, v float64) bool {
+	for _, x := range values {
+		if x == v {
+			return true
+		}
+	}
+	return false
+}
+
+// GetProbeComparison compares probes over a time range. UptimePercent is the
+// share of expected samples received, where each probe is expected to report
+// once per its own report_interval from the later of start and its creation.
 func (r *AnalyticsRepository) GetProbeComparison(ctx context.Context, probeIDs []string, start, end time.Time) ([]ProbeComparison, error) {
 	query := `
         SELECT 
@@ -446,7 +765,10 @@ func (r *AnalyticsRepository) GetProbeComparison(ctx context.Context, probeIDs [
             COALESCE(AVG(t.latency), 0) as avg_latency,
             COALESCE(AVG(t.packet_loss), 0) as avg_packet_loss,
             COALESCE(AVG(t.link_quality), 0) as avg_link_quality,
-            0 as uptime_percent,
+            LEAST(100, COUNT(*) * 100.0 / GREATEST(1,
+                EXTRACT(EPOCH FROM ($3 - GREATEST($2, p.created_at)))
+                / COALESCE(p.report_interval, $4)
+            )) as uptime_percent,
             COUNT(*) as sample_count,
             -- Stability score: 100 - (packet_loss * 5) - (latency / 10), with NULL protection
             100 - (COALESCE(AVG(t.packet_loss), 0) * 5) - (COALESCE(AVG(t.latency), 0) / 10) as stability_score
@@ -455,11 +777,11 @@ func (r *AnalyticsRepository) GetProbeComparison(ctx context.Context, probeIDs [
         WHERE t.probe_id = ANY($1)
           AND t.timestamp >= $2
           AND t.timestamp <= $3
-        GROUP BY t.probe_id, p.location
+        GROUP BY t.probe_id, p.location, p.created_at, p.report_interval
         ORDER BY avg_rssi DESC
     `
 
-	rows, err := r.db.QueryContext(ctx, query, pq.Array(probeIDs), start, end)
+	rows, err := r.db.QueryContext(ctx, query, pq.Array(probeIDs), start, end, int(r.defaultReportInterval.Seconds()))
 	if err != nil {
 		return nil, fmt.Errorf("failed to get probe comparison: %w", err)
 	}
@@ -486,8 +808,13 @@ func (r *AnalyticsRepository) GetProbeComparison(ctx context.Context, probeIDs [
 	return results, nil
 }
 
-func (r *AnalyticsRepository) GetNetworkHealth(ctx context.Context) (*NetworkHealth, error) {
-	const activeWindow = 5 * time.Minute
+// GetNetworkHealth counts a probe as active if it reported within window. A
+// non-positive window falls back to the configured default.
+func (r *AnalyticsRepository) GetNetworkHealth(ctx context.Context, window time.Duration) (*NetworkHealth, error) {
+	activeWindow := window
+	if activeWindow <= 0 {
+		activeWindow = r.activeWindow
+	}
 
 	query := `
 		WITH metrics AS (
@@ -512,7 +839,8 @@ func (r *AnalyticsRepository) GetNetworkHealth(ctx context.Context) (*NetworkHea
 	`
 
 	health := &NetworkHealth{
-		Timestamp: time.Now(),
+		Timestamp:    time.Now(),
+		ActiveWindow: activeWindow.String(),
 	}
 
 	var total, active int
@@ -557,72 +885,82 @@ func (r *AnalyticsRepository) GetNetworkHealth(ctx context.Context) (*NetworkHea
 	return health, nil
 }
 
-func (r *AnalyticsRepository) DetectAnomalies(ctx context.Context, probeID string, hours int) ([]models.AnomalyDetection, error) {
-	query := `
+// AnomalyWindow sets, for one metric, how far back the baseline mean and
+// standard deviation are taken from and how recent a sample must be to be
+// checked against them.
+type AnomalyWindow struct {
+	Baseline time.Duration
+	Recent   time.Duration
+}
+
+// DefaultAnomalyWindows suits how each metric moves: RSSI drifts slowly, so
+// it gets a long baseline and an hour of recent samples, while packet loss
+// comes in bursts that a short recent window catches before they average out.
+var DefaultAnomalyWindows = map[string]AnomalyWindow{
+	"rssi":        {Baseline: 72 * time.Hour, Recent: time.Hour},
+	"latency":     {Baseline: 24 * time.Hour, Recent: 15 * time.Minute},
+	"packet_loss": {Baseline: 6 * time.Hour, Recent: 5 * time.Minute},
+}
+
+// DetectAnomalies flags recent samples more than two standard deviations
+// from their metric's baseline mean. windows is keyed by metric name; any
+// metric it leaves out uses DefaultAnomalyWindows.
+func (r *AnalyticsRepository) DetectAnomalies(ctx context.Context, probeID string, windows map[string]AnomalyWindow) ([]models.AnomalyDetection, error) {
+	anomalies := []models.AnomalyDetection{}
+	for metric, def := range DefaultAnomalyWindows {
+		window := def
+		if w, ok := windows[metric]; ok {
+			window = w
+		}
+		found, err := r.detectMetricAnomalies(ctx, probeID, metric, window)
+		if err != nil {
+			return nil, err
+		}
+		anomalies = append(anomalies, found...)
+	}
+
+	sort.Slice(anomalies, func(i, j int) bool {
+		return anomalies[i].Timestamp.After(anomalies[j].Timestamp)
+	})
+	return anomalies, nil
+}
+
+func (r *AnalyticsRepository) detectMetricAnomalies(ctx context.Context, probeID, metric string, window AnomalyWindow) ([]models.AnomalyDetection, error) {
+	col, ok := metricColumns[metric]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownMetric, metric)
+	}
+
+	query := fmt.Sprintf(`
 		WITH stats AS (
-			SELECT 
-				AVG(rssi) as avg_rssi,
-				STDDEV(rssi) as stddev_rssi,
-				AVG(latency) as avg_latency,
-				STDDEV(latency) as stddev_latency,
-				AVG(packet_loss) as avg_packet_loss,
-				STDDEV(packet_loss) as stddev_packet_loss
-			FROM telemetry
-			WHERE probe_id = $1
-			  AND timestamp >= NOW() - INTERVAL '1 hour' * $2
-		),
-		recent AS (
-			SELECT timestamp, rssi, latency, packet_loss
+			SELECT AVG(%[1]s) as avg_value, STDDEV(%[1]s) as stddev_value
 			FROM telemetry
 			WHERE probe_id = $1
-			  AND timestamp >= NOW() - INTERVAL '15 minutes'
+			  AND timestamp >= NOW() - INTERVAL '1 second' * $2
 		)
 		SELECT 
-			timestamp,
-			'rssi' as metric_type,
-			rssi as value,
-			s.avg_rssi as expected_value,
-			ABS(rssi - s.avg_rssi) / NULLIF(s.stddev_rssi, 0) as deviation
-		FROM recent r, stats s
-		WHERE ABS(rssi - s.avg_rssi) > 2 * s.stddev_rssi
-		  AND s.stddev_rssi > 0
-		UNION ALL
-		SELECT 
-			timestamp,
-			'latency' as metric_type,
-			latency as value,
-			s.avg_latency as expected_value,
-			ABS(latency - s.avg_latency) / NULLIF(s.stddev_latency, 0) as deviation
-		FROM recent r, stats s
-		WHERE ABS(latency - s.avg_latency) > 2 * s.stddev_latency
-		  AND s.stddev_latency > 0
-		  AND latency IS NOT NULL
-		UNION ALL
-		SELECT 
-			timestamp,
-			'packet_loss' as metric_type,
-			packet_loss as value,
-			s.avg_packet_loss as expected_value,
-			ABS(packet_loss - s.avg_packet_loss) / NULLIF(s.stddev_packet_loss, 0) as deviation
-		FROM recent r, stats s
-		WHERE ABS(packet_loss - s.avg_packet_loss) > 2 * s.stddev_packet_loss
-		  AND s.stddev_packet_loss > 0
-		  AND packet_loss IS NOT NULL
-		ORDER BY timestamp DESC
-	`
-
-	rows, err := r.db.QueryContext(ctx, query, probeID, hours)
+			t.timestamp,
+			t.%[1]s as value,
+			s.avg_value as expected_value,
+			ABS(t.%[1]s - s.avg_value) / s.stddev_value as deviation
+		FROM telemetry t, stats s
+		WHERE t.probe_id = $1
+		  AND t.timestamp >= NOW() - INTERVAL '1 second' * $3
+		  AND t.%[1]s IS NOT NULL
+		  AND s.stddev_value > 0
+		  AND ABS(t.%[1]s - s.avg_value) > 2 * s.stddev_value
+	`, col)
+
+	rows, err := r.db.QueryContext(ctx, query, probeID, window.Baseline.Seconds(), window.Recent.Seconds())
 	if err != nil {
-		return nil, fmt.Errorf("failed to detect anomalies: %w", err)
+		return nil, fmt.Errorf("failed to detect %s anomalies: %w", metric, err)
 	}
 	defer rows.Close()
 
-	anomalies := []models.AnomalyDetection{}
+	var anomalies []models.AnomalyDetection
 	for rows.Next() {
-		var a models.AnomalyDetection
-		a.ProbeID = probeID
-
-		if err := rows.Scan(&a.Timestamp, &a.MetricType, &a.Value, &a.ExpectedValue, &a.Deviation); err != nil {
+		a := models.AnomalyDetection{ProbeID: probeID, MetricType: metric}
+		if err := rows.Scan(&a.Timestamp, &a.Value, &a.ExpectedValue, &a.Deviation); err != nil {
 			return nil, fmt.Errorf("failed to scan anomaly: %w", err)
 		}
 
@@ -637,7 +975,7 @@ func (r *AnalyticsRepository) DetectAnomalies(ctx context.Context, probeID strin
 		anomalies = append(anomalies, a)
 	}
 
-	return anomalies, nil
+	return anomalies, rows.Err()
 }
 
 func (r *AnalyticsRepository) GetRoamingAnalysis(ctx context.Context, probeID string, start, end time.Time) ([]APAnalysis, error) {
@@ -691,6 +1029,218 @@ func (r *AnalyticsRepository) GetRoamingAnalysis(ctx context.Context, probeID st
 
 	return roaming, nil
 }
+
+// ProbeStability is one probe's stability score over a window, used to rank
+// the fleet.
+type ProbeStability struct {
+	ProbeID        string  `json:"probe_id"`
+	Location       string  `json:"location"`
+	Building       string  `json:"building"`
+	Floor          string  `json:"floor"`
+	AvgLatency     float64 `json:"avg_latency"`
+	AvgPacketLoss  float64 `json:"avg_packet_loss"`
+	SampleCount    int     `json:"sample_count"`
+	StabilityScore float64 `json:"stability_score"`
+}
+
+// GetProbeStabilityRanking scores every probe that reported latency in the
+// window with calculateStabilityScore and returns them worst first.
+func (r *AnalyticsRepository) GetProbeStabilityRanking(ctx context.Context, start, end time.Time) ([]ProbeStability, error) {
+	query := `
+		SELECT
+			p.probe_id,
+			p.location,
+			p.building,
+			p.floor,
+			AVG(t.latency) as avg_latency,
+			AVG(t.packet_loss) as avg_packet_loss,
+			COUNT(*) as sample_count
+		FROM telemetry t
+		JOIN probes p ON t.probe_id = p.probe_id
+		WHERE t.timestamp >= $1
+		  AND t.timestamp <= $2
+		  AND t.latency IS NOT NULL
+		GROUP BY p.probe_id, p.location, p.building, p.floor
+	`
+	rows, err := r.db.QueryContext(ctx, query, start, end)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get stability ranking: %w", err)
+	}
+	defer rows.Close()
+
+	ranking := []ProbeStability{}
+	for rows.Next() {
+		var s ProbeStability
+		var loss sql.NullFloat64
+		if err := rows.Scan(&s.ProbeID, &s.Location, &s.Building, &s.Floor, &s.AvgLatency, &loss, &s.SampleCount); err != nil {
+			return nil, fmt.Errorf("failed to scan stability: %w", err)
+		}
+		if loss.Valid {
+			s.AvgPacketLoss = loss.Float64
+		}
+		s.StabilityScore = calculateStabilityScore(s.AvgLatency, s.AvgPacketLoss)
+		ranking = append(ranking, s)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read stability ranking: %w", err)
+	}
+
+	sort.Slice(ranking, func(i, j int) bool {
+		if ranking[i].StabilityScore != ranking[j].StabilityScore {
+			return ranking[i].StabilityScore < ranking[j].StabilityScore
+		}
+		return ranking[i].ProbeID < ranking[j].ProbeID
+	})
+
+	return ranking, nil
+}
+
+// IngestLatency is how long a probe's readings took to reach the server,
+// measured as received_at minus the probe's own timestamp. A large positive
+// average points at buffered or offline replays or a slow clock; a negative
+// one at a probe clock running ahead.
+type IngestLatency struct {
+	ProbeID     string  `json:"probe_id"`
+	SampleCount int     `json:"sample_count"`
+	AvgSeconds  float64 `json:"avg_seconds"`
+	MinSeconds  float64 `json:"min_seconds"`
+	MaxSeconds  float64 `json:"max_seconds"`
+}
+
+// GetIngestLatency computes ingest latency per probe for readings received
+// between start and end, worst average first. An empty probeID covers every
+// probe.
+func (r *AnalyticsRepository) GetIngestLatency(ctx context.Context, probeID string, start, end time.Time) ([]IngestLatency, error) {
+	query := `
+		SELECT
+			probe_id,
+			COUNT(*) as sample_count,
+			AVG(EXTRACT(EPOCH FROM (received_at - timestamp))) as avg_seconds,
+			MIN(EXTRACT(EPOCH FROM (received_at - timestamp))) as min_seconds,
+			MAX(EXTRACT(EPOCH FROM (received_at - timestamp))) as max_seconds
+		FROM telemetry
+		WHERE received_at >= $1
+		  AND received_at <= $2
+		  AND ($3 = '' OR probe_id = $3)
+		GROUP BY probe_id
+		ORDER BY avg_seconds DESC, probe_id
+	`
+	rows, err := r.db.QueryContext(ctx, query, start, end, probeID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get ingest latency: %w", err)
+	}
+	defer rows.Close()
+
+	latencies := []IngestLatency{}
+	for rows.Next() {
+		var l IngestLatency
+		if err := rows.Scan(&l.ProbeID, &l.SampleCount, &l.AvgSeconds, &l.MinSeconds, &l.MaxSeconds); err != nil {
+			return nil, fmt.Errorf("failed to scan ingest latency: %w", err)
+		}
+		latencies = append(latencies, l)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read ingest latency: %w", err)
+	}
+
+	return latencies, nil
+}
+
+// HourlyUsage averages load indicators for one hour of the day (UTC).
+type HourlyUsage struct {
+	Hour           int     `json:"hour"`
+	AvgUtilization float64 `json:"avg_utilization"`
+	AvgNeighbors   float64 `json:"avg_neighbors"`
+	AvgOverlap     float64 `json:"avg_overlap"`
+	SampleCount    int     `json:"sample_count"`
+}
+
+// UsageProfile is a 24-hour load profile with the busiest and quietest hours
+// called out for maintenance planning.
+type UsageProfile struct {
+	ProbeID    string        `json:"probe_id"`
+	Days       int           `json:"days"`
+	Hours      []HourlyUsage `json:"hours"`
+	PeakHours  []int         `json:"peak_hours"`
+	QuietHours []int         `json:"quiet_hours"`
+}
+
+// usageProfileHighlight is how many hours are listed as peak and quiet.
+const usageProfileHighlight = 3
+
+// GetHourlyUsageProfile averages utilization and neighbor/overlap counts by
+// hour of day over the past days. Hours without samples are returned with
+// zero values so the profile always has 24 buckets.
+func (r *AnalyticsRepository) GetHourlyUsageProfile(ctx context.Context, probeID string, days int) (*UsageProfile, error) {
+	whereClause := "timestamp >= NOW() - make_interval(days => $1)"
+	args := []interface{}{days}
+	if probeID != "" && probeID != "all" {
+		whereClause += " AND probe_id = $2"
+		args = append(args, probeID)
+	}
+
+	query := fmt.Sprintf(`
+		SELECT
+			EXTRACT(HOUR FROM timestamp AT TIME ZONE 'UTC')::int as hour,
+			COALESCE(AVG(utilization), 0) as avg_utilization,
+			COALESCE(AVG(neighbors), 0) as avg_neighbors,
+			COALESCE(AVG(overlap), 0) as avg_overlap,
+			COUNT(*) as sample_count
+		FROM telemetry
+		WHERE %s
+		GROUP BY hour
+	`, whereClause)
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get usage profile: %w", err)
+	}
+	defer rows.Close()
+
+	profile := &UsageProfile{ProbeID: probeID, Days: days, Hours: make([]HourlyUsage, 24)}
+	for i := range profile.Hours {
+		profile.Hours[i].Hour = i
+	}
+	for rows.Next() {
+		var h HourlyUsage
+		if err := rows.Scan(&h.Hour, &h.AvgUtilization, &h.AvgNeighbors, &h.AvgOverlap, &h.SampleCount); err != nil {
+			return nil, fmt.Errorf("failed to scan usage profile: %w", err)
+		}
+		if h.Hour >= 0 && h.Hour < 24 {
+			profile.Hours[h.Hour] = h
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read usage profile: %w", err)
+	}
+
+	var observed []HourlyUsage
+	for _, h := range profile.Hours {
+		if h.SampleCount > 0 {
+			observed = append(observed, h)
+		}
+	}
+	sort.SliceStable(observed, func(i, j int) bool {
+		if observed[i].AvgUtilization != observed[j].AvgUtilization {
+			return observed[i].AvgUtilization > observed[j].AvgUtilization
+		}
+		return observed[i].AvgNeighbors > observed[j].AvgNeighbors
+	})
+
+	n := usageProfileHighlight
+	if len(observed) < 2*n {
+		n = len(observed) / 2
+	}
+	profile.PeakHours = []int{}
+	profile.QuietHours = []int{}
+	for i := 0; i < n; i++ {
+		profile.PeakHours = append(profile.PeakHours, observed[i].Hour)
+		profile.QuietHours = append(profile.QuietHours, observed[len(observed)-1-i].Hour)
+	}
+
+	return profile, nil
+}
+
 func calculateStabilityScore(latency, packetLoss float64) float64 {
 	score := 100.0
 	if latency > 40 {
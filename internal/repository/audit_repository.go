@@ -0,0 +1,81 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"CampusMonitorAPI/internal/models"
+)
+
+type AuditRepository struct {
+	db *sql.DB
+}
+
+func NewAuditRepository(db *sql.DB) *AuditRepository {
+	return &AuditRepository{db: db}
+}
+
+func (r *AuditRepository) Create(ctx context.Context, e *models.AuditEntry) error {
+	query := `
+		INSERT INTO audit_log (method, path, principal, client_ip, status, request_id, body)
+		VALUES ($1, $2, $3, NULLIF($4, ''), $5, NULLIF($6, ''), NULLIF($7, ''))
+		RETURNING id, created_at
+	`
+	err := r.db.QueryRowContext(ctx, query,
+		e.Method, e.Path, e.Principal, e.ClientIP, e.Status, e.RequestID, e.Body,
+	).Scan(&e.ID, &e.Timestamp)
+	if err != nil {
+		return fmt.Errorf("failed to create audit entry: %w", err)
+	}
+	return nil
+}
+
+// List returns matching entries, newest first.
+func (r *AuditRepository) List(ctx context.Context, q models.AuditQuery) ([]models.AuditEntry, error) {
+	var conditions []string
+	var args []interface{}
+	if q.Principal != "" {
+		args = append(args, q.Principal)
+		conditions = append(conditions, fmt.Sprintf("principal = $%d", len(args)))
+	}
+	if !q.Start.IsZero() {
+		args = append(args, q.Start)
+		conditions = append(conditions, fmt.Sprintf("created_at >= $%d", len(args)))
+	}
+	if !q.End.IsZero() {
+		args = append(args, q.End)
+		conditions = append(conditions, fmt.Sprintf("created_at <= $%d", len(args)))
+	}
+
+	query := `
+		SELECT id, created_at, method, path, principal, COALESCE(client_ip, ''), status,
+		       COALESCE(request_id, ''), COALESCE(body, '')
+		FROM audit_log
+	`
+	if len(conditions) > 0 {
+		query += " WHERE " + strings.Join(conditions, " AND ")
+	}
+	args = append(args, q.Limit, q.Offset)
+	query += fmt.Sprintf(" ORDER BY created_at DESC, id DESC LIMIT $%d OFFSET $%d", len(args)-1, len(args))
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list audit entries: %w", err)
+	}
+	defer rows.Close()
+
+	entries := []models.AuditEntry{}
+	for rows.Next() {
+		var e models.AuditEntry
+		if err := rows.Scan(
+			&e.ID, &e.Timestamp, &e.Method, &e.Path, &e.Principal, &e.ClientIP, &e.Status,
+			&e.RequestID, &e.Body,
+		); err != nil {
+			return nil, fmt.Errorf("failed to scan audit entry: %w", err)
+		}
+		entries = append(entries, e)
+	}
+	return entries, rows.Err()
+}
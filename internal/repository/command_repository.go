@@ -5,6 +5,7 @@ import (
 	"database/sql"
 	"encoding/json"
 	"fmt"
+	"strings"
 
 	"CampusMonitorAPI/internal/models"
 )
@@ -138,6 +139,80 @@ func (r *CommandRepository) GetByProbeID(ctx context.Context, probeID string, li
 	return commands, nil
 }
 
+// Query returns a page of a probe's commands, newest first, optionally
+// filtered by status and command type, along with the total match count.
+func (r *CommandRepository) Query(ctx context.Context, req *models.CommandHistoryRequest) ([]models.Command, int, error) {
+	conditions := []string{"probe_id = $1"}
+	args := []interface{}{req.ProbeID}
+	argCount := 2
+
+	if req.Status != "" {
+		conditions = append(conditions, fmt.Sprintf("status = $%d", argCount))
+		args = append(args, req.Status)
+		argCount++
+	}
+
+	if req.CommandType != "" {
+		conditions = append(conditions, fmt.Sprintf("command_type = $%d", argCount))
+		args = append(args, req.CommandType)
+		argCount++
+	}
+
+	whereClause := "WHERE " + strings.Join(conditions, " AND ")
+
+	var totalCount int
+	countQuery := fmt.Sprintf("SELECT COUNT(*) FROM commands %s", whereClause)
+	if err := r.db.QueryRowContext(ctx, countQuery, args...).Scan(&totalCount); err != nil {
+		return nil, 0, fmt.Errorf("failed to count commands: %w", err)
+	}
+
+	query := fmt.Sprintf(`
+		SELECT id, probe_id, command_type, payload, issued_at,
+		       executed_at, status, result
+		FROM commands
+		%s
+		ORDER BY issued_at DESC
+		LIMIT $%d OFFSET $%d
+	`, whereClause, argCount, argCount+1)
+	args = append(args, req.Limit, req.Offset)
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to query commands: %w", err)
+	}
+	defer rows.Close()
+
+	commands := []models.Command{}
+	for rows.Next() {
+		var cmd models.Command
+		var payloadBytes, resultBytes []byte
+
+		if err := rows.Scan(
+			&cmd.ID,
+			&cmd.ProbeID,
+			&cmd.CommandType,
+			&payloadBytes,
+			&cmd.IssuedAt,
+			&cmd.ExecutedAt,
+			&cmd.Status,
+			&resultBytes,
+		); err != nil {
+			return nil, 0, fmt.Errorf("failed to scan command: %w", err)
+		}
+
+		if payloadBytes != nil {
+			_ = json.Unmarshal(payloadBytes, &cmd.Payload)
+		}
+		if resultBytes != nil {
+			_ = json.Unmarshal(resultBytes, &cmd.Result)
+		}
+
+		commands = append(commands, cmd)
+	}
+
+	return commands, totalCount, rows.Err()
+}
+
 func (r *CommandRepository) GetPending(ctx context.Context) ([]models.Command, error) {
 	query := `
        SELECT id, probe_id, command_type, payload, issued_at, 
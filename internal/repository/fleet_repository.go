@@ -159,6 +159,42 @@ func (r *FleetRepository) GetFleetProbe(ctx context.Context, probeID string) (*m
 	return &fp, nil
 }
 
+// ListProbeIDsByTag returns the managed probes carrying a tag. A bare tag
+// such as "pilot" matches probes with that key in their tags; "ring=pilot"
+// matches only probes whose "ring" tag has that value.
+func (r *FleetRepository) ListProbeIDsByTag(ctx context.Context, tag string) ([]string, error) {
+	query := `
+		SELECT probe_id FROM fleet_probes
+		WHERE managed = true AND tags ? $1
+		ORDER BY probe_id
+	`
+	var arg interface{} = tag
+	if key, value, ok := strings.Cut(tag, "="); ok {
+		query = `
+			SELECT probe_id FROM fleet_probes
+			WHERE managed = true AND tags @> $1
+			ORDER BY probe_id
+		`
+		arg, _ = json.Marshal(map[string]string{key: value})
+	}
+
+	rows, err := r.db.QueryContext(ctx, query, arg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list probes tagged %s: %w", tag, err)
+	}
+	defer rows.Close()
+
+	var probeIDs []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		probeIDs = append(probeIDs, id)
+	}
+	return probeIDs, rows.Err()
+}
+
 func (r *FleetRepository) ListFleetProbes(ctx context.Context, managedOnly bool, group string) ([]models.FleetProbe, error) {
 	query := `
 		SELECT 
@@ -937,7 +973,7 @@ func generateGroupID(name string) string {
 func (r *FleetRepository) GetUnenrolledProbes(ctx context.Context) ([]models.Probe, error) {
 	query := `
 		SELECT p.probe_id, p.location, p.building, p.floor, p.department, 
-			   p.status, p.firmware_version, p.last_seen, p.created_at, p.updated_at
+			   p.status, p.firmware_version, p.report_interval, p.last_seen, p.created_at, p.updated_at
 		FROM probes p
 		WHERE NOT EXISTS (
 			SELECT 1 FROM fleet_probes fp WHERE fp.probe_id = p.probe_id
@@ -956,7 +992,7 @@ func (r *FleetRepository) GetUnenrolledProbes(ctx context.Context) ([]models.Pro
 		var p models.Probe
 		err := rows.Scan(
 			&p.ProbeID, &p.Location, &p.Building, &p.Floor, &p.Department,
-			&p.Status, &p.FirmwareVersion, &p.LastSeen, &p.CreatedAt, &p.UpdatedAt,
+			&p.Status, &p.FirmwareVersion, &p.ReportInterval, &p.LastSeen, &p.CreatedAt, &p.UpdatedAt,
 		)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan probe: %w", err)
@@ -5,8 +5,12 @@ import (
 	"context"
 	"database/sql"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"strings"
 	"time"
+
+	"github.com/lib/pq"
 )
 
 type ProbeRepository struct {
@@ -60,7 +64,7 @@ func (r *ProbeRepository) Create(ctx context.Context, probe *models.Probe) error
 func (r *ProbeRepository) GetByID(ctx context.Context, probeID string) (*models.Probe, error) {
 	query := `
         SELECT probe_id, location, building, floor, department, 
-               status, firmware_version, last_seen, created_at, updated_at, metadata
+               status, firmware_version, report_interval, last_seen, created_at, updated_at, metadata
         FROM probes
         WHERE probe_id = $1`
 
@@ -75,6 +79,7 @@ func (r *ProbeRepository) GetByID(ctx context.Context, probeID string) (*models.
 		&probe.Department,
 		&probe.Status,
 		&probe.FirmwareVersion,
+		&probe.ReportInterval,
 		&probe.LastSeen,
 		&probe.CreatedAt,
 		&probe.UpdatedAt,
@@ -98,15 +103,47 @@ func (r *ProbeRepository) GetByID(ctx context.Context, probeID string) (*models.
 }
 
 func (r *ProbeRepository) GetAll(ctx context.Context) ([]models.Probe, error) {
+	return r.List(ctx, models.ProbeQuery{})
+}
+
+// List returns the probes matching q, newest first. With LastSeenBefore set
+// they are ordered by last_seen instead, longest silent first.
+func (r *ProbeRepository) List(ctx context.Context, q models.ProbeQuery) ([]models.Probe, error) {
+	var conditions []string
+	var args []interface{}
+	if q.Status != "" {
+		args = append(args, q.Status)
+		conditions = append(conditions, fmt.Sprintf("status = $%d", len(args)))
+	}
+	if !q.CreatedAfter.IsZero() {
+		args = append(args, q.CreatedAfter)
+		conditions = append(conditions, fmt.Sprintf("created_at >= $%d", len(args)))
+	}
+	if !q.CreatedBefore.IsZero() {
+		args = append(args, q.CreatedBefore)
+		conditions = append(conditions, fmt.Sprintf("created_at < $%d", len(args)))
+	}
+	if !q.LastSeenBefore.IsZero() {
+		args = append(args, q.LastSeenBefore)
+		conditions = append(conditions, fmt.Sprintf("last_seen < $%d", len(args)))
+	}
+
 	query := `
 		SELECT probe_id, location, building, floor, department, 
-			   status, firmware_version, last_seen, 
+			   status, firmware_version, report_interval, last_seen, 
 			   created_at, updated_at, metadata
 		FROM probes
-		ORDER BY created_at DESC
 	`
+	if len(conditions) > 0 {
+		query += " WHERE " + strings.Join(conditions, " AND ")
+	}
+	if !q.LastSeenBefore.IsZero() {
+		query += " ORDER BY last_seen ASC, probe_id"
+	} else {
+		query += " ORDER BY created_at DESC"
+	}
 
-	rows, err := r.db.QueryContext(ctx, query)
+	rows, err := r.db.QueryContext(ctx, query, args...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to query probes: %w", err)
 	}
@@ -125,6 +162,7 @@ func (r *ProbeRepository) GetAll(ctx context.Context) ([]models.Probe, error) {
 			&probe.Department,
 			&probe.Status,
 			&probe.FirmwareVersion,
+			&probe.ReportInterval,
 			&probe.LastSeen,
 			&probe.CreatedAt,
 			&probe.UpdatedAt,
@@ -144,7 +182,28 @@ func (r *ProbeRepository) GetAll(ctx context.Context) ([]models.Probe, error) {
 
 	return probes, nil
 }
+
+// Update applies the non-nil fields of updates. When the building, floor or
+// location changes, the new location is recorded in probe_location_history
+// in the same transaction.
 func (r *ProbeRepository) Update(ctx context.Context, probeID string, updates *models.UpdateProbeRequest) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var before struct{ building, floor, location sql.NullString }
+	err = tx.QueryRowContext(ctx,
+		`SELECT building, floor, location FROM probes WHERE probe_id = $1 FOR UPDATE`, probeID,
+	).Scan(&before.building, &before.floor, &before.location)
+	if errors.Is(err, sql.ErrNoRows) {
+		return fmt.Errorf("probe %s not found", probeID)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to load probe: %w", err)
+	}
+
 	query := `
        UPDATE probes
        SET location = COALESCE($2, location),
@@ -168,7 +227,7 @@ func (r *ProbeRepository) Update(ctx context.Context, probeID string, updates *m
 		metadataArg = nil
 	}
 
-	result, err := r.db.ExecContext(
+	_, err = tx.ExecContext(
 		ctx, query,
 		probeID,
 		updates.Location,
@@ -183,23 +242,33 @@ func (r *ProbeRepository) Update(ctx context.Context, probeID string, updates *m
 		return fmt.Errorf("failed to update probe: %w", err)
 	}
 
-	rows, err := result.RowsAffected()
-	if err != nil {
-		return fmt.Errorf("failed to get affected rows: %w", err)
+	if movedFrom(before.building, updates.Building) || movedFrom(before.floor, updates.Floor) ||
+		movedFrom(before.location, updates.Location) {
+		if err := recordLocation(ctx, tx, probeID, before.building, before.floor, before.location); err != nil {
+			return err
+		}
 	}
 
-	if rows == 0 {
-		return fmt.Errorf("probe %s not found", probeID)
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
 	}
 
 	return nil
 }
 
+// ErrProbeHasDependents is returned by Delete when telemetry, commands,
+// alerts or tasks still reference the probe.
+var ErrProbeHasDependents = errors.New("probe still has telemetry, commands, alerts or tasks; delete with purge=true to remove them")
+
 func (r *ProbeRepository) Delete(ctx context.Context, probeID string) error {
 	query := `DELETE FROM probes WHERE probe_id = $1`
 
 	result, err := r.db.ExecContext(ctx, query, probeID)
 	if err != nil {
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) && pqErr.Code == "23503" {
+			return ErrProbeHasDependents
+		}
 		return fmt.Errorf("failed to delete probe: %w", err)
 	}
 
@@ -215,6 +284,155 @@ func (r *ProbeRepository) Delete(ctx context.Context, probeID string) error {
 	return nil
 }
 
+// DeleteWithDependents removes the probe together with its telemetry,
+// commands, alerts and scheduled tasks in a single transaction, so fleet-wide
+// analytics are not left counting rows for a probe that no longer exists.
+func (r *ProbeRepository) DeleteWithDependents(ctx context.Context, probeID string) (*models.ProbeDeleteResult, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	result := &models.ProbeDeleteResult{ProbeID: probeID, Purged: true}
+	dependents := []struct {
+		table string
+		count *int64
+	}{
+		{"telemetry", &result.Telemetry},
+		{"commands", &result.Commands},
+		{"alerts", &result.Alerts},
+		{"scheduled_tasks", &result.ScheduledTasks},
+	}
+
+	for _, d := range dependents {
+		res, err := tx.ExecContext(ctx, `DELETE FROM `+d.table+` WHERE probe_id = $1`, probeID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to delete %s for probe: %w", d.table, err)
+		}
+		if *d.count, err = res.RowsAffected(); err != nil {
+			return nil, fmt.Errorf("failed to get affected rows: %w", err)
+		}
+	}
+
+	res, err := tx.ExecContext(ctx, `DELETE FROM probes WHERE probe_id = $1`, probeID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to delete probe: %w", err)
+	}
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get affected rows: %w", err)
+	}
+	if rows == 0 {
+		return nil, fmt.Errorf("probe %s not found", probeID)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return result, nil
+}
+
+// movedFrom reports whether an update to a location field changes it.
+func movedFrom(current sql.NullString, update *string) bool {
+	return update != nil && *update != current.String
+}
+
+// recordLocation appends the probe's now-current location to its history.
+// A probe without history yet, such as one registered after the history
+// table was created, first gets its previous location as a baseline.
+func recordLocation(ctx context.Context, tx *sql.Tx, probeID string, building, floor, location sql.NullString) error {
+	_, err := tx.ExecContext(ctx, `
+		INSERT INTO probe_location_history (probe_id, building, floor, location, effective_from)
+		SELECT $1, $2, $3, $4, '-infinity'
+		WHERE NOT EXISTS (SELECT 1 FROM probe_location_history WHERE probe_id = $1)
+	`, probeID, building, floor, location)
+	if err != nil {
+		return fmt.Errorf("failed to record previous probe location: %w", err)
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO probe_location_history (probe_id, building, floor, location, effective_from)
+		SELECT probe_id, building, floor, location, NOW()
+		FROM probes
+		WHERE probe_id = $1
+		ON CONFLICT (probe_id, effective_from) DO UPDATE
+		SET building = EXCLUDED.building, floor = EXCLUDED.floor, location = EXCLUDED.location
+	`, probeID)
+	if err != nil {
+		return fmt.Errorf("failed to record probe location: %w", err)
+	}
+	return nil
+}
+
+// ErrProbeNotFound is returned by Decommission when no probe has the given ID.
+var ErrProbeNotFound = errors.New("probe not found")
+
+// Decommission marks the probe decommissioned and, in the same transaction,
+// resolves its active alerts with a system note and fails its pending
+// commands, so neither lingers for a probe that will never report again.
+func (r *ProbeRepository) Decommission(ctx context.Context, probeID string) (*models.ProbeDecommissionResult, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	res, err := tx.ExecContext(ctx, `
+		UPDATE probes
+		SET status = 'decommissioned', updated_at = NOW()
+		WHERE probe_id = $1
+	`, probeID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decommission probe: %w", err)
+	}
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get affected rows: %w", err)
+	}
+	if rows == 0 {
+		return nil, ErrProbeNotFound
+	}
+
+	result := &models.ProbeDecommissionResult{ProbeID: probeID}
+
+	res, err = tx.ExecContext(ctx, `
+		UPDATE alerts
+		SET resolved_at = NOW(),
+		    metadata = COALESCE(metadata, '{}'::jsonb) || jsonb_build_object(
+		        'resolved_by', 'system',
+		        'resolution_note', 'probe decommissioned')
+		WHERE probe_id = $1 AND resolved_at IS NULL
+	`, probeID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve alerts for probe: %w", err)
+	}
+	if result.AlertsResolved, err = res.RowsAffected(); err != nil {
+		return nil, fmt.Errorf("failed to get affected rows: %w", err)
+	}
+
+	res, err = tx.ExecContext(ctx, `
+		UPDATE commands
+		SET status = 'failed',
+		    result = jsonb_build_object('error', 'probe decommissioned'),
+		    executed_at = NOW()
+		WHERE probe_id = $1 AND status IN ('pending', 'sent', 'processing')
+	`, probeID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to cancel commands for probe: %w", err)
+	}
+	if result.CommandsCancelled, err = res.RowsAffected(); err != nil {
+		return nil, fmt.Errorf("failed to get affected rows: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return result, nil
+}
+
 func (r *ProbeRepository) UpdateLastSeen(ctx context.Context, probeID string, timestamp time.Time) error {
 	query := `
 		UPDATE probes
@@ -233,7 +451,7 @@ func (r *ProbeRepository) UpdateLastSeen(ctx context.Context, probeID string, ti
 func (r *ProbeRepository) GetActive(ctx context.Context) ([]models.Probe, error) {
 	query := `
 		SELECT probe_id, location, building, floor, department, 
-			   status, firmware_version, last_seen, 
+			   status, firmware_version, report_interval, last_seen, 
 			   created_at, updated_at, metadata
 		FROM probes
 		WHERE status = 'active'
@@ -257,6 +475,7 @@ func (r *ProbeRepository) GetActive(ctx context.Context) ([]models.Probe, error)
 			&probe.Department,
 			&probe.Status,
 			&probe.FirmwareVersion,
+			&probe.ReportInterval,
 			&probe.LastSeen,
 			&probe.CreatedAt,
 			&probe.UpdatedAt,
@@ -271,6 +490,45 @@ func (r *ProbeRepository) GetActive(ctx context.Context) ([]models.Probe, error)
 	return probes, nil
 }
 
+// CountByStatus returns the number of probes in each status.
+func (r *ProbeRepository) CountByStatus(ctx context.Context) (map[string]int, error) {
+	rows, err := r.db.QueryContext(ctx, `SELECT status, COUNT(*) FROM probes GROUP BY status`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count probes by status: %w", err)
+	}
+	defer rows.Close()
+
+	counts := make(map[string]int)
+	for rows.Next() {
+		var status string
+		var count int
+		if err := rows.Scan(&status, &count); err != nil {
+			return nil, err
+		}
+		counts[status] = count
+	}
+	return counts, rows.Err()
+}
+
+// GetGroupedByDepartment returns every probe keyed by its department, with
+// blank departments under models.UnassignedDepartment.
+func (r *ProbeRepository) GetGroupedByDepartment(ctx context.Context) (map[string][]models.Probe, error) {
+	probes, err := r.GetAll(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	groups := make(map[string][]models.Probe)
+	for _, p := range probes {
+		department := strings.TrimSpace(p.Department)
+		if department == "" {
+			department = models.UnassignedDepartment
+		}
+		groups[department] = append(groups[department], p)
+	}
+	return groups, nil
+}
+
 // GetDistinctLocations returns distinct values for building, floor, location, department from probes table.
 func (r *ProbeRepository) GetDistinctLocations(ctx context.Context) (*models.LocationOptions, error) {
 	opts := &models.LocationOptions{}
@@ -336,7 +594,7 @@ func (r *ProbeRepository) GetDistinctLocations(ctx context.Context) (*models.Loc
 func (r *ProbeRepository) GetByBuilding(ctx context.Context, building string) ([]models.Probe, error) {
 	query := `
 		SELECT probe_id, location, building, floor, department, 
-			   status, firmware_version, last_seen, 
+			   status, firmware_version, report_interval, last_seen, 
 			   created_at, updated_at, metadata
 		FROM probes
 		WHERE building = $1
@@ -360,6 +618,7 @@ func (r *ProbeRepository) GetByBuilding(ctx context.Context, building string) ([
 			&probe.Department,
 			&probe.Status,
 			&probe.FirmwareVersion,
+			&probe.ReportInterval,
 			&probe.LastSeen,
 			&probe.CreatedAt,
 			&probe.UpdatedAt,
@@ -374,6 +633,16 @@ func (r *ProbeRepository) GetByBuilding(ctx context.Context, building string) ([
 	return probes, nil
 }
 
+// UpdateReportInterval records the report interval, in seconds, a probe
+// confirmed it is using.
+func (r *ProbeRepository) UpdateReportInterval(ctx context.Context, probeID string, seconds int) error {
+	query := `UPDATE probes SET report_interval = $2, updated_at = NOW() WHERE probe_id = $1`
+	if _, err := r.db.ExecContext(ctx, query, probeID, seconds); err != nil {
+		return fmt.Errorf("failed to update report interval: %w", err)
+	}
+	return nil
+}
+
 func (r *ProbeRepository) UpdateFirmwareVersion(ctx context.Context, probeID, version string) error {
 	query := `
 		UPDATE probes
@@ -401,7 +670,7 @@ func (r *ProbeRepository) UpdateFirmwareVersion(ctx context.Context, probeID, ve
 func (r *ProbeRepository) GetStale(ctx context.Context, threshold time.Duration) ([]models.Probe, error) {
 	query := `
 		SELECT probe_id, location, building, floor, department, 
-			   status, firmware_version, last_seen, 
+			   status, firmware_version, report_interval, last_seen, 
 			   created_at, updated_at, metadata
 		FROM probes
 		WHERE last_seen < $1 AND status = 'active'
@@ -426,6 +695,7 @@ func (r *ProbeRepository) GetStale(ctx context.Context, threshold time.Duration)
 			&probe.Department,
 			&probe.Status,
 			&probe.FirmwareVersion,
+			&probe.ReportInterval,
 			&probe.LastSeen,
 			&probe.CreatedAt,
 			&probe.UpdatedAt,
@@ -444,7 +714,7 @@ func (r *ProbeRepository) GetStale(ctx context.Context, threshold time.Duration)
 func (r *ProbeRepository) GetByBuildingAndFloor(ctx context.Context, building string, floor string) ([]models.Probe, error) {
 	query := `
 		SELECT probe_id, location, building, floor, department, status, 
-		       firmware_version, last_seen, created_at, updated_at, metadata
+		       firmware_version, report_interval, last_seen, created_at, updated_at, metadata
 		FROM probes
 		WHERE building = $1 AND floor = $2
 	`
@@ -468,6 +738,7 @@ func (r *ProbeRepository) GetByBuildingAndFloor(ctx context.Context, building st
 			&p.Department,
 			&p.Status,
 			&p.FirmwareVersion,
+			&p.ReportInterval,
 			&p.LastSeen,
 			&p.CreatedAt,
 			&p.UpdatedAt,
@@ -501,7 +772,7 @@ func (r *ProbeRepository) AutoDiscover(ctx context.Context, probeID string) erro
 			probe_id, status, location, building, floor, department, 
 			firmware_version, last_seen, created_at, updated_at
 		) VALUES (
-			$1, 'pending', 'unknown', 'unknown', 'unknown', 'unknown', 
+			$1, 'unknown', 'unknown', 'unknown', 'unknown', 'unknown', 
 			'unknown', NOW(), NOW(), NOW()
 		)
 		ON CONFLICT (probe_id) DO NOTHING
@@ -44,7 +44,7 @@ func NewReportRepository(
 func (r *ReportRepository) AlertReportData(ctx context.Context, from, to time.Time, probeIDs []string) (*models.AlertReport, error) {
 
 	query := `
-		SELECT id, probe_id, alert_type, severity, message, threshold_value, actual_value, triggered_at, resolved_at, acknowledged
+		SELECT id, probe_id, alert_type, COALESCE(category, ''), severity, message, threshold_value, actual_value, triggered_at, resolved_at, acknowledged
 		FROM alerts
 		WHERE triggered_at BETWEEN $1 AND $2
 	`
@@ -70,7 +70,7 @@ func (r *ReportRepository) AlertReportData(ctx context.Context, from, to time.Ti
 		var a models.AlertHistoryEntry
 		var resolvedAt sql.NullTime
 		var threshold, actual sql.NullFloat64
-		err := rows.Scan(&a.ID, &a.ProbeID, &a.AlertType, &a.Severity, &a.Message,
+		err := rows.Scan(&a.ID, &a.ProbeID, &a.AlertType, &a.Category, &a.Severity, &a.Message,
 			&threshold, &actual, &a.TriggeredAt, &resolvedAt, &a.Acknowledged)
 		if err != nil {
 			return nil, err
@@ -106,7 +106,7 @@ func (r *ReportRepository) AlertReportData(ctx context.Context, from, to time.Ti
 
 func (r *ReportRepository) AnalyticsReportData(ctx context.Context, from, to time.Time, probeIDs []string) (*models.AnalyticsReport, error) {
 	// Overall metrics using analyticsRepo (it supports empty probeID for all)
-	perf, err := r.analyticsRepo.GetPerformanceMetrics(ctx, "", from, to)
+	perf, err := r.analyticsRepo.GetPerformanceMetrics(ctx, "", from, to, nil)
 	if err != nil {
 		return nil, err
 	}
@@ -557,7 +557,7 @@ func (r *ReportRepository) OutageReportData(ctx context.Context, from, to time.T
 // GetNetworkBaselineReportData fetches data for the network baseline report
 func (r *ReportRepository) GetNetworkBaselineReportData(ctx context.Context, from, to time.Time) (*models.NetworkBaselineReport, error) {
 	// Get overall metrics (which already includes latency percentiles)
-	perf, err := r.analyticsRepo.GetPerformanceMetrics(ctx, "", from, to)
+	perf, err := r.analyticsRepo.GetPerformanceMetrics(ctx, "", from, to, nil)
 	if err != nil {
 		return nil, err
 	}
@@ -813,8 +813,9 @@ func (r *ReportRepository) GetSiteSurveyReportData(ctx context.Context, building
 		probeIDs[i] = p.ProbeID
 	}
 
-	// 2. Heatmap data: RSSI per location (already grouped by building/floor/location)
-	heatmapData, err := r.analyticsRepo.GetHeatmapData(ctx, from, to)
+	// 2. Heatmap data: RSSI per location (already grouped by building/floor/location),
+	// attributed to where each probe was during the report period
+	heatmapData, err := r.analyticsRepo.GetHeatmapData(ctx, from, to, true)
 	if err != nil {
 		return nil, err
 	}
@@ -0,0 +1,199 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"CampusMonitorAPI/internal/models"
+)
+
+var (
+	ErrReportScheduleNotFound = errors.New("report schedule not found")
+	ErrReportNotFound         = errors.New("report not found")
+)
+
+type ReportScheduleRepository struct {
+	db *sql.DB
+}
+
+func NewReportScheduleRepository(db *sql.DB) *ReportScheduleRepository {
+	return &ReportScheduleRepository{db: db}
+}
+
+const reportScheduleColumns = `
+	id, name, cron, start_at, range_spec, top_n, target_type, COALESCE(target_url, ''),
+	enabled, last_run, next_run, created_at, updated_at
+`
+
+func scanReportSchedule(row interface{ Scan(...interface{}) error }) (*models.ReportSchedule, error) {
+	var s models.ReportSchedule
+	var startAt, lastRun, nextRun sql.NullTime
+	err := row.Scan(
+		&s.ID, &s.Name, &s.Cron, &startAt, &s.Range, &s.TopN, &s.TargetType, &s.TargetURL,
+		&s.Enabled, &lastRun, &nextRun, &s.CreatedAt, &s.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	if startAt.Valid {
+		s.StartAt = &startAt.Time
+	}
+	if lastRun.Valid {
+		s.LastRun = &lastRun.Time
+	}
+	if nextRun.Valid {
+		s.NextRun = &nextRun.Time
+	}
+	return &s, nil
+}
+
+func (r *ReportScheduleRepository) Create(ctx context.Context, s *models.ReportSchedule) error {
+	query := `
+		INSERT INTO report_schedules (id, name, cron, start_at, range_spec, top_n, target_type, target_url, enabled, next_run)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, NULLIF($8, ''), $9, $10)
+		RETURNING created_at, updated_at
+	`
+	err := r.db.QueryRowContext(ctx, query,
+		s.ID, s.Name, s.Cron, s.StartAt, s.Range, s.TopN, s.TargetType, s.TargetURL, s.Enabled, s.NextRun,
+	).Scan(&s.CreatedAt, &s.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create report schedule: %w", err)
+	}
+	return nil
+}
+
+func (r *ReportScheduleRepository) GetByID(ctx context.Context, id string) (*models.ReportSchedule, error) {
+	query := `SELECT ` + reportScheduleColumns + ` FROM report_schedules WHERE id = $1`
+	s, err := scanReportSchedule(r.db.QueryRowContext(ctx, query, id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrReportScheduleNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get report schedule: %w", err)
+	}
+	return s, nil
+}
+
+func (r *ReportScheduleRepository) List(ctx context.Context) ([]models.ReportSchedule, error) {
+	query := `SELECT ` + reportScheduleColumns + ` FROM report_schedules ORDER BY created_at`
+	return r.query(ctx, query)
+}
+
+// ListDue returns the enabled schedules whose next run is at or before now.
+func (r *ReportScheduleRepository) ListDue(ctx context.Context, now time.Time) ([]models.ReportSchedule, error) {
+	query := `
+		SELECT ` + reportScheduleColumns + `
+		FROM report_schedules
+		WHERE enabled AND next_run IS NOT NULL AND next_run <= $1
+		ORDER BY next_run
+	`
+	return r.query(ctx, query, now)
+}
+
+func (r *ReportScheduleRepository) query(ctx context.Context, query string, args ...interface{}) ([]models.ReportSchedule, error) {
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list report schedules: %w", err)
+	}
+	defer rows.Close()
+
+	schedules := []models.ReportSchedule{}
+	for rows.Next() {
+		s, err := scanReportSchedule(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan report schedule: %w", err)
+		}
+		schedules = append(schedules, *s)
+	}
+	return schedules, rows.Err()
+}
+
+func (r *ReportScheduleRepository) Delete(ctx context.Context, id string) error {
+	result, err := r.db.ExecContext(ctx, `DELETE FROM report_schedules WHERE id = $1`, id)
+	if err != nil {
+		return fmt.Errorf("failed to delete report schedule: %w", err)
+	}
+	if n, err := result.RowsAffected(); err == nil && n == 0 {
+		return ErrReportScheduleNotFound
+	}
+	return nil
+}
+
+func (r *ReportScheduleRepository) UpdateRun(ctx context.Context, id string, lastRun time.Time, nextRun *time.Time) error {
+	query := `UPDATE report_schedules SET last_run = $2, next_run = $3, updated_at = NOW() WHERE id = $1`
+	if _, err := r.db.ExecContext(ctx, query, id, lastRun, nextRun); err != nil {
+		return fmt.Errorf("failed to update report schedule run: %w", err)
+	}
+	return nil
+}
+
+// SaveReport stores a generated report, filling in its ID and GeneratedAt.
+func (r *ReportScheduleRepository) SaveReport(ctx context.Context, report *models.GeneratedReport) error {
+	query := `
+		INSERT INTO generated_reports (schedule_id, range_start, range_end, delivery_status, delivery_error, payload)
+		VALUES ($1, $2, $3, $4, NULLIF($5, ''), $6)
+		RETURNING id, generated_at
+	`
+	err := r.db.QueryRowContext(ctx, query,
+		report.ScheduleID, report.RangeStart, report.RangeEnd,
+		report.DeliveryStatus, report.DeliveryError, []byte(report.Payload),
+	).Scan(&report.ID, &report.GeneratedAt)
+	if err != nil {
+		return fmt.Errorf("failed to save generated report: %w", err)
+	}
+	return nil
+}
+
+// ListReports returns a schedule's most recent reports without their payloads.
+func (r *ReportScheduleRepository) ListReports(ctx context.Context, scheduleID string, limit int) ([]models.GeneratedReport, error) {
+	query := `
+		SELECT id, schedule_id, generated_at, range_start, range_end, delivery_status, COALESCE(delivery_error, '')
+		FROM generated_reports
+		WHERE schedule_id = $1
+		ORDER BY generated_at DESC
+		LIMIT $2
+	`
+	rows, err := r.db.QueryContext(ctx, query, scheduleID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list generated reports: %w", err)
+	}
+	defer rows.Close()
+
+	reports := []models.GeneratedReport{}
+	for rows.Next() {
+		var rep models.GeneratedReport
+		if err := rows.Scan(
+			&rep.ID, &rep.ScheduleID, &rep.GeneratedAt, &rep.RangeStart, &rep.RangeEnd,
+			&rep.DeliveryStatus, &rep.DeliveryError,
+		); err != nil {
+			return nil, fmt.Errorf("failed to scan generated report: %w", err)
+		}
+		reports = append(reports, rep)
+	}
+	return reports, rows.Err()
+}
+
+func (r *ReportScheduleRepository) GetReport(ctx context.Context, id int) (*models.GeneratedReport, error) {
+	query := `
+		SELECT id, schedule_id, generated_at, range_start, range_end, delivery_status, COALESCE(delivery_error, ''), payload
+		FROM generated_reports
+		WHERE id = $1
+	`
+	var rep models.GeneratedReport
+	var payload []byte
+	err := r.db.QueryRowContext(ctx, query, id).Scan(
+		&rep.ID, &rep.ScheduleID, &rep.GeneratedAt, &rep.RangeStart, &rep.RangeEnd,
+		&rep.DeliveryStatus, &rep.DeliveryError, &payload,
+	)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrReportNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get generated report: %w", err)
+	}
+	rep.Payload = payload
+	return &rep, nil
+}
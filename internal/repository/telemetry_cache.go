@@ -0,0 +1,59 @@
+package repository
+
+import (
+	"sync"
+	"time"
+
+	"CampusMonitorAPI/internal/models"
+)
+
+// latestCacheTTL bounds how long a cached reading is trusted before
+// GetLatestByProbe goes back to the database, so a value written by another
+// instance (or before a restart) is picked up eventually.
+const latestCacheTTL = 5 * time.Minute
+
+type latestEntry struct {
+	telemetry models.Telemetry
+	cachedAt  time.Time
+}
+
+// latestTelemetryCache keeps the newest reading per probe so live views don't
+// query the telemetry hypertable for a value we have just written.
+type latestTelemetryCache struct {
+	mu      sync.RWMutex
+	entries map[string]latestEntry
+}
+
+func newLatestTelemetryCache() *latestTelemetryCache {
+	return &latestTelemetryCache{entries: make(map[string]latestEntry)}
+}
+
+func (c *latestTelemetryCache) get(probeID string) (*models.Telemetry, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	entry, ok := c.entries[probeID]
+	if !ok || time.Since(entry.cachedAt) > latestCacheTTL {
+		return nil, false
+	}
+	t := entry.telemetry
+	return &t, true
+}
+
+// put stores t unless a newer reading is already cached; offline backfill
+// arrives out of order and must not replace the live value.
+func (c *latestTelemetryCache) put(t *models.Telemetry) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if entry, ok := c.entries[t.ProbeID]; ok && entry.telemetry.Timestamp.After(t.Timestamp) {
+		return
+	}
+	c.entries[t.ProbeID] = latestEntry{telemetry: *t, cachedAt: time.Now()}
+}
+
+func (c *latestTelemetryCache) forget(probeID string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.entries, probeID)
+}
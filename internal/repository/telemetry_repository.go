@@ -9,26 +9,36 @@ import (
 	"time"
 
 	"CampusMonitorAPI/internal/models"
+
+	"github.com/lib/pq"
 )
 
 type TelemetryRepository struct {
-	db *sql.DB
+	db     *sql.DB
+	latest *latestTelemetryCache
 }
 
 func NewTelemetryRepository(db *sql.DB) *TelemetryRepository {
-	return &TelemetryRepository{db: db}
+	return &TelemetryRepository{
+		db:     db,
+		latest: newLatestTelemetryCache(),
+	}
 }
 
 func (r *TelemetryRepository) Insert(ctx context.Context, telemetry *models.Telemetry) error {
+	if telemetry.ReceivedAt.IsZero() {
+		telemetry.ReceivedAt = time.Now()
+	}
+
 	query := `
 		INSERT INTO telemetry (
 			timestamp, probe_id, type, rssi, latency, packet_loss, 
 			dns_time, channel, bssid, neighbors, overlap, congestion,
 			snr, link_quality, utilization, phy_mode, throughput, 
-			noise_floor, uptime, metadata
+			noise_floor, uptime, metadata, received_at
 		) VALUES (
 			$1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12,
-			$13, $14, $15, $16, $17, $18, $19, $20
+			$13, $14, $15, $16, $17, $18, $19, $20, $21
 		)
 	`
 
@@ -66,17 +76,24 @@ func (r *TelemetryRepository) Insert(ctx context.Context, telemetry *models.Tele
 		telemetry.NoiseFloor,
 		telemetry.Uptime,
 		metadataVal,
+		telemetry.ReceivedAt,
 	)
 
 	if err != nil {
 		return fmt.Errorf("failed to insert telemetry: %w", err)
 	}
 
+	r.latest.put(telemetry)
 	return nil
 }
 
-// GetLatestByProbe retrieves the single most recent telemetry reading for a given probe.
+// GetLatestByProbe retrieves the single most recent telemetry reading for a
+// given probe, from the in-memory cache when it holds a fresh enough value.
 func (r *TelemetryRepository) GetLatestByProbe(ctx context.Context, probeID string) (*models.Telemetry, error) {
+	if t, ok := r.latest.get(probeID); ok {
+		return t, nil
+	}
+
 	query := `
 		SELECT timestamp, probe_id, type, rssi, latency, packet_loss, dns_time, 
 		       channel, bssid, neighbors, overlap, congestion, snr, link_quality, 
@@ -124,9 +141,16 @@ func (r *TelemetryRepository) GetLatestByProbe(ctx context.Context, probeID stri
 		_ = json.Unmarshal(metadataJSON, &t.Metadata)
 	}
 
+	r.latest.put(&t)
 	return &t, nil
 }
 
+// ForgetLatest drops the cached latest reading for a probe, e.g. after its
+// telemetry has been purged.
+func (r *TelemetryRepository) ForgetLatest(probeID string) {
+	r.latest.forget(probeID)
+}
+
 func (r *TelemetryRepository) InsertBatch(ctx context.Context, telemetries []models.Telemetry) error {
 	if len(telemetries) == 0 {
 		return nil
@@ -143,10 +167,10 @@ func (r *TelemetryRepository) InsertBatch(ctx context.Context, telemetries []mod
 			timestamp, probe_id, type, rssi, latency, packet_loss, 
 			dns_time, channel, bssid, neighbors, overlap, congestion,
 			snr, link_quality, utilization, phy_mode, throughput, 
-			noise_floor, uptime, metadata
+			noise_floor, uptime, metadata, received_at
 		) VALUES (
 			$1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12,
-			$13, $14, $15, $16, $17, $18, $19, $20
+			$13, $14, $15, $16, $17, $18, $19, $20, $21
 		)
 	`)
 	if err != nil {
@@ -154,7 +178,11 @@ func (r *TelemetryRepository) InsertBatch(ctx context.Context, telemetries []mod
 	}
 	defer stmt.Close()
 
-	for _, t := range telemetries {
+	for i := range telemetries {
+		t := &telemetries[i]
+		if t.ReceivedAt.IsZero() {
+			t.ReceivedAt = time.Now()
+		}
 		var metadataVal interface{}
 		if t.Metadata != nil && len(t.Metadata) > 0 {
 			metadataJSON, err := json.Marshal(t.Metadata)
@@ -171,7 +199,7 @@ func (r *TelemetryRepository) InsertBatch(ctx context.Context, telemetries []mod
 			t.Timestamp, t.ProbeID, t.Type, t.RSSI, t.Latency, t.PacketLoss,
 			t.DNSTime, t.Channel, t.BSSID, t.Neighbors, t.Overlap, t.Congestion,
 			t.SNR, t.LinkQuality, t.Utilization, t.PhyMode, t.Throughput,
-			t.NoiseFloor, t.Uptime, metadataVal,
+			t.NoiseFloor, t.Uptime, metadataVal, t.ReceivedAt,
 		)
 		if err != nil {
 			return fmt.Errorf("failed to insert telemetry batch: %w", err)
@@ -182,10 +210,15 @@ func (r *TelemetryRepository) InsertBatch(ctx context.Context, telemetries []mod
 		return fmt.Errorf("failed to commit transaction: %w", err)
 	}
 
+	for i := range telemetries {
+		r.latest.put(&telemetries[i])
+	}
 	return nil
 }
 
-func (r *TelemetryRepository) Query(ctx context.Context, req *models.TelemetryQueryRequest) ([]models.Telemetry, int, error) {
+// Query returns a page of matching telemetry, the total number of matches
+// and how many of them are of each type.
+func (r *TelemetryRepository) Query(ctx context.Context, req *models.TelemetryQueryRequest) ([]models.Telemetry, int, map[string]int, error) {
 	var conditions []string
 	var args []interface{}
 	argCount := 1
@@ -197,23 +230,61 @@ func (r *TelemetryRepository) Query(ctx context.Context, req *models.TelemetryQu
 			args = append(args, probeID)
 			argCount++
 		}
-		conditions = append(conditions, fmt.Sprintf("probe_id IN (%s)", strings.Join(placeholders, ",")))
+		conditions = append(conditions, fmt.Sprintf("t.probe_id IN (%s)", strings.Join(placeholders, ",")))
+	}
+
+	// Group filters join in the probe's registration, and for tags its
+	// fleet record, instead of making the caller list the probe ids.
+	joins := ""
+	groupFilters := []struct {
+		column string
+		value  string
+	}{
+		{"p.building", req.Building},
+		{"p.floor", req.Floor},
+		{"p.department", req.Department},
+	}
+	for _, f := range groupFilters {
+		if f.value == "" {
+			continue
+		}
+		if joins == "" {
+			joins = " JOIN probes p ON p.probe_id = t.probe_id"
+		}
+		conditions = append(conditions, fmt.Sprintf("%s = $%d", f.column, argCount))
+		args = append(args, f.value)
+		argCount++
+	}
+
+	if req.Tag != "" {
+		joins += " JOIN fleet_probes fp ON fp.probe_id = t.probe_id"
+		// Same matching as FleetRepository.ListProbeIDsByTag: a bare tag
+		// matches the key, "key=value" matches the value.
+		if key, value, ok := strings.Cut(req.Tag, "="); ok {
+			tagJSON, _ := json.Marshal(map[string]string{key: value})
+			conditions = append(conditions, fmt.Sprintf("fp.tags @> $%d", argCount))
+			args = append(args, tagJSON)
+		} else {
+			conditions = append(conditions, fmt.Sprintf("fp.tags ? $%d", argCount))
+			args = append(args, req.Tag)
+		}
+		argCount++
 	}
 
 	if req.Type != "" {
-		conditions = append(conditions, fmt.Sprintf("type = $%d", argCount))
+		conditions = append(conditions, fmt.Sprintf("t.type = $%d", argCount))
 		args = append(args, req.Type)
 		argCount++
 	}
 
 	if req.StartTime != nil {
-		conditions = append(conditions, fmt.Sprintf("timestamp >= $%d", argCount))
+		conditions = append(conditions, fmt.Sprintf("t.timestamp >= $%d", argCount))
 		args = append(args, *req.StartTime)
 		argCount++
 	}
 
 	if req.EndTime != nil {
-		conditions = append(conditions, fmt.Sprintf("timestamp <= $%d", argCount))
+		conditions = append(conditions, fmt.Sprintf("t.timestamp <= $%d", argCount))
 		args = append(args, *req.EndTime)
 		argCount++
 	}
@@ -223,11 +294,26 @@ func (r *TelemetryRepository) Query(ctx context.Context, req *models.TelemetryQu
 		whereClause = "WHERE " + strings.Join(conditions, " AND ")
 	}
 
-	countQuery := fmt.Sprintf("SELECT COUNT(*) FROM telemetry %s", whereClause)
-	var totalCount int
-	err := r.db.QueryRowContext(ctx, countQuery, args...).Scan(&totalCount)
+	countQuery := fmt.Sprintf("SELECT t.type, COUNT(*) FROM telemetry t%s %s GROUP BY t.type", joins, whereClause)
+	countRows, err := r.db.QueryContext(ctx, countQuery, args...)
 	if err != nil {
-		return nil, 0, fmt.Errorf("failed to count telemetry: %w", err)
+		return nil, 0, nil, fmt.Errorf("failed to count telemetry: %w", err)
+	}
+	var totalCount int
+	typeCounts := make(map[string]int)
+	for countRows.Next() {
+		var telemetryType string
+		var count int
+		if err := countRows.Scan(&telemetryType, &count); err != nil {
+			countRows.Close()
+			return nil, 0, nil, fmt.Errorf("failed to scan telemetry count: %w", err)
+		}
+		typeCounts[telemetryType] = count
+		totalCount += count
+	}
+	countRows.Close()
+	if err := countRows.Err(); err != nil {
+		return nil, 0, nil, fmt.Errorf("failed to count telemetry: %w", err)
 	}
 
 	limit := req.Limit
@@ -240,21 +326,21 @@ func (r *TelemetryRepository) Query(ctx context.Context, req *models.TelemetryQu
 	}
 
 	query := fmt.Sprintf(`
-		SELECT timestamp, probe_id, type, rssi, latency, packet_loss,
-			   dns_time, channel, bssid, neighbors, overlap, congestion,
-			   snr, link_quality, utilization, phy_mode, throughput,
-			   noise_floor, uptime, received_at, metadata
-		FROM telemetry
+		SELECT t.timestamp, t.probe_id, t.type, t.rssi, t.latency, t.packet_loss,
+			   t.dns_time, t.channel, t.bssid, t.neighbors, t.overlap, t.congestion,
+			   t.snr, t.link_quality, t.utilization, t.phy_mode, t.throughput,
+			   t.noise_floor, t.uptime, t.received_at, t.metadata
+		FROM telemetry t%s
 		%s
-		ORDER BY timestamp DESC
+		ORDER BY t.timestamp DESC
 		LIMIT $%d OFFSET $%d
-	`, whereClause, argCount, argCount+1)
+	`, joins, whereClause, argCount, argCount+1)
 
 	args = append(args, limit, offset)
 
 	rows, err := r.db.QueryContext(ctx, query, args...)
 	if err != nil {
-		return nil, 0, fmt.Errorf("failed to query telemetry: %w", err)
+		return nil, 0, nil, fmt.Errorf("failed to query telemetry: %w", err)
 	}
 	defer rows.Close()
 
@@ -270,19 +356,19 @@ func (r *TelemetryRepository) Query(ctx context.Context, req *models.TelemetryQu
 			&t.NoiseFloor, &t.Uptime, &t.ReceivedAt, &metadataJSON,
 		)
 		if err != nil {
-			return nil, 0, fmt.Errorf("failed to scan telemetry: %w", err)
+			return nil, 0, nil, fmt.Errorf("failed to scan telemetry: %w", err)
 		}
 
 		if metadataJSON.Valid && metadataJSON.String != "" {
 			if err := json.Unmarshal([]byte(metadataJSON.String), &t.Metadata); err != nil {
-				return nil, 0, fmt.Errorf("failed to unmarshal metadata: %w", err)
+				return nil, 0, nil, fmt.Errorf("failed to unmarshal metadata: %w", err)
 			}
 		}
 
 		telemetries = append(telemetries, t)
 	}
 
-	return telemetries, totalCount, nil
+	return telemetries, totalCount, typeCounts, nil
 }
 
 func (r *TelemetryRepository) GetLatest(ctx context.Context, probeID string, limit int) ([]models.Telemetry, error) {
@@ -334,6 +420,209 @@ func (r *TelemetryRepository) GetLatest(ctx context.Context, probeID string, lim
 	return telemetries, nil
 }
 
+// GetLatestForProbes returns each probe's most recent reading keyed by probe
+// id, for every registered probe when probeIDs is empty. Probes that have
+// not sent telemetry are left out. Fresh cached readings are used as-is, so
+// only the misses are queried, all in one statement.
+func (r *TelemetryRepository) GetLatestForProbes(ctx context.Context, probeIDs []string) (map[string]models.Telemetry, error) {
+	if len(probeIDs) == 0 {
+		ids, err := r.allProbeIDs(ctx)
+		if err != nil {
+			return nil, err
+		}
+		probeIDs = ids
+	}
+
+	result := make(map[string]models.Telemetry, len(probeIDs))
+	var missing []string
+	for _, id := range probeIDs {
+		if _, seen := result[id]; seen {
+			continue
+		}
+		if t, ok := r.latest.get(id); ok {
+			result[id] = *t
+		} else {
+			missing = append(missing, id)
+		}
+	}
+	if len(missing) == 0 {
+		return result, nil
+	}
+
+	query := `
+		SELECT t.timestamp, t.probe_id, t.type, t.rssi, t.latency, t.packet_loss,
+			   t.dns_time, t.channel, t.bssid, t.neighbors, t.overlap, t.congestion,
+			   t.snr, t.link_quality, t.utilization, t.phy_mode, t.throughput,
+			   t.noise_floor, t.uptime, t.received_at, t.metadata
+		FROM unnest($1::text[]) AS p(probe_id)
+		CROSS JOIN LATERAL (
+			SELECT * FROM telemetry
+			WHERE telemetry.probe_id = p.probe_id
+			ORDER BY timestamp DESC
+			LIMIT 1
+		) t
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, pq.Array(missing))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get latest telemetry: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var t models.Telemetry
+		var metadataJSON sql.NullString
+
+		err := rows.Scan(
+			&t.Timestamp, &t.ProbeID, &t.Type, &t.RSSI, &t.Latency, &t.PacketLoss,
+			&t.DNSTime, &t.Channel, &t.BSSID, &t.Neighbors, &t.Overlap, &t.Congestion,
+			&t.SNR, &t.LinkQuality, &t.Utilization, &t.PhyMode, &t.Throughput,
+			&t.NoiseFloor, &t.Uptime, &t.ReceivedAt, &metadataJSON,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan telemetry: %w", err)
+		}
+
+		if metadataJSON.Valid && metadataJSON.String != "" {
+			if err := json.Unmarshal([]byte(metadataJSON.String), &t.Metadata); err != nil {
+				return nil, fmt.Errorf("failed to unmarshal metadata: %w", err)
+			}
+		}
+
+		r.latest.put(&t)
+		result[t.ProbeID] = t
+	}
+
+	return result, rows.Err()
+}
+
+func (r *TelemetryRepository) allProbeIDs(ctx context.Context) ([]string, error) {
+	rows, err := r.db.QueryContext(ctx, `SELECT probe_id FROM probes`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list probes: %w", err)
+	}
+	defer rows.Close()
+
+	var ids []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("failed to scan probe id: %w", err)
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+// APAssociation is the access point a probe most recently reported.
+type APAssociation struct {
+	ProbeID   string    `json:"probe_id"`
+	BSSID     string    `json:"bssid"`
+	Channel   *int      `json:"channel,omitempty"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// GetLatestAssociations returns each probe's latest BSSID and channel among
+// readings newer than since.
+func (r *TelemetryRepository) GetLatestAssociations(ctx context.Context, since time.Time) ([]APAssociation, error) {
+	query := `
+		SELECT DISTINCT ON (probe_id) probe_id, bssid, channel, timestamp
+		FROM telemetry
+		WHERE timestamp >= $1 AND bssid IS NOT NULL AND bssid <> ''
+		ORDER BY probe_id, timestamp DESC
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, since)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get AP associations: %w", err)
+	}
+	defer rows.Close()
+
+	associations := []APAssociation{}
+	for rows.Next() {
+		var a APAssociation
+		if err := rows.Scan(&a.ProbeID, &a.BSSID, &a.Channel, &a.Timestamp); err != nil {
+			return nil, fmt.Errorf("failed to scan AP association: %w", err)
+		}
+		associations = append(associations, a)
+	}
+
+	return associations, rows.Err()
+}
+
+// Count returns the number of telemetry rows for a probe in [start, end]
+// along with the first and last timestamps seen.
+func (r *TelemetryRepository) Count(ctx context.Context, probeID string, start, end time.Time) (*models.TelemetryCount, error) {
+	query := `
+		SELECT COUNT(*), MIN(timestamp), MAX(timestamp)
+		FROM telemetry
+		WHERE probe_id = $1 AND timestamp BETWEEN $2 AND $3
+	`
+
+	result := &models.TelemetryCount{
+		ProbeID:   probeID,
+		StartTime: start,
+		EndTime:   end,
+	}
+	var first, last sql.NullTime
+
+	if err := r.db.QueryRowContext(ctx, query, probeID, start, end).Scan(&result.Count, &first, &last); err != nil {
+		return nil, fmt.Errorf("failed to count telemetry: %w", err)
+	}
+	if first.Valid {
+		result.First = &first.Time
+	}
+	if last.Valid {
+		result.Last = &last.Time
+	}
+
+	return result, nil
+}
+
+// GetMetricStats computes count, average, min, max and sample standard
+// deviation of one whitelisted telemetry column, returning ErrUnknownMetric
+// for any other metric name.
+func (r *TelemetryRepository) GetMetricStats(ctx context.Context, probeID, metric string, start, end time.Time) (*models.MetricStats, error) {
+	col, ok := metricColumns[metric]
+	if !ok {
+		return nil, fmt.Errorf("%w: %q", ErrUnknownMetric, metric)
+	}
+
+	// col comes from the whitelist above, never from the request.
+	query := fmt.Sprintf(`
+		SELECT COUNT(%[1]s), AVG(%[1]s), MIN(%[1]s), MAX(%[1]s), STDDEV_SAMP(%[1]s)
+		FROM telemetry
+		WHERE probe_id = $1 AND timestamp BETWEEN $2 AND $3
+	`, col)
+
+	stats := &models.MetricStats{
+		ProbeID:   probeID,
+		Metric:    metric,
+		StartTime: start,
+		EndTime:   end,
+	}
+	var avg, min, max, stddev sql.NullFloat64
+
+	err := r.db.QueryRowContext(ctx, query, probeID, start, end).Scan(&stats.Count, &avg, &min, &max, &stddev)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get %s stats: %w", metric, err)
+	}
+	if avg.Valid {
+		stats.Avg = &avg.Float64
+	}
+	if min.Valid {
+		stats.Min = &min.Float64
+	}
+	if max.Valid {
+		stats.Max = &max.Float64
+	}
+	if stddev.Valid {
+		stats.StdDev = &stddev.Float64
+	}
+
+	return stats, nil
+}
+
 func (r *TelemetryRepository) GetStats(ctx context.Context, probeID string, start, end time.Time) (*models.StatsResponse, error) {
 	query := `
 		SELECT 
@@ -480,3 +769,37 @@ func (r *TelemetryRepository) GetHourlyStats(ctx context.Context, probeID string
 
 	return stats, nil
 }
+
+// InsertNeighborAPs stores the access points from one deep scan in a single
+// transaction.
+func (r *TelemetryRepository) InsertNeighborAPs(ctx context.Context, neighbors []models.NeighborAP) error {
+	if len(neighbors) == 0 {
+		return nil
+	}
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.PrepareContext(ctx, `
+		INSERT INTO neighbor_aps (timestamp, seen_by_probe, bssid, ssid, channel, rssi)
+		VALUES ($1, $2, $3, $4, $5, $6)
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to prepare statement: %w", err)
+	}
+	defer stmt.Close()
+
+	for _, n := range neighbors {
+		if _, err := stmt.ExecContext(ctx, n.Timestamp, n.SeenByProbe, n.BSSID, n.SSID, n.Channel, n.RSSI); err != nil {
+			return fmt.Errorf("failed to insert neighbor AP: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
@@ -4,6 +4,7 @@ import (
 	"CampusMonitorAPI/internal/config"
 	"CampusMonitorAPI/internal/handler"
 	"CampusMonitorAPI/internal/logger"
+	"CampusMonitorAPI/internal/metrics"
 	"CampusMonitorAPI/internal/middleware"
 	"CampusMonitorAPI/internal/websocket"
 	"context"
@@ -12,6 +13,7 @@ import (
 	"net/http"
 	"os"
 	"path/filepath"
+	"strings"
 
 	"github.com/gorilla/mux"
 )
@@ -22,17 +24,29 @@ type Server struct {
 	cfg        *config.Config
 	log        *logger.Logger
 	wsHub      *websocket.Hub
+
+	rateLimiter *middleware.RateLimiter
+	apiKeys     *middleware.APIKeys
+	metrics     *metrics.Registry
 }
 
 func New(cfg *config.Config, log *logger.Logger) *Server {
 	router := mux.NewRouter()
-	wsHub := websocket.NewHub(log)
+	wsHub := websocket.NewHub(cfg.WebSocket.BatchWindow, websocket.Limits{
+		MaxMessageSize: cfg.WebSocket.MaxMessageSize,
+		SendBuffer:     cfg.WebSocket.SendBuffer,
+		ReadBuffer:     cfg.WebSocket.ReadBuffer,
+		WriteBuffer:    cfg.WebSocket.WriteBuffer,
+
+		BroadcastBuffer: cfg.WebSocket.BroadcastBuffer,
+	}, log)
 
 	server := &Server{
-		router: router,
-		cfg:    cfg,
-		log:    log,
-		wsHub:  wsHub,
+		router:  router,
+		cfg:     cfg,
+		log:     log,
+		wsHub:   wsHub,
+		metrics: metrics.NewRegistry(),
 		httpServer: &http.Server{
 			Addr:           fmt.Sprintf("%s:%d", cfg.Server.Host, cfg.Server.Port),
 			Handler:        router,
@@ -57,53 +71,141 @@ func (s *Server) RegisterHandlers(
 	scheduleHandler *handler.ScheduleHandler,
 	authHandler *handler.AuthHandler,
 	reportHandler *handler.ReportHandler,
+	reportScheduleHandler *handler.ReportScheduleHandler,
+	configHandler *handler.ConfigHandler,
+	versionHandler *handler.VersionHandler,
+	auditHandler *handler.AuditHandler,
+	auditRecorder middleware.AuditRecorder,
 ) {
 	// Public auth routes (no auth required)
-	s.router.Use(middleware.CORS(s.cfg.Security.CORSAllowedOrigins, s.cfg.Security.CORSAllowedMethods))
+	s.router.Use(middleware.RequestID)
+	if s.cfg.Server.EnableMetrics {
+		s.router.Use(middleware.Metrics(s.metrics))
+	}
+	if s.cfg.Security.EnableSecurityHeaders {
+		s.router.Use(middleware.SecurityHeaders(s.cfg.Security.ContentSecurityPolicy))
+	}
 	s.router.Use(middleware.Recovery(s.log))
+	if s.cfg.Server.EnableGzip {
+		s.router.Use(middleware.Gzip(s.cfg.Server.GzipMinSize))
+	}
+
+	proxies, err := middleware.ParseTrustedProxies(s.cfg.Security.TrustedProxies)
+	if err != nil {
+		s.log.Error("Ignoring TRUSTED_PROXIES: %v", err)
+	}
 
 	healthHandler.RegisterRoutes(s.router)
+	versionHandler.RegisterRoutes(s.router)
+	if s.cfg.Server.EnableMetrics {
+		s.router.Handle("/metrics", s.metrics.Handler()).Methods("GET")
+	}
 
 	authRouter := s.router.PathPrefix("/api/v1/auth").Subrouter()
-	authRouter.Use(middleware.RequestLogger(s.log))
+	authRouter.Use(middleware.RequestLogger(s.log, proxies))
 	authHandler.RegisterRoutes(authRouter)
 
 	api := s.router.PathPrefix("/api/v1").Subrouter()
-	api.Use(middleware.Auth(s.cfg.Auth.JWTSecret))
-	api.Use(middleware.RequestLogger(s.log))
-	if s.cfg.Security.EnableRateLimit {
-		api.Use(middleware.RateLimit(s.cfg.Security.RateLimitPerMinute))
+	// Installed even without keys so keys added on reload take effect.
+	s.apiKeys = middleware.NewAPIKeys(s.cfg.Security.APIKeys)
+	api.Use(middleware.APIKeyAuth(s.cfg.Security.APIKeyHeader, s.apiKeys, middleware.Auth(s.cfg.Auth.JWTSecret)))
+	api.Use(middleware.RequestLogger(s.log, proxies))
+	if s.cfg.Security.EnableAuditLog {
+		api.Use(middleware.AuditLog(auditRecorder, s.log, proxies))
 	}
-
+	api.Use(s.bodyLimit)
 	if s.cfg.Security.EnableRateLimit {
-		api.Use(middleware.RateLimit(s.cfg.Security.RateLimitPerMinute))
+		s.rateLimiter = middleware.NewRateLimiter(s.cfg.Security.RateLimitPerMinute, s.cfg.Security.RateLimitBurst, proxies)
+		s.rateLimiter.SetKeyLimits(s.cfg.Security.APIKeyRateLimits)
+		api.Use(s.rateLimiter.Middleware())
+	}
+	// The WebSocket route lives on the root router, so it isn't subject to this.
+	if s.cfg.Server.RequestTimeout > 0 {
+		api.Use(middleware.Timeout(s.cfg.Server.RequestTimeout))
 	}
 	probeHandler.RegisterRoutes(api)
 	telemetryHandler.RegisterRoutes(api)
 	commandHandler.RegisterRoutes(api)
-	analyticsHandler.RegisterRoutes(api)
+	if s.cfg.Server.AnalyticsCacheTTL > 0 {
+		// A matcher-less subrouter only scopes the middleware; unmatched
+		// requests fall through to the routes registered after it.
+		analytics := api.NewRoute().Subrouter()
+		analytics.Use(middleware.Cache(s.cfg.Server.AnalyticsCacheTTL, s.cfg.Server.AnalyticsCacheSize))
+		analyticsHandler.RegisterRoutes(analytics)
+	} else {
+		analyticsHandler.RegisterRoutes(api)
+	}
 	topologyHandler.RegisterRoutes(api)
 	alertHandler.RegisterRoutes(api)
 	fleetHandler.RegisterRoutes(api)
 	reportHandler.RegisterRoutes(api)
+	reportScheduleHandler.RegisterRoutes(api)
 	scheduleHandler.RegisterRoutes(api)
+	configHandler.RegisterRoutes(api)
+	auditHandler.RegisterRoutes(api)
 	s.router.NotFoundHandler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		w.Header().Set("Access-Control-Allow-Origin", "*")
-		w.Header().Set("Access-Control-Allow-Methods", "GET,POST,PUT,DELETE,OPTIONS,PATCH")
-		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization, X-API-Key")
-		if r.Method == "OPTIONS" {
-			w.WriteHeader(http.StatusNoContent)
-			return
-		}
 		w.WriteHeader(http.StatusNotFound)
 	})
-	s.router.HandleFunc("/api/v1/ws", func(w http.ResponseWriter, r *http.Request) {
-		websocket.ServeWs(s.wsHub, w, r, s.log)
+	// The WebSocket routes accept a token but don't require one, so
+	// anonymous dashboards keep working and only count towards presence.
+	ws := s.router.NewRoute().Subrouter()
+	ws.Use(middleware.OptionalAuth(s.cfg.Auth.JWTSecret))
+	ws.HandleFunc("/api/v1/ws", func(w http.ResponseWriter, r *http.Request) {
+		websocket.ServeWs(s.wsHub, w, r, middleware.Principal(r.Context()), s.log)
 	}).Methods("GET")
+	handler.NewPresenceHandler(s.wsHub).RegisterRoutes(ws)
+
+	s.httpServer.Handler = s.corsHandler(s.router)
 
 	s.log.Info("All handlers and WebSocket endpoint registered")
 }
 
+// bulkRoute matches endpoints that take many items in one request and get
+// MaxBulkBodyBytes instead of MaxBodyBytes.
+var bulkRoute = middleware.PathPrefixes(
+	"/api/v1/alerts/acknowledge",
+	"/api/v1/commands/broadcast",
+	"/api/v1/fleet/commands",
+)
+
+// bodyLimit applies the request body limit for the route.
+func (s *Server) bodyLimit(next http.Handler) http.Handler {
+	normal := middleware.MaxBodyBytes(s.cfg.Server.MaxBodyBytes)(next)
+	bulk := middleware.MaxBodyBytes(s.cfg.Server.MaxBulkBodyBytes)(next)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if bulkRoute(r.URL.Path) {
+			bulk.ServeHTTP(w, r)
+			return
+		}
+		normal.ServeHTTP(w, r)
+	})
+}
+
+// corsHandler wraps h with the configured CORS policy. Command and config
+// routes get CORSSensitiveOrigins when it is set.
+func (s *Server) corsHandler(h http.Handler) http.Handler {
+	sec := s.cfg.Security
+	def := middleware.CORSPolicy{
+		AllowedOrigins:   sec.CORSAllowedOrigins,
+		AllowedMethods:   sec.CORSAllowedMethods,
+		AllowCredentials: sec.CORSAllowCredentials,
+	}
+	if len(sec.CORSSensitiveOrigins) == 0 {
+		return middleware.CORS(def)(h)
+	}
+
+	sensitive := def
+	sensitive.AllowedOrigins = sec.CORSSensitiveOrigins
+	isCommandOrConfig := middleware.PathPrefixes("/api/v1/commands", "/api/v1/fleet/commands", "/api/v1/config", "/api/v1/log/")
+	return middleware.CORS(def, middleware.CORSRule{
+		Match: func(path string) bool {
+			return isCommandOrConfig(path) ||
+				(strings.HasPrefix(path, "/api/v1/probes/") && strings.HasSuffix(path, "/command"))
+		},
+		Policy: sensitive,
+	})(h)
+}
+
 func (s *Server) Start(ctx context.Context) error {
 	go s.wsHub.Run(ctx)
 
@@ -163,3 +265,28 @@ func (s *Server) hasTLSCertificates() bool {
 func (s *Server) GetHub() *websocket.Hub {
 	return s.wsHub
 }
+
+// Metrics returns the registry served on /metrics so other components can
+// register their own gauges.
+func (s *Server) Metrics() *metrics.Registry {
+	return s.metrics
+}
+
+// UpdateRateLimit applies a new per-minute rate, burst and per-API-key rates
+// to the running API limiter. It is a no-op when rate limiting was disabled
+// at startup.
+func (s *Server) UpdateRateLimit(requestsPerMinute, burst int, keyLimits map[string]int) {
+	if s.rateLimiter == nil {
+		return
+	}
+	s.rateLimiter.SetLimit(requestsPerMinute, burst)
+	s.rateLimiter.SetKeyLimits(keyLimits)
+}
+
+// UpdateAPIKeys replaces the static API keys accepted by the running API.
+func (s *Server) UpdateAPIKeys(keys map[string]string) {
+	if s.apiKeys == nil {
+		return
+	}
+	s.apiKeys.Set(keys)
+}
@@ -0,0 +1,93 @@
+package service
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"CampusMonitorAPI/internal/models"
+)
+
+// AlertSummaryMessageType carries the non-critical alerts a probe raised
+// within the coalesce window after its first one.
+const AlertSummaryMessageType = "ALERT_SUMMARY"
+
+// AlertSummary is the ALERT_SUMMARY payload.
+type AlertSummary struct {
+	ProbeID string          `json:"probe_id"`
+	Count   int             `json:"count"`
+	Alerts  []*models.Alert `json:"alerts"`
+}
+
+// alertCoalescer holds back rapid follow-up alerts per probe. The first
+// alert for a probe is sent at once and opens a window; alerts arriving
+// during it are sent together when it closes.
+type alertCoalescer struct {
+	window time.Duration
+	send   func(probeID string, alerts []*models.Alert)
+
+	mu      sync.Mutex
+	windows map[string][]*models.Alert
+}
+
+func newAlertCoalescer(window time.Duration, send func(probeID string, alerts []*models.Alert)) *alertCoalescer {
+	return &alertCoalescer{
+		window:  window,
+		send:    send,
+		windows: make(map[string][]*models.Alert),
+	}
+}
+
+// add reports whether alert may be sent now. If not, it has been queued
+// for the probe's open window.
+func (c *alertCoalescer) add(alert *models.Alert) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if queued, open := c.windows[alert.ProbeID]; open {
+		c.windows[alert.ProbeID] = append(queued, alert)
+		return false
+	}
+	c.windows[alert.ProbeID] = nil
+	time.AfterFunc(c.window, func() { c.close(alert.ProbeID) })
+	return true
+}
+
+func (c *alertCoalescer) close(probeID string) {
+	c.mu.Lock()
+	queued := c.windows[probeID]
+	delete(c.windows, probeID)
+	c.mu.Unlock()
+
+	if len(queued) > 0 {
+		c.send(probeID, queued)
+	}
+}
+
+// EnableAlertCoalescing limits WebSocket alert traffic during storms: after
+// a probe's first non-critical alert, further ones within window are sent
+// as one ALERT_SUMMARY message. Critical alerts are never held back. A
+// non-positive window leaves every alert sent on its own. Call it once,
+// before alerts are dispatched.
+func (s *AlertService) EnableAlertCoalescing(window time.Duration) {
+	if window <= 0 {
+		return
+	}
+	s.coalescer = newAlertCoalescer(window, s.sendCoalesced)
+}
+
+// sendCoalesced broadcasts the alerts held back for a probe, as a plain
+// ALERT when there is only one.
+func (s *AlertService) sendCoalesced(probeID string, alerts []*models.Alert) {
+	// Runs from the coalescing timer, after the dispatching request is gone.
+	building := s.buildingOf(context.Background(), probeID)
+	if len(alerts) == 1 {
+		s.hub.BroadcastToBuilding(building, "ALERT", alerts[0])
+		return
+	}
+	s.hub.BroadcastToBuilding(building, AlertSummaryMessageType, AlertSummary{
+		ProbeID: probeID,
+		Count:   len(alerts),
+		Alerts:  alerts,
+	})
+}
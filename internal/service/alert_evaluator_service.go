@@ -59,10 +59,39 @@ func (w *MetricWindow) IsConsistentlyAbove(threshold float64) bool {
 	return true
 }
 
+// Snapshot copies the window's contents for inspection.
+func (w *MetricWindow) Snapshot() []float64 {
+	return append([]float64{}, w.values...)
+}
+
 // ProbeState tracks the performance windows for a specific probe.
+// lowSignal and highLatency record whether the window was already breached
+// at the previous sample, so a sustained breach raises one alert rather than
+// one per message.
 type ProbeState struct {
 	RSSIWindow    *MetricWindow
 	LatencyWindow *MetricWindow
+	lowSignal     bool
+	highLatency   bool
+	mu            sync.Mutex
+}
+
+// WindowState is a point-in-time view of one metric window and how it
+// currently compares to its alert threshold.
+type WindowState struct {
+	Values       []float64 `json:"values"`
+	Size         int       `json:"size"`
+	Full         bool      `json:"full"`
+	Threshold    float64   `json:"threshold"`
+	Condition    string    `json:"condition"`
+	Consistently bool      `json:"consistently"`
+}
+
+// ProbeStateSnapshot is what the evaluator currently holds for a probe.
+type ProbeStateSnapshot struct {
+	ProbeID string      `json:"probe_id"`
+	RSSI    WindowState `json:"rssi"`
+	Latency WindowState `json:"latency"`
 }
 
 // IAlertEvaluator defines the interface for analyzing telemetry in real-time.
@@ -70,6 +99,7 @@ type IAlertEvaluator interface {
 	Evaluate(ctx context.Context, telemetry models.Telemetry) error
 	UpdateConfig(newCfg models.AlertConfig)
 	ResetProbe(probeID string)
+	GetProbeState(probeID string) (*ProbeStateSnapshot, bool)
 }
 
 type AlertEvaluator struct {
@@ -87,36 +117,53 @@ func NewAlertEvaluator(cfg models.AlertConfig, alertSvc IAlertService) *AlertEva
 	}
 }
 
-// Evaluate processes incoming telemetry through the sliding windows.
+// Evaluate processes incoming telemetry through the sliding windows and
+// raises an alert when a window becomes consistently breached. Metrics the
+// reading doesn't carry leave their window untouched.
 func (e *AlertEvaluator) Evaluate(ctx context.Context, telemetry models.Telemetry) error {
 	e.mu.Lock()
+	cfg := e.config
 	state, exists := e.probeStates[telemetry.ProbeID]
 	if !exists {
 		state = &ProbeState{
-			RSSIWindow:    NewMetricWindow(e.config.RSSIOccurrences),
-			LatencyWindow: NewMetricWindow(e.config.LatencyWindow),
+			RSSIWindow:    NewMetricWindow(cfg.RSSIOccurrences),
+			LatencyWindow: NewMetricWindow(cfg.LatencyWindow),
 		}
 		e.probeStates[telemetry.ProbeID] = state
 	}
 	e.mu.Unlock()
 
-	state.RSSIWindow.Push(float64(*telemetry.RSSI))
-	if state.RSSIWindow.IsConsistentlyBelow(e.config.RSSIThreshold) {
+	var lowSignal, highLatency bool
+	state.mu.Lock()
+	if telemetry.RSSI != nil {
+		state.RSSIWindow.Push(float64(*telemetry.RSSI))
+		breached := state.RSSIWindow.IsConsistentlyBelow(cfg.RSSIThreshold)
+		lowSignal = breached && !state.lowSignal
+		state.lowSignal = breached
+	}
+	if telemetry.Latency != nil {
+		state.LatencyWindow.Push(float64(*telemetry.Latency))
+		breached := state.LatencyWindow.IsConsistentlyAbove(cfg.LatencyThreshold)
+		highLatency = breached && !state.highLatency
+		state.highLatency = breached
+	}
+	state.mu.Unlock()
+
+	if lowSignal {
 		err := e.dispatch(ctx, telemetry, models.CategorySignal, models.SeverityWarning,
-			"rssi", e.config.RSSIThreshold, float64(*telemetry.RSSI),
+			"rssi", cfg.RSSIOccurrences, cfg.RSSIThreshold, float64(*telemetry.RSSI),
 			fmt.Sprintf("Sustained Low Signal: %d consecutive samples below %.0fdBm",
-				e.config.RSSIOccurrences, e.config.RSSIThreshold))
+				cfg.RSSIOccurrences, cfg.RSSIThreshold))
 		if err != nil {
 			return err
 		}
 	}
 
-	state.LatencyWindow.Push(float64(*telemetry.Latency))
-	if state.LatencyWindow.IsConsistentlyAbove(e.config.LatencyThreshold) {
+	if highLatency {
 		err := e.dispatch(ctx, telemetry, models.CategoryNetwork, models.SeverityCritical,
-			"latency", e.config.LatencyThreshold, float64(*telemetry.Latency),
+			"latency", cfg.LatencyWindow, cfg.LatencyThreshold, float64(*telemetry.Latency),
 			fmt.Sprintf("High Network Latency: %d consecutive samples above %.0fms",
-				e.config.LatencyWindow, e.config.LatencyThreshold))
+				cfg.LatencyWindow, cfg.LatencyThreshold))
 		if err != nil {
 			return err
 		}
@@ -126,7 +173,7 @@ func (e *AlertEvaluator) Evaluate(ctx context.Context, telemetry models.Telemetr
 }
 
 // dispatch creates the Alert object and hands it to the AlertService for WS push and storage.
-func (e *AlertEvaluator) dispatch(ctx context.Context, t models.Telemetry, cat, sev, key string, thresh, actual float64, msg string) error {
+func (e *AlertEvaluator) dispatch(ctx context.Context, t models.Telemetry, cat, sev, key string, occurrences int, thresh, actual float64, msg string) error {
 
 	// Because the actual and threshold values are pointers in the struct
 	// we create local variables so we can take their memory addresses.
@@ -136,15 +183,13 @@ func (e *AlertEvaluator) dispatch(ctx context.Context, t models.Telemetry, cat,
 	alert := &models.Alert{
 		ProbeID:        t.ProbeID,
 		AlertType:      key,
+		Category:       cat,
 		Severity:       sev,
 		Message:        msg,
 		ThresholdValue: &thresholdPtr,
 		ActualValue:    &actualPtr,
 		TriggeredAt:    time.Now(),
-		Metadata: map[string]interface{}{
-			"category":    cat,
-			"occurrences": e.config.RSSIOccurrences,
-		},
+		Occurrences:    occurrences,
 	}
 
 	return e.alertService.Dispatch(ctx, alert)
@@ -162,6 +207,44 @@ func (e *AlertEvaluator) UpdateConfig(newCfg models.AlertConfig) {
 	e.config = newCfg
 }
 
+// GetProbeState snapshots the probe's windows against the current
+// thresholds. It reports false when the evaluator has not seen the probe
+// since startup, its last reset or a window size change.
+func (e *AlertEvaluator) GetProbeState(probeID string) (*ProbeStateSnapshot, bool) {
+	e.mu.RLock()
+	state, exists := e.probeStates[probeID]
+	cfg := e.config
+	e.mu.RUnlock()
+	if !exists {
+		return nil, false
+	}
+
+	state.mu.Lock()
+	defer state.mu.Unlock()
+
+	rssi := state.RSSIWindow.Snapshot()
+	latency := state.LatencyWindow.Snapshot()
+	return &ProbeStateSnapshot{
+		ProbeID: probeID,
+		RSSI: WindowState{
+			Values:       rssi,
+			Size:         state.RSSIWindow.size,
+			Full:         len(rssi) >= state.RSSIWindow.size,
+			Threshold:    cfg.RSSIThreshold,
+			Condition:    "below",
+			Consistently: state.RSSIWindow.IsConsistentlyBelow(cfg.RSSIThreshold),
+		},
+		Latency: WindowState{
+			Values:       latency,
+			Size:         state.LatencyWindow.size,
+			Full:         len(latency) >= state.LatencyWindow.size,
+			Threshold:    cfg.LatencyThreshold,
+			Condition:    "above",
+			Consistently: state.LatencyWindow.IsConsistentlyAbove(cfg.LatencyThreshold),
+		},
+	}, true
+}
+
 // ResetProbe clears the in-memory state for a probe (e.g., after maintenance).
 func (e *AlertEvaluator) ResetProbe(probeID string) {
 	e.mu.Lock()
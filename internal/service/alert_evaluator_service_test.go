@@ -0,0 +1,88 @@
+package service
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"CampusMonitorAPI/internal/models"
+)
+
+// recordingAlerts is an IAlertService that only records dispatched alerts.
+type recordingAlerts struct {
+	IAlertService
+	mu     sync.Mutex
+	alerts []*models.Alert
+}
+
+func (r *recordingAlerts) Dispatch(_ context.Context, alert *models.Alert) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.alerts = append(r.alerts, alert)
+	return nil
+}
+
+func (r *recordingAlerts) count() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return len(r.alerts)
+}
+
+func TestEvaluateAlertsOncePerSustainedBreach(t *testing.T) {
+	alerts := &recordingAlerts{}
+	evaluator := NewAlertEvaluator(models.DEFAULT_ALERT_CONFIG, alerts)
+
+	weak, strong := -90, -50
+	for _, rssi := range []*int{&weak, &weak, &weak, &weak, &weak, &strong, &weak, &weak, &weak} {
+		err := evaluator.Evaluate(context.Background(), models.Telemetry{ProbeID: "probe-1", RSSI: rssi})
+		if err != nil {
+			t.Fatalf("Evaluate: %v", err)
+		}
+	}
+
+	if got := alerts.count(); got != 2 {
+		t.Fatalf("dispatched %d alerts, want 2 (one per breach)", got)
+	}
+}
+
+func TestEvaluateSkipsMissingMetrics(t *testing.T) {
+	alerts := &recordingAlerts{}
+	evaluator := NewAlertEvaluator(models.DEFAULT_ALERT_CONFIG, alerts)
+
+	if err := evaluator.Evaluate(context.Background(), models.Telemetry{ProbeID: "probe-1"}); err != nil {
+		t.Fatalf("Evaluate: %v", err)
+	}
+	state, ok := evaluator.GetProbeState("probe-1")
+	if !ok {
+		t.Fatal("no state after Evaluate")
+	}
+	if len(state.RSSI.Values) != 0 || len(state.Latency.Values) != 0 {
+		t.Errorf("windows = %v, %v, want empty", state.RSSI.Values, state.Latency.Values)
+	}
+}
+
+// TestEvaluateConcurrentWithUpdateConfig is meant for go test -race: a
+// SIGHUP reload calls UpdateConfig while telemetry is being evaluated.
+func TestEvaluateConcurrentWithUpdateConfig(t *testing.T) {
+	evaluator := NewAlertEvaluator(models.DEFAULT_ALERT_CONFIG, &recordingAlerts{})
+
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		rssi, latency := -90, 800
+		for i := 0; i < 200; i++ {
+			_ = evaluator.Evaluate(context.Background(), models.Telemetry{ProbeID: "probe-1", RSSI: &rssi, Latency: &latency})
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		cfg := models.DEFAULT_ALERT_CONFIG
+		for i := 0; i < 200; i++ {
+			cfg.RSSIThreshold = float64(-80 - i%10)
+			cfg.LatencyThreshold = float64(400 + i)
+			evaluator.UpdateConfig(cfg)
+		}
+	}()
+	wg.Wait()
+}
@@ -0,0 +1,128 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"CampusMonitorAPI/internal/models"
+)
+
+const (
+	// recurringPatternThreshold is the share of days (or weeks) a slot must
+	// alert in to count as recurring.
+	recurringPatternThreshold = 0.5
+
+	// minPatternSlots keeps short windows from flagging a coincidence: a
+	// slot must have alerted on at least this many separate days.
+	minPatternSlots = 3
+)
+
+type patternKey struct {
+	alertType string
+	weekday   time.Weekday
+	hour      int
+}
+
+type patternSlot struct {
+	occurrences int
+	dates       map[string]bool
+}
+
+// DetectRecurringPatterns groups a probe's alerts over the last days by
+// hour of day and by day of week and hour, and returns the slots that
+// alerted on at least recurringPatternThreshold of the available days or
+// weeks. A daily pattern is not repeated as seven weekly ones. Times are
+// bucketed in the server's local time zone, since that is what daily
+// routines on campus follow.
+func (s *AlertService) DetectRecurringPatterns(ctx context.Context, probeID string, days int) (*models.RecurringPatternReport, error) {
+	now := time.Now()
+	since := now.AddDate(0, 0, -days)
+
+	alerts, err := s.repo.GetProbeHistory(ctx, probeID, since)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load alert history: %w", err)
+	}
+
+	hourly := make(map[patternKey]*patternSlot)
+	weekly := make(map[patternKey]*patternSlot)
+	for _, a := range alerts {
+		t := a.TriggeredAt.Local()
+		date := t.Format("2006-01-02")
+		addPatternHit(hourly, patternKey{alertType: a.AlertType, hour: t.Hour()}, date)
+		addPatternHit(weekly, patternKey{alertType: a.AlertType, weekday: t.Weekday(), hour: t.Hour()}, date)
+	}
+
+	// How many of each weekday fall in the window, for the weekly frequency.
+	weekdays := make(map[time.Weekday]int)
+	for d := since.Local(); !d.After(now); d = d.AddDate(0, 0, 1) {
+		weekdays[d.Weekday()]++
+	}
+
+	report := &models.RecurringPatternReport{
+		ProbeID:     probeID,
+		Days:        days,
+		Threshold:   recurringPatternThreshold,
+		TotalAlerts: len(alerts),
+		Patterns:    []models.AlertPattern{},
+	}
+
+	daily := make(map[patternKey]bool)
+	for key, slot := range hourly {
+		if p, ok := recurringPattern(key, slot, days); ok {
+			p.Kind = models.PatternHourOfDay
+			report.Patterns = append(report.Patterns, p)
+			daily[key] = true
+		}
+	}
+	for key, slot := range weekly {
+		if daily[patternKey{alertType: key.alertType, hour: key.hour}] {
+			continue
+		}
+		if p, ok := recurringPattern(key, slot, weekdays[key.weekday]); ok {
+			p.Kind = models.PatternDayOfWeekHour
+			p.DayOfWeek = key.weekday.String()
+			report.Patterns = append(report.Patterns, p)
+		}
+	}
+
+	sort.Slice(report.Patterns, func(i, j int) bool {
+		pi, pj := report.Patterns[i], report.Patterns[j]
+		if pi.Frequency != pj.Frequency {
+			return pi.Frequency > pj.Frequency
+		}
+		return pi.Occurrences > pj.Occurrences
+	})
+
+	return report, nil
+}
+
+func addPatternHit(slots map[patternKey]*patternSlot, key patternKey, date string) {
+	slot, ok := slots[key]
+	if !ok {
+		slot = &patternSlot{dates: make(map[string]bool)}
+		slots[key] = slot
+	}
+	slot.occurrences++
+	slot.dates[date] = true
+}
+
+func recurringPattern(key patternKey, slot *patternSlot, totalSlots int) (models.AlertPattern, bool) {
+	active := len(slot.dates)
+	if totalSlots <= 0 || active < minPatternSlots {
+		return models.AlertPattern{}, false
+	}
+	frequency := float64(active) / float64(totalSlots)
+	if frequency < recurringPatternThreshold {
+		return models.AlertPattern{}, false
+	}
+	return models.AlertPattern{
+		AlertType:   key.alertType,
+		Hour:        key.hour,
+		Occurrences: slot.occurrences,
+		ActiveSlots: active,
+		TotalSlots:  totalSlots,
+		Frequency:   frequency,
+	}, true
+}
@@ -2,7 +2,10 @@ package service
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"strings"
+	"sync"
 	"time"
 
 	"CampusMonitorAPI/internal/models"
@@ -14,32 +17,72 @@ import (
 type IAlertService interface {
 	Dispatch(ctx context.Context, alert *models.Alert) error
 	Acknowledge(ctx context.Context, id uint) error
-	Resolve(ctx context.Context, id uint) error
+	AcknowledgeBulk(ctx context.Context, req models.BulkAcknowledgeRequest, user string) (int64, error)
+	Resolve(ctx context.Context, id uint, acknowledgedBy string) error
+	Reopen(ctx context.Context, id uint) error
 	DeleteAlert(ctx context.Context, id uint) error
 	GetActiveAlerts(ctx context.Context) ([]models.Alert, error)
 	GetProbeAlerts(ctx context.Context, probeID string) ([]models.Alert, error)
 	GetAlertHistory(ctx context.Context, limit, offset int) ([]models.Alert, error)
+	GetStatistics(ctx context.Context) (map[string]int, error)
+	GetSummary(ctx context.Context) (*models.AlertStatistics, error)
 	SendTestAlert(ctx context.Context) error
+	AddComment(ctx context.Context, alertID uint, author, body string) (*models.AlertComment, error)
+	GetComments(ctx context.Context, alertID uint) ([]models.AlertComment, error)
+	DetectRecurringPatterns(ctx context.Context, probeID string, days int) (*models.RecurringPatternReport, error)
 }
 
 type AlertService struct {
-	repo repository.IAlertRepository
-	hub  *websocket.Hub
+	repo      repository.IAlertRepository
+	probeRepo *repository.ProbeRepository
+	hub       *websocket.Hub
+	// coalescer, if set, batches rapid non-critical alerts per probe.
+	coalescer *alertCoalescer
+
+	locationsMu sync.Mutex
+	locations   map[string]probeLocation
+}
+
+// probeLocationTTL is how long a probe's looked-up location is reused, so a
+// burst of alerts costs one probe query while a moved probe is picked up
+// soon after. A failed lookup is remembered for probeLocationMissTTL, so a
+// database outage doesn't add a lookup timeout to every alert.
+const (
+	probeLocationTTL     = 5 * time.Minute
+	probeLocationMissTTL = 30 * time.Second
+)
+
+type probeLocation struct {
+	building  string
+	floor     string
+	location  string
+	missing   bool
+	fetchedAt time.Time
 }
 
-func NewAlertService(repo repository.IAlertRepository, hub *websocket.Hub) *AlertService {
+func NewAlertService(repo repository.IAlertRepository, probeRepo *repository.ProbeRepository, hub *websocket.Hub) *AlertService {
 	return &AlertService{
-		repo: repo,
-		hub:  hub,
+		repo:      repo,
+		probeRepo: probeRepo,
+		hub:       hub,
+		locations: make(map[string]probeLocation),
 	}
 }
 
+// ErrInvalidAlert is returned by Dispatch for alerts whose severity,
+// category or status is not one of the model constants.
+var ErrInvalidAlert = errors.New("invalid alert")
+
 func (s *AlertService) Dispatch(ctx context.Context, alert *models.Alert) error {
+	if err := validateAlert(alert); err != nil {
+		return err
+	}
+	s.addLocation(ctx, alert)
 	err := s.repo.Create(ctx, alert)
 	if err != nil {
 		return fmt.Errorf("failed to persist alert history: %w", err)
 	}
-	s.notify(alert)
+	s.notify(ctx, alert)
 	return nil
 }
 
@@ -47,8 +90,70 @@ func (s *AlertService) Acknowledge(ctx context.Context, id uint) error {
 	return s.repo.Acknowledge(ctx, id)
 }
 
-func (s *AlertService) Resolve(ctx context.Context, id uint) error {
-	return s.repo.Resolve(ctx, id)
+// ErrEmptyAlertFilter is returned when a bulk acknowledge names no alerts,
+// which would otherwise acknowledge every active alert.
+var ErrEmptyAlertFilter = errors.New("ids, probe_id or severity is required")
+
+// AcknowledgeBulk acknowledges all active alerts matching req on behalf of
+// user and returns how many changed.
+func (s *AlertService) AcknowledgeBulk(ctx context.Context, req models.BulkAcknowledgeRequest, user string) (int64, error) {
+	if len(req.IDs) == 0 && req.ProbeID == "" && req.Severity == "" {
+		return 0, ErrEmptyAlertFilter
+	}
+	return s.repo.AcknowledgeBulk(ctx, req, user)
+}
+
+// ErrInvalidComment wraps validation failures for alert comments.
+var ErrInvalidComment = errors.New("invalid comment")
+
+// maxCommentLength caps a single comment so the thread stays readable.
+const maxCommentLength = 4000
+
+// AddComment appends author's comment to an alert's discussion trail.
+// Comments can be added to resolved alerts too.
+func (s *AlertService) AddComment(ctx context.Context, alertID uint, author, body string) (*models.AlertComment, error) {
+	body = strings.TrimSpace(body)
+	if body == "" {
+		return nil, fmt.Errorf("%w: body is required", ErrInvalidComment)
+	}
+	if len(body) > maxCommentLength {
+		return nil, fmt.Errorf("%w: body must be at most %d characters", ErrInvalidComment, maxCommentLength)
+	}
+
+	comment := &models.AlertComment{
+		AlertID: int(alertID),
+		Author:  author,
+		Body:    body,
+	}
+	if err := s.repo.AddComment(ctx, comment); err != nil {
+		return nil, err
+	}
+	return comment, nil
+}
+
+func (s *AlertService) GetComments(ctx context.Context, alertID uint) ([]models.AlertComment, error) {
+	return s.repo.GetComments(ctx, alertID)
+}
+
+// Resolve resolves an active alert. A non-empty acknowledgedBy also
+// acknowledges it on that user's behalf if nobody had.
+func (s *AlertService) Resolve(ctx context.Context, id uint, acknowledgedBy string) error {
+	return s.repo.Resolve(ctx, id, acknowledgedBy)
+}
+
+// Reopen marks a resolved alert active again and re-broadcasts it so
+// dashboards show it alongside the other active alerts.
+func (s *AlertService) Reopen(ctx context.Context, id uint) error {
+	if err := s.repo.Reopen(ctx, id); err != nil {
+		return err
+	}
+
+	alert, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		return fmt.Errorf("failed to load reopened alert: %w", err)
+	}
+	s.notify(ctx, alert)
+	return nil
 }
 
 func (s *AlertService) DeleteAlert(ctx context.Context, id uint) error {
@@ -68,10 +173,127 @@ func (s *AlertService) GetAlertHistory(ctx context.Context, limit, offset int) (
 	return s.repo.GetHistory(ctx, limit, offset)
 }
 
-func (s *AlertService) notify(alert *models.Alert) {
-	if s.hub != nil {
-		s.hub.Broadcast("ALERT", alert)
+// validateAlert rejects alerts that would be stored with a typo'd severity,
+// category or status and then never match the filters on them. Status is
+// derived from resolved_at and acknowledged, so it is only checked when a
+// caller sets one.
+func validateAlert(alert *models.Alert) error {
+	if alert.ProbeID == "" {
+		return fmt.Errorf("%w: probe_id is required", ErrInvalidAlert)
+	}
+	if alert.AlertType == "" {
+		return fmt.Errorf("%w: alert_type is required", ErrInvalidAlert)
+	}
+	if !models.ValidSeverity(alert.Severity) {
+		return fmt.Errorf("%w: unknown severity %q", ErrInvalidAlert, alert.Severity)
+	}
+	if alert.Category != "" && !models.ValidCategory(alert.Category) {
+		return fmt.Errorf("%w: unknown category %q", ErrInvalidAlert, alert.Category)
+	}
+	if alert.Status != "" && !models.ValidStatus(alert.Status) {
+		return fmt.Errorf("%w: unknown status %q", ErrInvalidAlert, alert.Status)
+	}
+	return nil
+}
+
+// GetStatistics returns the number of unresolved alerts per severity.
+func (s *AlertService) GetStatistics(ctx context.Context) (map[string]int, error) {
+	return s.repo.GetStatistics(ctx)
+}
+
+// GetSummary returns the active-alert breakdown together with the number of
+// alerts resolved and acknowledged over the last 24 hours.
+func (s *AlertService) GetSummary(ctx context.Context) (*models.AlertStatistics, error) {
+	return s.repo.GetSummary(ctx, time.Now().Add(-24*time.Hour))
+}
+
+// notify broadcasts the alert tagged with its probe's building so
+// building-scoped WebSocket clients receive it. Critical alerts skip the
+// hub's batch window and alert coalescing.
+func (s *AlertService) notify(ctx context.Context, alert *models.Alert) {
+	if s.hub == nil {
+		return
+	}
+	if alert.Severity == models.SeverityCritical {
+		s.hub.BroadcastImmediate(s.buildingOf(ctx, alert.ProbeID), "ALERT", alert)
+		return
+	}
+	if s.coalescer != nil && !s.coalescer.add(alert) {
+		return
 	}
+	s.hub.BroadcastToBuilding(s.buildingOf(ctx, alert.ProbeID), "ALERT", alert)
+}
+
+// buildingOf resolves a probe's building, or "" if it can't be found.
+func (s *AlertService) buildingOf(ctx context.Context, probeID string) string {
+	loc, _ := s.locationOf(ctx, probeID)
+	return loc.building
+}
+
+// addLocation records the probe's building, floor and location in the
+// alert's metadata, so alert lists and exports don't need a probe lookup
+// and old alerts keep showing where the probe was at the time. Values the
+// alert already carries are kept.
+func (s *AlertService) addLocation(ctx context.Context, alert *models.Alert) {
+	loc, ok := s.locationOf(ctx, alert.ProbeID)
+	if !ok {
+		return
+	}
+	if alert.Metadata == nil {
+		alert.Metadata = make(map[string]interface{})
+	}
+	for key, value := range map[string]string{
+		"building": loc.building,
+		"floor":    loc.floor,
+		"location": loc.location,
+	} {
+		if _, set := alert.Metadata[key]; !set && value != "" {
+			alert.Metadata[key] = value
+		}
+	}
+}
+
+// locationOf returns a probe's location, from the cache when it was looked
+// up within probeLocationTTL. ok is false if the probe can't be found. The
+// lookup is bounded by ctx and at most 2s.
+func (s *AlertService) locationOf(ctx context.Context, probeID string) (probeLocation, bool) {
+	if s.probeRepo == nil || probeID == "" {
+		return probeLocation{}, false
+	}
+
+	s.locationsMu.Lock()
+	loc, ok := s.locations[probeID]
+	s.locationsMu.Unlock()
+	if ok && loc.missing && time.Since(loc.fetchedAt) < probeLocationMissTTL {
+		return probeLocation{}, false
+	}
+	if ok && !loc.missing && time.Since(loc.fetchedAt) < probeLocationTTL {
+		return loc, true
+	}
+
+	lookupCtx, cancel := context.WithTimeout(ctx, 2*time.Second)
+	defer cancel()
+
+	probe, err := s.probeRepo.GetByID(lookupCtx, probeID)
+	if err != nil && ctx.Err() != nil {
+		// The caller gave up; that says nothing about the probe.
+		return probeLocation{}, false
+	}
+	if err != nil {
+		loc = probeLocation{missing: true, fetchedAt: time.Now()}
+	} else {
+		loc = probeLocation{
+			building:  probe.Building,
+			floor:     probe.Floor,
+			location:  probe.Location,
+			fetchedAt: time.Now(),
+		}
+	}
+
+	s.locationsMu.Lock()
+	s.locations[probeID] = loc
+	s.locationsMu.Unlock()
+	return loc, !loc.missing
 }
 
 func (s *AlertService) CleanUpTask(ctx context.Context) {
@@ -87,11 +309,12 @@ func (s *AlertService) SendTestAlert(ctx context.Context) error {
 		ID:          int(time.Now().UnixNano() / 1e6),
 		ProbeID:     "TEST-PROBE-01",
 		AlertType:   "SYSTEM",
+		Category:    models.CategorySystem,
 		Severity:    "INFO",
 		Message:     "Simulation: This is a test alert to verify real-time notifications.",
 		TriggeredAt: time.Now(),
 	}
 
-	s.notify(testAlert)
+	s.notify(ctx, testAlert)
 	return nil
 }
@@ -0,0 +1,183 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"CampusMonitorAPI/internal/models"
+	"CampusMonitorAPI/internal/repository"
+)
+
+// alertStore stands in for the alerts table. It keeps inserted rows with
+// Postgres' NULLIF/COALESCE applied and answers the single-alert lookup.
+type alertStore struct {
+	mu   sync.Mutex
+	rows [][]driver.Value
+}
+
+// alertStoreConnector opens connections to an alertStore.
+type alertStoreConnector struct{ store *alertStore }
+
+func (c alertStoreConnector) Connect(context.Context) (driver.Conn, error) {
+	return alertStoreConn(c), nil
+}
+func (c alertStoreConnector) Driver() driver.Driver { return c }
+func (c alertStoreConnector) Open(string) (driver.Conn, error) {
+	return alertStoreConn(c), nil
+}
+
+type alertStoreConn struct{ store *alertStore }
+
+func (c alertStoreConn) Prepare(string) (driver.Stmt, error) {
+	return nil, errors.New("prepare not supported")
+}
+func (c alertStoreConn) Close() error { return nil }
+func (c alertStoreConn) Begin() (driver.Tx, error) {
+	return nil, errors.New("transactions not supported")
+}
+
+func (c alertStoreConn) QueryContext(_ context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	c.store.mu.Lock()
+	defer c.store.mu.Unlock()
+
+	switch {
+	case strings.Contains(query, "INSERT INTO alerts"):
+		v := make([]driver.Value, len(args))
+		for i, arg := range args {
+			v[i] = arg.Value
+		}
+		// probe_id, alert_type, category, severity, message, threshold_value,
+		// actual_value, triggered_at, resolved_at, acknowledged, occurrences,
+		// metadata.
+		if v[2] == "" {
+			v[2] = nil
+		}
+		if v[10] == int64(0) {
+			v[10] = nil
+		}
+		id := int64(len(c.store.rows) + 1)
+		c.store.rows = append(c.store.rows, append([]driver.Value{id}, v...))
+		return &alertRows{
+			columns: []string{"id", "triggered_at"},
+			rows:    [][]driver.Value{{id, v[7]}},
+		}, nil
+
+	case strings.Contains(query, "FROM alerts") && strings.Contains(query, "WHERE id = $1"):
+		rows := &alertRows{columns: []string{
+			"id", "probe_id", "alert_type", "category", "severity", "message",
+			"threshold_value", "actual_value", "triggered_at",
+			"resolved_at", "acknowledged", "occurrences", "metadata",
+		}}
+		id := args[0].Value.(int64)
+		if id >= 1 && int(id) <= len(c.store.rows) {
+			rows.rows = append(rows.rows, c.store.rows[id-1])
+		}
+		return rows, nil
+	}
+	return nil, errors.New("unexpected statement")
+}
+
+type alertRows struct {
+	columns []string
+	rows    [][]driver.Value
+}
+
+func (r *alertRows) Columns() []string { return r.columns }
+func (r *alertRows) Close() error      { return nil }
+func (r *alertRows) Next(dest []driver.Value) error {
+	if len(r.rows) == 0 {
+		return io.EOF
+	}
+	copy(dest, r.rows[0])
+	r.rows = r.rows[1:]
+	return nil
+}
+
+func TestDispatchedAlertReadsBackWithCategory(t *testing.T) {
+	db := sql.OpenDB(alertStoreConnector{store: &alertStore{}})
+	defer db.Close()
+	repo := repository.NewAlertRepository(db)
+
+	cfg := models.DEFAULT_ALERT_CONFIG
+	evaluator := NewAlertEvaluator(cfg, NewAlertService(repo, nil, nil))
+
+	rssi, latency := -90, 20
+	for i := 0; i < cfg.RSSIOccurrences; i++ {
+		err := evaluator.Evaluate(context.Background(), models.Telemetry{
+			ProbeID: "probe-1",
+			RSSI:    &rssi,
+			Latency: &latency,
+		})
+		if err != nil {
+			t.Fatalf("Evaluate: %v", err)
+		}
+	}
+
+	got, err := repo.GetByID(context.Background(), 1)
+	if err != nil {
+		t.Fatalf("GetByID: %v", err)
+	}
+
+	if got.ProbeID != "probe-1" || got.AlertType != "rssi" {
+		t.Errorf("probe_id, alert_type = %q, %q, want probe-1, rssi", got.ProbeID, got.AlertType)
+	}
+	if got.Category != models.CategorySignal {
+		t.Errorf("category = %q, want %q", got.Category, models.CategorySignal)
+	}
+	if got.Severity != models.SeverityWarning {
+		t.Errorf("severity = %q, want %q", got.Severity, models.SeverityWarning)
+	}
+	if got.Occurrences != cfg.RSSIOccurrences {
+		t.Errorf("occurrences = %d, want %d", got.Occurrences, cfg.RSSIOccurrences)
+	}
+	if got.ThresholdValue == nil || *got.ThresholdValue != cfg.RSSIThreshold {
+		t.Errorf("threshold_value = %v, want %v", got.ThresholdValue, cfg.RSSIThreshold)
+	}
+	if got.ActualValue == nil || *got.ActualValue != float64(rssi) {
+		t.Errorf("actual_value = %v, want %d", got.ActualValue, rssi)
+	}
+	if got.Status != models.StatusActive {
+		t.Errorf("status = %q, want %q", got.Status, models.StatusActive)
+	}
+	if _, ok := got.Metadata["occurrences"]; ok {
+		t.Errorf("metadata still carries occurrences: %v", got.Metadata)
+	}
+	if time.Since(got.TriggeredAt) > time.Minute {
+		t.Errorf("triggered_at = %v, want about now", got.TriggeredAt)
+	}
+}
+
+func TestLocationOfCachesMisses(t *testing.T) {
+	store := &ingestStore{probes: map[string]bool{}}
+	db := sql.OpenDB(ingestConnector{store: store})
+	defer db.Close()
+	svc := NewAlertService(nil, repository.NewProbeRepository(db), nil)
+
+	for i := 0; i < 3; i++ {
+		if _, ok := svc.locationOf(context.Background(), "probe-gone"); ok {
+			t.Fatal("found a probe that does not exist")
+		}
+	}
+	if store.queries != 1 {
+		t.Errorf("%d probe lookups, want 1 while the miss is cached", store.queries)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, ok := svc.locationOf(ctx, "probe-other"); ok {
+		t.Fatal("found a probe that does not exist")
+	}
+	svc.locationsMu.Lock()
+	_, cached := svc.locations["probe-other"]
+	svc.locationsMu.Unlock()
+	if cached {
+		t.Error("a lookup the caller cancelled was cached as a miss")
+	}
+}
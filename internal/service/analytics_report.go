@@ -0,0 +1,93 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"CampusMonitorAPI/internal/repository"
+)
+
+// AnalyticsReport is a point-in-time snapshot of the analytics endpoints for
+// one time range, meant to be archived as-is.
+type AnalyticsReport struct {
+	GeneratedAt         time.Time                        `json:"generated_at"`
+	Start               time.Time                        `json:"start"`
+	End                 time.Time                        `json:"end"`
+	NetworkHealth       *repository.NetworkHealth        `json:"network_health"`
+	Performance         *repository.PerformanceMetrics   `json:"performance"`
+	ChannelDistribution []repository.ChannelDistribution `json:"channel_distribution"`
+	WorstProbes         []repository.ProbeStability      `json:"worst_probes"`
+	ActiveAlerts        map[string]int                   `json:"active_alerts"`
+}
+
+// BuildReport runs the report's queries concurrently and merges them. The
+// first failing section fails the whole report so an archived snapshot is
+// never silently incomplete.
+func (s *AnalyticsService) BuildReport(ctx context.Context, start, end time.Time, topN int) (*AnalyticsReport, error) {
+	s.log.Debug("Building analytics report: %s to %s, top=%d", start.Format(time.RFC3339), end.Format(time.RFC3339), topN)
+
+	report := &AnalyticsReport{
+		GeneratedAt: time.Now(),
+		Start:       start,
+		End:         end,
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		wg       sync.WaitGroup
+		mu       sync.Mutex
+		firstErr error
+	)
+	run := func(section string, fn func() error) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := fn(); err != nil {
+				mu.Lock()
+				if firstErr == nil {
+					firstErr = fmt.Errorf("failed to build %s section: %w", section, err)
+					cancel()
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+
+	run("network health", func() (err error) {
+		report.NetworkHealth, err = s.GetNetworkHealth(ctx, 0)
+		return err
+	})
+	run("performance", func() (err error) {
+		report.Performance, err = s.GetPerformanceMetrics(ctx, "", start, end, nil)
+		return err
+	})
+	run("channel distribution", func() (err error) {
+		report.ChannelDistribution, err = s.GetChannelDistribution(ctx, start, end)
+		return err
+	})
+	run("worst probes", func() error {
+		ranking, err := s.GetProbeStabilityRanking(ctx, start, end)
+		if err != nil {
+			return err
+		}
+		if len(ranking) > topN {
+			ranking = ranking[:topN]
+		}
+		report.WorstProbes = ranking
+		return nil
+	})
+	run("active alerts", func() (err error) {
+		report.ActiveAlerts, err = s.alertRepo.GetStatistics(ctx)
+		return err
+	})
+
+	wg.Wait()
+	if firstErr != nil {
+		return nil, firstErr
+	}
+	return report, nil
+}
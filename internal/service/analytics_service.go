@@ -3,6 +3,8 @@ package service
 import (
 	"CampusMonitorAPI/internal/models"
 	"context"
+	"errors"
+	"fmt"
 	"time"
 
 	"CampusMonitorAPI/internal/logger"
@@ -11,19 +13,62 @@ import (
 
 type AnalyticsService struct {
 	analyticsRepo *repository.AnalyticsRepository
+	alertRepo     repository.IAlertRepository
+	probeRepo     *repository.ProbeRepository
 	log           *logger.Logger
 }
 
 func NewAnalyticsService(
 	analyticsRepo *repository.AnalyticsRepository,
+	alertRepo repository.IAlertRepository,
+	probeRepo *repository.ProbeRepository,
 	log *logger.Logger,
 ) *AnalyticsService {
 	return &AnalyticsService{
 		analyticsRepo: analyticsRepo,
+		alertRepo:     alertRepo,
+		probeRepo:     probeRepo,
 		log:           log,
 	}
 }
 
+// downsampleSteps are the bucket widths DownsampleInterval picks from, so
+// charts get round intervals instead of e.g. 7m13s.
+var downsampleSteps = []time.Duration{
+	30 * time.Second, time.Minute, 2 * time.Minute, 5 * time.Minute,
+	10 * time.Minute, 15 * time.Minute, 30 * time.Minute, time.Hour,
+	2 * time.Hour, 3 * time.Hour, 6 * time.Hour, 12 * time.Hour,
+	24 * time.Hour, 48 * time.Hour, 7 * 24 * time.Hour,
+}
+
+// DownsampleInterval returns the smallest time_bucket interval that keeps a
+// series between start and end at or under maxPoints buckets, formatted for
+// Postgres (e.g. "900 seconds").
+func DownsampleInterval(start, end time.Time, maxPoints int) string {
+	span := end.Sub(start)
+	if maxPoints < 1 || span <= 0 {
+		return "5 minutes"
+	}
+
+	minStep := span / time.Duration(maxPoints)
+	if span%time.Duration(maxPoints) != 0 {
+		minStep++
+	}
+	step := downsampleSteps[len(downsampleSteps)-1]
+	for _, candidate := range downsampleSteps {
+		if candidate >= minStep {
+			step = candidate
+			break
+		}
+	}
+	if step < minStep {
+		// Longer than a week per point: round up to whole days.
+		day := 24 * time.Hour
+		step = (minStep + day - 1) / day * day
+	}
+	return fmt.Sprintf("%d seconds", int64(step/time.Second))
+}
+
 func (s *AnalyticsService) GetRSSITimeSeries(ctx context.Context, probeID string, start, end time.Time, interval string) ([]repository.TimeSeriesPoint, error) {
 	s.log.Debug("Getting RSSI time series: probe=%s, interval=%s", probeID, interval)
 	return s.analyticsRepo.GetRSSITimeSeries(ctx, probeID, start, end, interval)
@@ -36,9 +81,27 @@ func (s *AnalyticsService) GetLatencyTimeSeries(ctx context.Context, probeID str
 func (s *AnalyticsService) GetDailyCoverage(ctx context.Context, probeID string, start, end time.Time) ([]models.DailyCoverage, error) {
 	return s.analyticsRepo.GetDailyCoverage(ctx, probeID, start, end)
 }
-func (s *AnalyticsService) GetHeatmapData(ctx context.Context, start, end time.Time) ([]repository.HeatmapData, error) {
-	s.log.Debug("Getting heatmap data")
-	return s.analyticsRepo.GetHeatmapData(ctx, start, end)
+func (s *AnalyticsService) GetCoverageGaps(ctx context.Context, start, end time.Time, rssiThreshold float64, interval string, historical bool) ([]repository.CoverageGap, error) {
+	s.log.Debug("Getting coverage gaps: threshold=%.1f, interval=%s, historical=%t", rssiThreshold, interval, historical)
+	return s.analyticsRepo.GetCoverageGaps(ctx, start, end, rssiThreshold, interval, historical)
+}
+func (s *AnalyticsService) GetProbeStabilityRanking(ctx context.Context, start, end time.Time) ([]repository.ProbeStability, error) {
+	s.log.Debug("Getting probe stability ranking")
+	return s.analyticsRepo.GetProbeStabilityRanking(ctx, start, end)
+}
+
+func (s *AnalyticsService) GetIngestLatency(ctx context.Context, probeID string, start, end time.Time) ([]repository.IngestLatency, error) {
+	s.log.Debug("Getting ingest latency: probe=%s", probeID)
+	return s.analyticsRepo.GetIngestLatency(ctx, probeID, start, end)
+}
+
+func (s *AnalyticsService) GetHourlyUsageProfile(ctx context.Context, probeID string, days int) (*repository.UsageProfile, error) {
+	s.log.Debug("Getting hourly usage profile: probe=%s, days=%d", probeID, days)
+	return s.analyticsRepo.GetHourlyUsageProfile(ctx, probeID, days)
+}
+func (s *AnalyticsService) GetHeatmapData(ctx context.Context, start, end time.Time, historical bool) ([]repository.HeatmapData, error) {
+	s.log.Debug("Getting heatmap data: historical=%t", historical)
+	return s.analyticsRepo.GetHeatmapData(ctx, start, end, historical)
 }
 
 func (s *AnalyticsService) GetChannelDistribution(ctx context.Context, start, end time.Time) ([]repository.ChannelDistribution, error) {
@@ -51,14 +114,57 @@ func (s *AnalyticsService) GetAPAnalysis(ctx context.Context, start, end time.Ti
 	return s.analyticsRepo.GetAPAnalysis(ctx, start, end)
 }
 
+func (s *AnalyticsService) GetMetricCorrelation(ctx context.Context, probeID, metricA, metricB string, start, end time.Time) (*repository.MetricCorrelation, error) {
+	s.log.Debug("Getting metric correlation: probe=%s, %s vs %s", probeID, metricA, metricB)
+	return s.analyticsRepo.GetMetricCorrelation(ctx, probeID, metricA, metricB, start, end)
+}
+
+func (s *AnalyticsService) GetNeighborAPs(ctx context.Context, start, end time.Time, probeID string) ([]repository.NeighborAPSummary, error) {
+	s.log.Debug("Getting neighbor APs: probe=%s", probeID)
+	return s.analyticsRepo.GetNeighborAPs(ctx, start, end, probeID)
+}
+
 func (s *AnalyticsService) GetCongestionAnalysis(ctx context.Context, start, end time.Time) ([]repository.CongestionAnalysis, error) {
 	s.log.Debug("Getting congestion analysis")
 	return s.analyticsRepo.GetCongestionAnalysis(ctx, start, end)
 }
 
-func (s *AnalyticsService) GetPerformanceMetrics(ctx context.Context, probeID string, start, end time.Time) (*repository.PerformanceMetrics, error) {
-	s.log.Debug("Getting performance metrics: probe=%s", probeID)
-	return s.analyticsRepo.GetPerformanceMetrics(ctx, probeID, start, end)
+func (s *AnalyticsService) GetPerformanceMetrics(ctx context.Context, probeID string, start, end time.Time, percentiles []float64) (*repository.PerformanceMetrics, error) {
+	s.log.Debug("Getting performance metrics: probe=%s, percentiles=%v", probeID, percentiles)
+	return s.analyticsRepo.GetPerformanceMetrics(ctx, probeID, start, end, percentiles)
+}
+
+// ErrNoFloorProbes is returned when no probe is assigned to the floor.
+var ErrNoFloorProbes = errors.New("no probes on this floor")
+
+// GetFloorPerformance aggregates performance metrics over every probe
+// assigned to building and floor.
+func (s *AnalyticsService) GetFloorPerformance(ctx context.Context, building, floor string, start, end time.Time) (*repository.FloorPerformance, error) {
+	s.log.Debug("Getting performance metrics: building=%s floor=%s", building, floor)
+
+	probes, err := s.probeRepo.GetByBuildingAndFloor(ctx, building, floor)
+	if err != nil {
+		return nil, err
+	}
+	if len(probes) == 0 {
+		return nil, ErrNoFloorProbes
+	}
+
+	probeIDs := make([]string, len(probes))
+	for i, p := range probes {
+		probeIDs[i] = p.ProbeID
+	}
+	metrics, err := s.analyticsRepo.GetPerformanceMetricsForProbes(ctx, probeIDs, start, end)
+	if err != nil {
+		return nil, err
+	}
+
+	return &repository.FloorPerformance{
+		Building:           building,
+		Floor:              floor,
+		ProbeIDs:           probeIDs,
+		PerformanceMetrics: metrics,
+	}, nil
 }
 
 func (s *AnalyticsService) GetProbeComparison(ctx context.Context, probeIDs []string, start, end time.Time) ([]repository.ProbeComparison, error) {
@@ -66,14 +172,16 @@ func (s *AnalyticsService) GetProbeComparison(ctx context.Context, probeIDs []st
 	return s.analyticsRepo.GetProbeComparison(ctx, probeIDs, start, end)
 }
 
-func (s *AnalyticsService) GetNetworkHealth(ctx context.Context) (*repository.NetworkHealth, error) {
-	s.log.Debug("Getting network health")
-	return s.analyticsRepo.GetNetworkHealth(ctx)
+// GetNetworkHealth summarises the fleet, counting probes that reported within
+// window as active. Pass 0 to use the configured ANALYTICS_ACTIVE_WINDOW.
+func (s *AnalyticsService) GetNetworkHealth(ctx context.Context, window time.Duration) (*repository.NetworkHealth, error) {
+	s.log.Debug("Getting network health: window=%v", window)
+	return s.analyticsRepo.GetNetworkHealth(ctx, window)
 }
 
-func (s *AnalyticsService) DetectAnomalies(ctx context.Context, probeID string, hours int) ([]models.AnomalyDetection, error) {
-	s.log.Info("Detecting anomalies: probe=%s, hours=%d", probeID, hours)
-	return s.analyticsRepo.DetectAnomalies(ctx, probeID, hours)
+func (s *AnalyticsService) DetectAnomalies(ctx context.Context, probeID string, windows map[string]repository.AnomalyWindow) ([]models.AnomalyDetection, error) {
+	s.log.Info("Detecting anomalies: probe=%s, windows=%v", probeID, windows)
+	return s.analyticsRepo.DetectAnomalies(ctx, probeID, windows)
 }
 
 func (s *AnalyticsService) GetRoamingAnalysis(ctx context.Context, probeID string, start, end time.Time) ([]repository.APAnalysis, error) {
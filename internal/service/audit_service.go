@@ -0,0 +1,35 @@
+package service
+
+import (
+	"context"
+
+	"CampusMonitorAPI/internal/models"
+	"CampusMonitorAPI/internal/repository"
+)
+
+// maxAuditPage caps how many audit entries one query returns.
+const maxAuditPage = 500
+
+type AuditService struct {
+	repo *repository.AuditRepository
+}
+
+func NewAuditService(repo *repository.AuditRepository) *AuditService {
+	return &AuditService{repo: repo}
+}
+
+// Record stores an entry; it satisfies middleware.AuditRecorder.
+func (s *AuditService) Record(ctx context.Context, entry *models.AuditEntry) error {
+	return s.repo.Create(ctx, entry)
+}
+
+// List returns the audit trail newest first, at most maxAuditPage entries.
+func (s *AuditService) List(ctx context.Context, q models.AuditQuery) ([]models.AuditEntry, error) {
+	if q.Limit <= 0 || q.Limit > maxAuditPage {
+		q.Limit = maxAuditPage
+	}
+	if q.Offset < 0 {
+		q.Offset = 0
+	}
+	return s.repo.List(ctx, q)
+}
@@ -0,0 +1,84 @@
+package service
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"CampusMonitorAPI/internal/logger"
+)
+
+// CleanupWorker periodically purges finished commands older than the
+// retention period and old resolved alerts, so neither table grows forever.
+// Pending commands are never removed.
+type CleanupWorker struct {
+	commandService *CommandService
+	alertService   *AlertService
+	interval       time.Duration
+	retentionDays  int
+	log            *logger.Logger
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+func NewCleanupWorker(commandService *CommandService, alertService *AlertService, interval time.Duration, retentionDays int, log *logger.Logger) *CleanupWorker {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	return &CleanupWorker{
+		commandService: commandService,
+		alertService:   alertService,
+		interval:       interval,
+		retentionDays:  retentionDays,
+		log:            log,
+		ctx:            ctx,
+		cancel:         cancel,
+	}
+}
+
+// Start runs a cleanup pass immediately and then every interval. A
+// non-positive interval disables the worker.
+func (c *CleanupWorker) Start() {
+	if c.interval <= 0 {
+		c.log.Info("Command cleanup disabled")
+		return
+	}
+
+	c.log.Info("Starting command cleanup every %v (retention %d days)", c.interval, c.retentionDays)
+	c.wg.Add(1)
+	go c.run()
+}
+
+func (c *CleanupWorker) Shutdown() {
+	c.cancel()
+	c.wg.Wait()
+	c.log.Info("Cleanup worker stopped")
+}
+
+func (c *CleanupWorker) run() {
+	defer c.wg.Done()
+
+	ticker := time.NewTicker(c.interval)
+	defer ticker.Stop()
+
+	c.cleanup()
+	for {
+		select {
+		case <-c.ctx.Done():
+			return
+		case <-ticker.C:
+			c.cleanup()
+		}
+	}
+}
+
+func (c *CleanupWorker) cleanup() {
+	ctx, cancel := context.WithTimeout(c.ctx, time.Minute)
+	defer cancel()
+
+	if _, err := c.commandService.DeleteOldCommands(ctx, c.retentionDays); err != nil {
+		c.log.Error("Command cleanup failed: %v", err)
+	}
+	c.alertService.CleanUpTask(ctx)
+}
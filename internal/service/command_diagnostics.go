@@ -0,0 +1,122 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"CampusMonitorAPI/internal/models"
+)
+
+// diagnosticsParts are the commands a diagnostics request sends. Each part
+// carries the diagnostics command's ID as payload.diagnostics_id, and the
+// diagnostics command keeps the parts' IDs in its result until it completes.
+var diagnosticsParts = []string{"get_status", "get_config"}
+
+// diagnosticsAlertWindow is how far back a diagnostics bundle collects
+// alerts.
+const diagnosticsAlertWindow = 24 * time.Hour
+
+// ErrNoDiagnostics is returned when a probe has no completed diagnostics
+// bundle.
+var ErrNoDiagnostics = errors.New("no diagnostics collected for this probe")
+
+// issueDiagnostics sends the parts of the diagnostics command cmd and
+// returns the result to record while they are outstanding.
+func (s *CommandService) issueDiagnostics(ctx context.Context, cmd *models.Command) (map[string]interface{}, error) {
+	parts := make(map[string]interface{}, len(diagnosticsParts))
+	for _, commandType := range diagnosticsParts {
+		part, err := s.IssueCommand(ctx, &models.CommandRequest{
+			ProbeID:     cmd.ProbeID,
+			CommandType: commandType,
+			Payload:     map[string]interface{}{"diagnostics_id": cmd.ID},
+		})
+		if err != nil {
+			return nil, err
+		}
+		parts[commandType+"_command_id"] = part.ID
+	}
+	return parts, nil
+}
+
+// collectDiagnostics is called when a get_status or get_config result
+// arrives. If the command was part of a diagnostics request and every part
+// has now answered, the bundle is assembled and stored as the diagnostics
+// command's result.
+func (s *CommandService) collectDiagnostics(ctx context.Context, cmdID int) {
+	part, err := s.commandRepo.GetByID(ctx, cmdID)
+	if err != nil {
+		return
+	}
+	parentID, ok := part.Payload["diagnostics_id"].(float64)
+	if !ok {
+		return
+	}
+	parent, err := s.commandRepo.GetByID(ctx, int(parentID))
+	if err != nil || parent.Status != "sent" {
+		return
+	}
+
+	results := make(map[string]map[string]interface{}, len(diagnosticsParts))
+	for _, commandType := range diagnosticsParts {
+		id, _ := parent.Result[commandType+"_command_id"].(float64)
+		part, err := s.commandRepo.GetByID(ctx, int(id))
+		if err != nil {
+			s.log.Warn("Diagnostics %d: failed to load %s command: %v", parent.ID, commandType, err)
+			return
+		}
+		switch part.Status {
+		case "completed":
+			results[commandType] = part.Result
+		case "failed":
+			failure := map[string]interface{}{"error": commandType + " failed", commandType: part.Result}
+			if err := s.commandRepo.UpdateStatus(ctx, parent.ID, "failed", failure); err != nil {
+				s.log.Warn("Failed to update diagnostics %d: %v", parent.ID, err)
+			}
+			return
+		default:
+			return
+		}
+	}
+
+	bundle := map[string]interface{}{
+		"status":       results["get_status"],
+		"config":       results["get_config"],
+		"commands":     parent.Result,
+		"collected_at": time.Now(),
+	}
+	if latest, err := s.telemetryService.GetLatestTelemetry(ctx, parent.ProbeID, 1); err == nil && len(latest) > 0 {
+		bundle["telemetry"] = latest[0]
+	} else if err != nil {
+		s.log.Warn("Diagnostics %d: failed to load latest telemetry: %v", parent.ID, err)
+	}
+	if alerts, err := s.alertRepo.GetProbeHistory(ctx, parent.ProbeID, time.Now().Add(-diagnosticsAlertWindow)); err == nil {
+		bundle["alerts"] = alerts
+	} else {
+		s.log.Warn("Diagnostics %d: failed to load alerts: %v", parent.ID, err)
+	}
+
+	if err := s.commandRepo.UpdateStatus(ctx, parent.ID, "completed", bundle); err != nil {
+		s.log.Warn("Failed to store diagnostics %d: %v", parent.ID, err)
+		return
+	}
+	s.log.Info("Diagnostics bundle %d collected for %s", parent.ID, parent.ProbeID)
+}
+
+// GetLatestDiagnostics returns the probe's most recent completed
+// diagnostics command, whose result is the bundle.
+func (s *CommandService) GetLatestDiagnostics(ctx context.Context, probeID string) (*models.Command, error) {
+	bundles, _, err := s.commandRepo.Query(ctx, &models.CommandHistoryRequest{
+		ProbeID:     probeID,
+		Status:      "completed",
+		CommandType: "diagnostics",
+		Limit:       1,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(bundles) == 0 {
+		return nil, ErrNoDiagnostics
+	}
+	return &bundles[0], nil
+}
@@ -0,0 +1,115 @@
+package service
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrCommandRateLimited is returned by IssueCommand when a probe has been sent
+// too many commands of one type.
+var ErrCommandRateLimited = errors.New("command rate limit exceeded")
+
+type commandBucket struct {
+	commandType string
+	tokens      float64
+	lastSeen    time.Time
+}
+
+// commandSweepInterval is how often allow drops buckets that have refilled.
+const commandSweepInterval = time.Minute
+
+// commandLimiter is a token bucket per probe and command type. Both come
+// from the request before the probe is known to exist, so buckets that have
+// refilled to their burst, and are therefore no different from a new one,
+// are swept out periodically rather than kept forever.
+type commandLimiter struct {
+	mu         sync.Mutex
+	buckets    map[string]*commandBucket
+	lastSweep  time.Time
+	now        func() time.Time
+	perMinute  int
+	burst      int
+	typeLimits map[string]int
+}
+
+func newCommandLimiter() *commandLimiter {
+	return &commandLimiter{buckets: make(map[string]*commandBucket), now: time.Now}
+}
+
+func (l *commandLimiter) set(perMinute, burst int, typeLimits map[string]int) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.perMinute = perMinute
+	l.burst = burst
+	l.typeLimits = typeLimits
+}
+
+// limitFor returns the per-minute rate and burst for a command type; a rate
+// of 0 means unlimited. The burst never exceeds the rate, so an expensive
+// type limited to 1/min cannot be fired five times back to back.
+func (l *commandLimiter) limitFor(commandType string) (int, int) {
+	perMinute := l.perMinute
+	if n, ok := l.typeLimits[commandType]; ok {
+		perMinute = n
+	}
+	burst := l.burst
+	if burst <= 0 || burst > perMinute {
+		burst = perMinute
+	}
+	return perMinute, burst
+}
+
+// allow takes a token for probeID/commandType, returning the configured
+// per-minute limit when none is available.
+func (l *commandLimiter) allow(probeID, commandType string) (bool, int) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	perMinute, burst := l.limitFor(commandType)
+	if perMinute <= 0 {
+		return true, 0
+	}
+
+	now := l.now()
+	if now.Sub(l.lastSweep) >= commandSweepInterval {
+		l.sweep(now)
+	}
+
+	key := probeID + "\x00" + commandType
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &commandBucket{commandType: commandType, tokens: float64(burst), lastSeen: now}
+		l.buckets[key] = b
+	}
+
+	b.tokens = refill(b, now, perMinute, burst)
+	b.lastSeen = now
+
+	if b.tokens < 1 {
+		return false, perMinute
+	}
+	b.tokens--
+	return true, perMinute
+}
+
+// refill returns b's tokens at now, capped at burst.
+func refill(b *commandBucket, now time.Time, perMinute, burst int) float64 {
+	tokens := b.tokens + now.Sub(b.lastSeen).Seconds()*float64(perMinute)/60
+	if tokens > float64(burst) {
+		tokens = float64(burst)
+	}
+	return tokens
+}
+
+// sweep drops buckets that are full again, or whose type is no longer
+// limited. Callers must hold l.mu.
+func (l *commandLimiter) sweep(now time.Time) {
+	for key, b := range l.buckets {
+		perMinute, burst := l.limitFor(b.commandType)
+		if perMinute <= 0 || refill(b, now, perMinute, burst) >= float64(burst) {
+			delete(l.buckets, key)
+		}
+	}
+	l.lastSweep = now
+}
@@ -0,0 +1,38 @@
+package service
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestCommandLimiterSweepsRefilledBuckets(t *testing.T) {
+	clock := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	l := newCommandLimiter()
+	l.now = func() time.Time { return clock }
+	l.set(6, 2, nil)
+
+	for i := 0; i < 1000; i++ {
+		if ok, _ := l.allow(fmt.Sprintf("made-up-%d", i), "anything"); !ok {
+			t.Fatalf("first command to a new probe was limited")
+		}
+	}
+	clock = clock.Add(50 * time.Second)
+	l.allow("probe-1", "reboot")
+	l.allow("probe-1", "reboot")
+	if ok, _ := l.allow("probe-1", "reboot"); ok {
+		t.Fatal("third reboot within the burst was allowed")
+	}
+
+	// 10s refills one token: the made-up buckets are full again, but
+	// probe-1's reboot bucket is still one short.
+	clock = clock.Add(commandSweepInterval - 50*time.Second)
+	l.allow("probe-2", "ping")
+
+	if _, ok := l.buckets["probe-1\x00reboot"]; !ok {
+		t.Error("sweep dropped a bucket that had not refilled")
+	}
+	if len(l.buckets) != 2 {
+		t.Errorf("%d buckets after sweep, want 2", len(l.buckets))
+	}
+}
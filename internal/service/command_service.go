@@ -3,7 +3,10 @@ package service
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"strconv"
+	"strings"
 	"sync"
 	"time"
 
@@ -16,38 +19,103 @@ import (
 type CommandService struct {
 	commandRepo      *repository.CommandRepository
 	probeRepo        *repository.ProbeRepository
+	alertRepo        *repository.AlertRepository
 	fleetService     *FleetService
 	telemetryService *TelemetryService
 	scheduleService  *ScheduleService
 	mqttClient       *mqtt.Client
 	log              *logger.Logger
 	pingStatus       map[string]bool
+	pingsInFlight    map[string]*pingFlight
 	pingStatusMux    sync.RWMutex
+	limiter          *commandLimiter
+	waiters          map[int]chan struct{}
+	waitersMux       sync.Mutex
+	ota              *otaTracker
+	// pingThreshold is how recently a probe must have been seen to skip
+	// the connectivity ping before a command.
+	pingThreshold time.Duration
 }
 
-const StaleThreshold = 60 * time.Second
+// pingSweepWorkers bounds how many probes the background sweep pings at once.
+const pingSweepWorkers = 16
+
+// pingFlight is an outstanding connectivity ping. Callers that want to ping
+// a probe that already has one wait on done and share its err instead of
+// sending another.
+type pingFlight struct {
+	done chan struct{}
+	err  error
+}
+
+// deepScanRetention is how many completed deep scans are kept per probe.
+const deepScanRetention = 5
 
 func NewCommandService(
 	commandRepo *repository.CommandRepository,
 	mqttClient *mqtt.Client,
 	probeRepo *repository.ProbeRepository,
+	alertRepo *repository.AlertRepository,
 	telemetryService *TelemetryService,
 	fleetService *FleetService,
 	scheduleService *ScheduleService,
+	thresholds models.Thresholds,
 	log *logger.Logger,
 ) *CommandService {
 	return &CommandService{
 		commandRepo:      commandRepo,
 		mqttClient:       mqttClient,
 		probeRepo:        probeRepo,
+		alertRepo:        alertRepo,
 		telemetryService: telemetryService,
 		fleetService:     fleetService,
 		scheduleService:  scheduleService,
 		log:              log,
 		pingStatus:       make(map[string]bool),
+		pingsInFlight:    make(map[string]*pingFlight),
+		limiter:          newCommandLimiter(),
+		waiters:          make(map[int]chan struct{}),
+		ota:              newOTATracker(),
+		pingThreshold:    thresholds.CommandPing,
 	}
 }
 
+// awaitResult registers a one-shot waiter for commandID. The returned channel
+// is closed when ProcessCommandResult sees the matching result; call the
+// cancel func once done waiting so abandoned waiters don't accumulate.
+func (s *CommandService) awaitResult(commandID int) (<-chan struct{}, func()) {
+	ch := make(chan struct{})
+
+	s.waitersMux.Lock()
+	s.waiters[commandID] = ch
+	s.waitersMux.Unlock()
+
+	return ch, func() {
+		s.waitersMux.Lock()
+		delete(s.waiters, commandID)
+		s.waitersMux.Unlock()
+	}
+}
+
+func (s *CommandService) signalResult(commandID int) {
+	s.waitersMux.Lock()
+	ch, ok := s.waiters[commandID]
+	delete(s.waiters, commandID)
+	s.waitersMux.Unlock()
+
+	if ok {
+		close(ch)
+	}
+}
+
+// SetRateLimit caps how many commands of each type a single probe may be
+// sent per minute. perMinute applies to types without an entry in
+// typeLimits; 0 disables the limit. Safe to call while serving, e.g. on
+// config reload.
+func (s *CommandService) SetRateLimit(perMinute, burst int, typeLimits map[string]int) {
+	s.limiter.set(perMinute, burst, typeLimits)
+}
+
 func (s *CommandService) UpdateResultByID(ctx context.Context, commandID int, result map[string]interface{}) error {
 	status := "completed"
 	err := s.commandRepo.UpdateStatus(ctx, commandID, status, result)
@@ -61,8 +129,17 @@ func (s *CommandService) UpdateResultByID(ctx context.Context, commandID int, re
 }
 
 func (s *CommandService) IssueCommand(ctx context.Context, req *models.CommandRequest) (*models.Command, error) {
+	if err := validateCommandRequest(req); err != nil {
+		return nil, err
+	}
 	s.log.Info("Issuing command: type=%s, probe=%s", req.CommandType, req.ProbeID)
 
+	if ok, limit := s.limiter.allow(req.ProbeID, req.CommandType); !ok {
+		s.log.Warn("Command rate limit hit: type=%s, probe=%s", req.CommandType, req.ProbeID)
+		return nil, fmt.Errorf("%w: %s commands to probe %s are limited to %d per minute",
+			ErrCommandRateLimited, req.CommandType, req.ProbeID, limit)
+	}
+
 	cmd := &models.Command{
 		ProbeID:     req.ProbeID,
 		CommandType: req.CommandType,
@@ -76,6 +153,8 @@ func (s *CommandService) IssueCommand(ctx context.Context, req *models.CommandRe
 	}
 
 	var err error
+	// sentResult is recorded with the "sent" status.
+	var sentResult map[string]interface{}
 	if req.CommandType != "ping" {
 		checkCtx, cancel := context.WithTimeout(ctx, 6*time.Second)
 		defer cancel()
@@ -116,11 +195,7 @@ func (s *CommandService) IssueCommand(ctx context.Context, req *models.CommandRe
 	case "set_wifi":
 		ssid, _ := req.Payload["ssid"].(string)
 		password, _ := req.Payload["password"].(string)
-		if ssid == "" || password == "" {
-			err = fmt.Errorf("set_wifi requires ssid and password")
-		} else {
-			err = s.mqttClient.SendSetWifi(req.ProbeID, cmd.ID, ssid, password)
-		}
+		err = s.mqttClient.SendSetWifi(req.ProbeID, cmd.ID, ssid, password)
 
 	case "set_mqtt":
 		broker, _ := req.Payload["broker"].(string)
@@ -131,19 +206,11 @@ func (s *CommandService) IssueCommand(ctx context.Context, req *models.CommandRe
 		user, _ := req.Payload["user"].(string)
 		password, _ := req.Payload["password"].(string)
 
-		if broker == "" {
-			err = fmt.Errorf("set_mqtt requires broker")
-		} else {
-			err = s.mqttClient.SendSetMqtt(req.ProbeID, cmd.ID, broker, port, user, password)
-		}
+		err = s.mqttClient.SendSetMqtt(req.ProbeID, cmd.ID, broker, port, user, password)
 
 	case "rename_probe":
 		newID, _ := req.Payload["new_id"].(string)
-		if newID == "" {
-			err = fmt.Errorf("rename_probe requires new_id")
-		} else {
-			err = s.mqttClient.SendRenameProbe(req.ProbeID, cmd.ID, newID)
-		}
+		err = s.mqttClient.SendRenameProbe(req.ProbeID, cmd.ID, newID)
 
 	case "restart":
 		delay := 2000
@@ -154,11 +221,7 @@ func (s *CommandService) IssueCommand(ctx context.Context, req *models.CommandRe
 
 	case "ota_update":
 		url, _ := req.Payload["url"].(string)
-		if url == "" {
-			err = fmt.Errorf("ota_update requires url")
-		} else {
-			err = s.mqttClient.SendOTAUpdate(req.ProbeID, cmd.ID, url)
-		}
+		err = s.mqttClient.SendOTAUpdate(req.ProbeID, cmd.ID, url)
 
 	case "factory_reset":
 		err = s.mqttClient.SendFactoryReset(req.ProbeID, cmd.ID)
@@ -169,6 +232,9 @@ func (s *CommandService) IssueCommand(ctx context.Context, req *models.CommandRe
 	case "get_status":
 		err = s.mqttClient.SendGetStatus(req.ProbeID, cmd.ID)
 
+	case "diagnostics":
+		sentResult, err = s.issueDiagnostics(ctx, cmd)
+
 	default:
 		s.log.Info("Sending custom command: %s", req.CommandType)
 		err = s.mqttClient.SendRawCommand(req.ProbeID, cmd.ID, req.CommandType, req.Payload)
@@ -183,24 +249,77 @@ func (s *CommandService) IssueCommand(ctx context.Context, req *models.CommandRe
 		return nil, fmt.Errorf("failed to send command: %w", err)
 	}
 
-	err = s.commandRepo.UpdateStatus(ctx, cmd.ID, "sent", nil)
+	err = s.commandRepo.UpdateStatus(ctx, cmd.ID, "sent", sentResult)
 	if err != nil {
 		return nil, err
 	}
 	s.log.Info("Command sent successfully: id=%d, type=%s, probe=%s", cmd.ID, req.CommandType, req.ProbeID)
 
+	if req.CommandType == "ota_update" {
+		s.ota.sent(req.ProbeID, strconv.Itoa(cmd.ID))
+	}
+	if req.CommandType == "diagnostics" {
+		// Both parts may have answered before their IDs were recorded.
+		if partID, ok := sentResult["get_config_command_id"].(int); ok {
+			s.collectDiagnostics(ctx, partID)
+		}
+	}
+
 	return cmd, nil
 }
 
+// GetOTAStatus rolls up the latest OTA progress of every probe that has been
+// sent an update or reported one since the server started.
+func (s *CommandService) GetOTAStatus() *models.OTARollup {
+	return s.ota.rollup()
+}
+
 // GetCommandByID fetches a single command record by its integer primary key.
 func (s *CommandService) GetCommandByID(ctx context.Context, id int) (*models.Command, error) {
 	s.log.Debug("Fetching command by ID: %d", id)
 	return s.commandRepo.GetByID(ctx, id)
 }
 
-func (s *CommandService) GetCommandHistory(ctx context.Context, probeID string) ([]models.Command, error) {
-	s.log.Debug("Fetching command history for probe: %s", probeID)
-	return s.commandRepo.GetByProbeID(ctx, probeID, 50)
+func (s *CommandService) GetCommandHistory(ctx context.Context, req *models.CommandHistoryRequest) (*models.CommandHistoryResponse, error) {
+	s.log.Debug("Fetching command history: probe=%s, status=%s, type=%s", req.ProbeID, req.Status, req.CommandType)
+
+	if req.Limit <= 0 {
+		req.Limit = 50
+	}
+	if req.Limit > 500 {
+		req.Limit = 500
+	}
+	if req.Offset < 0 {
+		req.Offset = 0
+	}
+
+	commands, total, err := s.commandRepo.Query(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.CommandHistoryResponse{
+		Data:       commands,
+		TotalCount: total,
+		Limit:      req.Limit,
+		Offset:     req.Offset,
+	}, nil
+}
+
+// GetDeepScanResults returns a probe's completed deep scans, newest first,
+// with their result payloads. Only the most recent deepScanRetention are kept.
+func (s *CommandService) GetDeepScanResults(ctx context.Context, probeID string, limit int) ([]models.Command, error) {
+	if limit <= 0 || limit > deepScanRetention {
+		limit = deepScanRetention
+	}
+
+	scans, _, err := s.commandRepo.Query(ctx, &models.CommandHistoryRequest{
+		ProbeID:     probeID,
+		Status:      "completed",
+		CommandType: "deep_scan",
+		Limit:       limit,
+	})
+	return scans, err
 }
 
 func (s *CommandService) GetPendingCommands(ctx context.Context) ([]models.Command, error) {
@@ -240,6 +359,88 @@ func (s *CommandService) BroadcastCommand(ctx context.Context, commandType strin
 	return nil
 }
 
+// ErrNoTaggedProbes is returned by BroadcastToTag when no managed probe has
+// the tag.
+var ErrNoTaggedProbes = errors.New("no managed probes have this tag")
+
+// BroadcastToTag issues a command to every managed probe carrying tag, one
+// IssueCommand per probe so validation and rate limits still apply. A probe
+// that can't be reached doesn't stop the rest; it is listed in Failed. The
+// tag and the probes it resolved to are recorded as a fleet command.
+func (s *CommandService) BroadcastToTag(ctx context.Context, tag, commandType string, params map[string]interface{}, user string) (*models.TagBroadcastResult, error) {
+	if key, _, _ := strings.Cut(tag, "="); strings.TrimSpace(key) == "" {
+		ve := &models.ValidationError{}
+		ve.Add("tag", "required")
+		return nil, ve
+	}
+
+	probeIDs, err := s.fleetService.ProbeIDsByTag(ctx, tag)
+	if err != nil {
+		return nil, err
+	}
+	if len(probeIDs) == 0 {
+		return nil, fmt.Errorf("%w: %s", ErrNoTaggedProbes, tag)
+	}
+
+	// The payload is the same for every probe, so check it once rather than
+	// reporting the same validation error for each of them.
+	if err := validateCommandRequest(&models.CommandRequest{
+		ProbeID:     probeIDs[0],
+		CommandType: commandType,
+		Payload:     params,
+	}); err != nil {
+		return nil, err
+	}
+
+	s.log.Info("Broadcasting command to tag: type=%s, tag=%s, probes=%d", commandType, tag, len(probeIDs))
+
+	result := &models.TagBroadcastResult{
+		Tag:          tag,
+		CommandType:  commandType,
+		TotalTargets: len(probeIDs),
+		Commands:     make(map[string]int),
+		Failed:       make(map[string]string),
+	}
+	for _, probeID := range probeIDs {
+		cmd, err := s.IssueCommand(ctx, &models.CommandRequest{
+			ProbeID:     probeID,
+			CommandType: commandType,
+			Payload:     params,
+		})
+		if err != nil {
+			result.Failed[probeID] = err.Error()
+			continue
+		}
+		result.Commands[probeID] = cmd.ID
+	}
+
+	status := "completed"
+	if len(result.Commands) == 0 {
+		status = "failed"
+	}
+	record := &models.FleetCommand{
+		CommandType:  commandType,
+		Payload:      params,
+		IssuedBy:     user,
+		TargetProbes: probeIDs,
+		TotalTargets: len(probeIDs),
+		Status:       status,
+		Metadata: map[string]interface{}{
+			"tag":      tag,
+			"commands": result.Commands,
+			"failed":   result.Failed,
+		},
+	}
+	if err := s.fleetService.RecordFleetCommand(ctx, record); err != nil {
+		s.log.Error("Failed to record tag broadcast for %s: %v", tag, err)
+	} else {
+		result.FleetCommandID = record.ID
+	}
+
+	s.log.Info("Tag broadcast done: tag=%s, sent=%d, failed=%d", tag, len(result.Commands), len(result.Failed))
+	return result, nil
+}
+
 func (s *CommandService) GetCommandStatistics(ctx context.Context) (map[string]interface{}, error) {
 	s.log.Debug("Fetching command statistics")
 	stats, err := s.commandRepo.GetStatistics(ctx)
@@ -295,6 +496,14 @@ func (s *CommandService) ProcessCommandResult(ctx context.Context, payload []byt
 			isIntID = true
 		}
 	}
+	// Tracked before the fleet branch so fleet OTA rollouts show up too.
+	if result.Command == "ota_update" && result.ProbeID != "" {
+		otaID := cmdIDStr
+		if otaID == "<nil>" {
+			otaID = ""
+		}
+		s.ota.record(result.ProbeID, otaID, result.Status, result.Result)
+	}
 	// Fleet commands
 	if !isIntID && s.fleetService != nil && cmdIDStr != "" && cmdIDStr != "<nil>" {
 		go func() {
@@ -315,6 +524,10 @@ func (s *CommandService) ProcessCommandResult(ctx context.Context, payload []byt
 		if err != nil {
 			s.log.Warn("Failed to update command %d: %v", cmdID, err)
 		}
+		s.signalResult(cmdID)
+		if result.Command == "get_status" || result.Command == "get_config" {
+			s.collectDiagnostics(ctx, cmdID)
+		}
 	} else {
 		// No usable ID — fall back to matching by probe + command type
 		err := s.commandRepo.UpdateLatestResult(ctx, result.ProbeID, result.Command, result.Status, result.Result)
@@ -326,7 +539,7 @@ func (s *CommandService) ProcessCommandResult(ctx context.Context, payload []byt
 	if result.Status == "completed" {
 		switch result.Command {
 		case "deep_scan":
-			if err := s.commandRepo.PruneOldScans(ctx, result.ProbeID, 5); err != nil {
+			if err := s.commandRepo.PruneOldScans(ctx, result.ProbeID, deepScanRetention); err != nil {
 				s.log.Warn("Failed to prune old deep scans: %v", err)
 			}
 			go func() {
@@ -341,6 +554,9 @@ func (s *CommandService) ProcessCommandResult(ctx context.Context, payload []byt
 
 		case "config_update", "set_wifi", "set_mqtt":
 			s.log.Info("Probe %s configuration updated successfully", result.ProbeID)
+			if result.Command == "config_update" {
+				s.recordReportInterval(ctx, result.ProbeID, cmdID, result.Result)
+			}
 
 		case "rename_probe":
 			if newID, ok := result.Result["new_id"].(string); ok && newID != "" {
@@ -358,6 +574,7 @@ func (s *CommandService) ProcessCommandResult(ctx context.Context, payload []byt
 
 		case "get_config":
 			s.log.Info("Probe %s config retrieved", result.ProbeID)
+			s.recordReportInterval(ctx, result.ProbeID, 0, result.Result)
 
 		case "ping":
 			_ = s.probeRepo.UpdateLastSeen(ctx, result.ProbeID, time.Now())
@@ -376,17 +593,90 @@ func (s *CommandService) ProcessCommandResult(ctx context.Context, payload []byt
 	return nil
 }
 
+// recordReportInterval stores the report interval a probe confirmed. The
+// result is checked first; a config_update result that doesn't echo the
+// interval falls back to the payload of the command it answers.
+func (s *CommandService) recordReportInterval(ctx context.Context, probeID string, cmdID int, result map[string]interface{}) {
+	seconds, ok := reportIntervalFrom(result)
+	if !ok && cmdID > 0 {
+		if cmd, err := s.commandRepo.GetByID(ctx, cmdID); err == nil {
+			seconds, ok = reportIntervalFrom(cmd.Payload)
+		}
+	}
+	if !ok {
+		return
+	}
+
+	if err := s.probeRepo.UpdateReportInterval(ctx, probeID, seconds); err != nil {
+		s.log.Warn("Failed to record report interval for %s: %v", probeID, err)
+		return
+	}
+	s.log.Info("Probe %s report interval is %ds", probeID, seconds)
+}
+
+// reportIntervalFrom finds a positive report_interval (seconds) at the top
+// level of a probe config payload or inside its "config" or "mqtt" section.
+func reportIntervalFrom(data map[string]interface{}) (int, bool) {
+	if v, ok := data["report_interval"].(float64); ok && v >= 1 {
+		return int(v), true
+	}
+	for _, section := range []string{"config", "mqtt"} {
+		if nested, ok := data[section].(map[string]interface{}); ok {
+			if v, ok := nested["report_interval"].(float64); ok && v >= 1 {
+				return int(v), true
+			}
+		}
+	}
+	return 0, false
+}
+
 func (s *CommandService) VerifyProbeConnectivity(ctx context.Context, probeID string) error {
 	probe, err := s.probeRepo.GetByID(ctx, probeID)
 	if err != nil {
 		return fmt.Errorf("probe lookup failed: %w", err)
 	}
-	if time.Since(probe.LastSeen) < StaleThreshold {
+	if time.Since(probe.LastSeen) < s.pingThreshold {
 		return nil
 	}
 
 	s.log.Info("Attempting to ping %s (last seen: %v)", probeID, probe.LastSeen)
 
+	if err := s.ping(ctx, probeID, 5*time.Second); err != nil {
+		return err
+	}
+	s.log.Info("Probe %s is back online!", probeID)
+	return nil
+}
+
+// ping sends a ping command and waits up to timeout for the reply. If a
+// ping to the probe is already outstanding it waits for that one instead,
+// so concurrent callers never stack up ping commands for the same probe.
+func (s *CommandService) ping(ctx context.Context, probeID string, timeout time.Duration) error {
+	s.pingStatusMux.Lock()
+	if flight, ok := s.pingsInFlight[probeID]; ok {
+		s.pingStatusMux.Unlock()
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-flight.done:
+			return flight.err
+		}
+	}
+	flight := &pingFlight{done: make(chan struct{})}
+	s.pingsInFlight[probeID] = flight
+	s.pingStatusMux.Unlock()
+
+	flight.err = s.sendPing(ctx, probeID, timeout)
+
+	s.pingStatusMux.Lock()
+	delete(s.pingsInFlight, probeID)
+	s.pingStatusMux.Unlock()
+	close(flight.done)
+
+	return flight.err
+}
+
+func (s *CommandService) sendPing(ctx context.Context, probeID string, timeout time.Duration) error {
 	tempCmd := &models.Command{
 		ProbeID:     probeID,
 		CommandType: "ping",
@@ -396,29 +686,21 @@ func (s *CommandService) VerifyProbeConnectivity(ctx context.Context, probeID st
 		return fmt.Errorf("failed to create ping command: %w", err)
 	}
 
+	// Register before sending so a fast reply can't slip past the waiter.
+	replied, cancel := s.awaitResult(tempCmd.ID)
+	defer cancel()
+
 	if err := s.mqttClient.SendPing(probeID, tempCmd.ID); err != nil {
 		return fmt.Errorf("failed to send wake-up ping: %w", err)
 	}
 
-	ticker := time.NewTicker(500 * time.Millisecond)
-	defer ticker.Stop()
-	timeout := time.After(5 * time.Second)
-
-	initialLastSeen := probe.LastSeen
-
-	for {
-		select {
-		case <-ctx.Done():
-			return ctx.Err()
-		case <-timeout:
-			return fmt.Errorf("probe unreachable: no response to ping after 5s")
-		case <-ticker.C:
-			p, err := s.probeRepo.GetByID(ctx, probeID)
-			if err == nil && p.LastSeen.After(initialLastSeen) {
-				s.log.Info("Probe %s is back online!", probeID)
-				return nil
-			}
-		}
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-time.After(timeout):
+		return fmt.Errorf("probe unreachable: no response to ping after %v", timeout)
+	case <-replied:
+		return nil
 	}
 }
 
@@ -453,34 +735,29 @@ func (s *CommandService) pingAllProbes(ctx context.Context) {
 		return
 	}
 
-	for _, probe := range probes {
-		go func(probeID string) {
-			tempCmd := &models.Command{
-				ProbeID:     probeID,
-				CommandType: "ping",
-				Status:      "pending",
-			}
-
-			if err := s.commandRepo.Create(ctx, tempCmd); err != nil {
-				s.setPingStatus(probeID, false)
-				return
-			}
-
-			if err := s.mqttClient.SendPing(probeID, tempCmd.ID); err != nil {
-				s.setPingStatus(probeID, false)
-				return
+	probeIDs := make(chan string)
+	var wg sync.WaitGroup
+	for i := 0; i < pingSweepWorkers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for probeID := range probeIDs {
+				err := s.ping(ctx, probeID, 3*time.Second)
+				s.setPingStatus(probeID, err == nil)
 			}
+		}()
+	}
 
-			time.Sleep(3 * time.Second)
-
-			cmd, err := s.commandRepo.GetByID(ctx, tempCmd.ID)
-			if err == nil && cmd.Status == "completed" {
-				s.setPingStatus(probeID, true)
-			} else {
-				s.setPingStatus(probeID, false)
-			}
-		}(probe.ProbeID)
+feed:
+	for _, probe := range probes {
+		select {
+		case probeIDs <- probe.ProbeID:
+		case <-ctx.Done():
+			break feed
+		}
 	}
+	close(probeIDs)
+	wg.Wait()
 }
 
 func (s *CommandService) setPingStatus(probeID string, status bool) {
@@ -131,6 +131,17 @@ func (s *FleetService) ListFleetProbes(ctx context.Context, group string) ([]mod
 	return s.fleetRepo.ListFleetProbes(ctx, true, group)
 }
 
+// ProbeIDsByTag lists the managed probes carrying tag ("key" or "key=value").
+func (s *FleetService) ProbeIDsByTag(ctx context.Context, tag string) ([]string, error) {
+	return s.fleetRepo.ListProbeIDsByTag(ctx, tag)
+}
+
+// RecordFleetCommand stores a fleet command that was sent outside a rollout,
+// so it still shows up in the fleet command history.
+func (s *FleetService) RecordFleetCommand(ctx context.Context, cmd *models.FleetCommand) error {
+	return s.fleetRepo.CreateFleetCommand(ctx, cmd)
+}
+
 // UpdateFleetProbe updates fleet probe metadata
 func (s *FleetService) UpdateFleetProbe(ctx context.Context, probeID string, req *models.FleetUpdateRequest) error {
 	s.log.Info("Updating fleet probe %s", probeID)
@@ -286,6 +297,9 @@ func (s *FleetService) UpdateFirmwareVersion(ctx context.Context, probeID, versi
 
 // Template Management
 func (s *FleetService) CreateTemplate(ctx context.Context, template *models.FleetConfigTemplate, user string) error {
+	if err := validateTemplate(template); err != nil {
+		return err
+	}
 	template.CreatedBy = user
 	return s.fleetRepo.CreateTemplate(ctx, template)
 }
@@ -0,0 +1,83 @@
+package service
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"CampusMonitorAPI/internal/logger"
+	"CampusMonitorAPI/internal/websocket"
+)
+
+// HealthBroadcaster periodically computes the network health snapshot and
+// pushes it to every connected dashboard so clients don't have to poll.
+type HealthBroadcaster struct {
+	analyticsService *AnalyticsService
+	hub              *websocket.Hub
+	interval         time.Duration
+	log              *logger.Logger
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+func NewHealthBroadcaster(analyticsService *AnalyticsService, hub *websocket.Hub, interval time.Duration, log *logger.Logger) *HealthBroadcaster {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	return &HealthBroadcaster{
+		analyticsService: analyticsService,
+		hub:              hub,
+		interval:         interval,
+		log:              log,
+		ctx:              ctx,
+		cancel:           cancel,
+	}
+}
+
+// Start launches the broadcast loop. A non-positive interval disables it.
+func (b *HealthBroadcaster) Start() {
+	if b.interval <= 0 {
+		b.log.Info("Network health broadcast disabled")
+		return
+	}
+
+	b.log.Info("Starting network health broadcast every %v", b.interval)
+	b.wg.Add(1)
+	go b.run()
+}
+
+func (b *HealthBroadcaster) Shutdown() {
+	b.cancel()
+	b.wg.Wait()
+	b.log.Info("Network health broadcaster stopped")
+}
+
+func (b *HealthBroadcaster) run() {
+	defer b.wg.Done()
+
+	ticker := time.NewTicker(b.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-b.ctx.Done():
+			return
+		case <-ticker.C:
+			b.broadcast()
+		}
+	}
+}
+
+func (b *HealthBroadcaster) broadcast() {
+	ctx, cancel := context.WithTimeout(b.ctx, 10*time.Second)
+	defer cancel()
+
+	health, err := b.analyticsService.GetNetworkHealth(ctx, 0)
+	if err != nil {
+		b.log.Error("Failed to compute network health for broadcast: %v", err)
+		return
+	}
+
+	b.hub.Broadcast("NETWORK_HEALTH", health)
+}
@@ -0,0 +1,93 @@
+package service
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"CampusMonitorAPI/internal/models"
+)
+
+// otaTracker keeps the latest OTA state reported by each probe. It lives in
+// memory like the ping status: a restart loses it, but an update in flight
+// reports again on its next progress message.
+type otaTracker struct {
+	mu     sync.RWMutex
+	probes map[string]models.OTAProbeProgress
+}
+
+func newOTATracker() *otaTracker {
+	return &otaTracker{probes: make(map[string]models.OTAProbeProgress)}
+}
+
+// sent resets a probe's entry when a new OTA command goes out to it.
+func (t *otaTracker) sent(probeID, commandID string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.probes[probeID] = models.OTAProbeProgress{
+		ProbeID:   probeID,
+		CommandID: commandID,
+		Phase:     models.OTAPhaseSent,
+		UpdatedAt: time.Now(),
+	}
+}
+
+// record applies an ota_update result. "processing" means downloading;
+// other statuses, such as an acknowledgement, only refresh UpdatedAt.
+func (t *otaTracker) record(probeID, commandID, status string, result map[string]interface{}) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	p := t.probes[probeID]
+	p.ProbeID = probeID
+	if commandID != "" {
+		p.CommandID = commandID
+	}
+	p.UpdatedAt = time.Now()
+	p.Error = ""
+
+	switch status {
+	case "processing":
+		p.Phase = models.OTAPhaseDownloading
+		if progress, ok := result["progress"].(float64); ok {
+			p.Progress = progress
+		}
+	case "completed":
+		p.Phase = models.OTAPhaseCompleted
+		p.Progress = 100
+	case "failed", "error":
+		p.Phase = models.OTAPhaseFailed
+		if msg, ok := result["error"].(string); ok {
+			p.Error = msg
+		} else {
+			p.Error = status
+		}
+	default:
+		if p.Phase == "" {
+			p.Phase = models.OTAPhaseSent
+		}
+	}
+	t.probes[probeID] = p
+}
+
+func (t *otaTracker) rollup() *models.OTARollup {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	r := &models.OTARollup{
+		ByPhase: map[string]int{
+			models.OTAPhaseSent:        0,
+			models.OTAPhaseDownloading: 0,
+			models.OTAPhaseCompleted:   0,
+			models.OTAPhaseFailed:      0,
+		},
+		Probes: make([]models.OTAProbeProgress, 0, len(t.probes)),
+	}
+	for _, p := range t.probes {
+		r.ByPhase[p.Phase]++
+		r.Probes = append(r.Probes, p)
+	}
+	r.Total = len(r.Probes)
+	sort.Slice(r.Probes, func(i, j int) bool { return r.Probes[i].ProbeID < r.Probes[j].ProbeID })
+	return r
+}
@@ -3,22 +3,32 @@ package service
 import (
 	"context"
 	"encoding/json"
+	"reflect"
+	"sort"
 	"sync"
 	"time"
 
 	"CampusMonitorAPI/internal/logger"
+	"CampusMonitorAPI/internal/models"
 	"CampusMonitorAPI/internal/mqtt"
 	"CampusMonitorAPI/internal/repository"
+	"CampusMonitorAPI/internal/websocket"
 )
 
 type ProbeMonitor struct {
 	mqttClient *mqtt.Client
 	probeRepo  *repository.ProbeRepository
+	hub        *websocket.Hub
 	log        *logger.Logger
+	thresholds models.Thresholds
 
 	probeStatus map[string]*ProbeStatusCache
 	probeConfig map[string]*ProbeConfigCache
 	pingStatus  map[string]*PingStatus
+	// lastSettings holds each probe's last reported wifi and mqtt settings
+	// for change detection. Unlike probeConfig it is never expired, so a
+	// change is noticed however long the probe was quiet.
+	lastSettings map[string]map[string]interface{}
 
 	statusMux sync.RWMutex
 	configMux sync.RWMutex
@@ -52,24 +62,35 @@ type ProbeConfigCache struct {
 	UpdatedAt time.Time              `json:"updated_at"`
 }
 
+// ConfigChange is the payload of the CONFIG_CHANGED WebSocket message.
+// Only the changed keys are sent, never their values, which may be secret.
+type ConfigChange struct {
+	ProbeID   string    `json:"probe_id"`
+	Changed   []string  `json:"changed"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
 type PingStatus struct {
 	Online    bool      `json:"online"`
 	LastSeen  time.Time `json:"last_seen"`
 	UpdatedAt time.Time `json:"updated_at"`
 }
 
-func NewProbeMonitor(mqttClient *mqtt.Client, probeRepo *repository.ProbeRepository, log *logger.Logger) *ProbeMonitor {
+func NewProbeMonitor(mqttClient *mqtt.Client, probeRepo *repository.ProbeRepository, hub *websocket.Hub, thresholds models.Thresholds, log *logger.Logger) *ProbeMonitor {
 	ctx, cancel := context.WithCancel(context.Background())
 
 	return &ProbeMonitor{
-		mqttClient:  mqttClient,
-		probeRepo:   probeRepo,
-		log:         log,
-		probeStatus: make(map[string]*ProbeStatusCache),
-		probeConfig: make(map[string]*ProbeConfigCache),
-		pingStatus:  make(map[string]*PingStatus),
-		ctx:         ctx,
-		cancel:      cancel,
+		mqttClient:   mqttClient,
+		probeRepo:    probeRepo,
+		hub:          hub,
+		thresholds:   thresholds,
+		log:          log,
+		probeStatus:  make(map[string]*ProbeStatusCache),
+		probeConfig:  make(map[string]*ProbeConfigCache),
+		pingStatus:   make(map[string]*PingStatus),
+		lastSettings: make(map[string]map[string]interface{}),
+		ctx:          ctx,
+		cancel:       cancel,
 	}
 }
 
@@ -233,11 +254,78 @@ func (pm *ProbeMonitor) handleConfigBroadcast(topic string, payload []byte) {
 		config.Timestamp = ts
 	}
 
+	if seconds, ok := reportIntervalFrom(data); ok {
+		if err := pm.probeRepo.UpdateReportInterval(pm.ctx, probeID, seconds); err != nil {
+			pm.log.Warn("Failed to record report interval for %s: %v", probeID, err)
+		}
+	}
+
+	settings := flattenSettings(config)
+
 	pm.configMux.Lock()
 	pm.probeConfig[probeID] = config
+	previous, known := pm.lastSettings[probeID]
+	pm.lastSettings[probeID] = settings
 	pm.configMux.Unlock()
 
 	pm.log.Debug("Cached config broadcast from %s", probeID)
+
+	if known {
+		if changed := changedKeys(previous, settings); len(changed) > 0 {
+			pm.reportConfigChange(probeID, changed)
+		}
+	}
+}
+
+// flattenSettings keys a config broadcast's wifi and mqtt settings as
+// "wifi.ssid", "mqtt.broker" and so on. Heap, uptime and temperature
+// change on every broadcast and are left out.
+func flattenSettings(config *ProbeConfigCache) map[string]interface{} {
+	settings := make(map[string]interface{}, len(config.WiFi)+len(config.MQTT))
+	for k, v := range config.WiFi {
+		settings["wifi."+k] = v
+	}
+	for k, v := range config.MQTT {
+		settings["mqtt."+k] = v
+	}
+	return settings
+}
+
+// changedKeys lists the keys added, removed or changed between two
+// flattened settings maps, sorted.
+func changedKeys(previous, current map[string]interface{}) []string {
+	var changed []string
+	for k, v := range current {
+		if old, ok := previous[k]; !ok || !reflect.DeepEqual(old, v) {
+			changed = append(changed, k)
+		}
+	}
+	for k := range previous {
+		if _, ok := current[k]; !ok {
+			changed = append(changed, k)
+		}
+	}
+	sort.Strings(changed)
+	return changed
+}
+
+// reportConfigChange logs a probe's changed settings and tells dashboards
+// with a CONFIG_CHANGED message, scoped to the probe's building.
+func (pm *ProbeMonitor) reportConfigChange(probeID string, changed []string) {
+	pm.log.Warn("Probe %s reported changed config: %v", probeID, changed)
+	if pm.hub == nil {
+		return
+	}
+
+	building := ""
+	if probe, err := pm.probeRepo.GetByID(pm.ctx, probeID); err == nil {
+		building = probe.Building
+	}
+	pm.hub.BroadcastToBuilding(building, "CONFIG_CHANGED", ConfigChange{
+		ProbeID:   probeID,
+		Changed:   changed,
+		Timestamp: time.Now(),
+	})
 }
 
 func (pm *ProbeMonitor) staleDataCleanup() {
@@ -259,7 +347,7 @@ func (pm *ProbeMonitor) staleDataCleanup() {
 
 func (pm *ProbeMonitor) cleanupStaleData() {
 	now := time.Now()
-	staleThreshold := 15 * time.Minute
+	staleThreshold := pm.thresholds.StatusRetention
 
 	// Cleanup stale status
 	pm.statusMux.Lock()
@@ -284,7 +372,7 @@ func (pm *ProbeMonitor) cleanupStaleData() {
 	// Mark offline probes
 	pm.pingMux.Lock()
 	for probeID, ping := range pm.pingStatus {
-		if now.Sub(ping.LastSeen) > 3*time.Minute {
+		if now.Sub(ping.LastSeen) > pm.thresholds.ProbeOffline {
 			pm.pingStatus[probeID] = &PingStatus{
 				Online:    false,
 				LastSeen:  ping.LastSeen,
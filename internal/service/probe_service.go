@@ -2,7 +2,10 @@ package service
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"strconv"
+	"strings"
 	"time"
 
 	"CampusMonitorAPI/internal/logger"
@@ -11,21 +14,27 @@ import (
 )
 
 type ProbeService struct {
-	probeRepo *repository.ProbeRepository
-	log       *logger.Logger
+	probeRepo     *repository.ProbeRepository
+	telemetryRepo *repository.TelemetryRepository
+	log           *logger.Logger
 }
 
 func NewProbeService(
 	probeRepo *repository.ProbeRepository,
+	telemetryRepo *repository.TelemetryRepository,
 	log *logger.Logger,
 ) *ProbeService {
 	return &ProbeService{
-		probeRepo: probeRepo,
-		log:       log,
+		probeRepo:     probeRepo,
+		telemetryRepo: telemetryRepo,
+		log:           log,
 	}
 }
 
 func (s *ProbeService) RegisterProbe(ctx context.Context, req *models.CreateProbeRequest) (*models.Probe, error) {
+	if err := validateCreateProbe(req); err != nil {
+		return nil, err
+	}
 	s.log.Info("Registering new probe: %s", req.ProbeID)
 
 	existing, err := s.probeRepo.GetByID(ctx, req.ProbeID)
@@ -58,8 +67,8 @@ func (s *ProbeService) GetProbe(ctx context.Context, probeID string) (*models.Pr
 	return s.probeRepo.GetByID(ctx, probeID)
 }
 
-func (s *ProbeService) ListProbes(ctx context.Context) ([]models.Probe, error) {
-	return s.probeRepo.GetAll(ctx)
+func (s *ProbeService) ListProbes(ctx context.Context, q models.ProbeQuery) ([]models.Probe, error) {
+	return s.probeRepo.List(ctx, q)
 }
 
 func (s *ProbeService) UpdateProbe(ctx context.Context, probeID string, req *models.UpdateProbeRequest) (*models.Probe, error) {
@@ -78,20 +87,97 @@ func (s *ProbeService) UpdateProbe(ctx context.Context, probeID string, req *mod
 	s.log.Info("Probe updated successfully: %s", probeID)
 	return probe, nil
 }
+
+// AdoptProbe completes an auto-discovered probe's details and marks it
+// active. Location, building and floor must end up with real values, from
+// req or already on the probe; any still blank or "unknown" are returned
+// together as a *models.ValidationError. A placeholder department is
+// cleared rather than kept.
+func (s *ProbeService) AdoptProbe(ctx context.Context, probeID string, req *models.UpdateProbeRequest) (*models.Probe, error) {
+	probe, err := s.probeRepo.GetByID(ctx, probeID)
+	if err != nil {
+		return nil, err
+	}
+
+	ve := &models.ValidationError{}
+	req.Location = adoptedValue(ve, "location", req.Location, probe.Location, true)
+	req.Building = adoptedValue(ve, "building", req.Building, probe.Building, true)
+	req.Floor = adoptedValue(ve, "floor", req.Floor, probe.Floor, true)
+	req.Department = adoptedValue(ve, "department", req.Department, probe.Department, false)
+	if err := ve.Err(); err != nil {
+		return nil, err
+	}
+
+	status := "active"
+	req.Status = &status
+
+	s.log.Info("Adopting probe: %s", probeID)
+	return s.UpdateProbe(ctx, probeID, req)
+}
+
+// adoptedValue picks the value a field should have after adoption: the
+// requested one if given, else the current one, with placeholders blanked.
+// A required field that ends up blank is recorded in ve.
+func adoptedValue(ve *models.ValidationError, field string, requested *string, current string, required bool) *string {
+	value := current
+	if requested != nil {
+		value = *requested
+	}
+	value = strings.TrimSpace(value)
+	if strings.EqualFold(value, models.PlaceholderUnknown) {
+		value = ""
+	}
+	if value == "" && required {
+		ve.Add(field, "required")
+	}
+	return &value
+}
+
 func (s *ProbeService) UpdateLastSeen(ctx context.Context, probeID string, timestamp time.Time) error {
 	s.log.Debug("Updating last_seen for probe %s", probeID)
 	return s.probeRepo.UpdateLastSeen(ctx, probeID, timestamp)
 }
-func (s *ProbeService) DeleteProbe(ctx context.Context, probeID string) error {
-	s.log.Warn("Deleting probe: %s", probeID)
 
-	if err := s.probeRepo.Delete(ctx, probeID); err != nil {
-		s.log.Error("Failed to delete probe: %v", err)
-		return err
+// DeleteProbe removes a probe. With purge set, its telemetry, commands,
+// alerts and scheduled tasks are deleted in the same transaction; without it
+// the delete fails with repository.ErrProbeHasDependents if any remain.
+func (s *ProbeService) DeleteProbe(ctx context.Context, probeID string, purge bool) (*models.ProbeDeleteResult, error) {
+	s.log.Warn("Deleting probe: %s (purge=%t)", probeID, purge)
+
+	if !purge {
+		if err := s.probeRepo.Delete(ctx, probeID); err != nil {
+			s.log.Error("Failed to delete probe: %v", err)
+			return nil, err
+		}
+		s.log.Info("Probe deleted successfully: %s", probeID)
+		return &models.ProbeDeleteResult{ProbeID: probeID}, nil
 	}
 
-	s.log.Info("Probe deleted successfully: %s", probeID)
-	return nil
+	result, err := s.probeRepo.DeleteWithDependents(ctx, probeID)
+	if err != nil {
+		s.log.Error("Failed to purge probe: %v", err)
+		return nil, err
+	}
+	s.telemetryRepo.ForgetLatest(probeID)
+
+	s.log.Info("Probe purged: %s (telemetry=%d commands=%d alerts=%d tasks=%d)",
+		probeID, result.Telemetry, result.Commands, result.Alerts, result.ScheduledTasks)
+	return result, nil
+}
+
+// DecommissionProbe retires a probe without deleting its history: the probe
+// is marked decommissioned, its active alerts are resolved and its pending
+// commands are failed in one transaction.
+func (s *ProbeService) DecommissionProbe(ctx context.Context, probeID string) (*models.ProbeDecommissionResult, error) {
+	result, err := s.probeRepo.Decommission(ctx, probeID)
+	if err != nil {
+		s.log.Error("Failed to decommission probe %s: %v", probeID, err)
+		return nil, err
+	}
+
+	s.log.Info("Probe decommissioned: %s (alerts_resolved=%d commands_cancelled=%d)",
+		probeID, result.AlertsResolved, result.CommandsCancelled)
+	return result, nil
 }
 
 func (s *ProbeService) GetActiveProbes(ctx context.Context) ([]models.Probe, error) {
@@ -128,6 +214,95 @@ func (s *ProbeService) UpdateFirmwareVersion(ctx context.Context, probeID, versi
 	return nil
 }
 
+// GetFirmwareCompliance compares every probe's firmware_version against
+// targetVersion.
+func (s *ProbeService) GetFirmwareCompliance(ctx context.Context, targetVersion string) (*models.FirmwareCompliance, error) {
+	target, ok := parseFirmwareVersion(targetVersion)
+	if !ok {
+		return nil, fmt.Errorf("%w: %q", ErrInvalidFirmwareVersion, targetVersion)
+	}
+
+	probes, err := s.probeRepo.GetAll(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &models.FirmwareCompliance{
+		TargetVersion:  targetVersion,
+		Total:          len(probes),
+		OutdatedProbes: []string{},
+	}
+	for _, p := range probes {
+		version, ok := parseFirmwareVersion(p.FirmwareVersion)
+		switch {
+		case !ok:
+			result.Unknown++
+		case compareFirmwareVersions(version, target) < 0:
+			result.Outdated++
+			result.OutdatedProbes = append(result.OutdatedProbes, p.ProbeID)
+		default:
+			result.UpToDate++
+		}
+	}
+	return result, nil
+}
+
+func (s *ProbeService) CountByStatus(ctx context.Context) (map[string]int, error) {
+	return s.probeRepo.CountByStatus(ctx)
+}
+
+func (s *ProbeService) GetProbesByDepartment(ctx context.Context) (map[string][]models.Probe, error) {
+	return s.probeRepo.GetGroupedByDepartment(ctx)
+}
+
 func (s *ProbeService) GetDistinctLocations(ctx context.Context) (*models.LocationOptions, error) {
 	return s.probeRepo.GetDistinctLocations(ctx)
 }
+
+// ErrInvalidFirmwareVersion is returned for a target version that isn't a
+// dotted number such as "1.4.2".
+var ErrInvalidFirmwareVersion = errors.New("invalid firmware version")
+
+// parseFirmwareVersion splits "v1.4.2" or "1.4.2-beta" into its numeric
+// components, ignoring a leading v and any pre-release or build suffix.
+func parseFirmwareVersion(v string) ([]int, bool) {
+	v = strings.TrimPrefix(strings.TrimSpace(v), "v")
+	if i := strings.IndexAny(v, "-+ "); i >= 0 {
+		v = v[:i]
+	}
+	if v == "" {
+		return nil, false
+	}
+
+	parts := strings.Split(v, ".")
+	nums := make([]int, len(parts))
+	for i, part := range parts {
+		n, err := strconv.Atoi(part)
+		if err != nil || n < 0 {
+			return nil, false
+		}
+		nums[i] = n
+	}
+	return nums, true
+}
+
+// compareFirmwareVersions returns -1, 0 or 1. Missing components count as
+// zero, so 1.4 equals 1.4.0.
+func compareFirmwareVersions(a, b []int) int {
+	for i := 0; i < len(a) || i < len(b); i++ {
+		var x, y int
+		if i < len(a) {
+			x = a[i]
+		}
+		if i < len(b) {
+			y = b[i]
+		}
+		if x != y {
+			if x < y {
+				return -1
+			}
+			return 1
+		}
+	}
+	return 0
+}
@@ -0,0 +1,293 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"CampusMonitorAPI/internal/logger"
+	"CampusMonitorAPI/internal/models"
+	"CampusMonitorAPI/internal/repository"
+
+	"github.com/google/uuid"
+)
+
+// ErrInvalidReportSchedule wraps validation failures for new schedules.
+var ErrInvalidReportSchedule = errors.New("invalid report schedule")
+
+// maxReportRange bounds how far back a scheduled report may look.
+const maxReportRange = 90 * 24 * time.Hour
+
+// ReportScheduler stores report schedules and, every interval, generates the
+// analytics report for each one that is due, saves it and posts it to the
+// schedule's webhook.
+type ReportScheduler struct {
+	repo             *repository.ReportScheduleRepository
+	analyticsService *AnalyticsService
+	client           *http.Client
+	guard            *webhookGuard
+	interval         time.Duration
+	log              *logger.Logger
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// NewReportScheduler runs due schedules every interval. Webhooks may only
+// target public addresses or those in allowedNetworks (IPs or CIDRs).
+func NewReportScheduler(repo *repository.ReportScheduleRepository, analyticsService *AnalyticsService, interval time.Duration, allowedNetworks []string, log *logger.Logger) *ReportScheduler {
+	ctx, cancel := context.WithCancel(context.Background())
+	guard := newWebhookGuard(allowedNetworks)
+
+	return &ReportScheduler{
+		repo:             repo,
+		analyticsService: analyticsService,
+		client:           guard.client(30 * time.Second),
+		guard:            guard,
+		interval:         interval,
+		log:              log,
+		ctx:              ctx,
+		cancel:           cancel,
+	}
+}
+
+func (s *ReportScheduler) Create(ctx context.Context, req *models.CreateReportScheduleRequest) (*models.ReportSchedule, error) {
+	schedule := &models.ReportSchedule{
+		ID:         uuid.New().String(),
+		Name:       req.Name,
+		Cron:       req.Cron,
+		StartAt:    req.StartAt,
+		Range:      req.Range,
+		TopN:       req.TopN,
+		TargetType: req.TargetType,
+		TargetURL:  req.TargetURL,
+		Enabled:    req.Enabled == nil || *req.Enabled,
+	}
+	if schedule.TopN == 0 {
+		schedule.TopN = 10
+	}
+	if schedule.TargetType == "" {
+		schedule.TargetType = models.ReportTargetNone
+	}
+	if err := validateReportSchedule(schedule); err != nil {
+		return nil, err
+	}
+	if schedule.TargetType == models.ReportTargetWebhook {
+		u, _ := url.Parse(schedule.TargetURL)
+		if err := s.guard.checkHost(ctx, u.Hostname()); err != nil {
+			return nil, fmt.Errorf("%w: target_url: %v", ErrInvalidReportSchedule, err)
+		}
+	}
+
+	if schedule.Enabled {
+		next := s.nextRun(schedule, time.Now().UTC())
+		schedule.NextRun = &next
+	}
+
+	s.log.Info("Creating report schedule %q (%s, range %s)", schedule.Name, schedule.Cron, schedule.Range)
+	if err := s.repo.Create(ctx, schedule); err != nil {
+		return nil, err
+	}
+	return schedule, nil
+}
+
+func validateReportSchedule(s *models.ReportSchedule) error {
+	if s.Name == "" {
+		return fmt.Errorf("%w: name is required", ErrInvalidReportSchedule)
+	}
+	switch s.Cron {
+	case "@hourly", "@daily", "@weekly":
+	default:
+		return fmt.Errorf("%w: cron must be @hourly, @daily or @weekly", ErrInvalidReportSchedule)
+	}
+	r, err := time.ParseDuration(s.Range)
+	if err != nil || r <= 0 || r > maxReportRange {
+		return fmt.Errorf("%w: range must be a duration between 1s and %v", ErrInvalidReportSchedule, maxReportRange)
+	}
+	if s.TopN < 1 || s.TopN > 100 {
+		return fmt.Errorf("%w: top_n must be between 1 and 100", ErrInvalidReportSchedule)
+	}
+	switch s.TargetType {
+	case models.ReportTargetNone:
+	case models.ReportTargetWebhook:
+		u, err := url.Parse(s.TargetURL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("%w: target_url must be an http(s) URL", ErrInvalidReportSchedule)
+		}
+	default:
+		return fmt.Errorf("%w: target_type must be none or webhook", ErrInvalidReportSchedule)
+	}
+	return nil
+}
+
+func (s *ReportScheduler) List(ctx context.Context) ([]models.ReportSchedule, error) {
+	return s.repo.List(ctx)
+}
+
+func (s *ReportScheduler) Get(ctx context.Context, id string) (*models.ReportSchedule, error) {
+	return s.repo.GetByID(ctx, id)
+}
+
+// Delete removes a schedule together with its stored reports.
+func (s *ReportScheduler) Delete(ctx context.Context, id string) error {
+	s.log.Info("Deleting report schedule %s", id)
+	return s.repo.Delete(ctx, id)
+}
+
+func (s *ReportScheduler) ListReports(ctx context.Context, scheduleID string, limit int) ([]models.GeneratedReport, error) {
+	if _, err := s.repo.GetByID(ctx, scheduleID); err != nil {
+		return nil, err
+	}
+	if limit <= 0 || limit > 200 {
+		limit = 50
+	}
+	return s.repo.ListReports(ctx, scheduleID, limit)
+}
+
+func (s *ReportScheduler) GetReport(ctx context.Context, id int) (*models.GeneratedReport, error) {
+	return s.repo.GetReport(ctx, id)
+}
+
+// Start checks for due schedules every interval. A non-positive interval
+// disables scheduled reports.
+func (s *ReportScheduler) Start() {
+	if s.interval <= 0 {
+		s.log.Info("Scheduled reports disabled")
+		return
+	}
+
+	s.log.Info("Starting report scheduler every %v", s.interval)
+	s.wg.Add(1)
+	go s.run()
+}
+
+func (s *ReportScheduler) Shutdown() {
+	s.cancel()
+	s.wg.Wait()
+	s.log.Info("Report scheduler stopped")
+}
+
+func (s *ReportScheduler) run() {
+	defer s.wg.Done()
+
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-s.ctx.Done():
+			return
+		case <-ticker.C:
+			s.runDue()
+		}
+	}
+}
+
+func (s *ReportScheduler) runDue() {
+	now := time.Now().UTC()
+
+	schedules, err := s.repo.ListDue(s.ctx, now)
+	if err != nil {
+		s.log.Error("Failed to load due report schedules: %v", err)
+		return
+	}
+
+	for i := range schedules {
+		if s.ctx.Err() != nil {
+			return
+		}
+		s.execute(&schedules[i], now)
+	}
+}
+
+// execute generates, stores and delivers one report, then advances the
+// schedule. The schedule advances even when generation fails so a broken
+// schedule doesn't retry on every tick.
+func (s *ReportScheduler) execute(schedule *models.ReportSchedule, now time.Time) {
+	ctx, cancel := context.WithTimeout(s.ctx, 2*time.Minute)
+	defer cancel()
+
+	next := s.nextRun(schedule, now)
+	defer func() {
+		if err := s.repo.UpdateRun(ctx, schedule.ID, now, &next); err != nil {
+			s.log.Error("Failed to advance report schedule %s: %v", schedule.ID, err)
+		}
+	}()
+
+	rangeDur, err := time.ParseDuration(schedule.Range)
+	if err != nil {
+		s.log.Error("Report schedule %s has invalid range %q: %v", schedule.ID, schedule.Range, err)
+		return
+	}
+	end := now
+	start := end.Add(-rangeDur)
+
+	report, err := s.analyticsService.BuildReport(ctx, start, end, schedule.TopN)
+	if err != nil {
+		s.log.Error("Failed to generate report for schedule %s: %v", schedule.ID, err)
+		return
+	}
+	payload, err := json.Marshal(report)
+	if err != nil {
+		s.log.Error("Failed to encode report for schedule %s: %v", schedule.ID, err)
+		return
+	}
+
+	generated := &models.GeneratedReport{
+		ScheduleID:     schedule.ID,
+		RangeStart:     start,
+		RangeEnd:       end,
+		DeliveryStatus: models.ReportDeliveryStored,
+		Payload:        payload,
+	}
+	if schedule.TargetType == models.ReportTargetWebhook {
+		if err := s.deliver(ctx, schedule, payload); err != nil {
+			s.log.Warn("Failed to deliver report for schedule %s: %v", schedule.ID, err)
+			generated.DeliveryStatus = models.ReportDeliveryFailed
+			generated.DeliveryError = err.Error()
+		} else {
+			generated.DeliveryStatus = models.ReportDeliveryDelivered
+		}
+	}
+
+	if err := s.repo.SaveReport(ctx, generated); err != nil {
+		s.log.Error("Failed to store report for schedule %s: %v", schedule.ID, err)
+		return
+	}
+	s.log.Info("Generated report %d for schedule %s (%s)", generated.ID, schedule.ID, generated.DeliveryStatus)
+}
+
+func (s *ReportScheduler) deliver(ctx context.Context, schedule *models.ReportSchedule, payload []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, schedule.TargetURL, bytes.NewReader(payload))
+	if err != nil {
+		return fmt.Errorf("failed to build webhook request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Report-Schedule", schedule.ID)
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("webhook request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook returned %s", resp.Status)
+	}
+	return nil
+}
+
+func (s *ReportScheduler) nextRun(schedule *models.ReportSchedule, after time.Time) time.Time {
+	base := after
+	if schedule.StartAt != nil {
+		base = schedule.StartAt.UTC()
+	}
+	return nextRecurrence(schedule.Cron, base, after)
+}
@@ -190,8 +190,14 @@ func (s *ScheduleService) computeNextRecurring(task *models.ScheduledTask, after
 	if task.Schedule.ExecuteAt != nil {
 		base = *task.Schedule.ExecuteAt
 	}
+	return nextRecurrence(task.Schedule.Cron, base, after)
+}
 
-	switch task.Schedule.Cron {
+// nextRecurrence returns the first occurrence of cron ("@hourly", "@daily" or
+// "@weekly") after `after`. base supplies the time of day and weekday for the
+// daily and weekly forms; anything else falls back to 24 hours later.
+func nextRecurrence(cron string, base, after time.Time) time.Time {
+	switch cron {
 	case "@hourly":
 		// Next whole hour after `after`
 		next := time.Date(after.Year(), after.Month(), after.Day(), after.Hour()+1, 0, 0, 0, time.UTC)
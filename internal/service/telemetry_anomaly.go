@@ -0,0 +1,193 @@
+package service
+
+import (
+	"math"
+	"sync"
+	"time"
+
+	"CampusMonitorAPI/internal/models"
+)
+
+const (
+	// anomalyAlpha weights each new reading in the running mean and
+	// variance, so the baseline follows roughly the last 20 readings.
+	anomalyAlpha = 0.1
+
+	// anomalyWarmup is how many readings a metric needs before it is judged.
+	anomalyWarmup = 20
+
+	// anomalyMaxProbes bounds the tracker; the probe seen least recently is
+	// forgotten to make room for a new one.
+	anomalyMaxProbes = 5000
+)
+
+// anomalyMinStdDev keeps a metric that barely moves from flagging every
+// small wobble: deviations are measured against at least this spread.
+var anomalyMinStdDev = map[string]float64{
+	"rssi":        2,
+	"latency":     5,
+	"packet_loss": 1,
+}
+
+// runningStat is an exponentially weighted mean and variance, constant in
+// size however many readings it has seen.
+type runningStat struct {
+	mean     float64
+	variance float64
+	count    int
+}
+
+// observe scores x against the readings seen so far, then folds it in.
+// The score is 0 until the stat has warmed up.
+func (s *runningStat) observe(x, minStdDev float64) (expected, deviation float64) {
+	expected = s.mean
+	if s.count >= anomalyWarmup {
+		deviation = math.Abs(x-s.mean) / math.Max(math.Sqrt(s.variance), minStdDev)
+	}
+
+	if s.count == 0 {
+		s.mean = x
+	} else {
+		diff := x - s.mean
+		incr := anomalyAlpha * diff
+		s.mean += incr
+		s.variance = (1 - anomalyAlpha) * (s.variance + diff*incr)
+	}
+	s.count++
+	return expected, deviation
+}
+
+type probeAnomalyState struct {
+	metrics  map[string]*runningStat
+	lastSeen time.Time
+}
+
+// anomalyTracker flags readings that deviate sharply from their probe's
+// recent baseline, as the ingest-time counterpart of DetectAnomalies.
+type anomalyTracker struct {
+	mu        sync.Mutex
+	threshold float64
+	probes    map[string]*probeAnomalyState
+}
+
+func newAnomalyTracker(threshold float64) *anomalyTracker {
+	return &anomalyTracker{
+		threshold: threshold,
+		probes:    make(map[string]*probeAnomalyState),
+	}
+}
+
+// check updates the probe's baselines with telemetry and returns the
+// metrics that deviated by threshold standard deviations or more.
+func (t *anomalyTracker) check(telemetry *models.Telemetry) []models.AnomalyDetection {
+	type reading struct {
+		metric string
+		value  float64
+	}
+	var values []reading
+	if telemetry.RSSI != nil {
+		values = append(values, reading{"rssi", float64(*telemetry.RSSI)})
+	}
+	if telemetry.Latency != nil {
+		values = append(values, reading{"latency", float64(*telemetry.Latency)})
+	}
+	if telemetry.PacketLoss != nil {
+		values = append(values, reading{"packet_loss", *telemetry.PacketLoss})
+	}
+	if len(values) == 0 {
+		return nil
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	state, ok := t.probes[telemetry.ProbeID]
+	if !ok {
+		if len(t.probes) >= anomalyMaxProbes {
+			t.evictOldest()
+		}
+		state = &probeAnomalyState{metrics: make(map[string]*runningStat)}
+		t.probes[telemetry.ProbeID] = state
+	}
+	state.lastSeen = time.Now()
+
+	var anomalies []models.AnomalyDetection
+	for _, r := range values {
+		metric, value := r.metric, r.value
+		stat, ok := state.metrics[metric]
+		if !ok {
+			stat = &runningStat{}
+			state.metrics[metric] = stat
+		}
+
+		expected, deviation := stat.observe(value, anomalyMinStdDev[metric])
+		if deviation < t.threshold {
+			continue
+		}
+
+		a := models.AnomalyDetection{
+			ProbeID:       telemetry.ProbeID,
+			Timestamp:     telemetry.Timestamp,
+			MetricType:    metric,
+			Value:         value,
+			ExpectedValue: expected,
+			Deviation:     deviation,
+		}
+		if deviation > 4 {
+			a.Severity = "critical"
+		} else if deviation > 3 {
+			a.Severity = "high"
+		} else {
+			a.Severity = "medium"
+		}
+		anomalies = append(anomalies, a)
+	}
+	return anomalies
+}
+
+// evictOldest drops the probe seen least recently. The caller must hold mu.
+func (t *anomalyTracker) evictOldest() {
+	var oldestID string
+	var oldest time.Time
+	for id, state := range t.probes {
+		if oldestID == "" || state.lastSeen.Before(oldest) {
+			oldestID, oldest = id, state.lastSeen
+		}
+	}
+	delete(t.probes, oldestID)
+}
+
+// EnableAnomalyFlagging marks readings that deviate from their probe's
+// running baseline by threshold standard deviations or more, setting
+// metadata.anomaly and metadata.anomaly_metrics before they are stored.
+// With broadcast, a TELEMETRY_ANOMALY message carrying the anomalies is
+// also sent over the WebSocket. A non-positive threshold leaves flagging
+// off. Call it once, before messages arrive.
+func (s *TelemetryService) EnableAnomalyFlagging(threshold float64, broadcast bool) {
+	if threshold <= 0 {
+		return
+	}
+	s.anomalies = newAnomalyTracker(threshold)
+	s.broadcastAnomalies = broadcast
+	s.log.Info("Ingest anomaly flagging enabled at %.1f standard deviations", threshold)
+}
+
+// flagAnomalies records any anomalies in telemetry's metadata and returns
+// them.
+func (s *TelemetryService) flagAnomalies(telemetry *models.Telemetry) []models.AnomalyDetection {
+	anomalies := s.anomalies.check(telemetry)
+	if len(anomalies) == 0 {
+		return nil
+	}
+
+	metrics := make([]string, len(anomalies))
+	for i, a := range anomalies {
+		metrics[i] = a.MetricType
+	}
+	if telemetry.Metadata == nil {
+		telemetry.Metadata = make(map[string]interface{})
+	}
+	telemetry.Metadata["anomaly"] = true
+	telemetry.Metadata["anomaly_metrics"] = metrics
+	return anomalies
+}
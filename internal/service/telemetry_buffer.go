@@ -0,0 +1,128 @@
+package service
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"CampusMonitorAPI/internal/models"
+)
+
+// telemetryBuffer holds parsed telemetry waiting for a batch insert.
+type telemetryBuffer struct {
+	mu       sync.Mutex
+	pending  []models.Telemetry
+	interval time.Duration
+	maxSize  int
+
+	flushNow chan struct{}
+	stop     chan struct{}
+	done     chan struct{}
+}
+
+// EnableWriteBuffer switches ProcessMessage from one insert per message to
+// a write-behind buffer, flushed with InsertBatch every interval or as soon
+// as maxSize readings are waiting. A non-positive interval keeps immediate
+// inserts. Call it once, before messages arrive, and call Shutdown on exit
+// so buffered readings are written.
+func (s *TelemetryService) EnableWriteBuffer(interval time.Duration, maxSize int) {
+	if interval <= 0 {
+		return
+	}
+	if maxSize < 1 {
+		maxSize = 1
+	}
+
+	s.buffer = &telemetryBuffer{
+		interval: interval,
+		maxSize:  maxSize,
+		flushNow: make(chan struct{}, 1),
+		stop:     make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+	s.log.Info("Telemetry write buffer enabled: flush every %v or at %d readings", interval, maxSize)
+	go s.runWriteBuffer()
+}
+
+// Shutdown stops the write buffer, if enabled, after a final flush.
+func (s *TelemetryService) Shutdown() {
+	if s.buffer == nil {
+		return
+	}
+	close(s.buffer.stop)
+	<-s.buffer.done
+	s.log.Info("Telemetry write buffer flushed and stopped")
+}
+
+// bufferTelemetry queues a reading and wakes the flusher when the buffer is
+// full. The reading is copied, so the caller may keep using telemetry.
+func (s *TelemetryService) bufferTelemetry(telemetry *models.Telemetry) {
+	b := s.buffer
+	b.mu.Lock()
+	b.pending = append(b.pending, *telemetry)
+	full := len(b.pending) >= b.maxSize
+	b.mu.Unlock()
+
+	if full {
+		select {
+		case b.flushNow <- struct{}{}:
+		default:
+		}
+	}
+}
+
+func (s *TelemetryService) runWriteBuffer() {
+	b := s.buffer
+	defer close(b.done)
+
+	ticker := time.NewTicker(b.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-b.stop:
+			s.flushTelemetry()
+			return
+		case <-ticker.C:
+			s.flushTelemetry()
+		case <-b.flushNow:
+			s.flushTelemetry()
+		}
+	}
+}
+
+// flushTelemetry writes everything buffered in one transaction. If the batch
+// fails, e.g. because one reading is rejected, the readings are retried one
+// by one so a single bad row doesn't lose the rest.
+func (s *TelemetryService) flushTelemetry() {
+	b := s.buffer
+	b.mu.Lock()
+	batch := b.pending
+	b.pending = nil
+	b.mu.Unlock()
+
+	if len(batch) == 0 {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	err := s.telemetryRepo.InsertBatch(ctx, batch)
+	if err == nil {
+		s.log.Debug("Flushed %d buffered telemetry readings", len(batch))
+		return
+	}
+
+	s.log.Warn("Batch insert of %d telemetry readings failed, inserting individually: %v", len(batch), err)
+	failed := 0
+	for i := range batch {
+		if err := s.telemetryRepo.Insert(ctx, &batch[i]); err != nil {
+			failed++
+			s.log.Error("Failed to insert buffered telemetry for probe %s: %v", batch[i].ProbeID, err)
+		}
+	}
+	if failed > 0 {
+		s.log.Error("Dropped %d of %d buffered telemetry readings", failed, len(batch))
+	}
+}
@@ -0,0 +1,66 @@
+package service
+
+import (
+	"fmt"
+
+	"CampusMonitorAPI/internal/models"
+)
+
+// TelemetryParser turns a decoded telemetry payload into a Telemetry row.
+type TelemetryParser func(data map[string]interface{}) (*models.Telemetry, error)
+
+// maxTelemetryTypeLen matches the telemetry.type column width.
+const maxTelemetryTypeLen = 10
+
+// commonTelemetryKeys are the payload keys the light and enhanced parsers map
+// onto Telemetry columns; anything else is kept in metadata by the generic
+// parser.
+var commonTelemetryKeys = map[string]bool{
+	"pid": true, "type": true, "epoch": true, "v": true,
+	"rssi": true, "lat": true, "loss": true, "dns": true, "ch": true,
+	"cong": true, "bssid": true, "neighbors": true, "overlap": true,
+	"snr": true, "qual": true, "util": true, "phy": true, "tput": true,
+	"noise": true, "up": true,
+}
+
+// RegisterParser adds or replaces the parser used for a telemetry type.
+// Call it during startup, before MQTT messages start flowing.
+func (s *TelemetryService) RegisterParser(telemetryType string, parser TelemetryParser) {
+	s.parsers[telemetryType] = parser
+}
+
+func (s *TelemetryService) parserFor(telemetryType string) TelemetryParser {
+	if parser, ok := s.parsers[telemetryType]; ok {
+		return parser
+	}
+	return func(data map[string]interface{}) (*models.Telemetry, error) {
+		return s.parseGenericTelemetry(telemetryType, data)
+	}
+}
+
+// parseGenericTelemetry handles types without a registered parser, so a
+// firmware release adding a message type is stored rather than dropped. The
+// recognised fields fill their columns and the rest goes into metadata.
+func (s *TelemetryService) parseGenericTelemetry(telemetryType string, data map[string]interface{}) (*models.Telemetry, error) {
+	if len(telemetryType) > maxTelemetryTypeLen {
+		return nil, fmt.Errorf("telemetry type %q exceeds %d characters", telemetryType, maxTelemetryTypeLen)
+	}
+
+	telemetry, err := s.parseEnhancedTelemetry(data)
+	if err != nil {
+		return nil, err
+	}
+	telemetry.Type = telemetryType
+
+	for key, val := range data {
+		if commonTelemetryKeys[key] {
+			continue
+		}
+		if telemetry.Metadata == nil {
+			telemetry.Metadata = make(map[string]interface{})
+		}
+		telemetry.Metadata[key] = val
+	}
+
+	return telemetry, nil
+}
@@ -0,0 +1,53 @@
+package service
+
+import (
+	"fmt"
+	"math"
+)
+
+// currentTelemetrySchema is the payload schema version assumed when a
+// payload carries no "v" field.
+const currentTelemetrySchema = 1
+
+// telemetrySchema describes how payloads of one schema version are read.
+// When firmware changes what a field means, add its version to
+// telemetrySchemas with the conversion back to the stored interpretation,
+// so readings from every firmware generation stay comparable.
+type telemetrySchema struct {
+	// packetLossScale converts the payload's "loss" to a percentage.
+	packetLossScale float64
+}
+
+var telemetrySchemas = map[int]telemetrySchema{
+	1: {packetLossScale: 1},
+}
+
+// telemetrySchemaOf returns the schema version a payload declares in "v"
+// and how to read it. Versions this server doesn't know are read as the
+// current schema, with known reporting false.
+func telemetrySchemaOf(data map[string]interface{}) (version int, schema telemetrySchema, known bool, err error) {
+	version = currentTelemetrySchema
+	if raw, ok := data["v"]; ok {
+		v, isNum := raw.(float64)
+		if !isNum || v != math.Trunc(v) || v < 1 {
+			return 0, telemetrySchema{}, false, fmt.Errorf("invalid schema version %v", raw)
+		}
+		version = int(v)
+	}
+
+	schema, known = telemetrySchemas[version]
+	if !known {
+		schema = telemetrySchemas[currentTelemetrySchema]
+	}
+	return version, schema, known, nil
+}
+
+// warnUnknownSchema logs the first payload seen with an unknown schema
+// version, so a new firmware generation is noticed without flooding the log.
+func (s *TelemetryService) warnUnknownSchema(version int, probeID string) {
+	if _, seen := s.unknownSchemas.LoadOrStore(version, true); seen {
+		return
+	}
+	s.log.Warn("Telemetry schema version %d from %s is unknown; reading it as version %d",
+		version, probeID, currentTelemetrySchema)
+}
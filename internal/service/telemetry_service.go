@@ -4,32 +4,49 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"sync"
 	"time"
 
 	"CampusMonitorAPI/internal/logger"
 	"CampusMonitorAPI/internal/models"
 	"CampusMonitorAPI/internal/repository"
+	"CampusMonitorAPI/internal/websocket"
 )
 
 type TelemetryService struct {
 	telemetryRepo *repository.TelemetryRepository
 	probeRepo     *repository.ProbeRepository
 	alertEval     IAlertEvaluator
+	hub           *websocket.Hub
+	parsers       map[string]TelemetryParser
 	log           *logger.Logger
+	// buffer is nil unless EnableWriteBuffer turned on batched inserts.
+	buffer *telemetryBuffer
+	// anomalies is nil unless EnableAnomalyFlagging turned it on.
+	anomalies          *anomalyTracker
+	broadcastAnomalies bool
+	// unknownSchemas records the unknown schema versions already warned about.
+	unknownSchemas sync.Map
 }
 
 func NewTelemetryService(
 	telemetryRepo *repository.TelemetryRepository,
 	probeRepo *repository.ProbeRepository,
 	alertEval IAlertEvaluator,
+	hub *websocket.Hub,
 	log *logger.Logger,
 ) *TelemetryService {
-	return &TelemetryService{
+	s := &TelemetryService{
 		telemetryRepo: telemetryRepo,
 		probeRepo:     probeRepo,
 		alertEval:     alertEval,
+		hub:           hub,
+		parsers:       make(map[string]TelemetryParser),
 		log:           log,
 	}
+	s.RegisterParser("light", s.parseLightTelemetry)
+	s.RegisterParser("enhanced", s.parseEnhancedTelemetry)
+	return s
 }
 
 func (s *TelemetryService) ProcessMessage(ctx context.Context, payload []byte) error {
@@ -46,26 +63,14 @@ func (s *TelemetryService) ProcessMessage(ctx context.Context, payload []byte) e
 		return fmt.Errorf("missing probe_id")
 	}
 
-	// Auto-register unknown probes
-	_, err := s.probeRepo.GetByID(ctx, probeID)
+	// Auto-register unknown probes. AutoDiscover is a no-op if the probe
+	// already exists, so two first messages racing each other both succeed.
+	probe, err := s.probeRepo.GetByID(ctx, probeID)
 	if err != nil {
 		s.log.Info("Unknown probe detected: %s, auto-registering", probeID)
 
-		probe := &models.Probe{
-			ProbeID:         probeID,
-			Location:        "Unknown",
-			Building:        "Unknown",
-			Floor:           "Unknown",
-			Department:      "Unknown",
-			Status:          "unknown",
-			FirmwareVersion: "unknown",
-			LastSeen:        time.Now(),
-		}
-
-		if createErr := s.probeRepo.Create(ctx, probe); createErr != nil {
-			s.log.Error("Failed to auto-register probe: %v", createErr)
-		} else {
-			s.log.Info("Auto-registered probe: %s with status 'unknown'", probeID)
+		if discoverErr := s.probeRepo.AutoDiscover(ctx, probeID); discoverErr != nil {
+			s.log.Error("Failed to auto-register probe: %v", discoverErr)
 		}
 	}
 
@@ -74,18 +79,7 @@ func (s *TelemetryService) ProcessMessage(ctx context.Context, payload []byte) e
 		return fmt.Errorf("missing or invalid 'type' field")
 	}
 
-	var telemetry *models.Telemetry
-	var parseErr error
-
-	switch telemetryType {
-	case "light":
-		telemetry, parseErr = s.parseLightTelemetry(rawData)
-	case "enhanced":
-		telemetry, parseErr = s.parseEnhancedTelemetry(rawData)
-	default:
-		return fmt.Errorf("unknown telemetry type: %s", telemetryType)
-	}
-
+	telemetry, parseErr := s.parserFor(telemetryType)(rawData)
 	if parseErr != nil {
 		s.log.Error("Failed to parse telemetry: %v", parseErr)
 		return parseErr
@@ -93,18 +87,47 @@ func (s *TelemetryService) ProcessMessage(ctx context.Context, payload []byte) e
 
 	telemetry.ReceivedAt = time.Now()
 
-	if err := s.telemetryRepo.Insert(ctx, telemetry); err != nil {
-		s.log.Error("Failed to insert telemetry: %v", err)
-		return err
+	var anomalies []models.AnomalyDetection
+	if s.anomalies != nil {
+		anomalies = s.flagAnomalies(telemetry)
 	}
 
-	s.log.Info("Telemetry stored: probe=%s, type=%s, rssi=%v",
-		telemetry.ProbeID, telemetry.Type, telemetry.RSSI)
+	if s.buffer != nil {
+		s.bufferTelemetry(telemetry)
+		s.log.Debug("Telemetry buffered: probe=%s, type=%s", telemetry.ProbeID, telemetry.Type)
+	} else {
+		if err := s.telemetryRepo.Insert(ctx, telemetry); err != nil {
+			s.log.Error("Failed to insert telemetry: %v", err)
+			return err
+		}
+
+		s.log.Info("Telemetry stored: probe=%s, type=%s, rssi=%v",
+			telemetry.ProbeID, telemetry.Type, telemetry.RSSI)
+	}
 
 	if err := s.probeRepo.UpdateLastSeen(ctx, telemetry.ProbeID, telemetry.Timestamp); err != nil {
 		s.log.Warn("Failed to update probe last_seen: %v", err)
 	}
 
+	if s.alertEval != nil {
+		if err := s.alertEval.Evaluate(ctx, *telemetry); err != nil {
+			s.log.Warn("Failed to evaluate alerts for probe %s: %v", telemetry.ProbeID, err)
+		}
+	}
+
+	// Live telemetry only goes to clients that subscribed to the probe's
+	// building; unscoped dashboards keep receiving alerts and health only.
+	if s.hub != nil && probe != nil && probe.Building != "" && s.hub.HasSubscribers() {
+		s.hub.PublishToBuilding(probe.Building, "TELEMETRY", telemetry)
+	}
+	if len(anomalies) > 0 && s.broadcastAnomalies && s.hub != nil {
+		building := ""
+		if probe != nil {
+			building = probe.Building
+		}
+		s.hub.BroadcastToBuilding(building, "TELEMETRY_ANOMALY", anomalies)
+	}
+
 	return nil
 }
 
@@ -121,11 +144,22 @@ func (s *TelemetryService) parseLightTelemetry(data map[string]interface{}) (*mo
 
 	timestamp := time.Unix(int64(epoch), 0)
 
+	version, schema, known, err := telemetrySchemaOf(data)
+	if err != nil {
+		return nil, err
+	}
+	if !known {
+		s.warnUnknownSchema(version, probeID)
+	}
+
 	telemetry := &models.Telemetry{
 		Timestamp: timestamp,
 		ProbeID:   probeID,
 		Type:      "light",
 	}
+	if _, ok := data["v"]; ok {
+		telemetry.Metadata = map[string]interface{}{"schema_version": version}
+	}
 
 	if val, ok := data["rssi"].(float64); ok {
 		rssi := int(val)
@@ -138,7 +172,8 @@ func (s *TelemetryService) parseLightTelemetry(data map[string]interface{}) (*mo
 	}
 
 	if val, ok := data["loss"].(float64); ok {
-		telemetry.PacketLoss = &val
+		loss := val * schema.packetLossScale
+		telemetry.PacketLoss = &loss
 	}
 
 	if val, ok := data["dns"].(float64); ok {
@@ -173,12 +208,21 @@ func (s *TelemetryService) parseLightTelemetry(data map[string]interface{}) (*mo
 	return telemetry, nil
 }
 
-// RecordDeepScanAsTelemetry converts a deep scan result into a telemetry record
+// RecordDeepScanAsTelemetry converts a deep scan result into a telemetry
+// record and stores the scan's neighbor list in neighbor_aps.
 func (s *TelemetryService) RecordDeepScanAsTelemetry(ctx context.Context, probeID string, result map[string]interface{}) error {
 	s.log.Info("Converting Deep Scan result to Enhanced Telemetry for probe %s", probeID)
+	now := time.Now()
+
+	if neighbors := parseNeighborAPs(probeID, now, result); len(neighbors) > 0 {
+		if err := s.telemetryRepo.InsertNeighborAPs(ctx, neighbors); err != nil {
+			s.log.Error("Failed to store %d neighbor APs for probe %s: %v", len(neighbors), probeID, err)
+		}
+	}
+
 	t := &models.Telemetry{
 		ProbeID:   probeID,
-		Timestamp: time.Now(),
+		Timestamp: now,
 		Type:      "enhanced",
 	}
 	if v, ok := result["rssi"].(float64); ok {
@@ -222,6 +266,46 @@ func (s *TelemetryService) RecordDeepScanAsTelemetry(ctx context.Context, probeI
 	return s.telemetryRepo.Insert(ctx, t)
 }
 
+// parseNeighborAPs reads the deep scan's "neighbors" list (older firmware
+// calls it "networks"). Entries without a BSSID are skipped.
+func parseNeighborAPs(probeID string, ts time.Time, result map[string]interface{}) []models.NeighborAP {
+	list, ok := result["neighbors"].([]interface{})
+	if !ok {
+		list, _ = result["networks"].([]interface{})
+	}
+
+	neighbors := make([]models.NeighborAP, 0, len(list))
+	for _, item := range list {
+		entry, ok := item.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		bssid, _ := entry["bssid"].(string)
+		if bssid == "" {
+			continue
+		}
+
+		n := models.NeighborAP{
+			Timestamp:   ts,
+			SeenByProbe: probeID,
+			BSSID:       bssid,
+		}
+		if v, ok := entry["ssid"].(string); ok {
+			n.SSID = &v
+		}
+		if v, ok := entry["channel"].(float64); ok {
+			ch := int(v)
+			n.Channel = &ch
+		}
+		if v, ok := entry["rssi"].(float64); ok {
+			rssi := int(v)
+			n.RSSI = &rssi
+		}
+		neighbors = append(neighbors, n)
+	}
+	return neighbors
+}
+
 func (s *TelemetryService) parseEnhancedTelemetry(data map[string]interface{}) (*models.Telemetry, error) {
 	telemetry, err := s.parseLightTelemetry(data)
 	if err != nil {
@@ -267,7 +351,7 @@ func (s *TelemetryService) parseEnhancedTelemetry(data map[string]interface{}) (
 func (s *TelemetryService) GetTelemetry(ctx context.Context, req *models.TelemetryQueryRequest) (*models.TelemetryQueryResponse, error) {
 	s.log.Debug("Querying telemetry: probes=%v, type=%s", req.ProbeIDs, req.Type)
 
-	data, totalCount, err := s.telemetryRepo.Query(ctx, req)
+	data, totalCount, typeCounts, err := s.telemetryRepo.Query(ctx, req)
 	if err != nil {
 		return nil, err
 	}
@@ -275,6 +359,7 @@ func (s *TelemetryService) GetTelemetry(ctx context.Context, req *models.Telemet
 	response := &models.TelemetryQueryResponse{
 		Data:       data,
 		TotalCount: totalCount,
+		TypeCounts: typeCounts,
 		Limit:      req.Limit,
 		Offset:     req.Offset,
 	}
@@ -295,6 +380,20 @@ func (s *TelemetryService) GetProbeStats(ctx context.Context, probeID string, ho
 	return stats, nil
 }
 
+func (s *TelemetryService) GetMetricStats(ctx context.Context, probeID, metric string, start, end time.Time) (*models.MetricStats, error) {
+	return s.telemetryRepo.GetMetricStats(ctx, probeID, metric, start, end)
+}
+
+func (s *TelemetryService) CountTelemetry(ctx context.Context, probeID string, start, end time.Time) (*models.TelemetryCount, error) {
+	return s.telemetryRepo.Count(ctx, probeID, start, end)
+}
+
+// GetLatestForProbes returns the latest reading per probe; an empty
+// probeIDs means every probe.
+func (s *TelemetryService) GetLatestForProbes(ctx context.Context, probeIDs []string) (map[string]models.Telemetry, error) {
+	return s.telemetryRepo.GetLatestForProbes(ctx, probeIDs)
+}
+
 func (s *TelemetryService) GetLatestTelemetry(ctx context.Context, probeID string, limit int) ([]models.Telemetry, error) {
 	return s.telemetryRepo.GetLatest(ctx, probeID, limit)
 }
@@ -0,0 +1,195 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"CampusMonitorAPI/internal/logger"
+	"CampusMonitorAPI/internal/models"
+	"CampusMonitorAPI/internal/repository"
+)
+
+// ingestStore stands in for the probes and telemetry tables that
+// ProcessMessage touches. probe_id is unique, as in Postgres: a second
+// insert of the same probe is an error unless it is an ON CONFLICT DO
+// NOTHING insert, which then writes nothing.
+type ingestStore struct {
+	mu        sync.Mutex
+	probes    map[string]bool
+	inserted  int
+	telemetry int
+	queries   int
+	// lookups, when set, holds each probe lookup until all expected
+	// callers have made one, so none of them sees another's insert.
+	lookups *sync.WaitGroup
+}
+
+type ingestConnector struct{ store *ingestStore }
+
+func (c ingestConnector) Connect(context.Context) (driver.Conn, error) {
+	return ingestConn(c), nil
+}
+func (c ingestConnector) Driver() driver.Driver { return c }
+func (c ingestConnector) Open(string) (driver.Conn, error) {
+	return ingestConn(c), nil
+}
+
+type ingestConn struct{ store *ingestStore }
+
+func (c ingestConn) Prepare(string) (driver.Stmt, error) {
+	return nil, errors.New("prepare not supported")
+}
+func (c ingestConn) Close() error { return nil }
+func (c ingestConn) Begin() (driver.Tx, error) {
+	return nil, errors.New("transactions not supported")
+}
+
+func (c ingestConn) QueryContext(_ context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	if !strings.Contains(query, "FROM probes") {
+		return nil, fmt.Errorf("unexpected query: %s", query)
+	}
+	probeID := args[0].Value.(string)
+	c.store.mu.Lock()
+	known := c.store.probes[probeID]
+	c.store.queries++
+	c.store.mu.Unlock()
+	if c.store.lookups != nil {
+		c.store.lookups.Done()
+		c.store.lookups.Wait()
+	}
+
+	if !known {
+		return &alertRows{columns: make([]string, 12)}, nil
+	}
+	now := time.Now()
+	return &alertRows{
+		columns: make([]string, 12),
+		rows: [][]driver.Value{{
+			probeID, "unknown", "unknown", "unknown", "unknown",
+			"unknown", "unknown", int64(0), now, now, now, nil,
+		}},
+	}, nil
+}
+
+func (c ingestConn) ExecContext(_ context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	c.store.mu.Lock()
+	defer c.store.mu.Unlock()
+
+	switch {
+	case strings.Contains(query, "INSERT INTO probes"):
+		probeID := args[0].Value.(string)
+		if c.store.probes[probeID] {
+			if strings.Contains(query, "ON CONFLICT (probe_id) DO NOTHING") {
+				return driver.RowsAffected(0), nil
+			}
+			return nil, errors.New(`pq: duplicate key value violates unique constraint "probes_pkey"`)
+		}
+		c.store.probes[probeID] = true
+		c.store.inserted++
+		return driver.RowsAffected(1), nil
+	case strings.Contains(query, "INSERT INTO telemetry"):
+		c.store.telemetry++
+		return driver.RowsAffected(1), nil
+	case strings.Contains(query, "UPDATE probes"):
+		return driver.RowsAffected(1), nil
+	}
+	return nil, fmt.Errorf("unexpected statement: %s", query)
+}
+
+// newIngestService returns a TelemetryService backed by store whose
+// warnings and errors are written to the returned log file.
+func newIngestService(t *testing.T, store *ingestStore, eval IAlertEvaluator) (*TelemetryService, string) {
+	t.Helper()
+	logPath := filepath.Join(t.TempDir(), "ingest.log")
+	log, err := logger.New(logger.Config{Level: logger.WARN, LogFilePath: logPath})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { log.Close() })
+
+	db := sql.OpenDB(ingestConnector{store: store})
+	t.Cleanup(func() { db.Close() })
+
+	return NewTelemetryService(repository.NewTelemetryRepository(db), repository.NewProbeRepository(db), eval, nil, log), logPath
+}
+
+func lightTelemetry(probeID string, rssi, latency int) []byte {
+	return []byte(fmt.Sprintf(`{"pid":%q,"type":"light","epoch":%d,"rssi":%d,"lat":%d}`,
+		probeID, time.Now().Unix(), rssi, latency))
+}
+
+func TestProcessMessageConcurrentUnknownProbe(t *testing.T) {
+	const callers = 2
+	var lookups sync.WaitGroup
+	lookups.Add(callers)
+	store := &ingestStore{probes: map[string]bool{}, lookups: &lookups}
+	svc, logPath := newIngestService(t, store, nil)
+
+	errs := make(chan error, callers)
+	for i := 0; i < callers; i++ {
+		go func() {
+			errs <- svc.ProcessMessage(context.Background(), lightTelemetry("probe-new", -60, 20))
+		}()
+	}
+	for i := 0; i < callers; i++ {
+		if err := <-errs; err != nil {
+			t.Errorf("ProcessMessage: %v", err)
+		}
+	}
+
+	if store.inserted != 1 || !store.probes["probe-new"] {
+		t.Errorf("probe inserts = %d (%v), want exactly one probe-new", store.inserted, store.probes)
+	}
+	if store.telemetry != callers {
+		t.Errorf("telemetry rows = %d, want %d", store.telemetry, callers)
+	}
+
+	logged, err := os.ReadFile(logPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(logged), "Failed to auto-register") {
+		t.Errorf("losing insert was logged as a failure:\n%s", logged)
+	}
+}
+
+func TestProcessMessageFeedsEvaluatorState(t *testing.T) {
+	store := &ingestStore{probes: map[string]bool{"probe-1": true}}
+	alerts := &recordingAlerts{}
+	cfg := models.DEFAULT_ALERT_CONFIG
+	evaluator := NewAlertEvaluator(cfg, alerts)
+	svc, _ := newIngestService(t, store, evaluator)
+
+	if _, ok := evaluator.GetProbeState("probe-1"); ok {
+		t.Fatal("state before any telemetry")
+	}
+
+	for i := 0; i < cfg.RSSIOccurrences; i++ {
+		if err := svc.ProcessMessage(context.Background(), lightTelemetry("probe-1", -90, 20)); err != nil {
+			t.Fatalf("ProcessMessage: %v", err)
+		}
+	}
+
+	state, ok := evaluator.GetProbeState("probe-1")
+	if !ok {
+		t.Fatal("no evaluator state after telemetry was processed")
+	}
+	if len(state.RSSI.Values) != cfg.RSSIOccurrences || !state.RSSI.Full || !state.RSSI.Consistently {
+		t.Errorf("rssi window = %+v, want %d values, full and consistently below", state.RSSI, cfg.RSSIOccurrences)
+	}
+	if state.Latency.Consistently {
+		t.Errorf("latency window = %+v, want not breached", state.Latency)
+	}
+	if got := alerts.count(); got != 1 {
+		t.Errorf("dispatched %d alerts, want 1", got)
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"math"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
@@ -15,8 +16,29 @@ import (
 type TopologyLayout struct {
 	Center    BuildingNode   `json:"center"`
 	Buildings []BuildingNode `json:"buildings"`
+	APs       []APNode       `json:"aps"`
+	Edges     []TopologyEdge `json:"edges"`
 }
 
+// APNode is an access point seen by at least one probe.
+type APNode struct {
+	ID         string `json:"id"` // the BSSID
+	Channel    *int   `json:"channel,omitempty"`
+	ProbeCount int    `json:"probe_count"`
+}
+
+// TopologyEdge links a probe to the AP it is currently associated with.
+type TopologyEdge struct {
+	ProbeID  string    `json:"probe_id"`
+	APID     string    `json:"ap_id"`
+	Channel  *int      `json:"channel,omitempty"`
+	LastSeen time.Time `json:"last_seen"`
+}
+
+// associationWindow is how recent a probe's BSSID report must be to count as
+// its current association.
+const associationWindow = 24 * time.Hour
+
 type BuildingNode struct {
 	ID          string      `json:"id"`
 	Name        string      `json:"name"`
@@ -40,9 +62,16 @@ type FloorNode struct {
 type HeatmapResponse struct {
 	Timestamp   time.Time     `json:"timestamp"`
 	Metric      string        `json:"metric"`
+	Aggregate   string        `json:"aggregate"`
 	HeatmapData []FloorHealth `json:"heatmap_data"`
 }
 
+// How calculateFloorHealth combines the readings of a floor's probes.
+const (
+	AggregateAvg   = "avg"   // mean across probes
+	AggregateWorst = "worst" // the single worst probe decides the floor
+)
+
 type FloorHealth struct {
 	BuildingID   string  `json:"building_id"`
 	FloorID      string  `json:"floor_id"`
@@ -52,6 +81,12 @@ type FloorHealth struct {
 	ActiveAlerts int     `json:"active_alerts"`
 }
 
+// FloorComparison is one floor's health within a building comparison.
+type FloorComparison struct {
+	FloorHealth
+	ProbeCount int `json:"probe_count"`
+}
+
 type FloorDetails struct {
 	Building      string        `json:"building"`
 	Floor         string        `json:"floor"`
@@ -71,25 +106,30 @@ type ProbeDetail struct {
 
 type ITopologyService interface {
 	GetLayout(ctx context.Context) (*TopologyLayout, error)
-	GetHeatmap(ctx context.Context, metric string) (*HeatmapResponse, error)
+	GetHeatmap(ctx context.Context, metric, aggregate string) (*HeatmapResponse, error)
 	GetFloorDetails(ctx context.Context, building string, floor string) (*FloorDetails, error)
+	CompareFloors(ctx context.Context, building, metric, aggregate string) ([]FloorComparison, error)
 }
 
 type TopologyService struct {
 	probeRepo     *repository.ProbeRepository
 	telemetryRepo *repository.TelemetryRepository
 	alertRepo     *repository.AlertRepository
+	// telemetryStale leaves older readings out of floor health.
+	telemetryStale time.Duration
 }
 
 func NewTopologyService(
 	probeRepo *repository.ProbeRepository,
 	telemetryRepo *repository.TelemetryRepository,
 	alertRepo *repository.AlertRepository,
+	thresholds models.Thresholds,
 ) *TopologyService {
 	return &TopologyService{
-		probeRepo:     probeRepo,
-		telemetryRepo: telemetryRepo,
-		alertRepo:     alertRepo,
+		probeRepo:      probeRepo,
+		telemetryRepo:  telemetryRepo,
+		alertRepo:      alertRepo,
+		telemetryStale: thresholds.TelemetryStale,
 	}
 }
 
@@ -169,11 +209,36 @@ func (s *TopologyService) GetLayout(ctx context.Context) (*TopologyLayout, error
 		currentAngle += angleStep
 	}
 
+	associations, err := s.telemetryRepo.GetLatestAssociations(ctx, time.Now().Add(-associationWindow))
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch AP associations: %w", err)
+	}
+
+	layout.APs = []APNode{}
+	layout.Edges = make([]TopologyEdge, 0, len(associations))
+	apIndex := make(map[string]int)
+	for _, a := range associations {
+		idx, ok := apIndex[a.BSSID]
+		if !ok {
+			idx = len(layout.APs)
+			apIndex[a.BSSID] = idx
+			layout.APs = append(layout.APs, APNode{ID: a.BSSID, Channel: a.Channel})
+		}
+		layout.APs[idx].ProbeCount++
+
+		layout.Edges = append(layout.Edges, TopologyEdge{
+			ProbeID:  a.ProbeID,
+			APID:     a.BSSID,
+			Channel:  a.Channel,
+			LastSeen: a.Timestamp,
+		})
+	}
+
 	return layout, nil
 }
 
 // GetHeatmap aggregates telemetry (e.g., RSSI, latency) to calculate color codes for the UI squares.
-func (s *TopologyService) GetHeatmap(ctx context.Context, metric string) (*HeatmapResponse, error) {
+func (s *TopologyService) GetHeatmap(ctx context.Context, metric, aggregate string) (*HeatmapResponse, error) {
 	probes, err := s.probeRepo.GetAll(ctx)
 	if err != nil {
 		return nil, fmt.Errorf("failed to fetch probes for heatmap: %w", err)
@@ -200,12 +265,13 @@ func (s *TopologyService) GetHeatmap(ctx context.Context, metric string) (*Heatm
 	heatmap := &HeatmapResponse{
 		Timestamp:   time.Now(),
 		Metric:      metric,
+		Aggregate:   aggregate,
 		HeatmapData: []FloorHealth{},
 	}
 
 	for bName, floors := range floorProbes {
 		for fName, pIDs := range floors {
-			health := s.calculateFloorHealth(ctx, pIDs, metric)
+			health := s.calculateFloorHealth(ctx, pIDs, metric, aggregate)
 			health.BuildingID = strings.ReplaceAll(strings.ToUpper(bName), " ", "_")
 			health.FloorID = fName
 			heatmap.HeatmapData = append(heatmap.HeatmapData, health)
@@ -270,7 +336,10 @@ func (s *TopologyService) GetFloorDetails(ctx context.Context, building string,
 	return details, nil
 }
 
-func (s *TopologyService) calculateFloorHealth(ctx context.Context, probeIDs []string, metric string) FloorHealth {
+// calculateFloorHealth colours a floor from its probes' latest readings. With
+// AggregateWorst the floor takes the worst probe's value (lowest RSSI, highest
+// latency or loss) so a single dead spot is not averaged away.
+func (s *TopologyService) calculateFloorHealth(ctx context.Context, probeIDs []string, metric, aggregate string) FloorHealth {
 	health := FloorHealth{
 		Status:       "OFFLINE",
 		ColorHex:     "#52525b", // Zinc-600 (Offline/Unknown)
@@ -282,8 +351,7 @@ func (s *TopologyService) calculateFloorHealth(ctx context.Context, probeIDs []s
 		return health
 	}
 
-	var totalValue float64
-	var validReadings int
+	var values []float64
 
 	for _, pid := range probeIDs {
 		// 1. Check for alerts
@@ -293,8 +361,8 @@ func (s *TopologyService) calculateFloorHealth(ctx context.Context, probeIDs []s
 
 		// 2. Fetch recent telemetry from hypertable
 		if tel, err := s.telemetryRepo.GetLatestByProbe(ctx, pid); err == nil && tel != nil {
-			// Skip stale data (older than 15 mins)
-			if time.Since(tel.Timestamp) > 15*time.Minute {
+			// Skip stale data
+			if time.Since(tel.Timestamp) > s.telemetryStale {
 				continue
 			}
 
@@ -302,24 +370,23 @@ func (s *TopologyService) calculateFloorHealth(ctx context.Context, probeIDs []s
 			case "signal", "rssi":
 				// RSSI is usually stored as a negative integer (e.g., -60)
 				if tel.RSSI != nil {
-					totalValue += float64(*tel.RSSI)
-					validReadings++
+					values = append(values, float64(*tel.RSSI))
 				}
 			case "latency":
 				if tel.Latency != nil {
-					totalValue += float64(*tel.Latency)
-					validReadings++
+					values = append(values, float64(*tel.Latency))
 				}
 			case "packet_loss":
-				totalValue += float64(*tel.PacketLoss)
-				validReadings++
+				if tel.PacketLoss != nil {
+					values = append(values, *tel.PacketLoss)
+				}
 			}
 		}
 	}
 
 	// 3. Determine Color and Status based on aggregated metric
-	if validReadings > 0 {
-		health.AverageValue = totalValue / float64(validReadings)
+	if len(values) > 0 {
+		health.AverageValue = aggregateFloorValues(values, metric, aggregate)
 
 		switch metric {
 		case "signal", "rssi":
@@ -364,6 +431,96 @@ func (s *TopologyService) calculateFloorHealth(ctx context.Context, probeIDs []s
 	return health
 }
 
+// CompareFloors scores every floor of a building on metric and returns them
+// worst first. building matches either the probe's building name or the
+// BuildingID used in the layout and heatmap.
+func (s *TopologyService) CompareFloors(ctx context.Context, building, metric, aggregate string) ([]FloorComparison, error) {
+	probes, err := s.probeRepo.GetAll(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch probes for floor comparison: %w", err)
+	}
+
+	buildingID := strings.ReplaceAll(strings.ToUpper(building), " ", "_")
+	floors := make(map[string][]string)
+	for _, p := range probes {
+		bName := p.Building
+		if bName == "" {
+			bName = "Unknown Building"
+		}
+		if bName != building && strings.ReplaceAll(strings.ToUpper(bName), " ", "_") != buildingID {
+			continue
+		}
+		fName := p.Floor
+		if fName == "" {
+			fName = "Ground"
+		}
+		floors[fName] = append(floors[fName], p.ProbeID)
+	}
+
+	comparison := make([]FloorComparison, 0, len(floors))
+	for fName, pIDs := range floors {
+		health := s.calculateFloorHealth(ctx, pIDs, metric, aggregate)
+		health.BuildingID = buildingID
+		health.FloorID = fName
+		comparison = append(comparison, FloorComparison{FloorHealth: health, ProbeCount: len(pIDs)})
+	}
+
+	lowerIsWorse := metric == "signal" || metric == "rssi"
+	sort.Slice(comparison, func(i, j int) bool {
+		a, b := comparison[i], comparison[j]
+		if ra, rb := floorStatusRank(a.Status), floorStatusRank(b.Status); ra != rb {
+			return ra > rb
+		}
+		if a.AverageValue != b.AverageValue {
+			if lowerIsWorse {
+				return a.AverageValue < b.AverageValue
+			}
+			return a.AverageValue > b.AverageValue
+		}
+		if a.ActiveAlerts != b.ActiveAlerts {
+			return a.ActiveAlerts > b.ActiveAlerts
+		}
+		return parseFloorLevel(a.FloorID) < parseFloorLevel(b.FloorID)
+	})
+
+	return comparison, nil
+}
+
+// floorStatusRank orders floor statuses from best (0) to worst.
+func floorStatusRank(status string) int {
+	switch status {
+	case "CRITICAL":
+		return 4
+	case "OFFLINE":
+		return 3
+	case "WARNING":
+		return 2
+	case "UNKNOWN":
+		return 1
+	}
+	return 0
+}
+
+func aggregateFloorValues(values []float64, metric, aggregate string) float64 {
+	if aggregate == AggregateWorst {
+		// Lower is worse for RSSI; higher is worse for latency and loss.
+		lowerIsWorse := metric == "signal" || metric == "rssi"
+		worst := values[0]
+		for _, v := range values[1:] {
+			if (lowerIsWorse && v < worst) || (!lowerIsWorse && v > worst) {
+				worst = v
+			}
+		}
+		return worst
+	}
+
+	var total float64
+	for _, v := range values {
+		total += v
+	}
+	return total / float64(len(values))
+}
+
 func parseFloorLevel(floorStr string) int {
 	lower := strings.ToLower(strings.TrimSpace(floorStr))
 	if strings.Contains(lower, "ground") || lower == "g" {
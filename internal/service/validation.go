@@ -0,0 +1,129 @@
+package service
+
+import (
+	"net/url"
+	"strings"
+
+	"CampusMonitorAPI/internal/models"
+)
+
+// maxProbeIDLength matches the probes.probe_id column.
+const maxProbeIDLength = 50
+
+// validateProbeID checks a probe ID is usable in the column and in the
+// probe's MQTT topics.
+func validateProbeID(ve *models.ValidationError, field, id string) {
+	switch {
+	case id == "":
+		ve.Add(field, "required")
+	case len(id) > maxProbeIDLength:
+		ve.Add(field, "must be at most 50 characters")
+	case strings.ContainsAny(id, "/+# \t\n"):
+		ve.Add(field, "must not contain '/', '+', '#' or whitespace")
+	}
+}
+
+func validateCreateProbe(req *models.CreateProbeRequest) error {
+	ve := &models.ValidationError{}
+	validateProbeID(ve, "probe_id", req.ProbeID)
+	if len(req.FirmwareVersion) > 50 {
+		ve.Add("firmware_version", "must be at most 50 characters")
+	}
+	return ve.Err()
+}
+
+// validateCommandRequest checks the fields and, for the built-in command
+// types, the payload fields the probe needs. Custom commands are passed
+// through unchecked.
+func validateCommandRequest(req *models.CommandRequest) error {
+	ve := &models.ValidationError{}
+	validateProbeID(ve, "probe_id", req.ProbeID)
+	if req.CommandType == "" {
+		ve.Add("command_type", "required")
+	}
+
+	p := req.Payload
+	switch req.CommandType {
+	case "deep_scan":
+		if v, ok := p["duration"]; ok && !positiveNumber(v) {
+			ve.Add("payload.duration", "must be a positive number of seconds")
+		}
+	case "config_update":
+		validateProbeConfig(ve, "payload", p)
+	case "set_wifi":
+		ssid, _ := p["ssid"].(string)
+		if ssid == "" {
+			ve.Add("payload.ssid", "required")
+		} else if len(ssid) > 32 {
+			ve.Add("payload.ssid", "must be at most 32 characters")
+		}
+		if password, _ := p["password"].(string); password == "" {
+			ve.Add("payload.password", "required")
+		}
+	case "set_mqtt":
+		if broker, _ := p["broker"].(string); broker == "" {
+			ve.Add("payload.broker", "required")
+		}
+		if v, ok := p["port"]; ok && !validPort(v) {
+			ve.Add("payload.port", "must be between 1 and 65535")
+		}
+	case "rename_probe":
+		newID, _ := p["new_id"].(string)
+		validateProbeID(ve, "payload.new_id", newID)
+	case "restart":
+		if v, ok := p["delay"]; ok {
+			if n, isNum := v.(float64); !isNum || n < 0 {
+				ve.Add("payload.delay", "must be a non-negative number of milliseconds")
+			}
+		}
+	case "ota_update":
+		raw, _ := p["url"].(string)
+		if raw == "" {
+			ve.Add("payload.url", "required")
+		} else if u, err := url.Parse(raw); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			ve.Add("payload.url", "must be an http(s) URL")
+		}
+	}
+
+	return ve.Err()
+}
+
+// validateProbeConfig checks the probe settings config_update understands.
+// Unknown keys are left alone so newer firmware settings still pass.
+func validateProbeConfig(ve *models.ValidationError, prefix string, config map[string]interface{}) {
+	if v, ok := config["report_interval"]; ok && !positiveNumber(v) {
+		ve.Add(prefix+".report_interval", "must be a positive number of seconds")
+	}
+	if v, ok := config["mqtt_server"]; ok {
+		if s, isStr := v.(string); !isStr || s == "" {
+			ve.Add(prefix+".mqtt_server", "must be a non-empty string")
+		}
+	}
+	if v, ok := config["mqtt_port"]; ok && !validPort(v) {
+		ve.Add(prefix+".mqtt_port", "must be between 1 and 65535")
+	}
+	if v, ok := config["telemetry_topic"]; ok {
+		if s, isStr := v.(string); !isStr || s == "" || strings.ContainsAny(s, "+#") {
+			ve.Add(prefix+".telemetry_topic", "must be a topic without wildcards")
+		}
+	}
+}
+
+func validateTemplate(template *models.FleetConfigTemplate) error {
+	ve := &models.ValidationError{}
+	if strings.TrimSpace(template.Name) == "" {
+		ve.Add("name", "required")
+	}
+	validateProbeConfig(ve, "config", template.Config)
+	return ve.Err()
+}
+
+func positiveNumber(v interface{}) bool {
+	n, ok := v.(float64)
+	return ok && n > 0
+}
+
+func validPort(v interface{}) bool {
+	n, ok := v.(float64)
+	return ok && n >= 1 && n <= 65535 && n == float64(int(n))
+}
@@ -0,0 +1,104 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// webhookGuard keeps report webhooks away from the server's own network:
+// loopback, private, link-local (including cloud metadata endpoints),
+// unspecified and multicast addresses are refused unless they fall inside
+// an explicitly allowed network.
+type webhookGuard struct {
+	allowed []*net.IPNet
+}
+
+// newWebhookGuard parses allowed, a list of IPs or CIDRs. Invalid entries
+// are skipped; the config is validated at startup.
+func newWebhookGuard(allowed []string) *webhookGuard {
+	g := &webhookGuard{}
+	for _, entry := range allowed {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		if !strings.Contains(entry, "/") {
+			if ip := net.ParseIP(entry); ip != nil {
+				bits := 8 * len(ip)
+				g.allowed = append(g.allowed, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			}
+			continue
+		}
+		if _, network, err := net.ParseCIDR(entry); err == nil {
+			g.allowed = append(g.allowed, network)
+		}
+	}
+	return g
+}
+
+// permits reports whether a webhook may be delivered to ip.
+func (g *webhookGuard) permits(ip net.IP) bool {
+	for _, network := range g.allowed {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+	return !(ip.IsLoopback() || ip.IsPrivate() || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() ||
+		ip.IsInterfaceLocalMulticast() || ip.IsMulticast() || ip.IsUnspecified())
+}
+
+// checkHost resolves host and fails if any of its addresses is refused, so
+// a bad target is rejected when the schedule is created.
+func (g *webhookGuard) checkHost(ctx context.Context, host string) error {
+	if ip := net.ParseIP(host); ip != nil {
+		if !g.permits(ip) {
+			return fmt.Errorf("%s is not an allowed webhook address", ip)
+		}
+		return nil
+	}
+
+	addrs, err := net.DefaultResolver.LookupIPAddr(ctx, host)
+	if err != nil {
+		return fmt.Errorf("cannot resolve %s: %w", host, err)
+	}
+	for _, addr := range addrs {
+		if !g.permits(addr.IP) {
+			return fmt.Errorf("%s resolves to %s, which is not an allowed webhook address", host, addr.IP)
+		}
+	}
+	return nil
+}
+
+// client returns an HTTP client that checks every address it actually
+// connects to, including after redirects and DNS changes since the
+// schedule was created. It never uses a proxy, which would hide the target.
+func (g *webhookGuard) client(timeout time.Duration) *http.Client {
+	dialer := &net.Dialer{
+		Timeout: 10 * time.Second,
+		Control: func(network, address string, _ syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			if ip := net.ParseIP(host); ip == nil || !g.permits(ip) {
+				return fmt.Errorf("webhook address %s is not allowed", host)
+			}
+			return nil
+		},
+	}
+
+	return &http.Client{
+		Timeout: timeout,
+		Transport: &http.Transport{
+			DialContext:         dialer.DialContext,
+			TLSHandshakeTimeout: 10 * time.Second,
+			MaxIdleConns:        10,
+			IdleConnTimeout:     90 * time.Second,
+		},
+	}
+}
@@ -1,7 +1,10 @@
 package websocket
 
 import (
+	"encoding/json"
 	"net/http"
+	"strings"
+	"sync"
 	"time"
 
 	"CampusMonitorAPI/internal/logger"
@@ -10,24 +13,118 @@ import (
 )
 
 const (
-	writeWait      = 10 * time.Second
-	pongWait       = 60 * time.Second
-	pingPeriod     = (pongWait * 9) / 10
-	maxMessageSize = 512
+	writeWait  = 10 * time.Second
+	pongWait   = 60 * time.Second
+	pingPeriod = (pongWait * 9) / 10
 )
 
-var upgrader = websocket.Upgrader{
-	ReadBufferSize:  1024,
-	WriteBufferSize: 1024,
-	CheckOrigin: func(r *http.Request) bool {
-		return true
-	},
+// Limits sizes each connection. Zero fields take the DefaultLimits value.
+type Limits struct {
+	// MaxMessageSize is the largest message, in bytes, a client may send.
+	MaxMessageSize int64
+	// SendBuffer is how many outgoing messages may queue for a client
+	// before it is dropped as too slow.
+	SendBuffer int
+	// ReadBuffer and WriteBuffer size the connection's I/O buffers in bytes.
+	ReadBuffer  int
+	WriteBuffer int
+	// BroadcastBuffer is how many broadcasts may queue for the hub before
+	// publishers block.
+	BroadcastBuffer int
 }
 
+// DefaultLimits are the limits used when none are configured.
+var DefaultLimits = Limits{
+	MaxMessageSize:  512,
+	SendBuffer:      256,
+	ReadBuffer:      1024,
+	WriteBuffer:     1024,
+	BroadcastBuffer: 1024,
+}
+
+func (l Limits) withDefaults() Limits {
+	if l.MaxMessageSize <= 0 {
+		l.MaxMessageSize = DefaultLimits.MaxMessageSize
+	}
+	if l.SendBuffer <= 0 {
+		l.SendBuffer = DefaultLimits.SendBuffer
+	}
+	if l.ReadBuffer <= 0 {
+		l.ReadBuffer = DefaultLimits.ReadBuffer
+	}
+	if l.WriteBuffer <= 0 {
+		l.WriteBuffer = DefaultLimits.WriteBuffer
+	}
+	if l.BroadcastBuffer <= 0 {
+		l.BroadcastBuffer = DefaultLimits.BroadcastBuffer
+	}
+	return l
+}
+
+// buildingScopePrefix marks a subscription scope naming a building.
+const buildingScopePrefix = "building:"
+
 type Client struct {
 	hub  *Hub
 	conn *websocket.Conn
 	send chan Message
+	// principal is who the client authenticated as; empty if anonymous.
+	principal string
+
+	mu        sync.RWMutex
+	buildings map[string]bool
+}
+
+// subscriptionRequest is what clients send to narrow their feed, e.g.
+// {"action": "subscribe", "scopes": ["building:LIB-01"]}.
+type subscriptionRequest struct {
+	Action string   `json:"action"`
+	Scopes []string `json:"scopes"`
+}
+
+// handleMessage applies a subscribe or unsubscribe request and ignores
+// anything else.
+func (c *Client) handleMessage(data []byte) {
+	var req subscriptionRequest
+	if err := json.Unmarshal(data, &req); err != nil {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, scope := range req.Scopes {
+		if !strings.HasPrefix(scope, buildingScopePrefix) {
+			continue
+		}
+		building := strings.TrimPrefix(scope, buildingScopePrefix)
+		switch req.Action {
+		case "subscribe":
+			c.buildings[building] = true
+		case "unsubscribe":
+			delete(c.buildings, building)
+		}
+	}
+}
+
+func (c *Client) subscribed() bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return len(c.buildings) > 0
+}
+
+// wants reports whether msg should be delivered to this client. Untagged
+// messages go to everyone; tagged ones go to clients subscribed to that
+// building and, unless subscribersOnly, to clients with no subscriptions.
+func (c *Client) wants(msg Message) bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	if len(c.buildings) == 0 {
+		return !msg.subscribersOnly
+	}
+	if msg.Building == "" {
+		return !msg.subscribersOnly
+	}
+	return c.buildings[msg.Building]
 }
 
 // writePump pumps messages from the hub to the websocket connection.
@@ -57,14 +154,21 @@ func (c *Client) writePump() {
 	}
 }
 
-// ServeWs handles websocket requests from the peer.
-func ServeWs(hub *Hub, w http.ResponseWriter, r *http.Request, log *logger.Logger) {
-	conn, err := upgrader.Upgrade(w, r, nil)
+// ServeWs handles websocket requests from the peer. principal is who the
+// peer authenticated as, or empty for an anonymous connection.
+func ServeWs(hub *Hub, w http.ResponseWriter, r *http.Request, principal string, log *logger.Logger) {
+	conn, err := hub.upgrader.Upgrade(w, r, nil)
 	if err != nil {
 		log.Error("WS Upgrade Error: %v", err)
 		return
 	}
-	client := &Client{hub: hub, conn: conn, send: make(chan Message, 256)}
+	client := &Client{
+		hub:       hub,
+		conn:      conn,
+		send:      make(chan Message, hub.limits.SendBuffer),
+		principal: principal,
+		buildings: make(map[string]bool),
+	}
 	client.hub.register <- client
 	go client.writePump()
 	go func() {
@@ -72,14 +176,15 @@ func ServeWs(hub *Hub, w http.ResponseWriter, r *http.Request, log *logger.Logge
 			client.hub.unregister <- client
 			client.conn.Close()
 		}()
-		client.conn.SetReadLimit(maxMessageSize)
+		client.conn.SetReadLimit(hub.limits.MaxMessageSize)
 		client.conn.SetReadDeadline(time.Now().Add(pongWait))
 		client.conn.SetPongHandler(func(string) error { client.conn.SetReadDeadline(time.Now().Add(pongWait)); return nil })
 		for {
-			_, _, err := client.conn.ReadMessage()
+			_, data, err := client.conn.ReadMessage()
 			if err != nil {
 				break
 			}
+			client.handleMessage(data)
 		}
 	}()
 }
@@ -2,15 +2,59 @@ package websocket
 
 import (
 	"context"
+	"net/http"
+	"sort"
 	"sync"
+	"time"
 
 	"CampusMonitorAPI/internal/logger"
+
+	"github.com/gorilla/websocket"
 )
 
-// Message defines the generic structure for WS communication
+// BatchMessageType wraps several coalesced messages; its payload is the
+// array of messages in the order they were broadcast.
+const BatchMessageType = "BATCH"
+
+// maxBatchSize flushes a batch early so a burst can't build one unbounded
+// frame.
+const maxBatchSize = 100
+
+// PresenceMessageType announces a client joining or leaving; its payload is
+// a PresenceChange.
+const PresenceMessageType = "PRESENCE"
+
+// Presence describes who is connected. Principals lists each authenticated
+// principal once, however many connections it has open.
+type Presence struct {
+	Count      int      `json:"count"`
+	Principals []string `json:"principals,omitempty"`
+}
+
+// PresenceChange is the PRESENCE payload. It carries only the new count, so
+// anonymous dashboards don't learn who else is watching.
+type PresenceChange struct {
+	Event string `json:"event"`
+	Count int    `json:"count"`
+}
+
+// Message is the envelope every WS message is sent in. ID and Timestamp are
+// set by the hub as it takes the message in, so IDs increase in the order
+// messages are delivered; clients can use them to drop duplicates and spot
+// gaps after a reconnect. IDs restart from 1 when the server restarts.
 type Message struct {
-	Type    string      `json:"type"`
-	Payload interface{} `json:"payload"`
+	Type      string      `json:"type"`
+	ID        uint64      `json:"id,omitempty"`
+	Timestamp time.Time   `json:"timestamp"`
+	Payload   interface{} `json:"payload"`
+	// Building tags the message with the probe's building so it can be
+	// routed to clients subscribed to "building:<name>".
+	Building string `json:"building,omitempty"`
+
+	// subscribersOnly skips clients that have no subscriptions.
+	subscribersOnly bool
+	// immediate bypasses batching.
+	immediate bool
 }
 
 type Hub struct {
@@ -20,21 +64,95 @@ type Hub struct {
 	unregister chan *Client
 	log        *logger.Logger
 	mu         sync.RWMutex
+
+	// batchWindow is how long broadcasts are held to be sent together; 0
+	// sends each one as it arrives.
+	batchWindow time.Duration
+	pending     []Message
+
+	// lastID is the ID of the last message taken in; only Run touches it.
+	lastID uint64
+
+	limits   Limits
+	upgrader websocket.Upgrader
 }
 
-func NewHub(log *logger.Logger) *Hub {
+func NewHub(batchWindow time.Duration, limits Limits, log *logger.Logger) *Hub {
+	limits = limits.withDefaults()
 	return &Hub{
-		broadcast:  make(chan Message),
-		register:   make(chan *Client),
-		unregister: make(chan *Client),
-		clients:    make(map[*Client]bool),
-		log:        log,
+		broadcast:   make(chan Message, limits.BroadcastBuffer),
+		register:    make(chan *Client),
+		unregister:  make(chan *Client),
+		clients:     make(map[*Client]bool),
+		log:         log,
+		batchWindow: batchWindow,
+		limits:      limits,
+		upgrader: websocket.Upgrader{
+			ReadBufferSize:  limits.ReadBuffer,
+			WriteBufferSize: limits.WriteBuffer,
+			CheckOrigin: func(r *http.Request) bool {
+				return true
+			},
+		},
 	}
 }
 
 // Run starts the hub logic in a goroutine. It listens for context cancellation for clean shutdown.
 func (h *Hub) Run(ctx context.Context) {
 	h.log.Info("WebSocket Hub started")
+
+	var announce func(event string)
+
+	// unregister removes a client, whether it disconnected or was too slow
+	// to keep up, and tells the others it left.
+	unregister := func(client *Client) {
+		h.mu.Lock()
+		_, ok := h.clients[client]
+		if ok {
+			delete(h.clients, client)
+			close(client.send)
+		}
+		h.mu.Unlock()
+		if ok {
+			announce("leave")
+		}
+	}
+
+	// send delivers messages and unregisters the clients that couldn't
+	// take them.
+	send := func(messages []Message) {
+		for _, client := range h.deliver(messages) {
+			unregister(client)
+		}
+	}
+
+	// flush fires when the oldest pending message has waited batchWindow.
+	var flush <-chan time.Time
+	var timer *time.Timer
+	flushPending := func() {
+		if timer != nil {
+			timer.Stop()
+			timer, flush = nil, nil
+		}
+		if len(h.pending) > 0 {
+			pending := h.pending
+			h.pending = nil
+			send(pending)
+		}
+	}
+
+	// announce tells every client how many are now connected.
+	announce = func(event string) {
+		flushPending()
+		h.lastID++
+		send([]Message{{
+			Type:      PresenceMessageType,
+			ID:        h.lastID,
+			Timestamp: time.Now(),
+			Payload:   PresenceChange{Event: event, Count: h.ClientCount()},
+		}})
+	}
+
 	for {
 		select {
 		case <-ctx.Done():
@@ -45,26 +163,94 @@ func (h *Hub) Run(ctx context.Context) {
 			h.clients[client] = true
 			h.mu.Unlock()
 			h.log.Info("New WS Client connected. Total: %d", len(h.clients))
+			announce("join")
 		case client := <-h.unregister:
-			h.mu.Lock()
-			if _, ok := h.clients[client]; ok {
-				delete(h.clients, client)
-				close(client.send)
-			}
-			h.mu.Unlock()
+			unregister(client)
 		case message := <-h.broadcast:
-			h.mu.RLock()
-			for client := range h.clients {
-				select {
-				case client.send <- message:
-				default:
-					close(client.send)
-					delete(h.clients, client)
-				}
+			h.lastID++
+			message.ID = h.lastID
+			message.Timestamp = time.Now()
+
+			if h.batchWindow <= 0 || message.immediate {
+				// Anything already queued goes first to keep the order.
+				flushPending()
+				send([]Message{message})
+				continue
+			}
+			h.pending = append(h.pending, message)
+			if len(h.pending) >= maxBatchSize {
+				flushPending()
+			} else if timer == nil {
+				timer = time.NewTimer(h.batchWindow)
+				flush = timer.C
+			}
+		case <-flush:
+			timer, flush = nil, nil
+			flushPending()
+		}
+	}
+}
+
+// deliver sends messages to every client that wants them: a lone message
+// as itself and several as one BATCH message. It returns the clients whose
+// buffer was full, for Run to unregister.
+func (h *Hub) deliver(messages []Message) []*Client {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	var slow []*Client
+	for client := range h.clients {
+		wanted := make([]Message, 0, len(messages))
+		for _, m := range messages {
+			if client.wants(m) {
+				wanted = append(wanted, m)
 			}
-			h.mu.RUnlock()
+		}
+
+		var out Message
+		switch len(wanted) {
+		case 0:
+			continue
+		case 1:
+			out = wanted[0]
+		default:
+			// The batch has no ID of its own so the IDs inside stay
+			// contiguous.
+			out = Message{Type: BatchMessageType, Timestamp: time.Now(), Payload: wanted}
+		}
+
+		select {
+		case client.send <- out:
+		default:
+			slow = append(slow, client)
+		}
+	}
+	return slow
+}
+
+// ClientCount reports how many clients are currently connected.
+func (h *Hub) ClientCount() int {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return len(h.clients)
+}
+
+// Presence reports how many clients are connected and which principals
+// they authenticated as, sorted.
+func (h *Hub) Presence() Presence {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	seen := make(map[string]bool)
+	presence := Presence{Count: len(h.clients)}
+	for client := range h.clients {
+		if client.principal != "" && !seen[client.principal] {
+			seen[client.principal] = true
+			presence.Principals = append(presence.Principals, client.principal)
 		}
 	}
+	sort.Strings(presence.Principals)
+	return presence
 }
 
 // Broadcast sends a message to all connected clients
@@ -74,3 +260,50 @@ func (h *Hub) Broadcast(msgType string, payload interface{}) {
 		Payload: payload,
 	}
 }
+
+// BroadcastToBuilding sends a message tagged with building. Clients
+// subscribed to that building receive it, as do clients with no
+// subscriptions, which keep getting everything.
+func (h *Hub) BroadcastToBuilding(building, msgType string, payload interface{}) {
+	h.broadcast <- Message{
+		Type:     msgType,
+		Payload:  payload,
+		Building: building,
+	}
+}
+
+// PublishToBuilding sends a message only to clients subscribed to building.
+// It is meant for high-volume messages such as telemetry that unsubscribed
+// dashboards never asked for.
+func (h *Hub) PublishToBuilding(building, msgType string, payload interface{}) {
+	h.broadcast <- Message{
+		Type:            msgType,
+		Payload:         payload,
+		Building:        building,
+		subscribersOnly: true,
+	}
+}
+
+// BroadcastImmediate is BroadcastToBuilding without batching, for messages
+// such as critical alerts that must not wait for the batch window.
+func (h *Hub) BroadcastImmediate(building, msgType string, payload interface{}) {
+	h.broadcast <- Message{
+		Type:      msgType,
+		Payload:   payload,
+		Building:  building,
+		immediate: true,
+	}
+}
+
+// HasSubscribers reports whether any client is subscribed to a scope, so
+// publishers can skip building messages nobody will receive.
+func (h *Hub) HasSubscribers() bool {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	for client := range h.clients {
+		if client.subscribed() {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,51 @@
+package websocket
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"CampusMonitorAPI/internal/logger"
+)
+
+func TestSlowClientDropAnnouncesLeave(t *testing.T) {
+	log, err := logger.New(logger.Config{Level: logger.FATAL})
+	if err != nil {
+		t.Fatal(err)
+	}
+	hub := NewHub(0, Limits{}, log)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go hub.Run(ctx)
+
+	viewer := &Client{hub: hub, send: make(chan Message, 10)}
+	// Nobody reads slow's unbuffered channel, so its first message drops it.
+	slow := &Client{hub: hub, send: make(chan Message)}
+	hub.register <- viewer
+	hub.register <- slow
+
+	var events []PresenceChange
+	for len(events) < 3 {
+		select {
+		case m := <-viewer.send:
+			if m.Type == PresenceMessageType {
+				events = append(events, m.Payload.(PresenceChange))
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("presence events = %v, want join, join, leave", events)
+		}
+	}
+
+	want := []PresenceChange{{"join", 1}, {"join", 2}, {"leave", 1}}
+	for i := range want {
+		if events[i] != want[i] {
+			t.Errorf("event %d = %+v, want %+v", i, events[i], want[i])
+		}
+	}
+	if _, open := <-slow.send; open {
+		t.Error("dropped client's send channel is still open")
+	}
+	if n := hub.ClientCount(); n != 1 {
+		t.Errorf("ClientCount = %d, want 1", n)
+	}
+}